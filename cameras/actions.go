@@ -0,0 +1,99 @@
+package cameras
+
+import (
+	"strings"
+
+	"controlpanel/shared"
+)
+
+// ActionResult describes the installed version affected by a non-UI action.
+type ActionResult = shared.VideoActionResult
+
+func ensureReleaseCatalog() ([]string, error) {
+	if len(allReleases) == 0 {
+		releases, err := fetchReleases()
+		if err != nil {
+			return nil, err
+		}
+		allReleases = releases
+	}
+	return allReleases, nil
+}
+
+// moduleActions returns the install actions for the current install directory.
+func moduleActions() shared.VideoModuleActions {
+	return shared.VideoModuleActions{
+		Module:            "cameras",
+		InstallDir:        installDir,
+		DownloadURLPrefix: downloadURLPrefix,
+		BinaryName:        camerasExeName(),
+		ConfigArtifacts:   []string{"config.toml", "config"},
+		ExtractConfig:     runExtractConfig,
+		EnsureFFmpeg: func() error {
+			_, err := shared.EnsureFFmpegPrerequisite(nil)
+			return err
+		},
+	}
+}
+
+// GetAllInstalledVersions returns all installed versions sorted by semver descending.
+func GetAllInstalledVersions() []string {
+	return getAllInstalledVersions()
+}
+
+// ListAvailableReleases returns the releases published on GitHub, newest first.
+func ListAvailableReleases() ([]string, error) {
+	releases, err := ensureReleaseCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), releases...), nil
+}
+
+// ResolveInstallRelease resolves a GitHub release selector for a clean install.
+func ResolveInstallRelease(selector string) (string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.EqualFold(selector, "latest") {
+		if _, err := ensureReleaseCatalog(); err != nil {
+			return "", err
+		}
+		return getMostRecentStableRelease()
+	}
+	return selector, nil
+}
+
+// ResolveUpdateRelease resolves a GitHub release selector for an update target.
+func ResolveUpdateRelease(selector, fromVersion string) (string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector != "" && !strings.EqualFold(selector, "latest") {
+		return selector, nil
+	}
+	if _, err := ensureReleaseCatalog(); err != nil {
+		return "", err
+	}
+	if containsPreReleaseTag(fromVersion) {
+		return getMostRecentPrerelease()
+	}
+	return getMostRecentStableRelease()
+}
+
+// InstallRelease downloads the cameras binary, extracts its editable config
+// files and makes sure FFmpeg is available, without any UI.
+func InstallRelease(downloadVersion, installVersion string, progress shared.ProgressCallback) (ActionResult, error) {
+	return moduleActions().InstallRelease(downloadVersion, installVersion, progress)
+}
+
+// UpdateRelease downloads targetVersion and copies the configuration from existingVersion.
+func UpdateRelease(existingVersion, targetVersion string, progress shared.ProgressCallback, cancel <-chan bool) (ActionResult, error) {
+	return moduleActions().UpdateRelease(existingVersion, targetVersion, progress, cancel)
+}
+
+// ImportDataAndConfig copies config.toml and config/ from sourceVersion to destVersion.
+func ImportDataAndConfig(sourceVersion, destVersion string) (ActionResult, error) {
+	return moduleActions().ImportDataAndConfig(sourceVersion, destVersion)
+}
+
+// RemoveInstalledVersion removes an installed cameras version directory.
+func RemoveInstalledVersion(version string) error {
+	return moduleActions().RemoveInstalledVersion(version)
+}
@@ -76,6 +76,37 @@ func InitEnv() error {
 	return nil
 }
 
+// keepRunningEnv is the env.properties key that leaves cameras running when the
+// control panel closes.
+const keepRunningEnv = "CAMERAS_KEEP_RUNNING"
+
+// GetKeepRunning returns true if cameras should keep running after the control panel closes.
+func GetKeepRunning() bool {
+	props, err := properties.LoadFile(filepath.Join(installDir, "env.properties"), properties.UTF8)
+	if err != nil {
+		return false
+	}
+	value, _ := props.Get(keepRunningEnv)
+	value = strings.TrimSpace(strings.ToLower(value))
+	return value == "1" || value == "true" || value == "yes" || value == "on"
+}
+
+// SetKeepRunning persists the keep-running setting in env.properties.
+func SetKeepRunning(enabled bool) error {
+	if err := InitEnv(); err != nil {
+		return err
+	}
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	if err := shared.SavePropertyToFile(filepath.Join(installDir, "env.properties"), keepRunningEnv, value); err != nil {
+		return err
+	}
+	environment.Set(keepRunningEnv, value)
+	return nil
+}
+
 func getPortForRelease(version string) string {
 	if strings.TrimSpace(version) == "" {
 		return ""
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"controlpanel/shared"
 
@@ -17,6 +18,8 @@ import (
 var (
 	camerasPIDFile = filepath.Join(getInstallDir(), "cameras.pid")
 	replaysPIDFile = filepath.Join(getInstallDir(), "replays.pid")
+	// activeRuntime is the recorded cameras process, whether launched or reattached.
+	activeRuntime *shared.RuntimeMetadata
 )
 
 func replaysInstallDir() string {
@@ -62,18 +65,24 @@ func replaysExeName() string {
 	}
 }
 
-func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
+// prepareCamerasLaunch checks the cameras binary, provisions FFmpeg and runs the
+// extract bootstrap when needed. A nil window skips the FFmpeg progress dialog.
+func prepareCamerasLaunch(version string, w fyne.Window) (*exec.Cmd, error) {
 	versionDir := filepath.Join(installDir, version)
 	configDir := versionDir
 	exePath := filepath.Join(versionDir, camerasExeName())
 
 	if _, err := os.Stat(exePath); os.IsNotExist(err) {
-		return fmt.Errorf("cameras binary not found: %s", exePath)
+		return nil, fmt.Errorf("cameras binary not found: %s", exePath)
+	}
+	if err := shared.CheckConfigBeforeLaunch("cameras", installDir, version); err != nil {
+		return nil, err
 	}
 
 	// Ensure FFmpeg is available (download if needed)
-	if _, err := shared.EnsureFFmpegPrerequisite(w); err != nil {
-		return fmt.Errorf("FFmpeg prerequisite: %w", err)
+	ffmpegPath, err := shared.EnsureFFmpegPrerequisite(w)
+	if err != nil {
+		return nil, fmt.Errorf("FFmpeg prerequisite: %w", err)
 	}
 
 	// Make executable on Linux
@@ -86,7 +95,7 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 	if shared.ShouldRunVideoExtract(versionDir, "cameras") {
 		log.Printf("Running cameras extract bootstrap for %s", versionDir)
 		if err := shared.RunVideoExtractBootstrap(exePath, versionDir); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -95,12 +104,179 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 
 	logPath := filepath.Join(versionDir, "logs", "cameras.log")
 	if err := shared.ResetLogFile(logPath); err != nil {
-		return fmt.Errorf("failed to reset cameras log: %w", err)
+		return nil, fmt.Errorf("failed to reset cameras log: %w", err)
+	}
+
+	env, err := shared.InjectSecrets(shared.BuildVideoLaunchEnv(versionDir))
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = env
+	camerasFFmpegWarning = shared.CheckVideoFFmpeg(ffmpegPath, cmd.Env)
+	return cmd, nil
+}
+
+func runtimeMetadataPath() string {
+	return filepath.Join(installDir, "cameras-run.json")
+}
+
+// RuntimeMetadataPath returns the cameras runtime metadata path.
+func RuntimeMetadataPath() string {
+	return runtimeMetadataPath()
+}
+
+// PIDFilePath returns the cameras PID file path.
+func PIDFilePath() string {
+	return camerasPIDFile
+}
+
+func clearRuntimeState() {
+	activeRuntime = nil
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear cameras runtime metadata: %v", err)
+	}
+}
+
+// GetLastRunVersion returns the version recorded in the runtime metadata, or empty string.
+func GetLastRunVersion() string {
+	metadata, err := shared.LoadRuntimeMetadata(runtimeMetadataPath())
+	if err != nil || metadata == nil {
+		return ""
+	}
+	return metadata.Version
+}
+
+func recordCamerasStart(pid int, version string, daemon bool) *shared.RuntimeMetadata {
+	if err := os.WriteFile(camerasPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		log.Printf("Failed to write cameras PID file: %v", err)
+	}
+	emitVideoEvent("cameras", shared.EventStarted, version, "", pid, "")
+	metadata, err := shared.WriteRuntimeMetadata(runtimeMetadataPath(), pid, version, "", daemon)
+	if err != nil {
+		log.Printf("Failed to write cameras runtime metadata: %v", err)
+		return nil
+	}
+	return metadata
+}
+
+// emitVideoEvent reports a lifecycle event of a cameras or replays process.
+func emitVideoEvent(module, event, version, port string, pid int, detail string) {
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: event, Module: module, Version: version, Port: port, PID: pid, Detail: detail})
+}
+
+// prepareHeadlessLaunch initializes env.properties and refuses to start a
+// second cameras process when the PID file points to a live one.
+func prepareHeadlessLaunch(version string) (*exec.Cmd, error) {
+	if err := InitEnv(); err != nil {
+		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	if pid, _, err := shared.ResolvePIDFromFileOrPort(camerasPIDFile, ""); err == nil && pid > 0 {
+		return nil, fmt.Errorf("cameras is already running (PID %d)", pid)
+	}
+	return prepareCamerasLaunch(version, nil)
+}
+
+// waitForCamerasStartup checks that the process survives its first seconds;
+// cameras has no port to probe.
+func waitForCamerasStartup(version string, pid int) error {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if !shared.IsProcessRunning(pid) {
+			return fmt.Errorf("cameras %s (PID %d) exited during startup", version, pid)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
+
+// LaunchDaemon starts cameras headlessly (no UI) in daemon mode.
+// FFmpeg is downloaded first if it is not installed.
+func LaunchDaemon(version string) error {
+	log.Printf("LaunchDaemon: starting cameras %s headlessly", version)
+
+	cmd, err := prepareHeadlessLaunch(version)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureNoConsoleWindow(cmd)
+	shared.ConfigureDetachedDaemonProcess(cmd, true)
+
+	logPath := filepath.Join(cmd.Dir, "logs", "cameras-console.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Printf("Warning: could not open cameras console log: %v", err)
+	} else {
+		// Pass the file descriptor directly so the child does not hold a pipe
+		// to a control panel that exits right after the launch.
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+	}
+
+	log.Printf("LaunchDaemon: command %v in %s", cmd.Args, cmd.Dir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start cameras %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordCamerasStart(pid, version, true)
+	if err := waitForCamerasStartup(version, pid); err != nil {
+		return err
 	}
+	emitVideoEvent("cameras", shared.EventReady, version, "", pid, "")
+	log.Printf("LaunchDaemon: cameras %s running (PID %d)", version, pid)
+	return nil
+}
+
+// LaunchForeground starts cameras from the command line and blocks until it exits.
+func LaunchForeground(version string) error {
+	log.Printf("LaunchForeground: starting cameras %s", version)
 
-	cmd.Env = shared.BuildVideoLaunchEnv(versionDir)
+	cmd, err := prepareHeadlessLaunch(version)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureNoConsoleWindow(cmd)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 
-	log.Printf("Starting cameras %s: %s", version, exePath)
+	log.Printf("LaunchForeground: command %v in %s", cmd.Args, cmd.Dir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start cameras %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordCamerasStart(pid, version, false)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
+	if err := waitForCamerasStartup(version, pid); err != nil {
+		log.Printf("LaunchForeground: %v", err)
+	} else {
+		emitVideoEvent("cameras", shared.EventReady, version, "", pid, "")
+		fmt.Printf("cameras %s started successfully\n", version)
+	}
+
+	waitErr := cmd.Wait()
+	emitVideoEvent("cameras", shared.ExitEvent(waitErr, false), version, "", pid, shared.EventDetail(waitErr))
+	os.Remove(camerasPIDFile)
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear cameras runtime metadata: %v", err)
+	}
+	if waitErr == nil {
+		log.Printf("LaunchForeground: cameras %s exited normally", version)
+		return nil
+	}
+	return waitErr
+}
+
+func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
+	versionDir := filepath.Join(installDir, version)
+	cmd, err := prepareCamerasLaunch(version, w)
+	if err != nil {
+		return err
+	}
+	keepRunning := GetKeepRunning()
+	shared.ConfigureDetachedDaemonProcess(cmd, keepRunning)
+
+	log.Printf("Starting cameras %s: %s", version, cmd.Path)
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start cameras %s: %w", version, err)
 	}
@@ -109,12 +285,11 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 	camerasVersion = version
 
 	pid := cmd.Process.Pid
-	if err := os.WriteFile(camerasPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
-		log.Printf("Failed to write cameras PID file: %v", err)
-	}
+	activeRuntime = recordCamerasStart(pid, version, keepRunning)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 
 	if statusLabel != nil {
-		statusLabel.SetText(fmt.Sprintf("Cameras %s running (PID: %d)", version, pid))
+		statusLabel.SetText(runningStatusText("Cameras", version, pid, camerasFFmpegWarning))
 	}
 	cameraStopButton.SetText(fmt.Sprintf("Stop Cameras %s", version))
 	cameraStopButton.Show()
@@ -142,9 +317,11 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 			log.Printf("Cameras %s (PID: %d) exited normally\n", version, pid)
 		}
 
+		emitVideoEvent("cameras", shared.ExitEvent(err, killedByUs), version, "", pid, shared.EventDetail(err))
 		camerasProcess = nil
 		killedByUs = false
 		os.Remove(camerasPIDFile)
+		clearRuntimeState()
 		cameraStopButton.Hide()
 		updateStopContainer()
 
@@ -158,7 +335,7 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 			checkForNewerVersion()
 		} else {
 			if statusLabel != nil {
-				statusLabel.SetText(fmt.Sprintf("Replays %s running (PID: %d)", replaysVersion, replaysProcess.Process.Pid))
+				statusLabel.SetText(runningStatusText("Replays", replaysVersion, replaysProcess.Process.Pid, replaysFFmpegWarning))
 			}
 		}
 	}()
@@ -175,9 +352,13 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 	if _, err := os.Stat(exePath); os.IsNotExist(err) {
 		return fmt.Errorf("replays binary not found: %s", exePath)
 	}
+	if err := shared.CheckConfigBeforeLaunch("replays", replaysInstallDir(), version); err != nil {
+		return err
+	}
 
 	// Ensure FFmpeg is available (download if needed)
-	if _, err := shared.EnsureFFmpegPrerequisite(w); err != nil {
+	ffmpegPath, err := shared.EnsureFFmpegPrerequisite(w)
+	if err != nil {
 		return fmt.Errorf("FFmpeg prerequisite: %w", err)
 	}
 
@@ -203,7 +384,12 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 		return fmt.Errorf("failed to reset replays log: %w", err)
 	}
 
-	cmd.Env = shared.BuildVideoLaunchEnv(versionDir)
+	env, err := shared.InjectSecrets(shared.BuildVideoLaunchEnv(versionDir))
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	replaysFFmpegWarning = shared.CheckVideoFFmpeg(ffmpegPath, cmd.Env)
 
 	if targetPort != "" && shared.CheckPort(targetPort) == nil {
 		log.Printf("Replays port %s is in use, attempting to free it...", targetPort)
@@ -227,9 +413,10 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 	if err := os.WriteFile(replaysPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
 		log.Printf("Failed to write replays PID file: %v", err)
 	}
+	emitVideoEvent("replays", shared.EventStarted, version, targetPort, pid, "")
 
 	if statusLabel != nil {
-		statusLabel.SetText(fmt.Sprintf("Replays %s running (PID: %d)", version, pid))
+		statusLabel.SetText(runningStatusText("Replays", version, pid, replaysFFmpegWarning))
 	}
 	replaysStopButton.SetText(fmt.Sprintf("Stop Replays %s", version))
 	replaysStopButton.Show()
@@ -250,6 +437,7 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 			log.Printf("Replays %s (PID: %d) exited normally\n", version, pid)
 		}
 
+		emitVideoEvent("replays", shared.ExitEvent(err, killedByUs), version, targetPort, pid, shared.EventDetail(err))
 		replaysProcess = nil
 		killedByUs = false
 		os.Remove(replaysPIDFile)
@@ -266,7 +454,7 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 			checkForNewerVersion()
 		} else {
 			if statusLabel != nil {
-				statusLabel.SetText(fmt.Sprintf("Cameras %s running (PID: %d)", camerasVersion, camerasProcess.Process.Pid))
+				statusLabel.SetText(runningStatusText("Cameras", camerasVersion, camerasProcess.Process.Pid, camerasFFmpegWarning))
 			}
 		}
 	}()
@@ -274,6 +462,41 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 	return nil
 }
 
+func restoreCamerasRunningUI(version string, pid int) {
+	camerasVersion = version
+	if statusLabel != nil {
+		statusLabel.SetText(runningStatusText("Cameras", version, pid, camerasFFmpegWarning))
+		statusLabel.Show()
+	}
+	cameraStopButton.SetText(fmt.Sprintf("Stop Cameras %s", version))
+	cameraStopButton.Show()
+	updateStopContainer()
+	setVideoTabModeRunning()
+	configureCamerasRunLinks(version, filepath.Join(installDir, version))
+}
+
+func reconnectCamerasRuntime() bool {
+	metadata, running := shared.CheckDaemonRunning(runtimeMetadataPath())
+	if !running {
+		clearRuntimeState()
+		return false
+	}
+
+	activeRuntime = metadata
+	restoreCamerasRunningUI(metadata.Version, metadata.PID)
+	return true
+}
+
+// ReconnectRuntime attaches the tab to a process started outside of it, such
+// as by a launch profile, unless the tab already tracks a running process.
+// Must be called on the UI thread.
+func ReconnectRuntime() {
+	if IsRunning() {
+		return
+	}
+	reconnectCamerasRuntime()
+}
+
 func configureCamerasRunLinks(version, versionDir string) {
 	if camerasDirLink != nil {
 		camerasDirLink.SetText(fmt.Sprintf("Open Cameras %s configuration directory", version))
@@ -324,6 +547,16 @@ func configureReplaysRunLinks(version, versionDir string) {
 	}
 }
 
+// runningStatusText describes a running video process in the tab, with the
+// FFmpeg warning found when it was launched.
+func runningStatusText(module, version string, pid int, ffmpegWarning string) string {
+	text := fmt.Sprintf("%s %s running (PID: %d)", module, version, pid)
+	if ffmpegWarning != "" {
+		text += "\nWarning: " + ffmpegWarning
+	}
+	return text
+}
+
 func hideAllRunLinks() {
 	if appDirLink != nil {
 		appDirLink.Hide()
@@ -20,15 +20,24 @@ func stopCamerasProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 		statusLabel.SetText(fmt.Sprintf("Stopping cameras %s...", curVersion))
 	}
 
-	if curProcess == nil || curProcess.Process == nil {
-		return
-	}
-	pid := curProcess.Process.Pid
+	var pid int
 	killedByUs = true
-
-	if err := shared.StopOwnedProcess(curProcess, 10*time.Second); err != nil {
+	if curProcess != nil && curProcess.Process != nil {
+		pid = curProcess.Process.Pid
+		if err := shared.StopOwnedProcess(curProcess, 10*time.Second); err != nil {
+			killedByUs = false
+			dialog.ShowError(fmt.Errorf("failed to stop cameras %s (PID: %d): %w", curVersion, pid, err), w)
+			return
+		}
+	} else if activeRuntime != nil {
+		pid = activeRuntime.PID
+		if err := shared.StopPIDFileOrPortProcess(camerasPIDFile, ""); err != nil {
+			killedByUs = false
+			dialog.ShowError(fmt.Errorf("failed to stop cameras %s (PID: %d): %w", curVersion, pid, err), w)
+			return
+		}
+	} else {
 		killedByUs = false
-		dialog.ShowError(fmt.Errorf("failed to stop cameras %s (PID: %d): %w", curVersion, pid, err), w)
 		return
 	}
 
@@ -36,8 +45,10 @@ func stopCamerasProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 	if statusLabel != nil {
 		statusLabel.SetText(fmt.Sprintf("Cameras %s stopped", curVersion))
 	}
+	attached := curProcess == nil
 	camerasProcess = nil
 	os.Remove(camerasPIDFile)
+	clearRuntimeState()
 	if stopBtn != nil {
 		stopBtn.Hide()
 	}
@@ -46,6 +57,11 @@ func stopCamerasProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 	downloadContainer.Show()
 	versionContainer.Show()
 	hideAllRunLinks()
+	if attached {
+		// No wait goroutine restores the UI for a reattached process.
+		killedByUs = false
+		setVideoTabMode(w)
+	}
 }
 
 func stopReplaysProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget.Button, w fyne.Window) {
@@ -107,7 +123,7 @@ func updateStopContainer() {
 	if stopContainer == nil {
 		return
 	}
-	if camerasProcess == nil && replaysProcess == nil {
+	if camerasProcess == nil && replaysProcess == nil && activeRuntime == nil {
 		stopContainer.Hide()
 	} else {
 		stopContainer.Show()
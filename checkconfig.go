@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+)
+
+// portKeys are the env.properties keys holding the port of each module.
+var portKeys = map[string]string{
+	"owlcms":  "OWLCMS_PORT",
+	"tracker": "TRACKER_PORT",
+	"firmata": "FIRMATA_PORT",
+}
+
+// runCheckConfigCommand executes --check-config: it checks every
+// env.properties and config.toml of the current instance and the shared
+// modules, prints the problems as file:line: severity: message, and fails
+// when one of them is an error.
+func runCheckConfigCommand(out io.Writer) error {
+	// The runtime finders log every candidate they look at.
+	log.SetOutput(io.Discard)
+
+	issues, err := shared.CheckEnvFile(shared.ControlPanelModule, shared.ControlPanelEnvPath())
+	if err != nil {
+		return err
+	}
+	var ports []shared.ModulePort
+	for _, module := range []struct {
+		name string
+		dir  string
+	}{{"owlcms", owlcms.GetInstallDir()}, {"tracker", tracker.GetInstallDir()}, {"firmata", firmata.GetInstallDir()}} {
+		parentEnvPath := filepath.Join(module.dir, "env.properties")
+		parentIssues, err := shared.CheckEnvFile(module.name, parentEnvPath)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, parentIssues...)
+
+		for _, version := range installedVersionDirectories(module.dir) {
+			versionIssues, err := shared.CheckModuleVersionConfig(module.name, module.dir, version, true)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, versionIssues...)
+
+			location, err := shared.LocateEnvValue(module.name, portKeys[module.name], parentEnvPath, filepath.Join(module.dir, version, "env.properties"))
+			if err != nil {
+				return err
+			}
+			if location.Value != "" {
+				ports = append(ports, shared.ModulePort{Module: module.name, Version: version, Port: location.Value, File: location.File, Line: location.Line})
+			}
+		}
+	}
+
+	for _, module := range []struct {
+		name string
+		dir  string
+	}{{"cameras", cameras.GetInstallDir()}, {"replays", replays.GetInstallDir()}} {
+		for _, version := range installedVersionDirectories(module.dir) {
+			configPath := filepath.Join(module.dir, version, "config.toml")
+			tomlIssues, err := shared.CheckVideoConfigTOML(configPath)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, tomlIssues...)
+			if module.name != "replays" {
+				continue
+			}
+			if port, err := shared.ReadTopLevelTOMLValue(configPath, "port"); err == nil && port != "" {
+				ports = append(ports, shared.ModulePort{Module: module.name, Version: version, Port: port, File: configPath})
+			}
+		}
+	}
+
+	registry, err := shared.LoadPortRegistry()
+	if err != nil {
+		return err
+	}
+	issues = append(issues, shared.CheckPortConflicts(ports, registry, shared.CurrentInstanceName())...)
+
+	shared.SortConfigIssues(issues)
+	for _, issue := range issues {
+		fmt.Fprintln(out, issue)
+	}
+	errorCount := shared.CountConfigErrors(issues)
+	warningCount := len(issues) - errorCount
+	if len(issues) == 0 {
+		fmt.Fprintln(out, "No configuration problems found")
+		return nil
+	}
+	fmt.Fprintf(out, "%d error(s), %d warning(s)\n", errorCount, warningCount)
+	if errorCount > 0 {
+		return fmt.Errorf("%d configuration error(s)", errorCount)
+	}
+	return nil
+}
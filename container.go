@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+)
+
+// containerReadyTimeout bounds the wait for a module to listen on its port,
+// including the first start of OWLCMS creating its database.
+const containerReadyTimeout = 3 * time.Minute
+
+// containerStopTimeout bounds the wait for a stopped module's launcher to return.
+const containerStopTimeout = 30 * time.Second
+
+// containerConfig is the --container configuration, read from the environment.
+type containerConfig struct {
+	Modules        []string // in start order: tracker before owlcms
+	OwlcmsVersion  string
+	TrackerVersion string
+	OwlcmsPort     string
+	TrackerPort    string
+	TrackerURL     string
+	OwlcmsZip      string
+	TrackerZip     string
+	MQTT           bool
+}
+
+func (c containerConfig) runs(module string) bool {
+	for _, m := range c.Modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// loadContainerConfig reads the container settings:
+//
+//	CONTROLPANEL_MODULES  owlcms, tracker or owlcms,tracker (default owlcms)
+//	OWLCMS_VERSION        version to run, or latest (default)
+//	TRACKER_VERSION       version to run, or latest (default)
+//	OWLCMS_PORT           OWLCMS port
+//	TRACKER_PORT          Tracker port
+//	OWLCMS_TRACKER_URL    ws://host:port/ws of a Tracker outside the container
+//	OWLCMS_RELEASE_ZIP    release ZIP to install instead of downloading from GitHub
+//	TRACKER_RELEASE_ZIP   release ZIP to install instead of downloading from GitHub
+//	OWLCMS_EMBEDDED_MQTT  true to start the embedded MQTT broker
+func loadContainerConfig(getenv func(string) string) (containerConfig, error) {
+	value := func(key string) string { return strings.TrimSpace(getenv(key)) }
+	cfg := containerConfig{
+		OwlcmsVersion:  defaultVersion(value("OWLCMS_VERSION")),
+		TrackerVersion: defaultVersion(value("TRACKER_VERSION")),
+		OwlcmsPort:     value("OWLCMS_PORT"),
+		TrackerPort:    value("TRACKER_PORT"),
+		TrackerURL:     value("OWLCMS_TRACKER_URL"),
+		OwlcmsZip:      value("OWLCMS_RELEASE_ZIP"),
+		TrackerZip:     value("TRACKER_RELEASE_ZIP"),
+		MQTT:           strings.EqualFold(value("OWLCMS_EMBEDDED_MQTT"), "true"),
+	}
+
+	modules := value("CONTROLPANEL_MODULES")
+	if modules == "" {
+		modules = "owlcms"
+	}
+	selected := map[string]bool{}
+	for _, module := range strings.Split(modules, ",") {
+		module = strings.ToLower(strings.TrimSpace(module))
+		switch module {
+		case "":
+		case "owlcms", "tracker":
+			selected[module] = true
+		default:
+			return cfg, fmt.Errorf("CONTROLPANEL_MODULES: unsupported module %q (use owlcms and/or tracker)", module)
+		}
+	}
+	for _, module := range []string{"tracker", "owlcms"} {
+		if selected[module] {
+			cfg.Modules = append(cfg.Modules, module)
+		}
+	}
+	if len(cfg.Modules) == 0 {
+		return cfg, fmt.Errorf("CONTROLPANEL_MODULES selects no module")
+	}
+
+	for key, port := range map[string]string{"OWLCMS_PORT": cfg.OwlcmsPort, "TRACKER_PORT": cfg.TrackerPort} {
+		if n, err := strconv.Atoi(port); port != "" && (err != nil || n < 1 || n > 65535) {
+			return cfg, fmt.Errorf("%s: invalid port %q", key, port)
+		}
+	}
+	if cfg.TrackerURL != "" && cfg.runs("tracker") {
+		return cfg, fmt.Errorf("OWLCMS_TRACKER_URL is for a Tracker outside the container; remove it or tracker from CONTROLPANEL_MODULES")
+	}
+	return cfg, nil
+}
+
+// containerModule is a module launched in the foreground by --container.
+type containerModule struct {
+	name    string
+	version string
+	done    chan struct{} // closed when the launcher returns
+	err     error
+}
+
+// runContainer is --container: it installs the configured versions on first
+// start, runs them in the foreground as PID 1 would, and stops them in order
+// on SIGTERM. It returns the process exit code.
+func runContainer(opts cliOptions) int {
+	// Containers collect stdout; nothing is written to control-panel.log.
+	log.SetOutput(shared.NewLogPathShorteningWriter(os.Stdout))
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+	log.Printf("Starting OWLCMS Control Panel %s in container mode", shared.GetLauncherVersion())
+
+	cfg, err := loadContainerConfig(os.Getenv)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return 2
+	}
+	cfg.MQTT = cfg.MQTT || opts.mqtt
+
+	// As PID 1 the default action of SIGTERM is to ignore it, so it must be
+	// caught even while the first start downloads the releases.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigChan)
+	signaled := func() bool {
+		select {
+		case sig := <-sigChan:
+			log.Printf("Signal %v caught during startup", sig)
+			return true
+		default:
+			return false
+		}
+	}
+
+	if os.Getpid() == 1 {
+		stopReaper := shared.StartZombieReaper(isContainerModulePID)
+		defer stopReaper()
+	}
+
+	versions := map[string]string{}
+	for _, module := range cfg.Modules {
+		version, err := prepareContainerModule(cfg, module, os.Stdout)
+		if err != nil {
+			log.Printf("ERROR: %s: %v", module, err)
+			return 1
+		}
+		versions[module] = version
+		if signaled() {
+			return 0
+		}
+	}
+	if cfg.runs("owlcms") {
+		if err := wireContainerTracker(cfg, versions["owlcms"], versions["tracker"]); err != nil {
+			log.Printf("ERROR: %v", err)
+			return 1
+		}
+	}
+
+	var running []*containerModule
+	stopAll := func() {
+		// Stop in reverse start order: OWLCMS before the Tracker it uses.
+		for i := len(running) - 1; i >= 0; i-- {
+			m := running[i]
+			select {
+			case <-m.done:
+				continue
+			default:
+			}
+			log.Printf("Stopping %s %s", m.name, m.version)
+			if err := stopModule(m.name, os.Stdout); err != nil {
+				log.Printf("Stopping %s: %v", m.name, err)
+			}
+			select {
+			case <-m.done:
+			case <-time.After(containerStopTimeout):
+				log.Printf("%s did not stop within %v", m.name, containerStopTimeout)
+			}
+		}
+		shared.FlushEvents(eventFlushTimeout)
+	}
+
+	exited := make(chan *containerModule, len(cfg.Modules))
+	for _, module := range cfg.Modules {
+		launched := time.Now()
+		m := launchContainerModule(module, versions[module], cfg.MQTT)
+		running = append(running, m)
+		go func() {
+			<-m.done
+			exited <- m
+		}()
+
+		// OWLCMS starts once the Tracker it connects to is listening.
+		select {
+		case sig := <-sigChan:
+			log.Printf("Signal %v caught while starting %s", sig, module)
+			stopAll()
+			return 0
+		case <-m.done:
+			log.Printf("ERROR: %s %s exited before becoming ready: %v", module, m.version, m.err)
+			stopAll()
+			return 1
+		case err := <-waitContainerModuleReady(module, launched):
+			if err != nil {
+				log.Printf("ERROR: %v", err)
+				stopAll()
+				return 1
+			}
+		}
+	}
+	log.Printf("Container modules ready: %s", strings.Join(cfg.Modules, ", "))
+
+	select {
+	case sig := <-sigChan:
+		log.Printf("Signal %v caught, stopping %s", sig, strings.Join(cfg.Modules, ", "))
+		stopAll()
+		return 0
+	case m := <-exited:
+		log.Printf("%s %s exited (%v); stopping the container", m.name, m.version, m.err)
+		stopAll()
+		if m.err != nil {
+			return 1
+		}
+		return 0
+	}
+}
+
+// prepareContainerModule returns the installed version to run, installing it
+// from the release ZIP or GitHub when missing, with its Java or Node.js
+// runtime, and applies the configured port.
+func prepareContainerModule(cfg containerConfig, module string, out io.Writer) (string, error) {
+	requested, zipPath, port := cfg.OwlcmsVersion, cfg.OwlcmsZip, cfg.OwlcmsPort
+	if module == "tracker" {
+		requested, zipPath, port = cfg.TrackerVersion, cfg.TrackerZip, cfg.TrackerPort
+	}
+
+	version, err := resolveLocalModuleVersion(module, requested)
+	if err != nil {
+		log.Printf("%s %s is not installed (%v); installing it", module, requested, err)
+		install := moduleCLICommand{Module: module}
+		if zipPath != "" {
+			install.Action = "install-zip"
+			install.InstallZipPath = zipPath
+			if !strings.EqualFold(requested, "latest") {
+				install.Version = requested
+			}
+			err = executeModuleInstallZip(install, out)
+		} else {
+			install.Action = "install"
+			install.InstallVersion = requested
+			err = executeModuleInstall(install, out)
+		}
+		if err != nil {
+			return "", fmt.Errorf("installing %s %s: %w", module, requested, err)
+		}
+		if version, err = resolveLocalModuleVersion(module, requested); err != nil {
+			return "", err
+		}
+	}
+
+	if module == "owlcms" {
+		_, err = owlcms.EnsureJavaForRelease(version)
+	} else {
+		_, err = tracker.EnsureNodeForRelease(version)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if port != "" {
+		if err := saveModulePortForRelease(module, version, port); err != nil {
+			return "", err
+		}
+	}
+	log.Printf("Container will run %s %s", module, version)
+	return version, nil
+}
+
+// wireContainerTracker connects OWLCMS to the Tracker of the container, or to
+// OWLCMS_TRACKER_URL. Without either, the release settings are left as they are.
+func wireContainerTracker(cfg containerConfig, owlcmsVersion, trackerVersion string) error {
+	switch {
+	case cfg.runs("tracker"):
+		return configureTrackerConnectionForHeadlessTandem(owlcmsVersion, trackerVersion)
+	case cfg.TrackerURL != "":
+		return owlcms.ConfigureTrackerConnectionForReleaseFromURL(owlcmsVersion, cfg.TrackerURL)
+	}
+	return nil
+}
+
+func launchContainerModule(module, version string, mqtt bool) *containerModule {
+	m := &containerModule{name: module, version: version, done: make(chan struct{})}
+	go func() {
+		defer close(m.done)
+		if module == "owlcms" {
+			m.err = owlcms.LaunchForeground(version, mqtt, shared.IsAutoPortEnabled())
+		} else {
+			m.err = tracker.LaunchForeground(version, shared.IsAutoPortEnabled())
+		}
+	}()
+	return m
+}
+
+// waitContainerModuleReady reports on the returned channel when the module
+// launched after since is listening on its port. The launchers record the
+// readiness time in the runtime metadata whenever the port opens, even after
+// their own startup wait.
+func waitContainerModuleReady(module string, since time.Time) <-chan error {
+	ready := make(chan error, 1)
+	go func() {
+		deadline := time.Now().Add(containerReadyTimeout)
+		for time.Now().Before(deadline) {
+			// Metadata left by an earlier run of the container is older than since.
+			if metadata, err := shared.LoadRuntimeMetadata(moduleRuntimeMetadataPath(module)); err == nil && metadata != nil && metadata.ReadyAt != "" {
+				if started, err := time.Parse(time.RFC3339Nano, metadata.StartedAt); err == nil && !started.Before(since.Add(-time.Second)) {
+					ready <- nil
+					return
+				}
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		ready <- fmt.Errorf("%s did not become ready within %v", module, containerReadyTimeout)
+	}()
+	return ready
+}
+
+// isContainerModulePID reports whether pid is OWLCMS or the Tracker, whose
+// launchers wait for them; the zombie reaper leaves them alone.
+func isContainerModulePID(pid int) bool {
+	for _, module := range []string{"owlcms", "tracker"} {
+		if metadata, err := shared.LoadRuntimeMetadata(moduleRuntimeMetadataPath(module)); err == nil && metadata != nil && metadata.PID == pid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadContainerConfigStartsTrackerBeforeOwlcms(t *testing.T) {
+	env := map[string]string{
+		"CONTROLPANEL_MODULES": "owlcms, Tracker",
+		"OWLCMS_VERSION":       "64.0.1",
+		"OWLCMS_PORT":          "8080",
+		"TRACKER_PORT":         "8096",
+	}
+	cfg, err := loadContainerConfig(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("loadContainerConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Modules, []string{"tracker", "owlcms"}) {
+		t.Fatalf("modules = %v, want tracker before owlcms", cfg.Modules)
+	}
+	if cfg.OwlcmsVersion != "64.0.1" || cfg.TrackerVersion != "latest" {
+		t.Fatalf("versions = %q/%q", cfg.OwlcmsVersion, cfg.TrackerVersion)
+	}
+}
+
+func TestLoadContainerConfigDefaultsToOwlcms(t *testing.T) {
+	cfg, err := loadContainerConfig(func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadContainerConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Modules, []string{"owlcms"}) || cfg.OwlcmsVersion != "latest" {
+		t.Fatalf("config = %+v", cfg)
+	}
+}
+
+func TestLoadContainerConfigRejectsInvalidSettings(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"CONTROLPANEL_MODULES": "owlcms,firmata"},
+		{"OWLCMS_PORT": "80a"},
+		{"TRACKER_PORT": "70000"},
+		{"CONTROLPANEL_MODULES": "owlcms,tracker", "OWLCMS_TRACKER_URL": "ws://tracker:8096/ws"},
+	} {
+		if _, err := loadContainerConfig(func(key string) string { return env[key] }); err == nil {
+			t.Errorf("loadContainerConfig(%v) accepted", env)
+		}
+	}
+}
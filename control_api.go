@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"fyne.io/fyne/v2"
+)
+
+// controlAPIInfo is written next to the control panel runtime metadata so that
+// command-line invocations can reach the running control panel.
+type controlAPIInfo struct {
+	PID   int    `json:"pid"`
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// controlAPIResult is the reply to a forwarded module command.
+type controlAPIResult struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// controlAPIModuleStatus describes one module in the /status reply.
+type controlAPIModuleStatus struct {
+	Module  string `json:"module"`
+	Running bool   `json:"running"`
+	Version string `json:"version,omitempty"`
+	Port    string `json:"port,omitempty"`
+	PID     int    `json:"pid,omitempty"`
+}
+
+// controlAPIStatus is the /status reply.
+type controlAPIStatus struct {
+	Instance string                   `json:"instance"`
+	PID      int                      `json:"pid"`
+	Modules  []controlAPIModuleStatus `json:"modules"`
+}
+
+// controlAPIActions are the module actions the running control panel accepts.
+var controlAPIActions = map[string]bool{
+	"launch":  true,
+	"stop":    true,
+	"install": true,
+	"update":  true,
+}
+
+var (
+	controlAPIServer *http.Server
+	controlAPIMutex  sync.Mutex
+)
+
+func controlAPIInfoPath() string {
+	return filepath.Join(shared.GetControlPanelInstallDir(), "controlpanel-api.json")
+}
+
+func newControlAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// startControlAPI serves the control API on a loopback port and records the
+// port and access token in controlAPIInfoPath, readable only by the user.
+func startControlAPI(w fyne.Window) error {
+	token, err := newControlAPIToken()
+	if err != nil {
+		return fmt.Errorf("generate control API token: %w", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen for control API: %w", err)
+	}
+
+	info := controlAPIInfo{
+		PID:   os.Getpid(),
+		URL:   "http://" + listener.Addr().String(),
+		Token: token,
+	}
+	content, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("marshal control API info: %w", err)
+	}
+	if err := writeFileAtomically(controlAPIInfoPath(), content, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("write control API info: %w", err)
+	}
+
+	controlAPIServer = &http.Server{
+		Handler:           newControlAPIHandler(token, w),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := controlAPIServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Control API stopped: %v", err)
+		}
+	}()
+	log.Printf("Control API listening on %s", info.URL)
+	return nil
+}
+
+func stopControlAPI() {
+	if controlAPIServer == nil {
+		return
+	}
+	if err := controlAPIServer.Close(); err != nil {
+		log.Printf("Failed to close control API: %v", err)
+	}
+	controlAPIServer = nil
+}
+
+func newControlAPIHandler(token string, w fyne.Window) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeControlAPIJSON(rw, http.StatusOK, controlAPIStatus{
+			Instance: currentControlPanelInstanceName(),
+			PID:      os.Getpid(),
+			Modules:  moduleStatuses(),
+		})
+	})
+	mux.HandleFunc("/command", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var cmd moduleCLICommand
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&cmd); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid command: %v", err), http.StatusBadRequest)
+			return
+		}
+		var output bytes.Buffer
+		result := controlAPIResult{}
+		if err := runForwardedModuleCommand(cmd, &output, w); err != nil {
+			result.Error = err.Error()
+		}
+		result.Output = output.String()
+		writeControlAPIJSON(rw, http.StatusOK, result)
+	})
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+func writeControlAPIJSON(rw http.ResponseWriter, status int, value interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(value); err != nil {
+		log.Printf("Failed to write control API reply: %v", err)
+	}
+}
+
+func moduleStatuses() []controlAPIModuleStatus {
+	modules := []string{"owlcms", "tracker", "firmata"}
+	if shared.GetGoos() != "darwin" {
+		modules = append(modules, "cameras", "replays")
+	}
+	statuses := make([]controlAPIModuleStatus, 0, len(modules))
+	for _, module := range modules {
+		status := controlAPIModuleStatus{Module: module}
+		if metadata, ok := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(module)); ok {
+			status.Running = true
+			status.Version = metadata.Version
+			status.Port = metadata.Port
+			status.PID = metadata.PID
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// runForwardedModuleCommand executes a command received through the control
+// API, one at a time, and brings the affected tabs up to date. Launches always
+// run in the background since the caller does not own the process.
+func runForwardedModuleCommand(cmd moduleCLICommand, out io.Writer, w fyne.Window) error {
+	if !controlAPIActions[cmd.Action] {
+		return fmt.Errorf("action %q cannot be forwarded to the running control panel", cmd.Action)
+	}
+	if cmd.ProfilePath == "" && !isSupportedModule(cmd.Module) {
+		return fmt.Errorf("unsupported module %q", cmd.Module)
+	}
+
+	controlAPIMutex.Lock()
+	defer controlAPIMutex.Unlock()
+
+	log.Printf("Control API: %s %s%s", cmd.Action, cmd.Module, cmd.ProfilePath)
+	if cmd.Action == "launch" {
+		cmd.DaemonMode = true
+	}
+	err := executeModuleCommand(cmd, out)
+
+	modules := []string{cmd.Module}
+	if cmd.ProfilePath != "" {
+		modules = nil
+		if profile, loadErr := loadLaunchProfile(cmd.ProfilePath); loadErr == nil {
+			for _, m := range profile.Modules {
+				modules = append(modules, m.Name)
+			}
+		}
+	}
+	fyne.Do(func() {
+		for _, module := range modules {
+			refreshModuleTab(module, w)
+		}
+	})
+	return err
+}
+
+// refreshModuleTab attaches the tab to a module started outside of it and
+// reloads its installed versions.
+func refreshModuleTab(module string, w fyne.Window) {
+	if isVideoModule(module) && shared.GetGoos() == "darwin" {
+		return
+	}
+	switch module {
+	case "owlcms":
+		owlcms.ReconnectRuntime()
+		owlcms.RefreshVersionList(w)
+	case "tracker":
+		tracker.ReconnectRuntime()
+		tracker.RefreshVersionList(w)
+	case "firmata":
+		firmata.ReconnectRuntime()
+		firmata.RefreshVersionList(w)
+	case "cameras":
+		cameras.ReconnectRuntime()
+		cameras.RefreshVersionList(w)
+	case "replays":
+		replays.ReconnectRuntime()
+		replays.RefreshVersionList(w)
+	}
+}
+
+func readControlAPIInfo() (*controlAPIInfo, error) {
+	content, err := os.ReadFile(controlAPIInfoPath())
+	if err != nil {
+		return nil, err
+	}
+	var info controlAPIInfo
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil, fmt.Errorf("unmarshal control API info: %w", err)
+	}
+	if info.URL == "" || info.Token == "" {
+		return nil, fmt.Errorf("incomplete control API info in %s", controlAPIInfoPath())
+	}
+	return &info, nil
+}
+
+// canForwardModuleCommand reports whether cmd may be sent to a running
+// control panel instead of being run by this process.
+func canForwardModuleCommand(cmd moduleCLICommand) bool {
+	return controlAPIActions[cmd.Action]
+}
+
+// forwardModuleCommand sends cmd to the control panel described by running and
+// copies its output to out.
+func forwardModuleCommand(running *controlPanelRuntimeMetadata, cmd moduleCLICommand, out io.Writer) error {
+	info, err := readControlAPIInfo()
+	if err != nil {
+		return err
+	}
+	if info.PID != running.PID {
+		return fmt.Errorf("control API info belongs to PID %d, not the running control panel", info.PID)
+	}
+
+	for _, path := range []*string{&cmd.ProfilePath, &cmd.InstallZipPath, &cmd.CreateZipPath} {
+		if *path == "" {
+			continue
+		}
+		absolute, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		*path = absolute
+	}
+
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, info.URL+"/command", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+info.Token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("contact running control panel: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return fmt.Errorf("running control panel refused the command: %s", strings.TrimSpace(string(message)))
+	}
+
+	var result controlAPIResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("read control panel reply: %w", err)
+	}
+	fmt.Fprint(out, result.Output)
+	if result.Error != "" {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return nil
+}
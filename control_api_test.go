@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+)
+
+func TestControlAPIRejectsMissingToken(t *testing.T) {
+	handler := newControlAPIHandler("secret", nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/status", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/status", nil)
+	request.Header.Set("Authorization", "Bearer wrong")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", recorder.Code)
+	}
+}
+
+func TestControlAPIRefusesUnforwardedAction(t *testing.T) {
+	handler := newControlAPIHandler("secret", nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/command", strings.NewReader(`{"Module":"owlcms","Action":"remove","RemoveVersion":"1.0.0"}`))
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", recorder.Code)
+	}
+
+	var result controlAPIResult
+	if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+		t.Fatalf("decode reply: %v", err)
+	}
+	if !strings.Contains(result.Error, "cannot be forwarded") {
+		t.Fatalf("expected forwarding refusal, got %#v", result)
+	}
+}
+
+func TestForwardedLaunchLeavesDaemonSettingAlone(t *testing.T) {
+	owlcmsDir := t.TempDir()
+	t.Cleanup(resetInstallDirsForTest)
+	owlcms.SetInstallDir(owlcmsDir)
+	mustMkdir(t, owlcmsDir, "65.0.0")
+	t.Setenv(shared.RunAsDaemonEnv, "false")
+
+	var launched []string
+	previous := launchModuleDaemon
+	launchModuleDaemon = func(module, version string, enableEmbeddedMQTT, autoPort bool) error {
+		launched = append(launched, module+" "+version)
+		return nil
+	}
+	t.Cleanup(func() { launchModuleDaemon = previous })
+
+	// A forwarded launch always runs in the background.
+	cmd := moduleCLICommand{Module: "owlcms", Action: "launch", Version: "65.0.0", DaemonMode: true}
+	if err := executeModuleCommand(cmd, io.Discard); err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+	if strings.Join(launched, ",") != "owlcms 65.0.0" {
+		t.Fatalf("expected a background launch of owlcms 65.0.0, got %v", launched)
+	}
+	if value := os.Getenv(shared.RunAsDaemonEnv); value != "false" {
+		t.Fatalf("expected %s to stay false, got %q", shared.RunAsDaemonEnv, value)
+	}
+}
@@ -36,11 +36,7 @@ func controlPanelRuntimeMetadataPath() string {
 }
 
 func currentControlPanelInstanceName() string {
-	instance := strings.TrimSpace(os.Getenv("CONTROLPANEL_INSTANCE"))
-	if instance == "" {
-		return mainInstanceName
-	}
-	return instance
+	return shared.CurrentInstanceName()
 }
 
 func writeFileAtomically(path string, content []byte, perm os.FileMode) error {
@@ -100,7 +96,11 @@ func writeCurrentControlPanelRuntime() error {
 }
 
 func readControlPanelRuntimeMetadata() (*controlPanelRuntimeMetadata, error) {
-	content, err := os.ReadFile(controlPanelRuntimeMetadataPath())
+	return readControlPanelRuntimeMetadataFile(controlPanelRuntimeMetadataPath())
+}
+
+func readControlPanelRuntimeMetadataFile(path string) (*controlPanelRuntimeMetadata, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +131,9 @@ func clearControlPanelRuntimeFiles() {
 	if err := os.Remove(controlPanelRuntimeMetadataPath()); err != nil && !os.IsNotExist(err) {
 		log.Printf("Failed to remove control panel runtime metadata: %v", err)
 	}
+	if err := os.Remove(controlAPIInfoPath()); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove control API info: %v", err)
+	}
 }
 
 func clearCurrentControlPanelRuntime() {
@@ -148,6 +151,7 @@ func clearCurrentControlPanelRuntime() {
 		return
 	}
 
+	stopControlAPI()
 	clearControlPanelRuntimeFiles()
 	log.Printf("Cleared control panel runtime metadata for PID %d", currentPID)
 }
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+)
+
+// defaultDashboardAddress keeps the dashboard on the local machine unless an
+// explicit LAN address is given.
+const defaultDashboardAddress = "127.0.0.1:8070"
+
+// dashboardPasswordEnv overrides the generated dashboard password.
+const dashboardPasswordEnv = "CONTROLPANEL_DASHBOARD_PASSWORD"
+
+// dashboardLogLines is how many log lines the logs page shows.
+const dashboardLogLines = 200
+
+type dashboardVersion struct {
+	Version    string
+	Port       string
+	TrackerURL string
+}
+
+type dashboardModule struct {
+	Name         string
+	Title        string
+	Status       controlAPIModuleStatus
+	Versions     []dashboardVersion
+	Releases     []string
+	ReleaseError string
+	HasPort      bool
+}
+
+type dashboardPage struct {
+	Instance string
+	CSRF     string
+	Message  string
+	Failed   bool
+	Modules  []dashboardModule
+}
+
+type dashboardServer struct {
+	password string
+	csrf     string
+
+	// actionMutex serializes module actions, as a single control panel would.
+	actionMutex sync.Mutex
+
+	stateMutex    sync.Mutex
+	releases      map[string][]string
+	releaseErrors map[string]string
+	message       string
+	failed        bool
+}
+
+// dashboardListenAddress accepts a host:port, or a bare port bound to loopback.
+func dashboardListenAddress(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultDashboardAddress
+	}
+	if !strings.Contains(value, ":") {
+		return "127.0.0.1:" + value
+	}
+	return value
+}
+
+func dashboardPasswordPath() string {
+	return filepath.Join(shared.GetControlPanelInstallDir(), "dashboard-password")
+}
+
+// loadDashboardPassword returns the password from dashboardPasswordEnv, or
+// the one stored in dashboardPasswordPath, generating it on first use.
+func loadDashboardPassword() (string, error) {
+	if password := strings.TrimSpace(os.Getenv(dashboardPasswordEnv)); password != "" {
+		return password, nil
+	}
+	path := dashboardPasswordPath()
+	if content, err := os.ReadFile(path); err == nil {
+		if password := strings.TrimSpace(string(content)); password != "" {
+			return password, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("read dashboard password: %w", err)
+	}
+
+	password, err := newControlAPIToken()
+	if err != nil {
+		return "", fmt.Errorf("generate dashboard password: %w", err)
+	}
+	password = password[:20]
+	if err := writeFileAtomically(path, []byte(password+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("write dashboard password: %w", err)
+	}
+	return password, nil
+}
+
+// runDashboard serves the web dashboard on address until the process is
+// interrupted. It takes the place of the interactive control panel.
+func runDashboard(address string, out io.Writer) error {
+	if running, ok := runningControlPanelMetadata(); ok {
+		return fmt.Errorf("another OWLCMS Control Panel is already running (%s); close it before starting the dashboard", describeControlPanelRuntime(running))
+	}
+
+	password, err := loadDashboardPassword()
+	if err != nil {
+		return err
+	}
+	csrf, err := newControlAPIToken()
+	if err != nil {
+		return err
+	}
+	server := &dashboardServer{
+		password:      password,
+		csrf:          csrf,
+		releases:      map[string][]string{},
+		releaseErrors: map[string]string{},
+	}
+
+	listener, err := net.Listen("tcp", dashboardListenAddress(address))
+	if err != nil {
+		return fmt.Errorf("listen for dashboard: %w", err)
+	}
+	if err := writeCurrentControlPanelRuntime(); err != nil {
+		listener.Close()
+		return err
+	}
+	defer clearCurrentControlPanelRuntime()
+
+	httpServer := &http.Server{
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		log.Printf("Signal %v caught, stopping dashboard.", sig)
+		httpServer.Close()
+	}()
+
+	fmt.Fprintf(out, "Dashboard listening on http://%s\n", listener.Addr().String())
+	if os.Getenv(dashboardPasswordEnv) == "" {
+		fmt.Fprintf(out, "Sign in with any user name and the password stored in %s\n", dashboardPasswordPath())
+	}
+	log.Printf("Dashboard listening on %s", listener.Addr().String())
+
+	if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (d *dashboardServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.handleIndex)
+	mux.HandleFunc("/logs", d.handleLogs)
+	mux.HandleFunc("/releases", d.handleReleases)
+	mux.HandleFunc("/action", d.handleAction)
+	mux.HandleFunc("/settings", d.handleSettings)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(d.password)) != 1 {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="OWLCMS Control Panel"`)
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(d.csrf)) != 1 {
+			http.Error(rw, "invalid form token; reload the page", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+func (d *dashboardServer) setMessage(message string, failed bool) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	d.message = message
+	d.failed = failed
+}
+
+func (d *dashboardServer) handleIndex(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(rw, r)
+		return
+	}
+
+	d.stateMutex.Lock()
+	page := dashboardPage{
+		Instance: currentControlPanelInstanceName(),
+		CSRF:     d.csrf,
+		Message:  d.message,
+		Failed:   d.failed,
+	}
+	d.message = ""
+	d.failed = false
+	releases := d.releases
+	releaseErrors := d.releaseErrors
+	d.stateMutex.Unlock()
+
+	for _, status := range moduleStatuses() {
+		module := dashboardModule{
+			Name:         status.Module,
+			Title:        dashboardModuleTitle(status.Module),
+			Status:       status,
+			Releases:     releases[status.Module],
+			ReleaseError: releaseErrors[status.Module],
+			HasPort:      !isVideoModule(status.Module),
+		}
+		for _, version := range installedVersionDirectories(moduleInstallDir(status.Module)) {
+			entry := dashboardVersion{Version: version, Port: modulePortForRelease(status.Module, version)}
+			if status.Module == "owlcms" {
+				entry.TrackerURL = owlcms.GetTrackerConnectionURLForRelease(version)
+			}
+			module.Versions = append(module.Versions, entry)
+		}
+		page.Modules = append(page.Modules, module)
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(rw, page); err != nil {
+		log.Printf("Failed to render dashboard: %v", err)
+	}
+}
+
+// handleReleases fetches the available releases of every module from GitHub.
+func (d *dashboardServer) handleReleases(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	releases := map[string][]string{}
+	releaseErrors := map[string]string{}
+	for _, status := range moduleStatuses() {
+		list, err := listModuleReleases(status.Module)
+		if err != nil {
+			releaseErrors[status.Module] = err.Error()
+			continue
+		}
+		releases[status.Module] = list
+	}
+
+	d.stateMutex.Lock()
+	d.releases = releases
+	d.releaseErrors = releaseErrors
+	d.stateMutex.Unlock()
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
+
+// handleAction runs a module command with the same code as the command line.
+func (d *dashboardServer) handleAction(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cmd := moduleCLICommand{
+		Module:         r.FormValue("module"),
+		Action:         r.FormValue("action"),
+		Version:        r.FormValue("version"),
+		InstallVersion: r.FormValue("release"),
+		UpdateTo:       r.FormValue("release"),
+		FromVersion:    r.FormValue("from"),
+		ToVersion:      r.FormValue("to"),
+		RemoveVersion:  r.FormValue("version"),
+		DaemonMode:     true,
+	}
+	if !isSupportedModule(cmd.Module) {
+		http.Error(rw, fmt.Sprintf("unsupported module %q", cmd.Module), http.StatusBadRequest)
+		return
+	}
+	switch cmd.Action {
+	case "launch", "stop", "install", "update", "import", "remove":
+	default:
+		http.Error(rw, fmt.Sprintf("unsupported action %q", cmd.Action), http.StatusBadRequest)
+		return
+	}
+	// Installed versions must name a version directory; releases are looked up
+	// in the release list, but must still be a plain name.
+	for _, field := range []*string{&cmd.Version, &cmd.RemoveVersion, &cmd.FromVersion, &cmd.ToVersion} {
+		if *field == "" {
+			continue
+		}
+		version, err := installedModuleVersion(cmd.Module, *field)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*field = version
+	}
+	if err := checkVersionName(cmd.Module, cmd.InstallVersion); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Dashboard: %s %s", cmd.Action, cmd.Module)
+	if cmd.Action == "launch" && shared.IsRunningUnderSystemd() {
+		// Under systemd a launch only returns when the module stops.
+		go func() {
+			d.actionMutex.Lock()
+			defer d.actionMutex.Unlock()
+			if err := executeModuleCommand(cmd, io.Discard); err != nil {
+				log.Printf("Dashboard launch of %s failed: %v", cmd.Module, err)
+			}
+		}()
+		d.setMessage(fmt.Sprintf("%s is starting; reload the page to follow its status", cmd.Module), false)
+		http.Redirect(rw, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	d.actionMutex.Lock()
+	var output bytes.Buffer
+	err := executeModuleCommand(cmd, &output)
+	d.actionMutex.Unlock()
+
+	message := strings.TrimSpace(output.String())
+	if err != nil {
+		message = strings.TrimSpace(message + "\n" + err.Error())
+	} else if message == "" {
+		message = fmt.Sprintf("%s %s done", cmd.Module, cmd.Action)
+	}
+	d.setMessage(message, err != nil)
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
+
+// handleSettings stores the port and, for OWLCMS, the tracker connection of
+// an installed version.
+func (d *dashboardServer) handleSettings(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	module := r.FormValue("module")
+	if !isSupportedModule(module) || isVideoModule(module) {
+		http.Error(rw, fmt.Sprintf("unsupported module %q", module), http.StatusBadRequest)
+		return
+	}
+	version, err := installedModuleVersion(module, r.FormValue("version"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.actionMutex.Lock()
+	err = applyDashboardSettings(module, version, strings.TrimSpace(r.FormValue("port")), r.Form.Has("tracker"), strings.TrimSpace(r.FormValue("tracker")))
+	d.actionMutex.Unlock()
+
+	if err != nil {
+		d.setMessage(err.Error(), true)
+	} else {
+		d.setMessage(fmt.Sprintf("%s %s settings saved", module, version), false)
+	}
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
+
+func applyDashboardSettings(module, version, port string, hasTracker bool, trackerURL string) error {
+	if port != "" && port != modulePortForRelease(module, version) {
+		if value, err := strconv.Atoi(port); err != nil || value < 1 || value > 65535 {
+			return fmt.Errorf("port must be a number between 1 and 65535")
+		}
+		if err := saveModulePortForRelease(module, version, port); err != nil {
+			return err
+		}
+	}
+	if module != "owlcms" || !hasTracker || trackerURL == owlcms.GetTrackerConnectionURLForRelease(version) {
+		return nil
+	}
+	if trackerURL == "" {
+		return owlcms.DisableTrackerConnectionForRelease(version)
+	}
+	return owlcms.ConfigureTrackerConnectionForReleaseFromURL(version, trackerURL)
+}
+
+// handleLogs shows the end of the most recent log of an installed version.
+func (d *dashboardServer) handleLogs(rw http.ResponseWriter, r *http.Request) {
+	module := r.URL.Query().Get("module")
+	if !isSupportedModule(module) {
+		http.Error(rw, fmt.Sprintf("unsupported module %q", module), http.StatusBadRequest)
+		return
+	}
+	version, err := installedModuleVersion(module, r.URL.Query().Get("version"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logPath, lines, err := tailNewestLog(filepath.Join(moduleInstallDir(module), version, "logs"), dashboardLogLines)
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		fmt.Fprintf(rw, "No log available for %s %s: %v\n", module, version, err)
+		return
+	}
+	fmt.Fprintf(rw, "%s\n\n", logPath)
+	for _, line := range lines {
+		fmt.Fprintln(rw, line)
+	}
+}
+
+// installedModuleVersion only accepts names of installed version directories,
+// so request values can never point outside the module install directory.
+func installedModuleVersion(module, requested string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	for _, version := range installedVersionDirectories(moduleInstallDir(module)) {
+		if version == requested {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("%s version %q is not installed", module, requested)
+}
+
+// tailNewestLog returns the last lines of the most recently written .log file in dir.
+func tailNewestLog(dir string, count int) (string, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest = filepath.Join(dir, entry.Name())
+			newestTime = info.ModTime()
+		}
+	}
+	if newest == "" {
+		return "", nil, fmt.Errorf("no log files in %s", dir)
+	}
+
+	file, err := os.Open(newest)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > count {
+			lines = lines[1:]
+		}
+	}
+	return newest, lines, scanner.Err()
+}
+
+func moduleInstallDir(module string) string {
+	switch module {
+	case "owlcms":
+		return owlcms.GetInstallDir()
+	case "tracker":
+		return tracker.GetInstallDir()
+	case "firmata":
+		return firmata.GetInstallDir()
+	case "cameras":
+		return cameras.GetInstallDir()
+	default:
+		return replays.GetInstallDir()
+	}
+}
+
+func modulePortForRelease(module, version string) string {
+	switch module {
+	case "owlcms":
+		return owlcms.GetPortForRelease(version)
+	case "tracker":
+		return tracker.GetPortForRelease(version)
+	case "firmata":
+		return firmata.GetPortForRelease(version)
+	default:
+		return ""
+	}
+}
+
+func listModuleReleases(module string) ([]string, error) {
+	switch module {
+	case "owlcms":
+		return owlcms.ListAvailableReleases()
+	case "tracker":
+		return tracker.ListAvailableReleases()
+	case "firmata":
+		return firmata.ListAvailableReleases()
+	case "cameras":
+		return cameras.ListAvailableReleases()
+	default:
+		return replays.ListAvailableReleases()
+	}
+}
+
+func dashboardModuleTitle(module string) string {
+	switch module {
+	case "owlcms":
+		return "OWLCMS"
+	case "tracker":
+		return "Tracker"
+	case "firmata":
+		return "Arduino Devices"
+	case "cameras":
+		return "Cameras"
+	default:
+		return "Replays"
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>OWLCMS Control Panel ({{.Instance}})</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+section { border: 1px solid #ccc; border-radius: 4px; padding: 0.5em 1em; margin-bottom: 1em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.6em; text-align: left; }
+form { display: inline; }
+.message { white-space: pre-wrap; padding: 0.5em; background: #eef6ee; }
+.failed { background: #f8e5e5; }
+.running { color: #080; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>OWLCMS Control Panel ({{.Instance}})</h1>
+{{if .Message}}<div class="message{{if .Failed}} failed{{end}}">{{.Message}}</div>{{end}}
+<form method="post" action="/releases"><input type="hidden" name="csrf" value="{{.CSRF}}"><button>Refresh available releases</button></form>
+{{$csrf := .CSRF}}
+{{range .Modules}}{{$module := .}}
+<section>
+<h2>{{.Title}}</h2>
+<p>{{if .Status.Running}}<span class="running">Running</span> version {{.Status.Version}}{{if .Status.Port}} on port {{.Status.Port}}{{end}}, PID {{.Status.PID}}
+<form method="post" action="/action"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{.Name}}"><input type="hidden" name="action" value="stop"><button>Stop</button></form>
+{{else}}Stopped{{end}}</p>
+{{if .Versions}}
+<table>
+<tr><th>Version</th>{{if .HasPort}}<th>Settings</th>{{end}}<th></th></tr>
+{{range .Versions}}
+<tr>
+<td>{{.Version}}</td>
+{{if $module.HasPort}}<td>
+<form method="post" action="/settings"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{$module.Name}}"><input type="hidden" name="version" value="{{.Version}}">
+Port <input name="port" size="6" value="{{.Port}}">
+{{if eq $module.Name "owlcms"}}Tracker <input name="tracker" size="28" value="{{.TrackerURL}}" placeholder="ws://localhost:8096/ws">{{end}}
+<button>Save</button></form>
+</td>{{end}}
+<td>
+<form method="post" action="/action"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{$module.Name}}"><input type="hidden" name="version" value="{{.Version}}"><input type="hidden" name="action" value="launch"><button>Launch</button></form>
+<form method="post" action="/action"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{$module.Name}}"><input type="hidden" name="version" value="{{.Version}}"><input type="hidden" name="action" value="update"><input type="hidden" name="release" value="latest"><button>Update</button></form>
+<form method="post" action="/action" onsubmit="return confirm('Remove {{$module.Name}} {{.Version}}?')"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{$module.Name}}"><input type="hidden" name="version" value="{{.Version}}"><input type="hidden" name="action" value="remove"><button>Remove</button></form>
+<a href="/logs?module={{$module.Name}}&amp;version={{.Version}}">Log</a>
+</td>
+</tr>
+{{end}}
+</table>
+{{if gt (len .Versions) 1}}
+<p><form method="post" action="/action"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{.Name}}"><input type="hidden" name="action" value="import">
+Import data and configuration from <select name="from">{{range .Versions}}<option>{{.Version}}</option>{{end}}</select>
+to <select name="to">{{range .Versions}}<option>{{.Version}}</option>{{end}}</select>
+<button>Import</button></form></p>
+{{end}}
+{{else}}<p>No installed versions.</p>{{end}}
+<p><form method="post" action="/action"><input type="hidden" name="csrf" value="{{$csrf}}"><input type="hidden" name="module" value="{{.Name}}"><input type="hidden" name="action" value="install">
+Install <select name="release"><option value="latest">latest</option>{{range .Releases}}<option>{{.}}</option>{{end}}</select>
+<button>Install</button></form>
+{{if .ReleaseError}}<span class="failed">{{.ReleaseError}}</span>{{end}}</p>
+</section>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"controlpanel/owlcms"
+)
+
+func TestDashboardListenAddressDefaultsToLoopback(t *testing.T) {
+	cases := map[string]string{
+		"":             defaultDashboardAddress,
+		"8071":         "127.0.0.1:8071",
+		"0.0.0.0:8070": "0.0.0.0:8070",
+	}
+	for input, expected := range cases {
+		if got := dashboardListenAddress(input); got != expected {
+			t.Fatalf("dashboardListenAddress(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestDashboardRequiresPasswordAndFormToken(t *testing.T) {
+	server := &dashboardServer{password: "secret", csrf: "token"}
+	handler := server.handler()
+
+	request := httptest.NewRequest(http.MethodGet, "/logs", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without password, got %d", recorder.Code)
+	}
+
+	form := url.Values{"module": {"owlcms"}, "action": {"stop"}, "csrf": {"wrong"}}
+	request = httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth("admin", "secret")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 with a wrong form token, got %d", recorder.Code)
+	}
+}
+
+func TestTailNewestLogReturnsLastLinesOfNewestFile(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "startup.log")
+	newer := filepath.Join(dir, "owlcms.log")
+	if err := os.WriteFile(older, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newer, []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	path, lines, err := tailNewestLog(dir, 2)
+	if err != nil {
+		t.Fatalf("tailNewestLog returned error: %v", err)
+	}
+	if path != newer {
+		t.Fatalf("expected %s, got %s", newer, path)
+	}
+	if strings.Join(lines, ",") != "two,three" {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+}
+
+func TestDashboardActionRejectsVersionOutsideInstallDir(t *testing.T) {
+	base := t.TempDir()
+	owlcmsDir := filepath.Join(base, "owlcms")
+	t.Cleanup(resetInstallDirsForTest)
+	owlcms.SetInstallDir(owlcmsDir)
+	if err := os.MkdirAll(filepath.Join(owlcmsDir, "65.0.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &dashboardServer{password: "secret", csrf: "token"}
+	handler := server.handler()
+	for _, version := range []string{"..", "../owlcms", base} {
+		form := url.Values{"module": {"owlcms"}, "action": {"remove"}, "version": {version}, "csrf": {"token"}}
+		request := httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("version %q: expected 400, got %d", version, recorder.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(owlcmsDir, "65.0.0")); err != nil {
+		t.Fatalf("expected the install directory to be left alone: %v", err)
+	}
+
+	if _, err := resolveLocalVersionSelector("owlcms", "..", nil, owlcmsDir); err == nil {
+		t.Fatal("expected .. to be rejected as a version")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"controlpanel/shared"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// eventFlushTimeout bounds how long an exiting control panel waits for
+// lifecycle events that are still being delivered.
+const eventFlushTimeout = 3 * time.Second
+
+// validateEventHooks rejects settings that can never deliver an event.
+func validateEventHooks(hooks shared.EventHooks) error {
+	if hooks.WebhookURL != "" {
+		parsed, err := url.Parse(hooks.WebhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("webhook URL must be an http or https URL: %q", hooks.WebhookURL)
+		}
+	}
+	known := map[string]bool{}
+	for _, event := range shared.AllEvents {
+		known[event] = true
+	}
+	for _, event := range hooks.Events {
+		if !known[event] {
+			return fmt.Errorf("unknown event %q; expected one of %s", event, strings.Join(shared.AllEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// showEventHooksDialog edits where module lifecycle events are sent for this
+// instance.
+func showEventHooksDialog(w fyne.Window) {
+	hooks := shared.CurrentEventHooks()
+
+	commandEntry := widget.NewEntry()
+	commandEntry.SetPlaceHolder("/usr/local/bin/notify-owlcms.sh")
+	commandEntry.SetText(hooks.Command)
+	webhookEntry := widget.NewEntry()
+	webhookEntry.SetPlaceHolder("https://example.com/hooks/owlcms")
+	webhookEntry.SetText(hooks.WebhookURL)
+	fileEntry := widget.NewEntry()
+	fileEntry.SetPlaceHolder("/var/log/owlcms/events.jsonl")
+	fileEntry.SetText(hooks.File)
+	eventsEntry := widget.NewEntry()
+	eventsEntry.SetPlaceHolder("all events")
+	eventsEntry.SetText(strings.Join(hooks.Events, ","))
+
+	dialog.ShowForm(
+		"Lifecycle Events",
+		"Save",
+		"Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Run Command", commandEntry),
+			widget.NewFormItem("Webhook URL", webhookEntry),
+			widget.NewFormItem("Append to File", fileEntry),
+			widget.NewFormItem("Events", eventsEntry),
+			widget.NewFormItem("", widget.NewLabel("Events: "+strings.Join(shared.AllEvents, ", ")+".\nLeave a field empty to disable that output.")),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+
+			updated := shared.EventHooks{
+				Command:    strings.TrimSpace(commandEntry.Text),
+				WebhookURL: strings.TrimSpace(webhookEntry.Text),
+				File:       strings.TrimSpace(fileEntry.Text),
+				Events:     shared.ParseEventFilter(eventsEntry.Text),
+			}
+			if err := validateEventHooks(updated); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if err := shared.SaveEventHooks(updated); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save event settings: %w", err), w)
+				return
+			}
+			dialog.ShowInformation("Lifecycle Events", "Event settings saved.", w)
+		},
+		w,
+	)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"controlpanel/shared"
+)
+
+func TestValidateEventHooksRejectsUnknownEvents(t *testing.T) {
+	if err := validateEventHooks(shared.EventHooks{Events: []string{"crashed", "exploded"}}); err == nil {
+		t.Fatal("expected unknown event to be rejected")
+	}
+	if err := validateEventHooks(shared.EventHooks{Events: []string{"crashed", "restarted"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateEventHooksRequiresHTTPWebhook(t *testing.T) {
+	if err := validateEventHooks(shared.EventHooks{WebhookURL: "ftp://example.com/hook"}); err == nil {
+		t.Fatal("expected non-HTTP webhook to be rejected")
+	}
+	if err := validateEventHooks(shared.EventHooks{WebhookURL: "https://example.com/hook"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package firmata
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"controlpanel/firmata/javacheck"
+	"controlpanel/shared"
+)
+
+const firmataJarName = "owlcms-firmata.jar"
+
+// ActionResult describes the installed version affected by a non-UI action.
+type ActionResult struct {
+	Version      string
+	Path         string
+	ConfigCopied bool
+	EnvCopied    bool
+}
+
+func releaseJarURL(version string) string {
+	return fmt.Sprintf("https://github.com/jflamy/owlcms-firmata/releases/download/%s/%s", version, firmataJarName)
+}
+
+func ensureReleaseCatalog() ([]string, error) {
+	if len(allReleases) == 0 {
+		releases, err := fetchReleases()
+		if err != nil {
+			return nil, err
+		}
+		allReleases = releases
+	}
+	return allReleases, nil
+}
+
+// computeUpdateTargetVersion keeps the build metadata of the existing version
+// so that duplicated installs remain distinguishable after an update.
+func computeUpdateTargetVersion(existingVersion, targetVersion string) string {
+	targetBaseVersion, _ := shared.ParseVersionWithBuild(targetVersion)
+	existingBuild := shared.GetCurrentBuildString(existingVersion)
+	if existingBuild == "" {
+		return targetVersion
+	}
+	resolvedBuild := shared.ResolveCollisionForBuild(installDir, targetBaseVersion, existingBuild)
+	return fmt.Sprintf("%s+%s", targetBaseVersion, resolvedBuild)
+}
+
+// GetAllInstalledVersions returns all installed versions sorted by semver descending.
+func GetAllInstalledVersions() []string {
+	return getAllInstalledVersions()
+}
+
+// ListAvailableReleases returns the releases published on GitHub, newest first.
+func ListAvailableReleases() ([]string, error) {
+	releases, err := ensureReleaseCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), releases...), nil
+}
+
+// ResolveInstallRelease resolves a GitHub release selector for a clean install.
+func ResolveInstallRelease(selector string) (string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.EqualFold(selector, "latest") {
+		if _, err := ensureReleaseCatalog(); err != nil {
+			return "", err
+		}
+		return getMostRecentStableRelease()
+	}
+	return selector, nil
+}
+
+// ResolveUpdateRelease resolves a GitHub release selector for an update target.
+func ResolveUpdateRelease(selector, fromVersion string) (string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector != "" && !strings.EqualFold(selector, "latest") {
+		return selector, nil
+	}
+	if _, err := ensureReleaseCatalog(); err != nil {
+		return "", err
+	}
+	if containsPreReleaseTag(fromVersion) {
+		return getMostRecentPrerelease()
+	}
+	return getMostRecentStableRelease()
+}
+
+// EnsureJavaForRelease returns the Java runtime required by a release,
+// downloading it into the shared runtime directory when missing.
+func EnsureJavaForRelease(version string) (string, error) {
+	return javacheck.EnsureJavaForVersion(GetTemurinVersionForRelease(version))
+}
+
+// InstallRelease downloads the firmata jar for downloadVersion into installVersion.
+func InstallRelease(downloadVersion, installVersion string, progress shared.ProgressCallback) (ActionResult, error) {
+	downloadVersion = strings.TrimSpace(downloadVersion)
+	installVersion = strings.TrimSpace(installVersion)
+	if downloadVersion == "" {
+		return ActionResult{}, fmt.Errorf("download version is required")
+	}
+	if installVersion == "" {
+		installVersion = downloadVersion
+	}
+
+	versionDir := filepath.Join(installDir, installVersion)
+	if _, err := os.Stat(versionDir); err == nil {
+		return ActionResult{}, fmt.Errorf("owlcms-firmata version %q already exists", installVersion)
+	} else if !os.IsNotExist(err) {
+		return ActionResult{}, fmt.Errorf("checking install directory %s: %w", versionDir, err)
+	}
+	if err := shared.EnsureDir0755(versionDir); err != nil {
+		return ActionResult{}, fmt.Errorf("creating firmata version directory: %w", err)
+	}
+
+	jarURL := releaseJarURL(downloadVersion)
+	log.Printf("Downloading owlcms-firmata from: %s", jarURL)
+	if err := shared.DownloadArchive(jarURL, filepath.Join(versionDir, firmataJarName), progress, nil); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return ActionResult{}, fmt.Errorf("download failed: %w", err)
+	}
+	if err := EnsureParentEnvDefaults(); err != nil {
+		return ActionResult{}, fmt.Errorf("failed to create env.properties: %w", err)
+	}
+
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventInstalled, Module: "firmata", Version: installVersion})
+	return ActionResult{Version: installVersion, Path: versionDir}, nil
+}
+
+// UpdateRelease downloads targetVersion and copies config/ and env.properties from existingVersion.
+func UpdateRelease(existingVersion, targetVersion string, progress shared.ProgressCallback, cancel <-chan bool) (ActionResult, error) {
+	existingVersion = strings.TrimSpace(existingVersion)
+	targetVersion = strings.TrimSpace(targetVersion)
+	if existingVersion == "" {
+		return ActionResult{}, fmt.Errorf("source version is required")
+	}
+	if targetVersion == "" {
+		return ActionResult{}, fmt.Errorf("target version is required")
+	}
+
+	existingVersionDir := filepath.Join(installDir, existingVersion)
+	if info, err := os.Stat(existingVersionDir); err != nil {
+		return ActionResult{}, fmt.Errorf("source version %q not found: %w", existingVersion, err)
+	} else if !info.IsDir() {
+		return ActionResult{}, fmt.Errorf("source version %q is not a directory", existingVersion)
+	}
+
+	targetInstallVersion := computeUpdateTargetVersion(existingVersion, targetVersion)
+	newVersionDir := filepath.Join(installDir, targetInstallVersion)
+	if _, err := os.Stat(newVersionDir); err == nil {
+		return ActionResult{}, fmt.Errorf("target install version %q already exists", targetInstallVersion)
+	} else if !os.IsNotExist(err) {
+		return ActionResult{}, fmt.Errorf("checking target install directory: %w", err)
+	}
+	if err := shared.EnsureDir0755(newVersionDir); err != nil {
+		return ActionResult{}, fmt.Errorf("creating install directory: %w", err)
+	}
+
+	if err := shared.DownloadArchive(releaseJarURL(targetVersion), filepath.Join(newVersionDir, firmataJarName), progress, cancel); err != nil {
+		_ = os.RemoveAll(newVersionDir)
+		return ActionResult{}, fmt.Errorf("download failed: %w", err)
+	}
+
+	result, err := copyConfigAndEnv(existingVersionDir, newVersionDir)
+	if err != nil {
+		_ = os.RemoveAll(newVersionDir)
+		return ActionResult{}, err
+	}
+	result.Version = targetInstallVersion
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventUpdateInstalled, Module: "firmata", Version: targetInstallVersion, Detail: "updated from " + existingVersion})
+	return result, nil
+}
+
+// ImportDataAndConfig copies config/ and env.properties from sourceVersion to destVersion.
+func ImportDataAndConfig(sourceVersion, destVersion string) (ActionResult, error) {
+	sourceVersion = strings.TrimSpace(sourceVersion)
+	destVersion = strings.TrimSpace(destVersion)
+	sourceDir := filepath.Join(installDir, sourceVersion)
+	destDir := filepath.Join(installDir, destVersion)
+	if info, err := os.Stat(sourceDir); err != nil {
+		return ActionResult{}, fmt.Errorf("source version %q does not exist: %w", sourceVersion, err)
+	} else if !info.IsDir() {
+		return ActionResult{}, fmt.Errorf("source version %q is not a directory", sourceVersion)
+	}
+	if info, err := os.Stat(destDir); err != nil {
+		return ActionResult{}, fmt.Errorf("destination version %q does not exist: %w", destVersion, err)
+	} else if !info.IsDir() {
+		return ActionResult{}, fmt.Errorf("destination version %q is not a directory", destVersion)
+	}
+
+	result, err := copyConfigAndEnv(sourceDir, destDir)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	result.Version = destVersion
+	return result, nil
+}
+
+func copyConfigAndEnv(sourceDir, destDir string) (ActionResult, error) {
+	result := ActionResult{Path: destDir}
+	if err := copyFiles(filepath.Join(sourceDir, "config"), filepath.Join(destDir, "config"), true); err != nil {
+		log.Printf("No config files to copy from %s: %v", sourceDir, err)
+	} else {
+		result.ConfigCopied = true
+	}
+
+	srcEnv := filepath.Join(sourceDir, "env.properties")
+	if _, err := os.Stat(srcEnv); err == nil {
+		if err := copyFile(srcEnv, filepath.Join(destDir, "env.properties")); err != nil {
+			return ActionResult{}, fmt.Errorf("failed to copy env.properties: %w", err)
+		}
+		result.EnvCopied = true
+	}
+	return result, nil
+}
+
+// RemoveInstalledVersion removes an installed firmata version directory.
+func RemoveInstalledVersion(version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("version is required")
+	}
+	dir := filepath.Join(installDir, version)
+	if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("owlcms-firmata version %q is not installed: %w", version, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("owlcms-firmata version %q is not a directory", version)
+	}
+	return os.RemoveAll(dir)
+}
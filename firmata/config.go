@@ -45,6 +45,72 @@ func GetPort() string {
 	return port
 }
 
+// keepRunningEnv is the env.properties key that leaves firmata running when the
+// control panel closes.
+const keepRunningEnv = "FIRMATA_KEEP_RUNNING"
+
+// GetKeepRunning returns true if firmata should keep running after the control panel closes.
+func GetKeepRunning() bool {
+	if environment == nil {
+		if err := EnsureParentEnvDefaults(); err != nil {
+			return false
+		}
+	}
+	value, _ := environment.Get(keepRunningEnv)
+	value = strings.TrimSpace(strings.ToLower(value))
+	return value == "1" || value == "true" || value == "yes" || value == "on"
+}
+
+// SetKeepRunning persists the keep-running setting in the shared env.properties.
+func SetKeepRunning(enabled bool) error {
+	if err := EnsureParentEnvDefaults(); err != nil {
+		return err
+	}
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	if err := shared.SavePropertyToFile(filepath.Join(installDir, "env.properties"), keepRunningEnv, value); err != nil {
+		return err
+	}
+	environment.Set(keepRunningEnv, value)
+	return nil
+}
+
+// SaveProperty saves a key-value pair to the shared env.properties.
+func SaveProperty(key, value string) error {
+	if err := EnsureParentEnvDefaults(); err != nil {
+		return err
+	}
+	if err := shared.SavePropertyToFile(filepath.Join(installDir, "env.properties"), key, value); err != nil {
+		return err
+	}
+	environment.Set(key, value)
+	log.Printf("Saved property %s = %s to %s", key, value, filepath.Join(installDir, "env.properties"))
+	return nil
+}
+
+// mqttServerEnv and mqttPortEnv point owlcms-firmata at the MQTT broker used
+// by the refereeing devices, usually the one embedded in OWLCMS.
+const (
+	mqttServerEnv = "FIRMATA_MQTTSERVER"
+	mqttPortEnv   = "FIRMATA_MQTTPORT"
+)
+
+// ConfigureMQTTConnectionForRelease stores the MQTT broker host and port in the
+// version-specific env.properties.
+func ConfigureMQTTConnectionForRelease(releaseVersion, host, port string) error {
+	host = strings.TrimSpace(host)
+	port = strings.TrimSpace(port)
+	if host == "" || port == "" {
+		return fmt.Errorf("MQTT host and port are required")
+	}
+	if err := SavePropertyForRelease(releaseVersion, mqttServerEnv, host); err != nil {
+		return err
+	}
+	return SavePropertyForRelease(releaseVersion, mqttPortEnv, port)
+}
+
 // GetTemurinVersion returns the configured Temurin version from env.properties
 func GetTemurinVersion() string {
 	if environment == nil {
@@ -78,6 +144,14 @@ func GetPortForRelease(releaseVersion string) string {
 	return GetPort()
 }
 
+// selectFreePortForRelease moves releaseVersion to the next free port when
+// its port is busy and returns the port to launch on.
+func selectFreePortForRelease(releaseVersion string) (string, error) {
+	return shared.SelectFreeModulePort("firmata", releaseVersion, GetPortForRelease(releaseVersion), func(port string) error {
+		return SavePropertyForRelease(releaseVersion, "FIRMATA_PORT", port)
+	})
+}
+
 // GetTemurinVersionForRelease returns the Temurin version for a specific release.
 // It first checks the version-specific env.properties file, then falls back to
 // the shared env.properties file, and finally to the default value.
@@ -229,6 +303,39 @@ func LoadEnvironmentForRelease(releaseVersion string) error {
 	return nil
 }
 
+// SavePropertyForRelease saves a key-value pair to a version-specific env.properties
+// file. The release file only overlays the shared one, so other keys are not copied.
+func SavePropertyForRelease(releaseVersion, key, value string) error {
+	releaseVersion = strings.TrimSpace(releaseVersion)
+	if releaseVersion == "" {
+		return fmt.Errorf("release version is required")
+	}
+
+	releaseEnvPath := filepath.Join(installDir, releaseVersion, "env.properties")
+	props := properties.NewProperties()
+	if _, err := os.Stat(releaseEnvPath); err == nil {
+		loaded, err := properties.LoadFile(releaseEnvPath, properties.UTF8)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", releaseEnvPath, err)
+		}
+		props = loaded
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("checking %s: %w", releaseEnvPath, err)
+	}
+
+	props.Set(key, value)
+	file, err := os.Create(releaseEnvPath)
+	if err != nil {
+		return fmt.Errorf("opening %s for writing: %w", releaseEnvPath, err)
+	}
+	defer file.Close()
+	if _, err := props.Write(file, properties.UTF8); err != nil {
+		return fmt.Errorf("writing %s: %w", releaseEnvPath, err)
+	}
+	log.Printf("Saved property %s = %s to %s", key, value, releaseEnvPath)
+	return nil
+}
+
 // CheckForUpdates checks for updates to the firmata control panel
 func CheckForUpdates(win fyne.Window) {
 	const repoURL = "https://api.github.com/repos/firmata/firmata-controlpanel/releases/latest"
@@ -32,9 +32,27 @@ func FindLocalJava() (string, error) {
 	return FindLocalJavaForVersion(temurinVersion)
 }
 
-// FindLocalJavaForVersion finds local Java installation for a specific Temurin version
+// FindLocalJavaForVersion finds the Java installation for a specific Temurin version
+// allowed by the runtime policy of the instance
 func FindLocalJavaForVersion(temurinVersion string) (string, error) {
-	return shared.FindLocalJavaForVersion(temurinVersion, shared.GetGoos)
+	return shared.FindJavaForVersion(temurinVersion, shared.GetGoos)
+}
+
+// EnsureJavaForVersion finds the local Java for a Temurin version, downloading it
+// without any UI when it is missing. Used by the command-line actions.
+func EnsureJavaForVersion(temurinVersion string) (string, error) {
+	javaPath, err := FindLocalJavaForVersion(temurinVersion)
+	if err == nil {
+		return javaPath, nil
+	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return "", err
+	}
+	log.Printf("Java %s not found locally, downloading from Temurin", temurinVersion)
+	if err := shared.DownloadAndInstallJava(temurinVersion, nil, nil, shared.GetGoos); err != nil {
+		return "", fmt.Errorf("installing Java %s: %w", temurinVersion, err)
+	}
+	return FindLocalJavaForVersion(temurinVersion)
 }
 
 // CheckJava checks for Java 17 or later and downloads/installs it if necessary.
@@ -47,6 +65,9 @@ func CheckJava(statusLabel *widget.Label) error {
 	} else {
 		log.Printf("*** Local Java not found at %s: %v\n", javaPath, err)
 	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return err
+	}
 
 	// // Then check for system Java
 	// javaPath, err = findJava()
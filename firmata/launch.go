@@ -19,6 +19,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/Masterminds/semver/v3"
 	"github.com/gofrs/flock"
+	"github.com/magiconair/properties"
 )
 
 func configureTailLogLink(version, appDir string) {
@@ -51,6 +52,7 @@ var (
 	pidFilePath        = filepath.Join(installDir, "java.pid")
 	javaPID            int          // Add a global variable to store the Java process PID
 	lock               *flock.Flock // Add a global variable to store the lock
+	activeRuntime      *shared.RuntimeMetadata
 	startupLogMu       sync.Mutex
 	startupLogStopCh   chan struct{}
 	startupLogLastText string
@@ -122,6 +124,295 @@ func killLockingProcess() error {
 	return nil
 }
 
+func runtimeMetadataPath() string {
+	return filepath.Join(installDir, "firmata-run.json")
+}
+
+// RuntimeMetadataPath returns the path to the firmata runtime metadata file.
+func RuntimeMetadataPath() string {
+	return runtimeMetadataPath()
+}
+
+// PIDFilePath returns the path to the firmata Java process PID file.
+func PIDFilePath() string {
+	return pidFilePath
+}
+
+func clearRuntimeState() {
+	activeRuntime = nil
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear firmata runtime metadata: %v", err)
+	}
+}
+
+type firmataLaunchParams struct {
+	VersionDir string
+	JavaPath   string
+	TargetPort string
+	Args       []string
+	Env        []string
+}
+
+// prepareFirmataLaunch verifies the jar, loads the release environment, builds
+// the process env slice and extracts the jSerialComm native library.
+// Callers must ensure the parent env.properties has been initialized.
+func prepareFirmataLaunch(version, javaPath string) (*firmataLaunchParams, error) {
+	versionDir := filepath.Join(installDir, version)
+	jarPath := filepath.Join(versionDir, firmataJarName)
+	if _, err := os.Stat(jarPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("'%s' not found in %s", firmataJarName, versionDir)
+	}
+	if err := shared.CheckConfigBeforeLaunch("firmata", installDir, version); err != nil {
+		return nil, err
+	}
+
+	if err := LoadEnvironmentForRelease(version); err != nil {
+		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	targetPort := GetPort()
+
+	shared.PurgeJSerialCommCaches()
+	env, err := shared.InjectSecrets(buildFirmataEnv(environment))
+	if err != nil {
+		return nil, err
+	}
+
+	// Force jSerialComm to use the correct native library by extracting it from
+	// the jar to a deterministic location and pointing the JVM at it. This
+	// bypasses jSerialComm's autodetection (which is unreliable when the JVM
+	// is launched from a Go binary under WoW emulation on Windows).
+	var args []string
+	jSerialLibPath, jsErr := shared.ExtractJSerialCommNative(jarPath, versionDir)
+	if jsErr != nil {
+		log.Printf("Warning: could not extract jSerialComm native: %v (falling back to jSerialComm autodetection)", jsErr)
+	} else {
+		args = append(args, "-DjSerialComm.library.path="+jSerialLibPath)
+	}
+	args = append(args, "-jar", firmataJarName, "--port", targetPort, "--device-configs", "./config")
+
+	return &firmataLaunchParams{
+		VersionDir: versionDir,
+		JavaPath:   javaPath,
+		TargetPort: targetPort,
+		Args:       args,
+		Env:        env,
+	}, nil
+}
+
+// buildFirmataEnv returns the environment of the firmata process for the
+// merged env.properties of a version.
+func buildFirmataEnv(props *properties.Properties) []string {
+	env := shared.NormalizeChildProcessEnv(os.Environ())
+	lv := shared.GetLauncherVersionSemver()
+	env = append(env, fmt.Sprintf("FIRMATA_LAUNCHER=%s", lv))
+	env = append(env, fmt.Sprintf("OWLCMS_CONTROLPANEL=%s", lv))
+	log.Printf("Setting OWLCMS_CONTROLPANEL=%s for firmata process", lv)
+	// Add all properties from env.properties to the process env
+	for _, key := range props.Keys() {
+		value, _ := props.Get(key)
+		log.Printf("   %s=%s", key, value)
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	return env
+}
+
+// EffectiveLaunchEnvironment returns the variables a launch of version would
+// pass to firmata, with where each one comes from.
+func EffectiveLaunchEnvironment(version string) ([]shared.LaunchEnvVar, error) {
+	versionDir := filepath.Join(installDir, version)
+	if _, err := os.Stat(versionDir); err != nil {
+		return nil, fmt.Errorf("version directory not found: %s", versionDir)
+	}
+	if err := EnsureParentEnvDefaults(); err != nil {
+		return nil, err
+	}
+	parentEnvPath := filepath.Join(installDir, "env.properties")
+	releaseEnvPath := filepath.Join(versionDir, "env.properties")
+	merged, err := shared.MergeEnvironmentProperties(parentEnvPath, releaseEnvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	return shared.DescribeLaunchEnv(buildFirmataEnv(merged), parentEnvPath, releaseEnvPath, nil), nil
+}
+
+// recordFirmataStart writes the PID file and runtime metadata after a successful cmd.Start().
+func recordFirmataStart(pid int, version, port string, daemon bool) *shared.RuntimeMetadata {
+	if err := os.WriteFile(pidFilePath, []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
+		log.Printf("Failed to write PID to PID file: %v\n", err)
+	} else {
+		log.Printf("Wrote PID %d to PID file %s\n", pid, pidFilePath)
+	}
+
+	SaveLastRunVersion(version)
+
+	emitFirmataEvent(shared.EventStarted, version, port, pid, "")
+
+	metadata, err := shared.WriteRuntimeMetadata(runtimeMetadataPath(), pid, version, port, daemon)
+	if err != nil {
+		log.Printf("Failed to write firmata runtime metadata: %v", err)
+		return nil
+	}
+	return metadata
+}
+
+// emitFirmataEvent reports a lifecycle event of an owlcms-firmata process.
+func emitFirmataEvent(event, version, port string, pid int, detail string) {
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: event, Module: "firmata", Version: version, Port: port, PID: pid, Detail: detail})
+}
+
+// SaveLastRunVersion persists the launched version so that --stop can report it.
+func SaveLastRunVersion(version string) {
+	p := filepath.Join(installDir, "last-version.txt")
+	if err := os.WriteFile(p, []byte(version), 0644); err != nil {
+		log.Printf("Failed to save firmata last-run version: %v", err)
+	}
+}
+
+// GetLastRunVersion returns the previously launched firmata version, or empty string.
+func GetLastRunVersion() string {
+	data, err := os.ReadFile(filepath.Join(installDir, "last-version.txt"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// prepareHeadlessLaunch initializes the environment, provisions Java and
+// checks that the firmata port is free before a command-line launch. With
+// autoPort, a busy port is replaced by the next free one.
+func prepareHeadlessLaunch(version string, autoPort bool) (*firmataLaunchParams, error) {
+	if err := EnsureParentEnvDefaults(); err != nil {
+		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	if autoPort {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			return nil, err
+		}
+	}
+	javaPath, err := EnsureJavaForRelease(version)
+	if err != nil {
+		return nil, err
+	}
+	params, err := prepareFirmataLaunch(version, javaPath)
+	if err != nil {
+		return nil, err
+	}
+	if shared.CheckPort(params.TargetPort) == nil {
+		return nil, fmt.Errorf("port %s is already in use", params.TargetPort)
+	}
+	return params, nil
+}
+
+func waitForFirmataPort(version, port string, pid int) error {
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) {
+		if shared.CheckPort(port) == nil {
+			return nil
+		}
+		if !shared.IsProcessRunning(pid) {
+			return fmt.Errorf("owlcms-firmata %s (PID %d) exited before becoming ready", version, pid)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for owlcms-firmata %s on port %s", version, port)
+}
+
+// LaunchDaemon starts owlcms-firmata headlessly (no UI) in daemon mode.
+// Java is downloaded first if the release requires a version that is not installed.
+func LaunchDaemon(version string, autoPort bool) error {
+	log.Printf("LaunchDaemon: starting owlcms-firmata %s headlessly", version)
+
+	params, err := prepareHeadlessLaunch(version, autoPort)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(params.JavaPath, params.Args...)
+	shared.ConfigureNoConsoleWindow(cmd)
+	shared.ConfigureDetachedDaemonProcess(cmd, true)
+	cmd.Env = params.Env
+	cmd.Dir = params.VersionDir
+
+	logPath := filepath.Join(params.VersionDir, "logs", "firmata-console.log")
+	_ = shared.EnsureDir0755(filepath.Dir(logPath))
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Printf("Warning: could not open firmata console log: %v", err)
+	} else {
+		// Pass the file descriptor directly so the child does not hold a pipe
+		// to a control panel that exits right after the launch.
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+	}
+
+	log.Printf("LaunchDaemon: command %v in %s", cmd.Args, params.VersionDir)
+	err = cmd.Start()
+	if logFile != nil {
+		// The child holds its own copy of the descriptor.
+		_ = logFile.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start owlcms-firmata %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordFirmataStart(pid, version, params.TargetPort, true)
+	log.Printf("LaunchDaemon: owlcms-firmata %s (PID %d), waiting for port %s...", version, pid, params.TargetPort)
+	if err := waitForFirmataPort(version, params.TargetPort, pid); err != nil {
+		return err
+	}
+	log.Printf("LaunchDaemon: owlcms-firmata %s ready on port %s (PID %d)", version, params.TargetPort, pid)
+	shared.MarkRuntimeReady(runtimeMetadataPath())
+	emitFirmataEvent(shared.EventReady, version, params.TargetPort, pid, "")
+	return nil
+}
+
+// LaunchForeground starts owlcms-firmata from the command line and blocks until it exits.
+func LaunchForeground(version string, autoPort bool) error {
+	log.Printf("LaunchForeground: starting owlcms-firmata %s", version)
+
+	params, err := prepareHeadlessLaunch(version, autoPort)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(params.JavaPath, params.Args...)
+	shared.ConfigureNoConsoleWindow(cmd)
+	cmd.Env = params.Env
+	cmd.Dir = params.VersionDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	log.Printf("LaunchForeground: command %v in %s", cmd.Args, params.VersionDir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start owlcms-firmata %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordFirmataStart(pid, version, params.TargetPort, false)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
+	log.Printf("LaunchForeground: owlcms-firmata %s (PID %d), waiting for port %s...", version, pid, params.TargetPort)
+	if err := waitForFirmataPort(version, params.TargetPort, pid); err != nil {
+		log.Printf("LaunchForeground: %v", err)
+	} else {
+		shared.MarkRuntimeReady(runtimeMetadataPath())
+		emitFirmataEvent(shared.EventReady, version, params.TargetPort, pid, "")
+		fmt.Printf("owlcms-firmata %s started successfully\n", version)
+	}
+
+	waitErr := cmd.Wait()
+	emitFirmataEvent(shared.ExitEvent(waitErr, false), version, params.TargetPort, pid, shared.EventDetail(waitErr))
+	os.Remove(pidFilePath)
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear firmata runtime metadata: %v", err)
+	}
+	if waitErr == nil {
+		log.Printf("LaunchForeground: owlcms-firmata %s exited normally", version)
+		return nil
+	}
+	return waitErr
+}
+
 func launchFirmata(version string, launchButton *widget.Button) error {
 	currentVersion = version // Store current version
 
@@ -134,6 +425,13 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 		return err
 	}
 
+	if shared.IsAutoPortEnabled() {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			goBackToMainScreen()
+			return err
+		}
+	}
+
 	targetPort := GetPortForRelease(version)
 
 	// Check if port is already in use
@@ -194,48 +492,22 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 		return fmt.Errorf("failed to find local Java: %w", err)
 	}
 
-	if err := LoadEnvironmentForRelease(version); err != nil {
+	params, err := prepareFirmataLaunch(version, localJava)
+	if err != nil {
 		statusLabel.SetText(fmt.Sprintf("Failed to initialize environment: %v", err))
 		launchButton.Show()
 		goBackToMainScreen()
 		releaseJavaLock()
-		return fmt.Errorf("failed to initialize environment: %w", err)
-	}
-	targetPort = GetPort()
-
-	env := shared.NormalizeChildProcessEnv(os.Environ())
-	shared.PurgeJSerialCommCaches()
-	newVar := shared.GetLauncherVersionSemver()
-	env = append(env, fmt.Sprintf("FIRMATA_LAUNCHER=%s", newVar))
-	env = append(env, fmt.Sprintf("OWLCMS_CONTROLPANEL=%s", newVar))
-	log.Printf("Setting OWLCMS_CONTROLPANEL=%s for firmata process", newVar)
-	// Add all properties from env.properties to the process env
-	for _, key := range environment.Keys() {
-		value, _ := environment.Get(key)
-		log.Printf("   %s=%s", key, value)
-		env = append(env, fmt.Sprintf("%s=%s", key, value))
-	}
-
-	// Force jSerialComm to use the correct native library by extracting it from
-	// the jar to a deterministic location and pointing the JVM at it. This
-	// bypasses jSerialComm's autodetection (which is unreliable when the JVM
-	// is launched from a Go binary under WoW emulation on Windows).
-	versionDir := filepath.Join(installDir, version)
-	jarFullPath := filepath.Join(versionDir, "owlcms-firmata.jar")
-	jSerialLibPath, jsErr := shared.ExtractJSerialCommNative(jarFullPath, versionDir)
-	if jsErr != nil {
-		log.Printf("Warning: could not extract jSerialComm native: %v (falling back to jSerialComm autodetection)", jsErr)
-	}
-
-	javaArgs := []string{}
-	if jsErr == nil {
-		javaArgs = append(javaArgs, "-DjSerialComm.library.path="+jSerialLibPath)
+		return err
 	}
-	javaArgs = append(javaArgs, "-jar", "owlcms-firmata.jar", "--port", targetPort, "--device-configs", "./config")
+	targetPort = params.TargetPort
 
-	cmd := exec.Command(localJava, javaArgs...)
+	keepRunning := GetKeepRunning()
+	cmd := exec.Command(params.JavaPath, params.Args...)
 	shared.ConfigureNoConsoleWindow(cmd)
-	cmd.Env = env
+	shared.ConfigureDetachedDaemonProcess(cmd, keepRunning)
+	cmd.Env = params.Env
+	cmd.Dir = params.VersionDir
 
 	// Remove startup.log if it exists to ensure fresh log output
 	if firmataSupportsStartupLog(version) {
@@ -256,13 +528,10 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 		return fmt.Errorf("failed to start owlcms-firmata %s: %w", version, err)
 	}
 
-	// Store the PID in the PID file and globally
+	// Store the PID in the PID file, the runtime metadata and globally
 	javaPID = cmd.Process.Pid
-	if err := os.WriteFile(pidFilePath, []byte(fmt.Sprintf("%d\n", javaPID)), 0644); err != nil {
-		log.Printf("Failed to write PID to PID file: %v\n", err)
-	} else {
-		log.Printf("Wrote PID %d to PID file %s\n", javaPID, pidFilePath)
-	}
+	activeRuntime = recordFirmataStart(javaPID, version, targetPort, keepRunning)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 
 	log.Printf("Launching owlcms-firmata %s (PID: %d), waiting for port %s...\n", version, javaPID, targetPort)
 	statusLabel.SetText(fmt.Sprintf("Starting owlcms-firmata %s (PID: %d), waiting for port %s.\nFull startup can take up to 30 seconds.", version, javaPID, targetPort))
@@ -303,6 +572,8 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 			stopContainer.Hide()
 			launchButton.Show()
 			currentProcess = nil
+			clearRuntimeState()
+			emitFirmataEvent(shared.ExitEvent(err, killedByUs), version, targetPort, cmd.Process.Pid, shared.EventDetail(err))
 			downloadContainer.Show()
 			versionContainer.Show()
 			showSelectionLayout()
@@ -311,6 +582,8 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 		}
 
 		log.Printf("owlcms-firmata process %d is ready (port %s responding)\n", javaPID, targetPort)
+		shared.MarkRuntimeReady(runtimeMetadataPath())
+		emitFirmataEvent(shared.EventReady, version, targetPort, cmd.Process.Pid, "")
 		statusLabel.SetText(fmt.Sprintf("owlcms-firmata running (PID: %d) on port %s", javaPID, targetPort))
 		url := fmt.Sprintf("http://localhost:%s", targetPort)
 		urlLink.SetURLFromString(url)
@@ -346,7 +619,9 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 			statusLabel.SetText(fmt.Sprintf("owlcms-firmata %s (PID: %d) exited normally", version, pid))
 		}
 
+		emitFirmataEvent(shared.ExitEvent(err, killedByUs), version, targetPort, pid, shared.EventDetail(err))
 		currentProcess = nil
+		clearRuntimeState()
 		killedByUs = false // Reset flag
 		stopButton.Hide()
 		stopContainer.Hide()
@@ -368,6 +643,55 @@ func launchFirmata(version string, launchButton *widget.Button) error {
 	return nil
 }
 
+func restoreFirmataRunningUI(version, port string, pid int) {
+	currentVersion = version
+	stopButton.SetText(fmt.Sprintf("Stop owlcms-firmata %s", version))
+	stopButton.Show()
+	statusLabel.SetText(fmt.Sprintf("owlcms-firmata running (PID: %d) on port %s", pid, port))
+	statusLabel.Show()
+	stopContainer.Show()
+	downloadContainer.Hide()
+	versionContainer.Hide()
+	setFirmataTabModeRunning()
+
+	url := fmt.Sprintf("http://localhost:%s", port)
+	urlLink.SetURLFromString(url)
+	urlLink.SetText("Open owlcms-firmata in a browser")
+	urlLink.Show()
+
+	appDir := filepath.Join(installDir, version)
+	appDirLink.SetText(fmt.Sprintf("Open owlcms-firmata %s directory", version))
+	appDirLink.SetURL(nil)
+	appDirLink.OnTapped = func() {
+		shared.OpenFileExplorer(appDir)
+	}
+	appDirLink.Show()
+	configureTailLogLink(version, appDir)
+	stopContainer.Refresh()
+}
+
+func reconnectFirmataRuntime() bool {
+	metadata, running := shared.CheckDaemonRunning(runtimeMetadataPath())
+	if !running {
+		clearRuntimeState()
+		return false
+	}
+
+	activeRuntime = metadata
+	restoreFirmataRunningUI(metadata.Version, metadata.Port, metadata.PID)
+	return true
+}
+
+// ReconnectRuntime attaches the tab to a process started outside of it, such
+// as by a launch profile, unless the tab already tracks a running process.
+// Must be called on the UI thread.
+func ReconnectRuntime() {
+	if IsRunning() {
+		return
+	}
+	reconnectFirmataRuntime()
+}
+
 // showStartupLogArea creates and shows the startup log text area
 func showStartupLogArea() {
 	// Reset/initialize stop channel for this run
@@ -0,0 +1,74 @@
+package firmata
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func useInstallDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previousDir, previousEnv := installDir, environment
+	installDir = dir
+	t.Cleanup(func() {
+		installDir, environment = previousDir, previousEnv
+	})
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrepareFirmataLaunchRequiresJar(t *testing.T) {
+	dir := useInstallDir(t)
+	if err := os.MkdirAll(filepath.Join(dir, "2.1.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prepareFirmataLaunch("2.1.0", "java"); err == nil || !strings.Contains(err.Error(), firmataJarName) {
+		t.Fatalf("expected a missing jar error, got %v", err)
+	}
+}
+
+func TestPrepareFirmataLaunchUsesReleasePort(t *testing.T) {
+	dir := useInstallDir(t)
+	writeFile(t, filepath.Join(dir, "env.properties"), "FIRMATA_PORT=8090\n")
+	writeFile(t, filepath.Join(dir, "2.1.0", "env.properties"), "FIRMATA_PORT=8123\n")
+	writeFile(t, filepath.Join(dir, "2.1.0", firmataJarName), "not a jar")
+
+	params, err := prepareFirmataLaunch("2.1.0", "/opt/java/bin/java")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if params.TargetPort != "8123" {
+		t.Fatalf("expected release port 8123, got %q", params.TargetPort)
+	}
+	if params.JavaPath != "/opt/java/bin/java" || params.VersionDir != filepath.Join(dir, "2.1.0") {
+		t.Fatalf("unexpected params %+v", params)
+	}
+	if i := slices.Index(params.Args, "--port"); i < 0 || params.Args[i+1] != "8123" {
+		t.Fatalf("expected --port 8123 in %v", params.Args)
+	}
+	if !slices.Contains(params.Env, "FIRMATA_PORT=8123") {
+		t.Fatalf("expected FIRMATA_PORT=8123 in the environment")
+	}
+}
+
+func TestPrepareFirmataLaunchRejectsInvalidConfig(t *testing.T) {
+	dir := useInstallDir(t)
+	writeFile(t, filepath.Join(dir, "env.properties"), "FIRMATA_PORT=80a\n")
+	writeFile(t, filepath.Join(dir, "2.1.0", firmataJarName), "not a jar")
+
+	if _, err := prepareFirmataLaunch("2.1.0", "java"); err == nil || !strings.Contains(err.Error(), "FIRMATA_PORT") {
+		t.Fatalf("expected a configuration error, got %v", err)
+	}
+}
@@ -64,6 +64,9 @@ func stopProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget.Button
 	statusLbl.SetText(fmt.Sprintf("Stopping owlcms-firmata %s...", curVersion))
 
 	if curProcess == nil || curProcess.Process == nil {
+		if activeRuntime != nil {
+			stopAttachedProcess(curVersion, stopBtn, statusLbl, w)
+		}
 		return
 	}
 	pid := curProcess.Process.Pid
@@ -85,3 +88,33 @@ func stopProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget.Button
 	versionCont.Show()
 	releaseJavaLock()
 }
+
+// stopAttachedProcess stops a firmata process that this control panel reattached
+// to on startup; there is no exec.Cmd to wait on, so the UI is restored here.
+func stopAttachedProcess(curVersion string, stopBtn *widget.Button, statusLbl *widget.Label, w fyne.Window) {
+	pid := activeRuntime.PID
+	port := activeRuntime.Port
+	killedByUs = true
+
+	if err := shared.StopPIDFileOrPortProcess(pidFilePath, port); err != nil {
+		killedByUs = false
+		dialog.ShowError(fmt.Errorf("failed to stop owlcms-firmata %s (PID: %d): %w", curVersion, pid, err), w)
+		return
+	}
+
+	log.Printf("owlcms-firmata %s (PID: %d) has been stopped\n", curVersion, pid)
+	statusLbl.SetText(fmt.Sprintf("owlcms-firmata %s (PID: %d) has been stopped", curVersion, pid))
+	killedByUs = false
+	clearRuntimeState()
+	stopBtn.Hide()
+	stopContainer.Hide()
+	urlLink.Hide()
+	if appDirLink != nil {
+		appDirLink.Hide()
+	}
+	if tailLogLink != nil {
+		tailLogLink.Hide()
+	}
+	releaseJavaLock()
+	setFirmataTabMode(w)
+}
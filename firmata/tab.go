@@ -231,7 +231,18 @@ func removeJava() {
 
 // IsRunning returns true if Firmata is currently running
 func IsRunning() bool {
-	return currentProcess != nil
+	return currentProcess != nil || activeRuntime != nil
+}
+
+// IsLocalProcessRunning returns true when the running process should stop with this control panel.
+func IsLocalProcessRunning() bool {
+	return IsRunning() && !IsDaemonRunning()
+}
+
+// IsDaemonRunning returns true when the running process was started to keep
+// running after the control panel closes.
+func IsDaemonRunning() bool {
+	return activeRuntime != nil && activeRuntime.Daemon
 }
 
 // StopRunningProcess stops the running Firmata process
@@ -239,6 +250,12 @@ func StopRunningProcess(w fyne.Window) {
 	if currentProcess != nil && currentProcess.Process != nil {
 		log.Println("Stopping Firmata process")
 		stopProcess(currentProcess, currentVersion, stopButton, downloadContainer, versionContainer, statusLabel, w)
+		return
+	}
+
+	if activeRuntime != nil {
+		log.Println("Stopping attached Firmata process")
+		stopProcess(nil, currentVersion, stopButton, downloadContainer, versionContainer, statusLabel, w)
 	}
 }
 
@@ -255,7 +272,13 @@ func HandleSignalCleanup() {
 			log.Printf("Firmata process %d killed\n", pid)
 		}
 		currentProcess = nil
+	} else if activeRuntime != nil {
+		killedByUs = true
+		if err := shared.ForcefullyKillPID(activeRuntime.PID); err != nil {
+			log.Printf("Failed to kill attached Firmata process %d: %v\n", activeRuntime.PID, err)
+		}
 	}
+	clearRuntimeState()
 	// Always release the lock and remove PID file on signal cleanup
 	releaseJavaLock()
 }
@@ -503,6 +526,17 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 	fileMenu := shared.CreateMenuButton("Files", fileMenuItems)
 
 	// Create the Processes menu button with popup
+	keepRunningItem := fyne.NewMenuItem("Keep Running When Control Panel Closes", nil)
+	keepRunningItem.Checked = GetKeepRunning()
+	keepRunningItem.Action = func() {
+		enabled := !keepRunningItem.Checked
+		if err := SetKeepRunning(enabled); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save setting: %w", err), w)
+			return
+		}
+		keepRunningItem.Checked = enabled
+	}
+
 	processMenuItems := []*fyne.MenuItem{
 		fyne.NewMenuItem("Kill Already Running Process", func() {
 			if err := killLockingProcess(); err != nil {
@@ -511,19 +545,57 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 				dialog.ShowInformation("Success", "Successfully killed the already running process", w)
 			}
 		}),
+		fyne.NewMenuItemSeparator(),
+		keepRunningItem,
 	}
 	processMenu := shared.CreateMenuButton("Processes", processMenuItems)
 
+	// Create the Options menu button with popup
+	optionsMenuItems := []*fyne.MenuItem{
+		fyne.NewMenuItem("All Settings", func() {
+			showSettingsEditor(w, "")
+		}),
+	}
+	optionsMenu := shared.CreateMenuButton("Options", optionsMenuItems)
+
 	// Add small vertical padding
 	spacer := canvas.NewRectangle(color.Transparent)
 	spacer.SetMinSize(fyne.NewSize(1, 5))
 
 	return container.NewVBox(
 		spacer,
-		container.NewHBox(fileMenu, processMenu),
+		container.NewHBox(fileMenu, processMenu, optionsMenu),
 	)
 }
 
+// showSettingsEditor edits the shared env.properties, or the one of version
+// when it is set.
+func showSettingsEditor(w fyne.Window, version string) {
+	editor := shared.EnvSettingsEditor{
+		Title:         "Firmata Settings",
+		Module:        "firmata",
+		ParentEnvPath: filepath.Join(installDir, "env.properties"),
+		Save:          SaveProperty,
+	}
+	if version != "" {
+		editor.Title = fmt.Sprintf("Firmata %s Settings", version)
+		editor.ReleaseEnvPath = filepath.Join(installDir, version, "env.properties")
+		editor.Save = func(key, value string) error {
+			return SavePropertyForRelease(version, key, value)
+		}
+	}
+	save := editor.Save
+	editor.Save = func(key, value string) error {
+		if key == "FIRMATA_PORT" && value != "" {
+			if err := shared.ClaimPort("firmata", version, value); err != nil {
+				return err
+			}
+		}
+		return save(key, value)
+	}
+	shared.ShowEnvSettingsEditor(w, editor)
+}
+
 func refreshAvailableVersions(w fyne.Window) {
 	go func() {
 		releases, err := fetchReleases()
@@ -554,6 +626,11 @@ func refreshAvailableVersions(w fyne.Window) {
 // initializeFirmataTab handles the async initialization of the Firmata tab
 func initializeFirmataTab(w fyne.Window) {
 	fyne.Do(func() {
+		if reconnectFirmataRuntime() {
+			log.Println("Firmata tab reattached to existing runtime")
+			return
+		}
+
 		// Set the appropriate mode based on installed versions
 		if len(getAllInstalledVersions()) == 0 {
 			setFirmataTabModeUninstalled(w)
@@ -642,14 +719,8 @@ func ShowDownloadables() {
 }
 
 func downloadAndInstallVersion(version string, w fyne.Window) {
-	var urlPrefix string
-	if containsPreReleaseTag(version) {
-		urlPrefix = "https://github.com/jflamy/owlcms-firmata/releases/download"
-	} else {
-		urlPrefix = "https://github.com/jflamy/owlcms-firmata/releases/download"
-	}
-	fileName := "owlcms-firmata.jar"
-	zipURL := fmt.Sprintf("%s/%s/%s", urlPrefix, version, fileName)
+	fileName := firmataJarName
+	zipURL := releaseJarURL(version)
 
 	// Ensure the firmata directory exists
 	owlcmsDir := installDir
@@ -827,3 +898,14 @@ func checkForNewerVersion() {
 		updateTitleContainer.Show()
 	}
 }
+
+// showEffectiveSettings lists the variables a launch of version passes to
+// Firmata, with where each one comes from.
+func showEffectiveSettings(w fyne.Window, version string) {
+	vars, err := EffectiveLaunchEnvironment(version)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	shared.ShowLaunchEnvironment(w, fmt.Sprintf("Firmata %s Effective Settings", version), vars)
+}
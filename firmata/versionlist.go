@@ -169,6 +169,7 @@ func createVersionList(w fyne.Window) *widget.List {
 			buttonContainer.RemoveAll()
 
 			createLaunchButton(w, version, buttonContainer)
+			createVersionOptionsButton(w, version, buttonContainer)
 			createFilesButton(version, w, buttonContainer)
 			if len(allReleases) > 0 {
 				createUpdateButton(version, w, buttonContainer)
@@ -210,6 +211,19 @@ func createVersionList(w fyne.Window) *widget.List {
 	return versionList
 }
 
+func createVersionOptionsButton(w fyne.Window, version string, buttonContainer *fyne.Container) {
+	menuItems := []*fyne.MenuItem{
+		fyne.NewMenuItem("All Settings", func() {
+			showSettingsEditor(w, version)
+		}),
+		fyne.NewMenuItem("Effective Settings", func() {
+			showEffectiveSettings(w, version)
+		}),
+	}
+
+	buttonContainer.Add(container.NewPadded(shared.CreateMenuButton("Options", menuItems)))
+}
+
 func createImportButton(versions []string, version string, w fyne.Window, buttonContainer *fyne.Container) {
 	importButton := widget.NewButton("Import", nil)
 	importButton.Show()
@@ -416,23 +430,9 @@ func adjustUpdateButton(mostRecent string, version string, updateButton *widget.
 func updateVersion(existingVersion string, targetVersion string, w fyne.Window) {
 	// Note the timestamp of the current version's top-level directory
 	currentVersionDir := filepath.Join(installDir, existingVersion)
-	targetBaseVersion, _ := shared.ParseVersionWithBuild(targetVersion)
-	existingBuild := shared.GetCurrentBuildString(existingVersion)
-	targetInstallVersion := targetVersion
-	if existingBuild != "" {
-		resolvedBuild := shared.ResolveCollisionForBuild(installDir, targetBaseVersion, existingBuild)
-		targetInstallVersion = fmt.Sprintf("%s+%s", targetBaseVersion, resolvedBuild)
-	}
-
-	// Download and extract the version given by string
-	var urlPrefix string
-	if containsPreReleaseTag(targetVersion) {
-		urlPrefix = "https://github.com/jflamy/owlcms-firmata/releases/download"
-	} else {
-		urlPrefix = "https://github.com/jflamy/owlcms-firmata/releases/download"
-	}
-	fileName := "owlcms-firmata.jar"
-	jarURL := fmt.Sprintf("%s/%s/%s", urlPrefix, targetVersion, fileName)
+	targetInstallVersion := computeUpdateTargetVersion(existingVersion, targetVersion)
+	fileName := firmataJarName
+	jarURL := releaseJarURL(targetVersion)
 
 	extractDir := filepath.Join(installDir, targetInstallVersion)
 	if err := shared.EnsureDir0755(extractDir); err != nil {
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"github.com/magiconair/properties"
+)
+
+const (
+	instanceArchiveFormat   = 1
+	instanceArchiveManifest = "manifest.json"
+)
+
+// runtimeArchiveDirs are the runtime directories added by --with-runtimes.
+var runtimeArchiveDirs = []string{"java", "node", "ffmpeg"}
+
+// instanceManifest describes an instance archive. Paths records where
+// each archive section came from so that imports can remap them.
+type instanceManifest struct {
+	Format              int               `json:"format"`
+	Instance            string            `json:"instance"`
+	ExportedAt          string            `json:"exportedAt"`
+	ControlPanelVersion string            `json:"controlPanelVersion"`
+	Platform            string            `json:"platform"`
+	Runtimes            bool              `json:"runtimes"`
+	Paths               map[string]string `json:"paths"`
+}
+
+// selectedInstancePaths returns the directories of the instance selected in
+// the process environment.
+func selectedInstancePaths() *instancePaths {
+	return &instancePaths{
+		InstanceName:    shared.CurrentInstanceName(),
+		ControlPanelDir: shared.GetControlPanelInstallDir(),
+		OwlcmsDir:       shared.GetOwlcmsInstallDir(),
+		TrackerDir:      shared.GetTrackerInstallDir(),
+	}
+}
+
+// archiveSections maps the top-level archive directories to the local
+// directories they hold.
+func archiveSections(paths *instancePaths, runtimeDir string) map[string]string {
+	return map[string]string{
+		"controlpanel": paths.ControlPanelDir,
+		"owlcms":       paths.OwlcmsDir,
+		"tracker":      paths.TrackerDir,
+		"firmata":      firmata.GetInstallDir(),
+		"runtime":      runtimeDir,
+	}
+}
+
+// exportInstance writes the selected instance to a ZIP archive: the control
+// panel settings, every OWLCMS, Tracker and firmata version with its data, and
+// optionally the Java, Node.js and FFmpeg runtimes.
+func exportInstance(archivePath string, withRuntimes bool, out io.Writer) error {
+	paths := selectedInstancePaths()
+	for _, process := range instanceRunningProcesses(paths) {
+		if process != "control panel" {
+			return fmt.Errorf("stop %s before exporting instance %q so that its databases are consistent", process, paths.InstanceName)
+		}
+	}
+
+	sections := archiveSections(paths, shared.GetRuntimeDir())
+	manifest := instanceManifest{
+		Format:              instanceArchiveFormat,
+		Instance:            paths.InstanceName,
+		ExportedAt:          time.Now().UTC().Format(time.RFC3339),
+		ControlPanelVersion: shared.GetLauncherVersion(),
+		Platform:            shared.GetGoos() + "/" + shared.GetGoarch(),
+		Runtimes:            withRuntimes,
+		Paths:               sections,
+	}
+
+	if err := shared.EnsureDir0755(filepath.Dir(archivePath)); err != nil {
+		return err
+	}
+	tempPath := archivePath + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", archivePath, err)
+	}
+	defer os.Remove(tempPath)
+
+	zipWriter := zip.NewWriter(file)
+	if err := writeInstanceArchive(zipWriter, manifest, sections, withRuntimes, out); err != nil {
+		zipWriter.Close()
+		file.Close()
+		return err
+	}
+	if err := zipWriter.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", archivePath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", archivePath, err)
+	}
+	if err := os.Rename(tempPath, archivePath); err != nil {
+		return fmt.Errorf("replace %s: %w", archivePath, err)
+	}
+	fmt.Fprintf(out, "Exported instance %q to %s\n", paths.InstanceName, archivePath)
+	return nil
+}
+
+func writeInstanceArchive(zipWriter *zip.Writer, manifest instanceManifest, sections map[string]string, withRuntimes bool, out io.Writer) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer, err := zipWriter.Create(instanceArchiveManifest)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+
+	for _, section := range []string{"controlpanel", "owlcms", "tracker", "firmata"} {
+		fmt.Fprintf(out, "Adding %s\n", sections[section])
+		if err := addDirToZip(zipWriter, sections[section], section); err != nil {
+			return fmt.Errorf("add %s: %w", sections[section], err)
+		}
+	}
+	if !withRuntimes {
+		return nil
+	}
+	for _, name := range runtimeArchiveDirs {
+		dir := filepath.Join(sections["runtime"], name)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		fmt.Fprintf(out, "Adding %s\n", dir)
+		if err := addDirToZip(zipWriter, dir, "runtime/"+name); err != nil {
+			return fmt.Errorf("add %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// addDirToZip stores dir under prefix, leaving out the top-level runtime
+// state of running processes, the shared runtimes and the secrets store.
+func addDirToZip(zipWriter *zip.Writer, dir, prefix string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	topLevel := !strings.HasPrefix(prefix, "runtime/")
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if topLevel && !strings.Contains(relPath, string(os.PathSeparator)) && (isInstanceRuntimeEntry(info.Name()) || info.Name() == shared.SecretsFileName) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = prefix + "/" + filepath.ToSlash(relPath)
+		if info.IsDir() {
+			header.Name += "/"
+			_, err = zipWriter.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(writer, file)
+		return err
+	})
+}
+
+// importInstance recreates an exported instance on this machine, under its
+// exported name or the one given with --instance. Paths of the exporting
+// machine found in env.properties files are replaced by the local ones, and the
+// ports are kept unless another instance holds them.
+func importInstance(archivePath, instanceArg, runtimeArg string, out io.Writer) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", archivePath, err)
+	}
+	defer reader.Close()
+
+	manifest, err := readInstanceManifest(&reader.Reader)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(instanceArg)
+	if name == "" {
+		name = manifest.Instance
+	}
+	if err := validateInstanceName(name); err != nil {
+		return err
+	}
+	paths, err := resolveInstancePaths(name)
+	if err != nil {
+		return err
+	}
+	if instanceHasVersions(paths) {
+		return fmt.Errorf("instance %q already has installed versions; import under another name with --instance", name)
+	}
+
+	runtimeDir := resolveRuntimeDir(runtimeArg)
+	if strings.TrimSpace(runtimeArg) == "" {
+		if stored, err := loadStoredRuntimeDir(paths.ControlPanelDir); err == nil && stored != "" {
+			runtimeDir = stored
+		}
+	}
+	sections := archiveSections(paths, runtimeDir)
+	if err := extractInstanceArchive(&reader.Reader, sections, out); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{paths.ControlPanelDir, paths.OwlcmsDir, paths.TrackerDir, sections["firmata"]} {
+		if err := remapEnvPaths(dir, manifest.Paths, sections); err != nil {
+			return err
+		}
+	}
+	if err := writeControlPanelEnv(paths.ControlPanelDir, runtimeDir, name); err != nil {
+		return err
+	}
+	if err := applyCLIInstanceOptions(cliOptions{instanceArg: name}); err != nil {
+		return err
+	}
+	if err := owlcms.InitEnv(); err != nil {
+		return err
+	}
+	if err := tracker.InitEnv(); err != nil {
+		return err
+	}
+	if err := registerRuntimeInstance(paths, runtimeDir); err != nil {
+		return err
+	}
+	oldOwlcmsPort, oldTrackerPort := owlcms.GetPort(), tracker.GetPort()
+	if err := registerInstancePorts(name); err != nil {
+		return err
+	}
+	if err := moveReleasePorts(paths, oldOwlcmsPort, oldTrackerPort); err != nil {
+		return err
+	}
+
+	printInitializedInstance(out, "Imported", name)
+	return nil
+}
+
+func readInstanceManifest(reader *zip.Reader) (*instanceManifest, error) {
+	file, err := reader.Open(instanceArchiveManifest)
+	if err != nil {
+		return nil, fmt.Errorf("not an instance archive: %w", err)
+	}
+	defer file.Close()
+
+	var manifest instanceManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("read %s: %w", instanceArchiveManifest, err)
+	}
+	if manifest.Format != instanceArchiveFormat {
+		return nil, fmt.Errorf("unsupported instance archive format %d", manifest.Format)
+	}
+	return &manifest, nil
+}
+
+// instanceHasVersions reports whether the OWLCMS or Tracker directory of the
+// instance holds any version.
+func instanceHasVersions(paths *instancePaths) bool {
+	for _, dir := range []string{paths.OwlcmsDir, paths.TrackerDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractInstanceArchive writes each archive section to its local directory.
+// Firmata versions and runtimes are shared with other instances, so those
+// already present are kept as they are.
+func extractInstanceArchive(reader *zip.Reader, sections map[string]string, out io.Writer) error {
+	skipped := map[string]bool{}
+	for _, file := range reader.File {
+		section, relPath, found := strings.Cut(file.Name, "/")
+		targetDir, known := sections[section]
+		if !found || !known || relPath == "" {
+			continue
+		}
+
+		if section == "firmata" || section == "runtime" {
+			// firmata/<version>/... and runtime/<java|node|ffmpeg>/<version>/...
+			depth := 1
+			if section == "runtime" {
+				depth = 2
+			}
+			parts := strings.SplitN(relPath, "/", depth+1)
+			if len(parts) > depth {
+				key := section + "/" + strings.Join(parts[:depth], "/")
+				if _, seen := skipped[key]; !seen {
+					_, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(strings.Join(parts[:depth], "/"))))
+					skipped[key] = err == nil
+					if err == nil {
+						fmt.Fprintf(out, "Keeping existing %s\n", key)
+					}
+				}
+				if skipped[key] {
+					continue
+				}
+			}
+		}
+
+		target, err := safeArchivePath(targetDir, relPath)
+		if err != nil {
+			return err
+		}
+		if err := extractArchiveFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// safeArchivePath joins relPath to dir, refusing entries that escape dir.
+func safeArchivePath(dir, relPath string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(relPath))
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q is outside its directory", relPath)
+	}
+	return target, nil
+}
+
+func extractArchiveFile(file *zip.File, target string) error {
+	if file.FileInfo().IsDir() {
+		return shared.EnsureDir0755(target)
+	}
+	if err := shared.EnsureDir0755(filepath.Dir(target)); err != nil {
+		return err
+	}
+	in, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", file.Name, err)
+	}
+	defer in.Close()
+
+	outFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode().Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", target, err)
+	}
+	if _, err := io.Copy(outFile, in); err != nil {
+		outFile.Close()
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	if err := outFile.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, file.Modified, file.Modified)
+}
+
+// remapEnvPaths replaces, in the values of every env.properties under dir,
+// the directories of the exporting machine by the matching local directories.
+// The files are decoded and written back with the properties API, so that
+// escaped Windows paths such as C\:\\owlcms are remapped too.
+func remapEnvPaths(dir string, exported, local map[string]string) error {
+	type replacement struct{ from, to string }
+	var pairs []replacement
+	for section, oldPath := range exported {
+		newPath := local[section]
+		if oldPath == "" || newPath == "" || oldPath == newPath {
+			continue
+		}
+		pairs = append(pairs, replacement{oldPath, newPath})
+		if slashed := strings.ReplaceAll(oldPath, `\`, "/"); slashed != oldPath {
+			pairs = append(pairs, replacement{slashed, filepath.ToSlash(newPath)})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	// Longest first, so that .../owlcms-tracker is not taken for .../owlcms.
+	sort.Slice(pairs, func(i, j int) bool { return len(pairs[i].from) > len(pairs[j].from) })
+	var replacements []string
+	for _, pair := range pairs {
+		replacements = append(replacements, pair.from, pair.to)
+	}
+	replacer := strings.NewReplacer(replacements...)
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || info.Name() != "env.properties" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		props := properties.NewProperties()
+		if err := props.Load(content, properties.UTF8); err != nil {
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+		changed := false
+		for _, key := range props.Keys() {
+			value, _ := props.Get(key)
+			if remapped := replacer.Replace(value); remapped != value {
+				if _, _, err := props.Set(key, remapped); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		var remapped bytes.Buffer
+		if _, err := props.WriteComment(&remapped, "# ", properties.UTF8); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		return os.WriteFile(path, remapped.Bytes(), info.Mode().Perm())
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportInstance(t *testing.T) {
+	base := setupInstancesTest(t)
+	var out bytes.Buffer
+	if err := runInstancesCommand(cliOptions{instances: []string{"create", "records"}}, nil, &out); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	release := filepath.Join(base, "records-owlcms", "64.0.0")
+	if err := os.MkdirAll(filepath.Join(release, "database"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "database", "owlcms.mv.db"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "env.properties"), []byte("OWLCMS_LOCALDIR="+filepath.Join(release, "local")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(base, "records-controlpanel", "secrets.properties"), []byte("db-password=hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "records.zip")
+	if err := exportInstance(archive, false, &out); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	if err := importInstance(archive, "backup", "", &out); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	imported := filepath.Join(base, "backup-owlcms", "64.0.0")
+	if content, err := os.ReadFile(filepath.Join(imported, "database", "owlcms.mv.db")); err != nil || string(content) != "data" {
+		t.Fatalf("database was not imported: %q %v", content, err)
+	}
+	env, err := os.ReadFile(filepath.Join(imported, "env.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), filepath.Join(imported, "local")) {
+		t.Fatalf("paths were not remapped: %s", env)
+	}
+	if _, err := os.Stat(filepath.Join(base, "backup-controlpanel", "secrets.properties")); !os.IsNotExist(err) {
+		t.Fatalf("expected the secrets store to be left out of the export, got %v", err)
+	}
+	if name := instanceProperty(filepath.Join(base, "backup-controlpanel"), "CONTROLPANEL_INSTANCE", ""); name != "backup" {
+		t.Fatalf("imported instance name = %q", name)
+	}
+
+	if err := importInstance(archive, "backup", "", &out); err == nil {
+		t.Fatal("expected a second import over existing versions to fail")
+	}
+}
+
+func TestRemapEnvPathsPrefersLongestPath(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "env.properties")
+	if err := os.WriteFile(envPath, []byte("A=/old/owlcms-tracker/x\nB=/old/owlcms/y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exported := map[string]string{"owlcms": "/old/owlcms", "tracker": "/old/owlcms-tracker"}
+	local := map[string]string{"owlcms": "/new/o", "tracker": "/new/t"}
+	if err := remapEnvPaths(dir, exported, local); err != nil {
+		t.Fatalf("remap: %v", err)
+	}
+	props, err := loadControlPanelEnv(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := props.GetString("A", ""), props.GetString("B", ""); a != "/new/t/x" || b != "/new/o/y" {
+		t.Fatalf("unexpected remap: A=%s B=%s", a, b)
+	}
+}
+
+func TestRemapEnvPathsDecodesEscapedWindowsPaths(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "env.properties")
+	content := "# exported on Windows\nOWLCMS_LOCALDIR=C\\:\\\\owlcms\\\\64.0.0\\\\local\nOWLCMS_PORT=8080\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exported := map[string]string{"owlcms": `C:\owlcms`}
+	local := map[string]string{"owlcms": "/home/owlcms/owlcms"}
+	if err := remapEnvPaths(dir, exported, local); err != nil {
+		t.Fatalf("remap: %v", err)
+	}
+	props, err := loadControlPanelEnv(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := props.GetString("OWLCMS_LOCALDIR", ""); got != `/home/owlcms/owlcms\64.0.0\local` {
+		t.Fatalf("unexpected remap: %q", got)
+	}
+	if got := props.GetString("OWLCMS_PORT", ""); got != "8080" {
+		t.Fatalf("OWLCMS_PORT changed to %q", got)
+	}
+	if remapped, _ := os.ReadFile(envPath); !strings.Contains(string(remapped), "exported on Windows") {
+		t.Fatalf("comments were lost:\n%s", remapped)
+	}
+}
+
+func TestSafeArchivePathRejectsEscapes(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := safeArchivePath(dir, "../evil"); err == nil {
+		t.Fatal("expected ../evil to be rejected")
+	}
+	if _, err := safeArchivePath(dir, "64.0.0/env.properties"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,7 +20,24 @@ type cliOptions struct {
 	instanceArg string
 	runtimeArg  string
 	init        bool
+	ports       bool
+	dashboard   string
+	metrics     string
 	mqtt        bool
+	mqttStatus  string
+	instances   []string
+	yes         bool
+	exportPath  string
+	importPath  string
+	runtimes    bool
+	runtimeCmd  []string
+	secretCmd   []string
+	dryRun      bool
+	checkConfig bool
+	service     string
+	container   bool
+	userScope   bool
+	systemScope bool
 	help        bool
 }
 
@@ -30,7 +48,7 @@ type instancePaths struct {
 	TrackerDir      string
 }
 
-const mainInstanceName = "owlcms"
+const mainInstanceName = shared.MainInstanceName
 
 func parseCLIOptions(args []string) cliOptions {
 	var opts cliOptions
@@ -52,7 +70,7 @@ func parseCLIOptions(args []string) cliOptions {
 				i++
 				opts.instanceArg = strings.TrimSpace(args[i])
 			}
-		case "-m", "--module", "--version", "--update-to", "--duplicate", "--from-version", "--to-version", "--remove", "--port", "--install-zip", "--create-zip":
+		case "-m", "--module", "--version", "--update-to", "--duplicate", "--from-version", "--to-version", "--remove", "--port", "--install-zip", "--create-zip", "--profile":
 			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
 				i++
 			}
@@ -60,15 +78,92 @@ func parseCLIOptions(args []string) cliOptions {
 			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
 				i++
 			}
-		case "--launch", "--stop", "--list", "--import", "--background":
+		case "--launch", "--stop", "--list", "--import", "--background", "--auto-port":
 		case "--owlcms", "--tracker":
 			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
 				i++
 			}
 		case "--init":
 			opts.init = true
+		case "--ports":
+			opts.ports = true
+		case "--metrics":
+			opts.metrics = defaultMetricsAddress
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.metrics = strings.TrimSpace(args[i])
+			}
+		case "--dashboard":
+			opts.dashboard = defaultDashboardAddress
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.dashboard = strings.TrimSpace(args[i])
+			}
 		case "--mqtt":
 			opts.mqtt = true
+		case "--mqtt-status":
+			opts.mqttStatus = defaultMQTTStatusBroker
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.mqttStatus = strings.TrimSpace(args[i])
+			}
+		case "--instances":
+			opts.instances = []string{"list"}
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				opts.instances = nil
+				for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					opts.instances = append(opts.instances, strings.TrimSpace(args[i]))
+				}
+			}
+		case "--runtimes":
+			opts.runtimeCmd = []string{"list"}
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				opts.runtimeCmd = nil
+				for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					opts.runtimeCmd = append(opts.runtimeCmd, strings.TrimSpace(args[i]))
+				}
+			}
+		case "--secret":
+			opts.secretCmd = []string{"list"}
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				opts.secretCmd = nil
+				for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					opts.secretCmd = append(opts.secretCmd, strings.TrimSpace(args[i]))
+				}
+			}
+		case "--yes":
+			opts.yes = true
+		case "--dry-run":
+			opts.dryRun = true
+		case "--check-config":
+			opts.checkConfig = true
+		case "--instance-export":
+			if i+1 < len(args) {
+				i++
+				opts.exportPath = strings.TrimSpace(args[i])
+			}
+		case "--instance-import":
+			if i+1 < len(args) {
+				i++
+				opts.importPath = strings.TrimSpace(args[i])
+			}
+		case "--with-runtimes":
+			opts.runtimes = true
+		case "--container":
+			opts.container = true
+		case "--install-service":
+			opts.service = "install"
+		case "--uninstall-service":
+			opts.service = "uninstall"
+		case "--service-status":
+			opts.service = "status"
+		case "--user":
+			opts.userScope = true
+		case "--system":
+			opts.systemScope = true
 		case "--help", "-h":
 			opts.help = true
 		default:
@@ -82,7 +177,7 @@ func parseCLIOptions(args []string) cliOptions {
 }
 
 func printUsage() {
-	fmt.Println("Usage: controlpanel [instance options] --module <owlcms|tracker> <action> [action options]")
+	fmt.Println("Usage: controlpanel [instance options] --module <owlcms|tracker|firmata|cameras|replays> <action> [action options]")
 	fmt.Println("")
 	fmt.Println("Most common cases:")
 	fmt.Println("  Start OWLCMS in the foreground:")
@@ -99,6 +194,15 @@ func printUsage() {
 	fmt.Println("    controlpanel --module owlcms --stop")
 	fmt.Println("  Stop Tracker:")
 	fmt.Println("    controlpanel --module tracker --stop")
+	fmt.Println("  Start or stop the refereeing devices (firmata):")
+	fmt.Println("    controlpanel --module firmata --launch --background")
+	fmt.Println("    controlpanel --module firmata --stop")
+	fmt.Println("  Start or stop the jury replays on a video box:")
+	fmt.Println("    controlpanel --module replays --launch --background")
+	fmt.Println("    controlpanel --module replays --stop")
+	fmt.Println("  Start or stop a full competition stack described in a profile:")
+	fmt.Println("    controlpanel --profile nationals.toml --launch")
+	fmt.Println("    controlpanel --profile nationals.toml --stop")
 	fmt.Println("")
 	fmt.Println("Version, update, and import commands:")
 	fmt.Println("  List installed versions:")
@@ -107,6 +211,8 @@ func printUsage() {
 	fmt.Println("  Install a new downloaded version:")
 	fmt.Println("    controlpanel --module owlcms --install latest")
 	fmt.Println("    controlpanel --module tracker --install latest")
+	fmt.Println("    controlpanel --module firmata --install latest")
+	fmt.Println("    controlpanel --module cameras --install latest")
 	fmt.Println("  Install from or create a local ZIP:")
 	fmt.Println("    controlpanel --module owlcms --install-zip C:/Downloads/owlcms_66.0.0.zip")
 	fmt.Println("    controlpanel --module tracker --create-zip C:/Backups/tracker.zip --version 3.4.0")
@@ -122,13 +228,17 @@ func printUsage() {
 	fmt.Println("")
 	fmt.Println("Switch reference:")
 	fmt.Println("  Module selection:")
-	fmt.Println("    -m, --module <owlcms|tracker>        Selects the module to manage")
+	fmt.Println("    -m, --module <module>                Selects owlcms, tracker, firmata, cameras or replays")
+	fmt.Println("    --profile <file>                     Selects the modules of a launch profile (.toml or .properties)")
+	fmt.Println("                                        Only --launch and --stop; modules stop in reverse order")
 	fmt.Println("  Actions:")
 	fmt.Println("    --launch                             Starts the selected module")
 	fmt.Println("    --stop                               Stops the selected running module")
 	fmt.Println("    --list                               Lists installed local versions")
+	fmt.Println("    --show-env                           Lists the variables a launch of --version passes to the module,")
+	fmt.Println("                                        with where each comes from; secrets are hidden")
 	fmt.Println("    --install [latest|<github-version>]  Downloads a clean new version")
-	fmt.Println("    --install-zip <zip-file>             Installs a local ZIP file, often from a federation")
+	fmt.Println("    --install-zip <zip-file>             Installs a local ZIP file, often from a federation (owlcms and tracker only)")
 	fmt.Println("    --create-zip <zip-file|directory>    Creates a ZIP from the version selected by --version")
 	fmt.Println("                                        Uses a .zip path exactly, or creates a timestamped file in an existing directory")
 	fmt.Println("    --update-to <latest|github-version>  Updates using --version as local source")
@@ -138,7 +248,9 @@ func printUsage() {
 	fmt.Println("  Launch options:")
 	fmt.Println("    --version <latest|previous|version>  Local version selector; default: latest")
 	fmt.Println("    --background                         Runs detached and returns the terminal")
-	fmt.Println("    --port <port>                        Stores a version-specific launch port")
+	fmt.Println("    --port <port>                        Stores a version-specific launch port (not cameras/replays)")
+	fmt.Println("    --auto-port                          Uses and stores the next free port when the port is busy,")
+	fmt.Println("                                        and moves OWLCMS's local tracker connection along")
 	fmt.Println("    --local-tracker [port]               OWLCMS only; default tracker port 8096")
 	fmt.Println("    --mqtt                               OWLCMS only; enables embedded MQTT")
 	fmt.Println("  Version-copy options:")
@@ -166,7 +278,88 @@ func printUsage() {
 	fmt.Println("    controlpanel --instance records --init")
 	fmt.Println("    controlpanel --instance records --runtime-dir runtime-records --init")
 	fmt.Println("    controlpanel --instance-dir C:/owlcms/controlpanel-records --runtime-dir C:/owlcms/runtime --init")
+	fmt.Println("  --init gives each new instance its own owlcms/tracker port block (8180/8196, 8280/8296, ...)")
+	fmt.Println("")
+	fmt.Println("Manage instances (sibling <name>-controlpanel, <name>-owlcms and <name>-tracker directories):")
+	fmt.Println("    controlpanel --instances list               Shows ports, running modules and runtime dir")
+	fmt.Println("    controlpanel --instances create records     Same as --instance records --init, refusing existing names")
+	fmt.Println("    controlpanel --instances clone records masters")
+	fmt.Println("                                        Copies versions, databases and settings to a new port block")
+	fmt.Println("    controlpanel --instances rename masters juniors")
+	fmt.Println("    controlpanel --instances delete juniors [--yes]")
+	fmt.Println("                                        Asks for the name unless --yes; refuses running instances")
+	fmt.Println("")
+	fmt.Println("Move an instance to another machine (versions, databases, settings and ports):")
+	fmt.Println("    controlpanel --instance records --instance-export records.zip [--with-runtimes]")
+	fmt.Println("    controlpanel --instance-import records.zip [--instance backup]")
+	fmt.Println("")
+	fmt.Println("Run in a container, configured by environment variables (see CommandlineGuide.md):")
+	fmt.Println("    CONTROLPANEL_MODULES=owlcms,tracker OWLCMS_VERSION=latest controlpanel --container")
+	fmt.Println("")
+	fmt.Println("Run the OWLCMS and Tracker of an instance as systemd services (Linux):")
+	fmt.Println("    controlpanel --instance records --install-service [--user|--system]")
+	fmt.Println("    controlpanel --instance records --service-status")
+	fmt.Println("    controlpanel --instance records --uninstall-service")
+	fmt.Println("  User services by default, system services when run as root")
+	fmt.Println("")
+	fmt.Println("Manage the Java, Node.js and FFmpeg builds of the runtime directory:")
+	fmt.Println("    controlpanel --runtimes list                Shows size and the versions/instances using each build")
+	fmt.Println("    controlpanel --runtimes install java jdk-25 Pre-stages a runtime; node defaults to the latest LTS")
+	fmt.Println("    controlpanel --runtimes remove node v22.11.0 [--yes]")
+	fmt.Println("                                        Refuses builds still in use unless --yes")
+	fmt.Println("    controlpanel --runtimes verify [java|node|ffmpeg]")
+	fmt.Println("    controlpanel --runtimes clean [java|node] [--dry-run]")
+	fmt.Println("                                        Removes builds no instance sharing the runtime dir uses")
+	fmt.Println("    controlpanel --runtimes pin java jdk-25.0.1+8 [owlcms|firmata] [module-version]")
+	fmt.Println("    controlpanel --runtimes pin node v22.11.0 [tracker] [module-version]")
+	fmt.Println("    controlpanel --runtimes policy [bundled|prefer-system|system|path] [java|node <executable>]")
+	fmt.Println("                                        Shows or sets where launches find Java and Node.js")
+	fmt.Println("")
+	fmt.Println("Keep passwords and tokens out of env.properties (secret:<name> refers to a secret):")
+	fmt.Println("    controlpanel --instance records --secret list")
+	fmt.Println("    controlpanel --instance records --secret set db-password < password.txt")
+	fmt.Println("    controlpanel --instance records --secret unset db-password")
 	fmt.Println("")
+	fmt.Println("Check every env.properties and config.toml of an instance (exits 1 on errors):")
+	fmt.Println("    controlpanel --instance records --check-config")
+	fmt.Println("")
+	fmt.Println("List the ports allocated to every instance sharing the runtime directory:")
+	fmt.Println("    controlpanel --ports")
+	fmt.Println("")
+	fmt.Println("Web dashboard for headless servers (password in dashboard-password in the control panel dir):")
+	fmt.Println("    controlpanel --dashboard                    Serves on " + defaultDashboardAddress)
+	fmt.Println("    controlpanel --dashboard 0.0.0.0:8070       Reachable from the local network")
+	fmt.Println("")
+	fmt.Println("Prometheus metrics on /metrics (interactive, --dashboard and foreground or systemd launches):")
+	fmt.Println("    controlpanel --dashboard --metrics          Serves on " + defaultMetricsAddress)
+	fmt.Println("    controlpanel --module owlcms --launch --metrics 0.0.0.0:9464")
+	fmt.Println("")
+	fmt.Println("Status over MQTT under owlcms/controlpanel/<instance> (same processes as metrics):")
+	fmt.Println("    controlpanel --dashboard --mqtt-status      Publishes to " + defaultMQTTStatusBroker)
+	fmt.Println("    controlpanel --module owlcms --launch --mqtt --mqtt-status broker.local:1883")
+	fmt.Println("")
+}
+
+// printPortAllocations writes the shared port registry, one instance/module per line.
+func printPortAllocations(out io.Writer) error {
+	registry, err := shared.LoadPortRegistry()
+	if err != nil {
+		return err
+	}
+	allocations := registry.Allocations()
+	if len(allocations) == 0 {
+		fmt.Fprintf(out, "No ports allocated in %s\n", shared.PortRegistryPath())
+		return nil
+	}
+	fmt.Fprintf(out, "%-20s %-10s %-16s %s\n", "INSTANCE", "MODULE", "VERSION", "PORT")
+	for _, allocation := range allocations {
+		version := allocation.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(out, "%-20s %-10s %-16s %s\n", allocation.Instance, allocation.Module, version, allocation.Port)
+	}
+	return nil
 }
 
 func maybeApplyImplicitInstanceForHeadless(opts cliOptions, owlcmsVersion, trackerVersion string) (string, string, error) {
@@ -318,7 +511,45 @@ func initializeInstanceLayout(paths *instancePaths, runtimeDir string) error {
 	if err := tracker.InitEnv(); err != nil {
 		return err
 	}
+	if err := registerRuntimeInstance(paths, runtimeDir); err != nil {
+		return err
+	}
+
+	return registerInstancePorts(paths.InstanceName)
+}
+
+// registerRuntimeInstance records instance in the instance registry of its
+// runtime directory, so the runtimes it uses are counted even when its
+// directories are not next to the default install directory.
+func registerRuntimeInstance(paths *instancePaths, runtimeDir string) error {
+	return shared.RegisterInstance(runtimeDir, shared.RegisteredInstance{
+		Name:            paths.InstanceName,
+		ControlPanelDir: paths.ControlPanelDir,
+		OwlcmsDir:       paths.OwlcmsDir,
+		TrackerDir:      paths.TrackerDir,
+	})
+}
+
+// registerInstancePorts records the owlcms and tracker ports of instance in the
+// shared port registry. When another instance already holds one of them, a
+// conflict-free port block is allocated and saved in the instance env.properties.
+func registerInstancePorts(instance string) error {
+	owlcmsErr := shared.ClaimInstancePort(instance, "owlcms", "", owlcms.GetPort())
+	trackerErr := shared.ClaimInstancePort(instance, "tracker", "", tracker.GetPort())
+	if owlcmsErr == nil && trackerErr == nil {
+		return nil
+	}
 
+	block, err := shared.AllocatePortBlock(instance)
+	if err != nil {
+		return err
+	}
+	if err := owlcms.SaveProperty("OWLCMS_PORT", block["owlcms"]); err != nil {
+		return fmt.Errorf("save owlcms port: %w", err)
+	}
+	if err := tracker.SaveProperty("TRACKER_PORT", block["tracker"]); err != nil {
+		return fmt.Errorf("save tracker port: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"controlpanel/shared"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/magiconair/properties"
+)
+
+// instancePickerKey in the main control panel env.properties turns the
+// startup instance picker off when set to false.
+const instancePickerKey = "CONTROLPANEL_INSTANCE_PICKER"
+
+const instancesOverviewRefresh = 5 * time.Second
+
+// instanceSelectionEnv are the variables set by --instance; they are removed
+// from the environment of the control panel opened for another instance.
+var instanceSelectionEnv = []string{"CONTROLPANEL_INSTALLDIR", "OWLCMS_INSTALLDIR", "TRACKER_INSTALLDIR", "RUNTIME_DIR", "CONTROLPANEL_INSTANCE"}
+
+// shouldShowInstancePicker reports whether the interactive control panel asks
+// which instance to open: no instance was selected, named instances exist, and
+// the picker was not turned off.
+func shouldShowInstancePicker(opts cliOptions) bool {
+	if strings.TrimSpace(opts.instanceArg) != "" || strings.TrimSpace(os.Getenv("CONTROLPANEL_INSTANCE")) != "" {
+		return false
+	}
+	if !instancePickerEnabled() {
+		return false
+	}
+	instances, err := discoverInstances()
+	if err != nil {
+		log.Printf("Failed to discover instances: %v", err)
+		return false
+	}
+	for _, instance := range instances {
+		if !isMainInstance(instance.Paths.InstanceName) {
+			return true
+		}
+	}
+	return false
+}
+
+func instancePickerEnabled() bool {
+	props, err := loadControlPanelEnv(shared.DefaultControlPanelInstallDir())
+	if err != nil || props == nil {
+		return true
+	}
+	value, ok := props.Get(instancePickerKey)
+	return !ok || !strings.EqualFold(strings.TrimSpace(value), "false")
+}
+
+func setInstancePickerEnabled(enabled bool) error {
+	dir := shared.DefaultControlPanelInstallDir()
+	props, err := loadControlPanelEnv(dir)
+	if err != nil {
+		return err
+	}
+	if props == nil {
+		props = properties.NewProperties()
+	}
+	props.Set(instancePickerKey, fmt.Sprintf("%t", enabled))
+	return writeFileAtomically(controlPanelEnvPath(dir), []byte(props.String()), 0644)
+}
+
+// openInstance starts the interactive control panel of another instance.
+func openInstance(name string) error {
+	paths, err := resolveInstancePaths(name)
+	if err != nil {
+		return err
+	}
+	for _, process := range instanceRunningProcesses(paths) {
+		if process == "control panel" {
+			return fmt.Errorf("the control panel of instance %q is already open", name)
+		}
+	}
+
+	cmd, err := controlPanelCommand("--instance", name)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureDetachedDaemonProcess(cmd, true)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start control panel for instance %q: %w", name, err)
+	}
+	log.Printf("Opened control panel for instance %q (PID %d)", name, cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// controlPanelCommand runs this executable without the instance selected in
+// this process, so that args alone choose the instance.
+func controlPanelCommand(args ...string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate control panel executable: %w", err)
+	}
+	cmd := exec.Command(executable, args...)
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		selected := false
+		for _, key := range instanceSelectionEnv {
+			if name == key {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			cmd.Env = append(cmd.Env, entry)
+		}
+	}
+	return cmd, nil
+}
+
+// createInstanceFromGUI runs --instances create in a separate process, since
+// creating an instance selects it and this process must keep its own.
+func createInstanceFromGUI(name string) error {
+	name = strings.TrimSpace(name)
+	if err := validateInstanceName(name); err != nil {
+		return err
+	}
+	cmd, err := controlPanelCommand("--instances", "create", name)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureNoConsoleWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	log.Printf("Created instance %q:\n%s", name, strings.TrimSpace(string(output)))
+	return nil
+}
+
+// instanceStatusText summarizes the running state of an instance.
+func instanceStatusText(instance instanceInfo) string {
+	status := fmt.Sprintf("ports %s/%s", instance.OwlcmsPort, instance.TrackerPort)
+	if len(instance.Running) == 0 {
+		return status + ", nothing running"
+	}
+	return status + ", running: " + strings.Join(instance.Running, ", ")
+}
+
+// newInstancePickerList shows one row per instance with an Open button. The
+// current instance is labeled instead of being openable.
+func newInstancePickerList(instances []instanceInfo, current string, onOpen func(name string)) fyne.CanvasObject {
+	rows := container.NewVBox()
+	for _, instance := range instances {
+		name := instance.Paths.InstanceName
+		title := widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		if isMainInstance(name) {
+			title.SetText(name + " (main)")
+		}
+		var action fyne.CanvasObject
+		if name == current {
+			action = widget.NewLabel("This window")
+		} else {
+			action = widget.NewButton("Open", func() { onOpen(name) })
+		}
+		rows.Add(container.NewHBox(title, widget.NewLabel(instanceStatusText(instance)), layout.NewSpacer(), action))
+	}
+	return rows
+}
+
+// showNewInstanceDialog asks for a name, creates the instance and opens it.
+func showNewInstanceDialog(w fyne.Window, onCreated func(name string)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("records")
+	dialog.ShowForm(
+		"New Instance",
+		"Create",
+		"Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("", widget.NewLabel("The instance gets its own versions, databases and the next free port block.")),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			name := strings.TrimSpace(nameEntry.Text)
+			go func() {
+				err := createInstanceFromGUI(name)
+				fyne.Do(func() {
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("failed to create instance %q: %w", name, err), w)
+						return
+					}
+					onCreated(name)
+				})
+			}()
+		},
+		w,
+	)
+}
+
+// showStartupInstancePicker fills w with the instance list. Choosing the main
+// instance continues in this window; another instance gets its own control
+// panel and this one quits.
+func showStartupInstancePicker(a fyne.App, w fyne.Window, initialWindowSize fyne.Size, continueMain func()) {
+	instances, err := discoverInstances()
+	if err != nil {
+		log.Printf("Failed to discover instances: %v", err)
+		continueMain()
+		return
+	}
+
+	open := func(name string) {
+		if isMainInstance(name) {
+			continueMain()
+			return
+		}
+		if err := openInstance(name); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		a.Quit()
+	}
+
+	// Set the initial state before OnChanged so that opening the picker does
+	// not save the setting.
+	askCheck := widget.NewCheck("Ask at startup", nil)
+	askCheck.Checked = true
+	askCheck.OnChanged = func(checked bool) {
+		if err := setInstancePickerEnabled(checked); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save setting: %w", err), w)
+		}
+	}
+
+	newButton := widget.NewButton("New Instance...", func() {
+		showNewInstanceDialog(w, open)
+	})
+
+	w.Resize(initialWindowSize)
+	w.SetContent(container.NewBorder(
+		widget.NewLabelWithStyle("Choose the instance to open", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(newButton, layout.NewSpacer(), askCheck),
+		nil,
+		nil,
+		container.NewVScroll(newInstancePickerList(instances, "", open)),
+	))
+	w.Show()
+}
+
+// showInstancePickerDialog lists the instances from the File menu and opens
+// the selected one in its own control panel.
+func showInstancePickerDialog(w fyne.Window) {
+	instances, err := discoverInstances()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	var picker *dialog.CustomDialog
+	open := func(name string) {
+		if err := openInstance(name); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		picker.Hide()
+	}
+
+	askCheck := widget.NewCheck("Ask at startup when several instances exist", nil)
+	askCheck.Checked = instancePickerEnabled()
+	askCheck.OnChanged = func(checked bool) {
+		if err := setInstancePickerEnabled(checked); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save setting: %w", err), w)
+		}
+	}
+
+	content := container.NewBorder(
+		nil,
+		container.NewVBox(
+			askCheck,
+			container.NewHBox(
+				widget.NewButton("New Instance...", func() { showNewInstanceDialog(w, open) }),
+				widget.NewButton("Overview", func() { showInstancesOverview() }),
+			),
+		),
+		nil,
+		nil,
+		container.NewVScroll(newInstancePickerList(instances, shared.CurrentInstanceName(), open)),
+	)
+	picker = dialog.NewCustom("Instances", "Close", content, w)
+	picker.Resize(fyne.NewSize(800, 400))
+	picker.Show()
+}
+
+var instancesOverviewWindow fyne.Window
+
+// showInstancesOverview opens a window showing every instance side by side,
+// refreshed while it stays open.
+func showInstancesOverview() {
+	if instancesOverviewWindow != nil {
+		instancesOverviewWindow.RequestFocus()
+		return
+	}
+
+	window := fyne.CurrentApp().NewWindow("OWLCMS Instances")
+	cards := container.NewHBox()
+	updated := widget.NewLabel("")
+	refresh := func() {
+		instances, err := discoverInstances()
+		fyne.Do(func() {
+			if err != nil {
+				updated.SetText(err.Error())
+				return
+			}
+			cards.RemoveAll()
+			for _, instance := range instances {
+				cards.Add(newInstanceCard(window, instance))
+			}
+			updated.SetText("Updated " + time.Now().Format("15:04:05"))
+		})
+	}
+
+	stop := make(chan struct{})
+	window.SetOnClosed(func() {
+		close(stop)
+		instancesOverviewWindow = nil
+	})
+	window.SetContent(container.NewBorder(nil, updated, nil, nil, container.NewScroll(cards)))
+	window.Resize(fyne.NewSize(900, 320))
+	instancesOverviewWindow = window
+	window.Show()
+
+	go func() {
+		ticker := time.NewTicker(instancesOverviewRefresh)
+		defer ticker.Stop()
+		refresh()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+func newInstanceCard(w fyne.Window, instance instanceInfo) fyne.CanvasObject {
+	name := instance.Paths.InstanceName
+	lines := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("OWLCMS port %s, Tracker port %s", instance.OwlcmsPort, instance.TrackerPort)),
+	)
+	if len(instance.Running) == 0 {
+		lines.Add(widget.NewLabel("Nothing running"))
+	}
+	controlPanelOpen := false
+	for _, process := range instance.Running {
+		if process == "control panel" {
+			controlPanelOpen = true
+		}
+		lines.Add(widget.NewLabel("Running: " + process))
+	}
+
+	if name != shared.CurrentInstanceName() && !controlPanelOpen {
+		lines.Add(widget.NewButton("Open", func() {
+			if err := openInstance(name); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}))
+	}
+
+	subtitle := instance.RuntimeDir
+	if name == shared.CurrentInstanceName() {
+		subtitle = "this window"
+	}
+	return widget.NewCard(name, subtitle, lines)
+}
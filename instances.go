@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+)
+
+// instanceDirSuffixes name the sibling directories that make up an instance.
+var instanceDirSuffixes = []string{"-controlpanel", "-owlcms", "-tracker"}
+
+// instanceInfo describes an instance found next to the main control panel directory.
+type instanceInfo struct {
+	Paths       *instancePaths
+	RuntimeDir  string
+	OwlcmsPort  string
+	TrackerPort string
+	Running     []string
+}
+
+// runInstancesCommand executes --instances list|create|clone|rename|delete.
+func runInstancesCommand(opts cliOptions, in io.Reader, out io.Writer) error {
+	action := "list"
+	var names []string
+	if len(opts.instances) > 0 {
+		action = strings.ToLower(opts.instances[0])
+		names = opts.instances[1:]
+	}
+
+	wantNames := map[string]int{"list": 0, "create": 1, "clone": 2, "rename": 2, "delete": 1}
+	count, ok := wantNames[action]
+	if !ok {
+		return fmt.Errorf("unknown --instances action %q; use list, create, clone, rename or delete", action)
+	}
+	if len(names) != count {
+		return fmt.Errorf("--instances %s expects %d instance name(s), got %d", action, count, len(names))
+	}
+	for _, name := range names {
+		if err := validateInstanceName(name); err != nil {
+			return err
+		}
+	}
+
+	switch action {
+	case "create":
+		return createInstance(names[0], opts.runtimeArg, out)
+	case "clone":
+		return cloneInstance(names[0], names[1], out)
+	case "rename":
+		return renameInstance(names[0], names[1], out)
+	case "delete":
+		return deleteInstance(names[0], opts.yes, in, out)
+	default:
+		return printInstances(out)
+	}
+}
+
+func validateInstanceName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid instance name %q", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("invalid instance name %q: use letters, digits, '-', '_' or '.'", name)
+		}
+	}
+	for _, suffix := range instanceDirSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("invalid instance name %q: it must not end with %s", name, suffix)
+		}
+	}
+	return nil
+}
+
+// discoverInstances returns the main instance and every named instance with at
+// least one of its directories next to the main control panel directory.
+func discoverInstances() ([]instanceInfo, error) {
+	baseDir := filepath.Dir(shared.DefaultControlPanelInstallDir())
+	entries, err := os.ReadDir(baseDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read %s: %w", baseDir, err)
+	}
+
+	names := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		base := entry.Name()
+		switch {
+		case base == "owlcms" || base == "owlcms-owlcms":
+			names[mainInstanceName] = true
+		case strings.HasSuffix(base, "-controlpanel") || strings.HasSuffix(base, "-owlcms") || strings.HasSuffix(base, "-tracker"):
+			names[deriveInstanceName(base)] = true
+		}
+	}
+
+	var instances []instanceInfo
+	for name := range names {
+		paths, err := resolveInstancePaths(name)
+		if err != nil {
+			continue
+		}
+		if !instanceExists(paths) {
+			continue
+		}
+		instances = append(instances, describeInstance(paths))
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		iMain, jMain := isMainInstance(instances[i].Paths.InstanceName), isMainInstance(instances[j].Paths.InstanceName)
+		if iMain != jMain {
+			return iMain
+		}
+		return instances[i].Paths.InstanceName < instances[j].Paths.InstanceName
+	})
+	return instances, nil
+}
+
+func instanceExists(paths *instancePaths) bool {
+	for _, dir := range []string{paths.ControlPanelDir, paths.OwlcmsDir, paths.TrackerDir} {
+		if _, err := os.Stat(dir); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func describeInstance(paths *instancePaths) instanceInfo {
+	info := instanceInfo{
+		Paths:       paths,
+		OwlcmsPort:  instanceProperty(paths.OwlcmsDir, "OWLCMS_PORT", "8080"),
+		TrackerPort: instanceProperty(paths.TrackerDir, "TRACKER_PORT", "8096"),
+		Running:     instanceRunningProcesses(paths),
+	}
+	if stored, err := loadStoredRuntimeDir(paths.ControlPanelDir); err == nil && stored != "" {
+		info.RuntimeDir = stored
+	} else {
+		info.RuntimeDir = shared.DefaultControlPanelInstallDir()
+	}
+	return info
+}
+
+// instanceProperty reads key from the env.properties in dir.
+func instanceProperty(dir, key, defaultValue string) string {
+	props, err := loadControlPanelEnv(dir)
+	if err != nil || props == nil {
+		return defaultValue
+	}
+	if value, ok := props.Get(key); ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return defaultValue
+}
+
+// instanceRunningProcesses lists the control panel and modules of the
+// instance that are currently running. firmata, cameras and replays have one
+// directory for all instances, so they count for the instance that started
+// them; metadata written before instances were recorded counts for the main
+// instance.
+func instanceRunningProcesses(paths *instancePaths) []string {
+	var running []string
+	if metadata, err := readControlPanelRuntimeMetadataFile(filepath.Join(paths.ControlPanelDir, "controlpanel-run.json")); err == nil {
+		if metadata.PID > 0 && shared.PIDMatchesStartTicks(metadata.PID, metadata.ProcessStartTicks) {
+			running = append(running, "control panel")
+		}
+	}
+	for _, module := range []struct {
+		name string
+		path string
+	}{
+		{name: "owlcms", path: filepath.Join(paths.ControlPanelDir, "owlcms-run.json")},
+		{name: "tracker", path: filepath.Join(paths.TrackerDir, "tracker-run.json")},
+	} {
+		if metadata, ok := shared.CheckDaemonRunning(module.path); ok {
+			running = append(running, module.name+" "+metadata.Version)
+		}
+	}
+	for _, module := range []struct {
+		name string
+		path string
+	}{
+		{name: "firmata", path: firmata.RuntimeMetadataPath()},
+		{name: "cameras", path: cameras.RuntimeMetadataPath()},
+		{name: "replays", path: replays.RuntimeMetadataPath()},
+	} {
+		metadata, ok := shared.CheckDaemonRunning(module.path)
+		if !ok {
+			continue
+		}
+		owner := metadata.Instance
+		if owner == "" {
+			owner = mainInstanceName
+		}
+		if owner == paths.InstanceName {
+			running = append(running, module.name+" "+metadata.Version)
+		}
+	}
+	return running
+}
+
+func printInstances(out io.Writer) error {
+	instances, err := discoverInstances()
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		fmt.Fprintf(out, "No instances found in %s\n", filepath.Dir(shared.DefaultControlPanelInstallDir()))
+		return nil
+	}
+	fmt.Fprintf(out, "%-20s %-7s %-8s %-30s %s\n", "INSTANCE", "OWLCMS", "TRACKER", "RUNNING", "RUNTIME DIR")
+	for _, instance := range instances {
+		running := "-"
+		if len(instance.Running) > 0 {
+			running = strings.Join(instance.Running, ", ")
+		}
+		fmt.Fprintf(out, "%-20s %-7s %-8s %-30s %s\n", instance.Paths.InstanceName, instance.OwlcmsPort, instance.TrackerPort, running, instance.RuntimeDir)
+	}
+	return nil
+}
+
+// printInitializedInstance describes the instance selected in the process environment.
+func printInitializedInstance(out io.Writer, verb, instance string) {
+	fmt.Fprintf(out, "%s instance %q\n", verb, instance)
+	fmt.Fprintf(out, "control panel dir: %s\n", shared.GetControlPanelInstallDir())
+	fmt.Fprintf(out, "owlcms dir:        %s\n", shared.GetOwlcmsInstallDir())
+	fmt.Fprintf(out, "tracker dir:       %s\n", shared.GetTrackerInstallDir())
+	fmt.Fprintf(out, "runtime dir:       %s\n", shared.GetRuntimeDir())
+	fmt.Fprintf(out, "owlcms port:       %s\n", owlcms.GetPort())
+	fmt.Fprintf(out, "tracker port:      %s\n", tracker.GetPort())
+}
+
+func requireNewInstance(name string) (*instancePaths, error) {
+	paths, err := resolveInstancePaths(name)
+	if err != nil {
+		return nil, err
+	}
+	if instanceExists(paths) {
+		return nil, fmt.Errorf("instance %q already exists", name)
+	}
+	return paths, nil
+}
+
+func requireExistingInstance(name string) (*instancePaths, error) {
+	paths, err := resolveInstancePaths(name)
+	if err != nil {
+		return nil, err
+	}
+	if !instanceExists(paths) {
+		return nil, fmt.Errorf("instance %q does not exist", name)
+	}
+	return paths, nil
+}
+
+func requireStoppedInstance(paths *instancePaths, action string) error {
+	if running := instanceRunningProcesses(paths); len(running) > 0 {
+		return fmt.Errorf("cannot %s instance %q while it is running (%s); stop it first", action, paths.InstanceName, strings.Join(running, ", "))
+	}
+	return nil
+}
+
+func createInstance(name, runtimeArg string, out io.Writer) error {
+	if _, err := requireNewInstance(name); err != nil {
+		return err
+	}
+	if err := applyCLIInstanceOptions(cliOptions{instanceArg: name, runtimeArg: runtimeArg, init: true}); err != nil {
+		return err
+	}
+	printInitializedInstance(out, "Created", name)
+	return nil
+}
+
+// cloneInstance copies the versions, databases and settings of source into a
+// new instance with its own port block.
+func cloneInstance(source, target string, out io.Writer) error {
+	sourcePaths, err := requireExistingInstance(source)
+	if err != nil {
+		return err
+	}
+	targetPaths, err := requireNewInstance(target)
+	if err != nil {
+		return err
+	}
+	if err := requireStoppedInstance(sourcePaths, "clone"); err != nil {
+		return err
+	}
+
+	for _, dir := range []struct{ from, to string }{
+		{sourcePaths.ControlPanelDir, targetPaths.ControlPanelDir},
+		{sourcePaths.OwlcmsDir, targetPaths.OwlcmsDir},
+		{sourcePaths.TrackerDir, targetPaths.TrackerDir},
+	} {
+		if _, err := os.Stat(dir.from); os.IsNotExist(err) {
+			continue
+		}
+		fmt.Fprintf(out, "Copying %s to %s\n", dir.from, dir.to)
+		if err := copyInstanceDir(dir.from, dir.to); err != nil {
+			return fmt.Errorf("copy %s: %w", dir.from, err)
+		}
+	}
+
+	runtimeDir := describeInstance(sourcePaths).RuntimeDir
+	if err := writeControlPanelEnv(targetPaths.ControlPanelDir, runtimeDir, target); err != nil {
+		return err
+	}
+	if err := applyCLIInstanceOptions(cliOptions{instanceArg: target}); err != nil {
+		return err
+	}
+	if err := owlcms.InitEnv(); err != nil {
+		return err
+	}
+	if err := tracker.InitEnv(); err != nil {
+		return err
+	}
+	if err := registerRuntimeInstance(targetPaths, runtimeDir); err != nil {
+		return err
+	}
+
+	oldOwlcmsPort, oldTrackerPort := owlcms.GetPort(), tracker.GetPort()
+	if err := registerInstancePorts(target); err != nil {
+		return err
+	}
+	if err := moveReleasePorts(targetPaths, oldOwlcmsPort, oldTrackerPort); err != nil {
+		return err
+	}
+
+	printInitializedInstance(out, "Cloned "+source+" to", target)
+	return nil
+}
+
+// copyInstanceDir copies an instance directory without the runtime state of
+// running processes, nor the shared Java, Node.js and FFmpeg runtimes that live
+// in the main control panel directory.
+func copyInstanceDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := shared.EnsureDir0755(dst); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if isInstanceRuntimeEntry(name) || name == shared.SecretsFileName {
+			// Secrets belong to one instance and are set again in the copy.
+			continue
+		}
+		from, to := filepath.Join(src, name), filepath.Join(dst, name)
+		if entry.IsDir() {
+			err = shared.CopyDir(from, to)
+		} else {
+			err = shared.CopyFile(from, to)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isInstanceRuntimeEntry(name string) bool {
+	switch name {
+	case "java", "node", "ffmpeg", "port-registry.json", "controlpanel-api.json":
+		return true
+	}
+	for _, suffix := range []string{".pid", ".lock", "-run.json", ".log", ".tmp"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// moveReleasePorts points copied or imported releases at the ports of the
+// instance: release ports equal to the old instance ports, and OWLCMS
+// connections to the old local tracker, follow the new port block.
+func moveReleasePorts(paths *instancePaths, oldOwlcmsPort, oldTrackerPort string) error {
+	newOwlcmsPort, newTrackerPort := owlcms.GetPort(), tracker.GetPort()
+
+	if newOwlcmsPort != oldOwlcmsPort {
+		for _, version := range owlcms.GetAllInstalledVersions() {
+			if instanceProperty(filepath.Join(paths.OwlcmsDir, version), "OWLCMS_PORT", "") != oldOwlcmsPort {
+				continue
+			}
+			if err := owlcms.SavePropertyForRelease(version, "OWLCMS_PORT", newOwlcmsPort); err != nil {
+				return fmt.Errorf("move owlcms %s port: %w", version, err)
+			}
+		}
+	}
+	if newTrackerPort == oldTrackerPort {
+		return nil
+	}
+
+	for _, version := range tracker.GetAllInstalledVersions() {
+		if instanceProperty(filepath.Join(paths.TrackerDir, version), "TRACKER_PORT", "") != oldTrackerPort {
+			continue
+		}
+		if err := tracker.SavePropertyForRelease(version, "TRACKER_PORT", newTrackerPort); err != nil {
+			return fmt.Errorf("move tracker %s port: %w", version, err)
+		}
+	}
+
+	if baseURL, port, enabled := owlcms.GetTrackerConnectionSettings(); port == oldTrackerPort && isLocalTrackerURL(baseURL) {
+		if err := owlcms.SaveDefaultTrackerConnection(baseURL, newTrackerPort, enabled); err != nil {
+			return fmt.Errorf("move default tracker connection: %w", err)
+		}
+	}
+	for _, version := range owlcms.GetAllInstalledVersions() {
+		connectionURL := owlcms.GetTrackerConnectionURLForRelease(version)
+		parsed, err := url.Parse(connectionURL)
+		if connectionURL == "" || err != nil || parsed.Port() != oldTrackerPort || !isLocalTrackerURL(connectionURL) {
+			continue
+		}
+		baseURL := fmt.Sprintf("%s://%s%s", parsed.Scheme, parsed.Hostname(), parsed.Path)
+		if err := owlcms.ConfigureTrackerConnectionForReleaseURL(version, baseURL, newTrackerPort); err != nil {
+			return fmt.Errorf("move owlcms %s tracker connection: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func isLocalTrackerURL(value string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	switch parsed.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func renameInstance(oldName, newName string, out io.Writer) error {
+	if isMainInstance(oldName) || isMainInstance(newName) {
+		return fmt.Errorf("the main instance %q cannot be renamed", mainInstanceName)
+	}
+	oldPaths, err := requireExistingInstance(oldName)
+	if err != nil {
+		return err
+	}
+	newPaths, err := requireNewInstance(newName)
+	if err != nil {
+		return err
+	}
+	if err := requireStoppedInstance(oldPaths, "rename"); err != nil {
+		return err
+	}
+	if err := requireUnusedRuntimeDir(oldPaths, "rename"); err != nil {
+		return err
+	}
+
+	runtimeDir := describeInstance(oldPaths).RuntimeDir
+	for _, dir := range []struct{ from, to string }{
+		{oldPaths.ControlPanelDir, newPaths.ControlPanelDir},
+		{oldPaths.OwlcmsDir, newPaths.OwlcmsDir},
+		{oldPaths.TrackerDir, newPaths.TrackerDir},
+	} {
+		if _, err := os.Stat(dir.from); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(dir.from, dir.to); err != nil {
+			return fmt.Errorf("rename %s: %w", dir.from, err)
+		}
+		fmt.Fprintf(out, "Renamed %s to %s\n", dir.from, dir.to)
+	}
+	if err := shared.EnsureDir0755(newPaths.ControlPanelDir); err != nil {
+		return err
+	}
+	if err := writeControlPanelEnv(newPaths.ControlPanelDir, runtimeDir, newName); err != nil {
+		return err
+	}
+	if err := shared.RenameInstancePorts(shared.PortRegistryPathIn(runtimeDir), oldName, newName); err != nil {
+		return err
+	}
+	if err := shared.UnregisterInstance(runtimeDir, oldName); err != nil {
+		return err
+	}
+	if err := registerRuntimeInstance(newPaths, runtimeDir); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Renamed instance %q to %q\n", oldName, newName)
+	return nil
+}
+
+// requireUnusedRuntimeDir refuses to move or delete a control panel directory
+// that another instance uses as its runtime directory.
+func requireUnusedRuntimeDir(paths *instancePaths, action string) error {
+	instances, err := discoverInstances()
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if instance.Paths.InstanceName == paths.InstanceName {
+			continue
+		}
+		if filepath.Clean(instance.RuntimeDir) == filepath.Clean(paths.ControlPanelDir) {
+			return fmt.Errorf("cannot %s instance %q: instance %q uses %s as its runtime dir", action, paths.InstanceName, instance.Paths.InstanceName, paths.ControlPanelDir)
+		}
+	}
+	return nil
+}
+
+// deleteInstance removes the directories of a named instance after the user
+// confirms by typing its name, unless yes is set.
+func deleteInstance(name string, yes bool, in io.Reader, out io.Writer) error {
+	if isMainInstance(name) {
+		return fmt.Errorf("the main instance %q cannot be deleted", mainInstanceName)
+	}
+	paths, err := requireExistingInstance(name)
+	if err != nil {
+		return err
+	}
+	if err := requireStoppedInstance(paths, "delete"); err != nil {
+		return err
+	}
+	if err := requireUnusedRuntimeDir(paths, "delete"); err != nil {
+		return err
+	}
+
+	var dirs []string
+	for _, dir := range []string{paths.ControlPanelDir, paths.OwlcmsDir, paths.TrackerDir} {
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	if !yes {
+		fmt.Fprintf(out, "This permanently deletes the versions, databases and settings of instance %q:\n", name)
+		for _, dir := range dirs {
+			fmt.Fprintf(out, "  %s\n", dir)
+		}
+		fmt.Fprintf(out, "Type the instance name to confirm: ")
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if strings.TrimSpace(answer) != name {
+			return fmt.Errorf("deletion of instance %q cancelled", name)
+		}
+	}
+
+	runtimeDir := describeInstance(paths).RuntimeDir
+	if err := shared.ReleaseInstancePorts(shared.PortRegistryPathIn(runtimeDir), name); err != nil {
+		return err
+	}
+	if err := shared.UnregisterInstance(runtimeDir, name); err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("remove %s: %w", dir, err)
+		}
+	}
+	fmt.Fprintf(out, "Deleted instance %q\n", name)
+	return nil
+}
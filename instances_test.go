@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupInstancesTest(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GOOS", "linux")
+	t.Setenv("APPDATA", "")
+	t.Setenv("CONTROLPANEL_INSTALLDIR", "")
+	t.Setenv("OWLCMS_INSTALLDIR", "")
+	t.Setenv("TRACKER_INSTALLDIR", "")
+	t.Setenv("RUNTIME_DIR", "")
+	t.Setenv("CONTROLPANEL_INSTANCE", "")
+	resetInstallDirsForTest()
+	t.Cleanup(resetInstallDirsForTest)
+	return filepath.Join(home, ".local", "share")
+}
+
+func TestParseCLIOptionsInstances(t *testing.T) {
+	opts := parseCLIOptions([]string{"--instances", "clone", "records", "masters", "--yes"})
+	if strings.Join(opts.instances, " ") != "clone records masters" || !opts.yes {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if opts.instanceArg != "" {
+		t.Fatalf("instance names must not select an instance, got %q", opts.instanceArg)
+	}
+
+	opts = parseCLIOptions([]string{"--instances"})
+	if strings.Join(opts.instances, " ") != "list" {
+		t.Fatalf("expected list by default, got %v", opts.instances)
+	}
+}
+
+func TestInstancesCreateCloneRenameDelete(t *testing.T) {
+	base := setupInstancesTest(t)
+	var out bytes.Buffer
+
+	if err := runInstancesCommand(cliOptions{instances: []string{"create", "records"}}, nil, &out); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := runInstancesCommand(cliOptions{instances: []string{"create", "records"}}, nil, &out); err == nil {
+		t.Fatal("expected creating an existing instance to fail")
+	}
+	database := filepath.Join(base, "records-owlcms", "64.0.0", "database", "owlcms.mv.db")
+	if err := os.MkdirAll(filepath.Dir(database), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(database, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runInstancesCommand(cliOptions{instances: []string{"clone", "records", "masters"}}, nil, &out); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(base, "masters-owlcms", "64.0.0", "database", "owlcms.mv.db")); err != nil || string(content) != "data" {
+		t.Fatalf("database was not cloned: %q %v", content, err)
+	}
+	if port := instanceProperty(filepath.Join(base, "masters-owlcms"), "OWLCMS_PORT", "8080"); port == "8080" {
+		t.Fatal("clone kept the port of its source")
+	}
+	if name := instanceProperty(filepath.Join(base, "masters-controlpanel"), "CONTROLPANEL_INSTANCE", ""); name != "masters" {
+		t.Fatalf("clone instance name = %q", name)
+	}
+
+	if err := runInstancesCommand(cliOptions{instances: []string{"rename", "masters", "juniors"}}, nil, &out); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "juniors-owlcms", "64.0.0")); err != nil {
+		t.Fatalf("renamed owlcms dir missing: %v", err)
+	}
+
+	out.Reset()
+	if err := printInstances(&out); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, name := range []string{"records", "juniors"} {
+		if !strings.Contains(out.String(), name) {
+			t.Fatalf("list does not show %s:\n%s", name, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "masters") {
+		t.Fatalf("list still shows renamed instance:\n%s", out.String())
+	}
+
+	if err := runInstancesCommand(cliOptions{instances: []string{"delete", "juniors"}}, strings.NewReader("nope\n"), &out); err == nil {
+		t.Fatal("expected delete without confirmation to be cancelled")
+	}
+	if err := runInstancesCommand(cliOptions{instances: []string{"delete", "juniors"}}, strings.NewReader("juniors\n"), &out); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, dir := range []string{"juniors-controlpanel", "juniors-owlcms", "juniors-tracker"} {
+		if _, err := os.Stat(filepath.Join(base, dir)); !os.IsNotExist(err) {
+			t.Fatalf("%s still exists: %v", dir, err)
+		}
+	}
+}
+
+func TestInstancesRefusesMainInstance(t *testing.T) {
+	setupInstancesTest(t)
+	for _, args := range [][]string{{"delete", "owlcms"}, {"rename", "owlcms", "other"}} {
+		if err := runInstancesCommand(cliOptions{instances: args, yes: true}, nil, &bytes.Buffer{}); err == nil {
+			t.Fatalf("expected %v to be refused", args)
+		}
+	}
+}
+
+func TestShouldShowInstancePicker(t *testing.T) {
+	setupInstancesTest(t)
+	if shouldShowInstancePicker(cliOptions{}) {
+		t.Fatal("picker shown without named instances")
+	}
+
+	if err := runInstancesCommand(cliOptions{instances: []string{"create", "records"}}, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	t.Setenv("CONTROLPANEL_INSTANCE", "")
+	if !shouldShowInstancePicker(cliOptions{}) {
+		t.Fatal("picker not shown with a named instance")
+	}
+	if shouldShowInstancePicker(cliOptions{instanceArg: "records"}) {
+		t.Fatal("picker shown although an instance was selected")
+	}
+
+	if err := setInstancePickerEnabled(false); err != nil {
+		t.Fatalf("disable picker: %v", err)
+	}
+	if shouldShowInstancePicker(cliOptions{}) {
+		t.Fatal("picker shown after being turned off")
+	}
+}
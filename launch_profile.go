@@ -0,0 +1,559 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/toml"
+	"github.com/magiconair/properties"
+)
+
+// launchProfile describes a competition stack: which modules to start, in
+// which order, and how they are connected. Modules stop in reverse order.
+type launchProfile struct {
+	Path    string
+	Modules []*profileModule
+	// KeepGoing still launches the remaining modules after one fails, as
+	// the --owlcms and --tracker headless launches do.
+	KeepGoing bool
+}
+
+// profileModule is one module of a launch profile.
+type profileModule struct {
+	Name    string
+	Version string
+	Port    string
+	// MQTT enables the embedded MQTT broker (owlcms only).
+	MQTT bool
+	// Tracker names the profile module OWLCMS sends its data to (owlcms only).
+	Tracker string
+	// MQTTBroker is the profile module, or host:port, providing the MQTT
+	// broker used by the devices (firmata only).
+	MQTTBroker string
+	// After lists modules that must be running before this one starts.
+	After []string
+}
+
+// profileModuleKeys are the per-module keys accepted in a profile.
+var profileModuleKeys = map[string]bool{
+	"version": true,
+	"port":    true,
+	"mqtt":    true,
+	"tracker": true,
+	"after":   true,
+}
+
+func (p *launchProfile) module(name string) *profileModule {
+	for _, m := range p.Modules {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// dependencies returns the modules that must be running before m starts:
+// the explicit "after" list plus the modules its connections point to.
+func (m *profileModule) dependencies() []string {
+	deps := append([]string(nil), m.After...)
+	for _, implied := range []string{m.Tracker, m.MQTTBroker} {
+		if implied == "" || !isSupportedModule(implied) {
+			continue
+		}
+		found := false
+		for _, dep := range deps {
+			if dep == implied {
+				found = true
+				break
+			}
+		}
+		if !found {
+			deps = append(deps, implied)
+		}
+	}
+	return deps
+}
+
+// loadLaunchProfile reads a profile from a .toml file (one [module] section
+// per module) or a .properties file (module.key entries).
+func loadLaunchProfile(path string) (*launchProfile, error) {
+	var values map[string]string
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		values, err = readTOMLProfileValues(path)
+	} else {
+		values, err = readPropertiesProfileValues(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading profile %s: %w", path, err)
+	}
+
+	profile, err := parseLaunchProfile(values)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", path, err)
+	}
+	profile.Path = path
+	return profile, nil
+}
+
+func readPropertiesProfileValues(path string) (map[string]string, error) {
+	props, err := properties.LoadFile(path, properties.UTF8)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for _, key := range props.Keys() {
+		values[key], _ = props.Get(key)
+	}
+	return values, nil
+}
+
+// readTOMLProfileValues flattens a TOML file into section.key entries.
+// Arrays become comma-separated lists.
+func readTOMLProfileValues(path string) (map[string]string, error) {
+	var document map[string]any
+	if _, err := toml.DecodeFile(path, &document); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if err := flattenTOMLProfileValues(values, "", document); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenTOMLProfileValues(values map[string]string, prefix string, table map[string]any) error {
+	for key, value := range table {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenTOMLProfileValues(values, key, v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				if _, nested := item.(map[string]any); nested {
+					return fmt.Errorf("%s: tables are not supported in a list", key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ", ")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// splitProfileList splits "a, b" or ["a", "b"] into its trimmed, lower-case items.
+func splitProfileList(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.Trim(strings.TrimSpace(item), `"'`))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseLaunchProfile validates profile values. Startup order is the order of
+// the modules list, and every dependency must be listed before the module
+// that needs it.
+func parseLaunchProfile(values map[string]string) (*launchProfile, error) {
+	names := splitProfileList(values["modules"])
+	if len(names) == 0 {
+		return nil, fmt.Errorf("modules is required (for example: modules = tracker, owlcms)")
+	}
+
+	profile := &launchProfile{}
+	position := make(map[string]int)
+	for i, name := range names {
+		if !isSupportedModule(name) {
+			return nil, fmt.Errorf("unsupported module %q in modules", name)
+		}
+		if _, seen := position[name]; seen {
+			return nil, fmt.Errorf("module %q is listed more than once", name)
+		}
+		position[name] = i
+		profile.Modules = append(profile.Modules, &profileModule{
+			Name:    name,
+			Version: defaultVersion(values[name+".version"]),
+			Port:    strings.TrimSpace(values[name+".port"]),
+		})
+	}
+
+	for key := range values {
+		if key == "modules" {
+			continue
+		}
+		name, field, ok := strings.Cut(key, ".")
+		if !ok || !profileModuleKeys[field] {
+			return nil, fmt.Errorf("unknown profile key %q", key)
+		}
+		if _, listed := position[name]; !listed {
+			return nil, fmt.Errorf("%s is set but %s is not listed in modules", key, name)
+		}
+	}
+
+	for _, m := range profile.Modules {
+		if m.Port != "" {
+			if isVideoModule(m.Name) {
+				return nil, fmt.Errorf("%s.port is not supported; edit config.toml instead", m.Name)
+			}
+			if port, err := strconv.Atoi(m.Port); err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("%s.port must be a number between 1 and 65535", m.Name)
+			}
+		}
+
+		if value := strings.TrimSpace(values[m.Name+".tracker"]); value != "" {
+			if m.Name != "owlcms" {
+				return nil, fmt.Errorf("%s.tracker is only supported for owlcms", m.Name)
+			}
+			if strings.ToLower(value) != "tracker" {
+				return nil, fmt.Errorf("owlcms.tracker must be tracker")
+			}
+			m.Tracker = "tracker"
+		}
+
+		if value := strings.TrimSpace(values[m.Name+".mqtt"]); value != "" {
+			switch m.Name {
+			case "owlcms":
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("owlcms.mqtt must be true or false")
+				}
+				m.MQTT = enabled
+			case "firmata":
+				if strings.EqualFold(value, "owlcms") {
+					m.MQTTBroker = "owlcms"
+				} else if _, _, err := net.SplitHostPort(value); err != nil {
+					return nil, fmt.Errorf("firmata.mqtt must be owlcms or host:port")
+				} else {
+					m.MQTTBroker = value
+				}
+			default:
+				return nil, fmt.Errorf("%s.mqtt is not supported", m.Name)
+			}
+		}
+
+		for _, dep := range splitProfileList(values[m.Name+".after"]) {
+			if dep == m.Name {
+				return nil, fmt.Errorf("%s.after cannot refer to %s itself", m.Name, m.Name)
+			}
+			m.After = append(m.After, dep)
+		}
+	}
+
+	for _, m := range profile.Modules {
+		for _, dep := range m.dependencies() {
+			depPosition, listed := position[dep]
+			if !listed {
+				return nil, fmt.Errorf("%s depends on %s, which is not listed in modules", m.Name, dep)
+			}
+			if depPosition > position[m.Name] {
+				return nil, fmt.Errorf("%s must be listed before %s in modules", dep, m.Name)
+			}
+		}
+	}
+
+	if firmataModule := profile.module("firmata"); firmataModule != nil && firmataModule.MQTTBroker == "owlcms" {
+		if !profile.module("owlcms").MQTT {
+			return nil, fmt.Errorf("firmata.mqtt = owlcms requires owlcms.mqtt = true")
+		}
+	}
+
+	return profile, nil
+}
+
+func moduleRuntimeMetadataPath(module string) string {
+	switch module {
+	case "owlcms":
+		return owlcms.RuntimeMetadataPath()
+	case "tracker":
+		return tracker.RuntimeMetadataPath()
+	case "firmata":
+		return firmata.RuntimeMetadataPath()
+	case "cameras":
+		return cameras.RuntimeMetadataPath()
+	default:
+		return replays.RuntimeMetadataPath()
+	}
+}
+
+// applyProfileModuleSettings writes the profile port and connections to the
+// release env.properties, as the matching --module options would.
+func applyProfileModuleSettings(m *profileModule, versions map[string]string) error {
+	version := versions[m.Name]
+	if m.Port != "" {
+		if err := saveModulePortForRelease(m.Name, version, m.Port); err != nil {
+			return err
+		}
+	}
+
+	if m.Tracker != "" {
+		if err := configureTrackerConnectionForHeadlessTandem(version, versions[m.Tracker]); err != nil {
+			return fmt.Errorf("failed to configure OWLCMS tracker connection: %w", err)
+		}
+	}
+
+	if m.MQTTBroker != "" {
+		host, port := "127.0.0.1", owlcms.EmbeddedMQTTPort
+		if m.MQTTBroker != "owlcms" {
+			host, port, _ = net.SplitHostPort(m.MQTTBroker)
+		}
+		if err := firmata.ConfigureMQTTConnectionForRelease(version, host, port); err != nil {
+			return fmt.Errorf("failed to configure firmata MQTT connection: %w", err)
+		}
+		log.Printf("Configured owlcms-firmata %s to use MQTT broker %s:%s", version, host, port)
+	}
+	return nil
+}
+
+// launchProfileModules resolves every version and applies ports and
+// connections before anything starts, then launches the modules in order.
+// Each LaunchDaemon returns once its module is ready, and a module is only
+// started when all of its dependencies are running. The first failure stops
+// the sequence so dependents are never started against a missing module,
+// unless the profile keeps going, in which case every failure is reported.
+// With autoPort, busy ports are replaced by the next free ones for this
+// launch only.
+func launchProfileModules(profile *launchProfile, out io.Writer, autoPort bool) error {
+	if shared.IsRunningUnderSystemd() {
+		// Under systemd OWLCMS's LaunchDaemon blocks on cmd.Wait().
+		for i, m := range profile.Modules {
+			if m.Name == "owlcms" && i != len(profile.Modules)-1 {
+				return fmt.Errorf("owlcms stays in the foreground under systemd and must be the last module of the profile")
+			}
+		}
+	}
+
+	versions := make(map[string]string)
+	for _, m := range profile.Modules {
+		version, err := resolveLocalModuleVersion(m.Name, m.Version)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		versions[m.Name] = version
+	}
+
+	for _, m := range profile.Modules {
+		if err := applyProfileModuleSettings(m, versions); err != nil {
+			return fmt.Errorf("%s %s: %w", m.Name, versions[m.Name], err)
+		}
+	}
+
+	var failures []error
+	for _, m := range profile.Modules {
+		version := versions[m.Name]
+		if meta, running := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(m.Name)); running {
+			log.Printf("%s %s is already running (PID %d, port %s)", m.Name, meta.Version, meta.PID, meta.Port)
+			fmt.Fprintf(out, "%s %s already running (PID %d)\n", m.Name, meta.Version, meta.PID)
+			continue
+		}
+
+		for _, dep := range m.dependencies() {
+			if _, running := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(dep)); running {
+				continue
+			}
+			if !profile.KeepGoing {
+				return fmt.Errorf("%s %s: %s is not running", m.Name, version, dep)
+			}
+			log.Printf("Launch profile: starting %s %s although %s is not running", m.Name, version, dep)
+		}
+
+		log.Printf("Launch profile: starting %s %s", m.Name, version)
+		if err := launchModuleDaemon(m.Name, version, m.MQTT, autoPort); err != nil {
+			log.Printf("ERROR: failed to launch %s %s: %v", m.Name, version, err)
+			err = fmt.Errorf("%s %s: %w", m.Name, version, err)
+			if !profile.KeepGoing {
+				return err
+			}
+			failures = append(failures, err)
+			continue
+		}
+		fmt.Fprintf(out, "%s %s started successfully\n", m.Name, version)
+		writeModuleURLs(out, m.Name, version)
+	}
+	return errors.Join(failures...)
+}
+
+// stopLaunchProfile stops the profile modules in reverse startup order,
+// attempting each one even when another fails.
+func stopLaunchProfile(profile *launchProfile, out io.Writer) error {
+	var failures []error
+	for i := len(profile.Modules) - 1; i >= 0; i-- {
+		if err := stopModule(profile.Modules[i].Name, out); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
+
+func executeProfileCommand(cmd moduleCLICommand, out io.Writer) error {
+	profile, err := loadLaunchProfile(cmd.ProfilePath)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Action {
+	case "launch":
+		return launchProfileModules(profile, out, cmd.AutoPort || shared.IsAutoPortEnabled())
+	case "stop":
+		if err := stopLaunchProfile(profile, out); err != nil {
+			return fmt.Errorf("some modules of profile %s could not be stopped: %w", cmd.ProfilePath, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("--profile requires --launch or --stop")
+	}
+}
+
+// reconnectProfileTabs lets each tab pick up the profile modules that are
+// running. The video tabs are not created on macOS.
+func reconnectProfileTabs(profile *launchProfile) {
+	for _, m := range profile.Modules {
+		if isVideoModule(m.Name) && shared.GetGoos() == "darwin" {
+			continue
+		}
+		switch m.Name {
+		case "owlcms":
+			owlcms.ReconnectRuntime()
+		case "tracker":
+			tracker.ReconnectRuntime()
+		case "firmata":
+			firmata.ReconnectRuntime()
+		case "cameras":
+			cameras.ReconnectRuntime()
+		case "replays":
+			replays.ReconnectRuntime()
+		}
+	}
+}
+
+// startProfileFromMenu asks for a profile file and starts its modules in the
+// background. The modules keep running when the control panel closes.
+func startProfileFromMenu(w fyne.Window) {
+	selectProfileFile(w, func(path string, err error) {
+		if err != nil {
+			fyne.Do(func() {
+				dialog.ShowError(err, w)
+			})
+			return
+		}
+		if path == "" {
+			return
+		}
+
+		profile, err := loadLaunchProfile(path)
+		if err != nil {
+			fyne.Do(func() {
+				dialog.ShowError(err, w)
+			})
+			return
+		}
+
+		fyne.Do(func() {
+			statusLabel := widget.NewLabel(fmt.Sprintf("Starting %s...", joinProfileModuleNames(profile)))
+			progressDialog := dialog.NewCustomWithoutButtons("Starting Profile", statusLabel, w)
+			progressDialog.Show()
+
+			go func() {
+				var output bytes.Buffer
+				err := launchProfileModules(profile, &output, shared.IsAutoPortEnabled())
+				fyne.Do(func() {
+					progressDialog.Hide()
+					reconnectProfileTabs(profile)
+					if err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					dialog.ShowInformation("Profile Started", output.String(), w)
+				})
+			}()
+		})
+	})
+}
+
+// stopProfileFromMenu asks for a profile file and stops its modules in
+// reverse startup order.
+func stopProfileFromMenu(w fyne.Window) {
+	selectProfileFile(w, func(path string, err error) {
+		if err != nil {
+			fyne.Do(func() {
+				dialog.ShowError(err, w)
+			})
+			return
+		}
+		if path == "" {
+			return
+		}
+
+		profile, err := loadLaunchProfile(path)
+		if err != nil {
+			fyne.Do(func() {
+				dialog.ShowError(err, w)
+			})
+			return
+		}
+
+		fyne.Do(func() {
+			// Attach first so modules started outside this control panel
+			// are stopped too.
+			reconnectProfileTabs(profile)
+			for i := len(profile.Modules) - 1; i >= 0; i-- {
+				stopProfileModuleTab(profile.Modules[i].Name, w)
+			}
+		})
+	})
+}
+
+// stopProfileModuleTab stops a module through its tab so the tab returns to
+// its stopped state.
+func stopProfileModuleTab(module string, w fyne.Window) {
+	if isVideoModule(module) && shared.GetGoos() == "darwin" {
+		return
+	}
+	switch module {
+	case "owlcms":
+		owlcms.StopRunningProcess(w)
+	case "tracker":
+		tracker.StopRunningProcess(w)
+	case "firmata":
+		firmata.StopRunningProcess(w)
+	case "cameras":
+		cameras.StopRunningProcess(w)
+	case "replays":
+		replays.StopRunningProcess(w)
+	}
+}
+
+func joinProfileModuleNames(profile *launchProfile) string {
+	names := make([]string, 0, len(profile.Modules))
+	for _, m := range profile.Modules {
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+)
+
+func writeProfile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing profile: %v", err)
+	}
+	return path
+}
+
+func TestLoadLaunchProfileTOML(t *testing.T) {
+	path := writeProfile(t, "nationals.toml", `# nationals
+modules = ["tracker", "owlcms", "firmata"]
+
+[tracker]
+version = "latest"
+port = 8097
+
+[owlcms]
+version = "65.0.0"
+mqtt = true
+tracker = "tracker"
+
+[firmata]
+mqtt = "owlcms"
+`)
+	profile, err := loadLaunchProfile(path)
+	if err != nil {
+		t.Fatalf("loadLaunchProfile returned error: %v", err)
+	}
+	if got := joinProfileModuleNames(profile); got != "tracker, owlcms, firmata" {
+		t.Fatalf("unexpected module order %q", got)
+	}
+	trackerModule := profile.module("tracker")
+	if trackerModule.Version != "latest" || trackerModule.Port != "8097" {
+		t.Fatalf("unexpected tracker module: %#v", trackerModule)
+	}
+	owlcmsModule := profile.module("owlcms")
+	if owlcmsModule.Version != "65.0.0" || !owlcmsModule.MQTT || owlcmsModule.Tracker != "tracker" {
+		t.Fatalf("unexpected owlcms module: %#v", owlcmsModule)
+	}
+	if deps := profile.module("firmata").dependencies(); len(deps) != 1 || deps[0] != "owlcms" {
+		t.Fatalf("expected firmata to depend on owlcms, got %v", deps)
+	}
+}
+
+func TestLoadLaunchProfileTOMLComments(t *testing.T) {
+	path := writeProfile(t, "club.toml", `modules = [
+  "tracker", # scoreboards
+  "owlcms",
+]
+
+[owlcms]
+port = "8080" # main
+after = ["tracker"]
+`)
+	profile, err := loadLaunchProfile(path)
+	if err != nil {
+		t.Fatalf("loadLaunchProfile returned error: %v", err)
+	}
+	if got := joinProfileModuleNames(profile); got != "tracker, owlcms" {
+		t.Fatalf("unexpected module order %q", got)
+	}
+	if port := profile.module("owlcms").Port; port != "8080" {
+		t.Fatalf("expected port 8080, got %q", port)
+	}
+
+	if _, err := loadLaunchProfile(writeProfile(t, "broken.toml", "modules = [\"owlcms\"\n[owlcms\n")); err == nil {
+		t.Fatal("expected a TOML syntax error")
+	}
+}
+
+func TestLoadLaunchProfileProperties(t *testing.T) {
+	path := writeProfile(t, "nationals.properties", `modules=tracker,owlcms
+owlcms.tracker=tracker
+owlcms.after=tracker
+`)
+	profile, err := loadLaunchProfile(path)
+	if err != nil {
+		t.Fatalf("loadLaunchProfile returned error: %v", err)
+	}
+	owlcmsModule := profile.module("owlcms")
+	if owlcmsModule.Version != "latest" {
+		t.Fatalf("expected default version latest, got %q", owlcmsModule.Version)
+	}
+	if deps := owlcmsModule.dependencies(); len(deps) != 1 || deps[0] != "tracker" {
+		t.Fatalf("expected a single tracker dependency, got %v", deps)
+	}
+}
+
+func TestParseLaunchProfileRejectsDependencyListedLater(t *testing.T) {
+	_, err := parseLaunchProfile(map[string]string{
+		"modules":        "owlcms, tracker",
+		"owlcms.tracker": "tracker",
+	})
+	if err == nil || !strings.Contains(err.Error(), "tracker must be listed before owlcms") {
+		t.Fatalf("expected ordering error, got %v", err)
+	}
+}
+
+func TestParseLaunchProfileRequiresEmbeddedMQTTForFirmata(t *testing.T) {
+	_, err := parseLaunchProfile(map[string]string{
+		"modules":      "owlcms, firmata",
+		"firmata.mqtt": "owlcms",
+	})
+	if err == nil || !strings.Contains(err.Error(), "requires owlcms.mqtt = true") {
+		t.Fatalf("expected embedded MQTT error, got %v", err)
+	}
+}
+
+func TestParseLaunchProfileRejectsInvalidEntries(t *testing.T) {
+	cases := []map[string]string{
+		{"modules": "replays", "replays.port": "8091"},
+		{"modules": "owlcms", "owlcms.prot": "8080"},
+		{"modules": "owlcms", "tracker.port": "8096"},
+		{"modules": "owlcms, owlcms"},
+		{"modules": "owlcms", "owlcms.after": "owlcms"},
+		{},
+	}
+	for _, values := range cases {
+		if _, err := parseLaunchProfile(values); err == nil {
+			t.Fatalf("expected error for %v", values)
+		}
+	}
+}
+
+func TestProfileLaunchPassesOptionsWithoutChangingTheProcess(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	t.Setenv(shared.RunAsDaemonEnv, "false")
+	owlcmsDir := t.TempDir()
+	t.Cleanup(resetInstallDirsForTest)
+	owlcms.SetInstallDir(owlcmsDir)
+	mustMkdir(t, owlcmsDir, "65.0.0")
+
+	var launches []string
+	previous := launchModuleDaemon
+	launchModuleDaemon = func(module, version string, enableEmbeddedMQTT, autoPort bool) error {
+		launches = append(launches, fmt.Sprintf("%s %s autoPort=%v", module, version, autoPort))
+		return nil
+	}
+	t.Cleanup(func() { launchModuleDaemon = previous })
+
+	path := writeProfile(t, "owlcms.toml", "modules = [\"owlcms\"]\n")
+	cmd := moduleCLICommand{ProfilePath: path, Action: "launch", AutoPort: true}
+	if err := executeModuleCommand(cmd, io.Discard); err != nil {
+		t.Fatalf("launch profile: %v", err)
+	}
+	if strings.Join(launches, ",") != "owlcms 65.0.0 autoPort=true" {
+		t.Fatalf("unexpected launches %v", launches)
+	}
+	if shared.IsAutoPortEnabled() {
+		t.Fatal("expected --auto-port to apply to the launch only")
+	}
+	if value := os.Getenv(shared.RunAsDaemonEnv); value != "false" {
+		t.Fatalf("expected %s to stay false, got %q", shared.RunAsDaemonEnv, value)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"io"
@@ -83,6 +84,20 @@ func main() {
 		fmt.Fprintf(os.Stderr, "command line: %v\n", moduleCommandErr)
 		os.Exit(1)
 	}
+	if cliOptions.instances != nil {
+		if err := runInstancesCommand(cliOptions, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "instances: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.importPath != "" {
+		if err := importInstance(cliOptions.importPath, cliOptions.instanceArg, cliOptions.runtimeArg, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "instance import: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := applyCLIInstanceOptions(cliOptions); err != nil {
 		fmt.Fprintf(os.Stderr, "instance setup: %v\n", err)
 		os.Exit(1)
@@ -92,16 +107,58 @@ func main() {
 		if initializedInstance == "" {
 			initializedInstance = strings.TrimSpace(cliOptions.instanceArg)
 		}
-		fmt.Printf("Initialized instance %q\n", initializedInstance)
-		fmt.Printf("control panel dir: %s\n", shared.GetControlPanelInstallDir())
-		fmt.Printf("owlcms dir:        %s\n", shared.GetOwlcmsInstallDir())
-		fmt.Printf("tracker dir:       %s\n", shared.GetTrackerInstallDir())
-		fmt.Printf("runtime dir:       %s\n", shared.GetRuntimeDir())
+		printInitializedInstance(os.Stdout, "Initialized", initializedInstance)
+		return
+	}
+	if cliOptions.service != "" {
+		if err := runServiceCommand(cliOptions, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "service: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.exportPath != "" {
+		if err := exportInstance(cliOptions.exportPath, cliOptions.runtimes, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "instance export: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.runtimeCmd != nil {
+		if err := runRuntimesCommand(cliOptions, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "runtimes: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.secretCmd != nil {
+		if err := runSecretCommand(cliOptions, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.checkConfig {
+		if err := runCheckConfigCommand(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "check-config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cliOptions.ports {
+		if err := printPortAllocations(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "ports: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
 	javacheck.InitJavaCheck(shared.GetOwlcmsInstallDir(), owlcms.GetTemurinVersion)
 
+	if cliOptions.container {
+		os.Exit(runContainer(cliOptions))
+	}
+
 	// Set up logging to file (and stderr if available)
 	controlPanelDir := shared.GetControlPanelInstallDir()
 	_ = shared.EnsureDir0755(controlPanelDir) // ignore error, can't log yet
@@ -127,17 +184,59 @@ func main() {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Llongfile)
 	log.Printf("Starting OWLCMS Control Panel %s", shared.GetLauncherVersion())
 
+	if cliOptions.dashboard != "" {
+		if err := startMetricsServer(metricsAddress(cliOptions.metrics)); err != nil {
+			log.Printf("Metrics endpoint unavailable: %v", err)
+		}
+		startMQTTStatus(mqttStatusSettings(cliOptions.mqttStatus), runHeadlessMQTTCommand)
+		err := runDashboard(cliOptions.dashboard, os.Stdout)
+		stopMQTTStatus()
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if moduleCommandFound {
 		moduleCommand.MQTT = moduleCommand.MQTT || cliOptions.mqtt
-		if moduleCommandRequiresExclusiveControlPanel(moduleCommand) {
+		if canForwardModuleCommand(moduleCommand) || moduleCommandRequiresExclusiveControlPanel(moduleCommand) {
 			if running, ok := runningControlPanelMetadata(); ok {
 				err := fmt.Errorf("another OWLCMS Control Panel is already running (%s); use that UI or close it before running module commands", describeControlPanelRuntime(running))
-				log.Printf("ERROR: %v", err)
-				fmt.Fprintf(os.Stderr, "%v\n", err)
-				os.Exit(1)
+				if canForwardModuleCommand(moduleCommand) {
+					// Let the running control panel execute the command so its
+					// tabs stay in charge of the modules.
+					forwardErr := forwardModuleCommand(running, moduleCommand, os.Stdout)
+					switch {
+					case forwardErr == nil:
+						return
+					case !errors.Is(forwardErr, os.ErrNotExist):
+						err = forwardErr
+					case !moduleCommandRequiresExclusiveControlPanel(moduleCommand):
+						log.Printf("Running control panel has no control API; running the command here")
+						err = nil
+					}
+				}
+				if err != nil {
+					log.Printf("ERROR: %v", err)
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					os.Exit(1)
+				}
 			}
 		}
-		if err := executeModuleCommand(moduleCommand, os.Stdout); err != nil {
+		if moduleCommand.Action == "launch" && (!moduleCommand.DaemonMode || shared.IsRunningUnderSystemd()) {
+			// This process stays up with the module, so it can serve metrics
+			// and publish status.
+			if err := startMetricsServer(metricsAddress(cliOptions.metrics)); err != nil {
+				log.Printf("Metrics endpoint unavailable: %v", err)
+			}
+			startMQTTStatus(mqttStatusSettings(cliOptions.mqttStatus), runHeadlessMQTTCommand)
+		}
+		err := executeModuleCommand(moduleCommand, os.Stdout)
+		stopMQTTStatus()
+		shared.FlushEvents(eventFlushTimeout)
+		if err != nil {
 			log.Printf("ERROR: %v", err)
 			fmt.Fprintf(os.Stderr, "%v\n", err)
 			os.Exit(1)
@@ -154,13 +253,28 @@ func main() {
 	a.Settings().SetTheme(newMyTheme())
 	w := a.NewWindow(windowTitle)
 	initialWindowSize := fyne.NewSize(950, 600)
-	if !startControlPanelRuntimeGate(a, w, initialWindowSize, func() {
-		startControlPanelUI(w, a, initialWindowSize)
-	}) {
+	startGatedUI := func() bool {
+		return startControlPanelRuntimeGate(a, w, initialWindowSize, func() {
+			startControlPanelUI(w, a, initialWindowSize)
+			if err := startMetricsServer(metricsAddress(cliOptions.metrics)); err != nil {
+				log.Printf("Metrics endpoint unavailable: %v", err)
+			}
+			startMQTTStatus(mqttStatusSettings(cliOptions.mqttStatus), guiMQTTCommandRunner(w))
+		})
+	}
+	if shouldShowInstancePicker(cliOptions) {
+		showStartupInstancePicker(a, w, initialWindowSize, func() {
+			if !startGatedUI() {
+				a.Quit()
+			}
+		})
+	} else if !startGatedUI() {
 		return
 	}
 	defer clearCurrentControlPanelRuntime()
 	a.Run()
+	stopMQTTStatus()
+	shared.FlushEvents(eventFlushTimeout)
 }
 
 func startControlPanelUI(w fyne.Window, a fyne.App, initialWindowSize fyne.Size) {
@@ -203,6 +317,10 @@ func startControlPanelUI(w fyne.Window, a fyne.App, initialWindowSize fyne.Size)
 	// the first content layout pass.
 	setupMenus(w)
 
+	if err := startControlAPI(w); err != nil {
+		log.Printf("Control API unavailable: %v", err)
+	}
+
 	// Combine into a stack so that dummyContent is initially visible, layout doesn't break,
 	// and we avoid swapping the root content in a way that causes rendering failures.
 	mainContent.Hide()
@@ -336,7 +454,7 @@ func anyProgramRunning() bool {
 }
 
 func anyDaemonRunning() bool {
-	return owlcms.IsRecoveredDaemonRunning() || tracker.IsRecoveredDaemonRunning()
+	return len(recoveredDaemonProgramNames()) > 0
 }
 
 func closableProgramNames() []string {
@@ -347,26 +465,35 @@ func closableProgramNames() []string {
 	if tracker.IsLocalProcessRunning() {
 		programs = append(programs, "Tracker")
 	}
-	if firmata.IsRunning() {
+	if firmata.IsLocalProcessRunning() {
 		programs = append(programs, "Firmata")
 	}
-	if cameras.IsRunning() {
+	if cameras.IsLocalProcessRunning() {
 		programs = append(programs, "Cameras")
 	}
-	if replays.IsRunning() {
+	if replays.IsLocalProcessRunning() {
 		programs = append(programs, "Replays")
 	}
 	return programs
 }
 
 func recoveredDaemonProgramNames() []string {
-	programs := make([]string, 0, 2)
+	programs := make([]string, 0, 5)
 	if owlcms.IsRecoveredDaemonRunning() {
 		programs = append(programs, "OWLCMS")
 	}
 	if tracker.IsRecoveredDaemonRunning() {
 		programs = append(programs, "Tracker")
 	}
+	if firmata.IsDaemonRunning() {
+		programs = append(programs, "Firmata")
+	}
+	if cameras.IsDaemonRunning() {
+		programs = append(programs, "Cameras")
+	}
+	if replays.IsDaemonRunning() {
+		programs = append(programs, "Replays")
+	}
 	return programs
 }
 
@@ -394,9 +521,15 @@ func stopClosableRunningProcesses(w fyne.Window) {
 	if tracker.IsLocalProcessRunning() {
 		tracker.StopRunningProcess(w)
 	}
-	firmata.StopRunningProcess(w)
-	cameras.StopRunningProcess(w)
-	replays.StopRunningProcess(w)
+	if firmata.IsLocalProcessRunning() {
+		firmata.StopRunningProcess(w)
+	}
+	if cameras.IsLocalProcessRunning() {
+		cameras.StopRunningProcess(w)
+	}
+	if replays.IsLocalProcessRunning() {
+		replays.StopRunningProcess(w)
+	}
 }
 
 func stopClosableRunningProcessesForSignal() {
@@ -408,97 +541,93 @@ func stopClosableRunningProcessesForSignal() {
 		log.Println("Signal cleanup: forcefully stopping Tracker process")
 		tracker.HandleSignalCleanup()
 	}
-	if firmata.IsRunning() {
+	if firmata.IsLocalProcessRunning() {
 		log.Println("Signal cleanup: forcefully stopping Firmata process")
 		firmata.HandleSignalCleanup()
 	}
-	if cameras.IsRunning() {
+	if cameras.IsLocalProcessRunning() {
 		log.Println("Signal cleanup: forcefully stopping Cameras process")
 		cameras.HandleSignalCleanup()
 	}
-	if replays.IsRunning() {
+	if replays.IsLocalProcessRunning() {
 		log.Println("Signal cleanup: forcefully stopping Replays process")
 		replays.HandleSignalCleanup()
 	}
 }
 
 func cleanupJavaVersions(w fyne.Window) {
-	dialog.ShowConfirm(
-		"Cleanup Java Versions",
-		"This will:\n• Scan env.properties files to find the highest required Java version\n• Download and install that version if not present\n• Remove all older Java versions from the control panel\n• Remove all legacy Java installations from owlcms and firmata directories\n\nContinue?",
-		func(confirm bool) {
-			if !confirm {
-				return
-			}
-
-			// Create a status label for progress updates
-			statusLabel := widget.NewLabel("Scanning for Java versions...")
-			progressDialog := dialog.NewCustom("Cleaning Up Java", "Close", statusLabel, w)
-			progressDialog.Show()
-
-			// Run cleanup in goroutine to allow UI updates
-			go func() {
-				// Pass legacy directories for bundled cleanup, but scanning happens in control panel
-				removed, err := shared.CleanupObsoleteJavaVersions(owlcms.GetInstallDir(), firmata.GetInstallDir(), statusLabel, w)
-				progressDialog.Hide()
-
-				if err != nil {
-					dialog.ShowError(fmt.Errorf("cleanup failed: %w", err), w)
-					return
-				}
-
-				if len(removed) == 0 {
-					dialog.ShowInformation("Cleanup Complete", "No obsolete Java versions found.", w)
-				} else {
-					message := "Cleanup results:\n\n"
-					for _, v := range removed {
-						message += "• " + v + "\n"
-					}
-					dialog.ShowInformation("Cleanup Complete", message, w)
-				}
-			}()
-		},
-		w,
-	)
+	cleanupRuntimeVersions(w, "Java", func(usage shared.RuntimeUsage, dryRun bool, statusLabel *widget.Label) ([]string, error) {
+		// Pass legacy directories for bundled cleanup, but scanning happens in control panel
+		return shared.CleanupObsoleteJavaVersions(owlcms.GetInstallDir(), firmata.GetInstallDir(), usage, dryRun, statusLabel, w)
+	})
 }
 
 func cleanupNodeVersions(w fyne.Window) {
-	dialog.ShowConfirm(
-		"Cleanup Node.js Versions",
-		"This will:\n• Keep only the latest Node.js version in the control panel\n• Remove all older Node.js versions from the control panel\n• Remove all bundled Node.js from tracker version directories\n\nContinue?",
-		func(confirm bool) {
-			if !confirm {
+	cleanupRuntimeVersions(w, "Node.js", func(usage shared.RuntimeUsage, dryRun bool, statusLabel *widget.Label) ([]string, error) {
+		return shared.CleanupObsoleteNodeVersions(usage, dryRun, statusLabel, w)
+	})
+}
+
+// cleanupRuntimeVersions runs a runtime cleanup as a dry run first, across
+// every instance sharing the runtime directory, and asks for confirmation
+// with the list of what would be removed and why.
+func cleanupRuntimeVersions(w fyne.Window, runtimeName string, cleanup func(usage shared.RuntimeUsage, dryRun bool, statusLabel *widget.Label) ([]string, error)) {
+	go func() {
+		usage, err := collectCleanupUsage()
+		var planned []string
+		if err == nil {
+			planned, err = cleanup(usage, true, nil)
+		}
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("cleanup failed: %w", err), w)
+				return
+			}
+			if len(planned) == 0 {
+				dialog.ShowInformation("Cleanup Complete", fmt.Sprintf("No obsolete %s versions found.", runtimeName), w)
 				return
 			}
 
-			// Create a status label for progress updates
-			statusLabel := widget.NewLabel("Scanning for Node.js versions...")
-			progressDialog := dialog.NewCustom("Cleaning Up Node.js", "Close", statusLabel, w)
-			progressDialog.Show()
-
-			// Run cleanup in goroutine to allow UI updates
-			go func() {
-				removed, err := shared.CleanupObsoleteNodeVersions(statusLabel, w)
-				progressDialog.Hide()
-
-				if err != nil {
-					dialog.ShowError(fmt.Errorf("cleanup failed: %w", err), w)
+			message := fmt.Sprintf("Versions used by any instance sharing %s are kept. This will:\n\n", shared.GetRuntimeDir())
+			for _, v := range planned {
+				message += "• " + v + "\n"
+			}
+			message += "\nContinue?"
+			dialog.ShowConfirm(fmt.Sprintf("Cleanup %s Versions", runtimeName), message, func(confirm bool) {
+				if !confirm {
 					return
 				}
 
-				if len(removed) == 0 {
-					dialog.ShowInformation("Cleanup Complete", "No obsolete Node.js versions found.", w)
-				} else {
-					message := "Cleanup results:\n\n"
-					for _, v := range removed {
-						message += "• " + v + "\n"
-					}
-					dialog.ShowInformation("Cleanup Complete", message, w)
-				}
-			}()
-		},
-		w,
-	)
+				// Create a status label for progress updates
+				statusLabel := widget.NewLabel(fmt.Sprintf("Scanning for %s versions...", runtimeName))
+				progressDialog := dialog.NewCustom(fmt.Sprintf("Cleaning Up %s", runtimeName), "Close", statusLabel, w)
+				progressDialog.Show()
+
+				// Run cleanup in goroutine to allow UI updates
+				go func() {
+					removed, err := cleanup(usage, false, statusLabel)
+					fyne.Do(func() {
+						progressDialog.Hide()
+
+						if err != nil {
+							dialog.ShowError(fmt.Errorf("cleanup failed: %w", err), w)
+							return
+						}
+
+						if len(removed) == 0 {
+							dialog.ShowInformation("Cleanup Complete", fmt.Sprintf("No obsolete %s versions found.", runtimeName), w)
+						} else {
+							message := "Cleanup results:\n\n"
+							for _, v := range removed {
+								message += "• " + v + "\n"
+							}
+							dialog.ShowInformation("Cleanup Complete", message, w)
+						}
+					})
+				}()
+			}, w)
+		})
+	}()
 }
 
 func requestExit(w fyne.Window) {
@@ -550,6 +679,18 @@ func requestExit(w fyne.Window) {
 
 // setupMenus sets up the application menu bar
 func setupMenus(w fyne.Window) {
+	autoPortItem := fyne.NewMenuItem("Use Next Free Port When a Port Is Busy", nil)
+	autoPortItem.Checked = shared.IsAutoPortEnabled()
+	autoPortItem.Action = func() {
+		enabled := !autoPortItem.Checked
+		if err := shared.SaveAutoPortEnabled(enabled); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save setting: %w", err), w)
+			return
+		}
+		autoPortItem.Checked = enabled
+		w.MainMenu().Refresh()
+	}
+
 	// Use "Quit" on all platforms - Fyne checks for this exact label
 	// and won't add its own duplicate if it finds one.
 	fileMenu := fyne.NewMenu(
@@ -567,6 +708,33 @@ func setupMenus(w fyne.Window) {
 			cleanupNodeVersions(w)
 		}),
 		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Start Profile...", func() {
+			startProfileFromMenu(w)
+		}),
+		fyne.NewMenuItem("Stop Profile...", func() {
+			stopProfileFromMenu(w)
+		}),
+		autoPortItem,
+		fyne.NewMenuItem("Prometheus Metrics...", func() {
+			showMetricsDialog(w)
+		}),
+		fyne.NewMenuItem("Lifecycle Events...", func() {
+			showEventHooksDialog(w)
+		}),
+		fyne.NewMenuItem("MQTT Status...", func() {
+			showMQTTStatusDialog(w)
+		}),
+		fyne.NewMenuItem("Secrets...", func() {
+			showSecretsDialog(w)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Instances...", func() {
+			showInstancePickerDialog(w)
+		}),
+		fyne.NewMenuItem("Instances Overview", func() {
+			showInstancesOverview()
+		}),
+		fyne.NewMenuItemSeparator(),
 		fyne.NewMenuItem("Refresh", func() {
 			owlcms.RefreshVersionList(w)
 			tracker.RefreshVersionList(w)
@@ -649,6 +817,7 @@ func setupSignalHandling() {
 			// Under systemd the Go process owns the child — stop everything
 			// so systemctl stop actually terminates OWLCMS/Tracker.
 			log.Println("Running under systemd — stopping all processes due to signal...")
+			shared.NotifySystemd("STOPPING=1")
 			stopClosableRunningProcessesForSignal()
 			log.Println("All processes stopped.")
 		} else {
@@ -662,6 +831,8 @@ func setupSignalHandling() {
 			}
 		}
 
+		stopMQTTStatus()
+		shared.FlushEvents(eventFlushTimeout)
 		log.Println("Exiting Control Panel...")
 		clearCurrentControlPanelRuntime()
 		os.Exit(0)
@@ -779,24 +950,15 @@ func configureTrackerConnectionForHeadlessTandem(owlcmsVersion, trackerVersion s
 
 // runHeadlessDaemons launches OWLCMS and/or Tracker in daemon mode without any UI,
 // then exits.  This is intended for boot-time systemd/init usage.
+// The pair is started as a launch profile: Tracker first, then OWLCMS
+// connected to it when both are selected. Each module is attempted even when
+// the other fails to start.
 func runHeadlessDaemons(owlcmsVersion, trackerVersion string, enableEmbeddedMQTT bool) {
 	// Force daemon mode on so the spawned processes are detached.
 	_ = shared.SetRunAsDaemonEnabled(true)
 
 	var failed bool
-	var resolvedOwlcmsVersion string
-	var resolvedTrackerVersion string
-
-	if owlcmsVersion != "" {
-		version, err := resolveVersion("owlcms", owlcmsVersion, owlcms.GetAllInstalledVersions(), owlcms.GetInstallDir(), owlcms.GetLastRunVersion)
-		if err != nil {
-			log.Printf("ERROR: %v", err)
-			fmt.Fprintf(os.Stderr, "owlcms: %v\n", err)
-			failed = true
-		} else {
-			resolvedOwlcmsVersion = version
-		}
-	}
+	profile := &launchProfile{KeepGoing: true}
 
 	if trackerVersion != "" {
 		version, err := resolveVersion("tracker", trackerVersion, tracker.GetAllInstalledVersions(), tracker.GetInstallDir(), tracker.GetLastRunVersion)
@@ -805,57 +967,32 @@ func runHeadlessDaemons(owlcmsVersion, trackerVersion string, enableEmbeddedMQTT
 			fmt.Fprintf(os.Stderr, "tracker: %v\n", err)
 			failed = true
 		} else {
-			resolvedTrackerVersion = version
+			profile.Modules = append(profile.Modules, &profileModule{Name: "tracker", Version: version})
 		}
 	}
 
-	if resolvedOwlcmsVersion != "" && resolvedTrackerVersion != "" {
-		if err := configureTrackerConnectionForHeadlessTandem(resolvedOwlcmsVersion, resolvedTrackerVersion); err != nil {
-			log.Printf("ERROR: failed to configure OWLCMS tracker connection: %v", err)
-			fmt.Fprintf(os.Stderr, "owlcms/tracker tandem: %v\n", err)
+	if owlcmsVersion != "" {
+		version, err := resolveVersion("owlcms", owlcmsVersion, owlcms.GetAllInstalledVersions(), owlcms.GetInstallDir(), owlcms.GetLastRunVersion)
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			fmt.Fprintf(os.Stderr, "owlcms: %v\n", err)
 			failed = true
-		}
-	}
-
-	if failed {
-		os.Exit(1)
-	}
-
-	// Launch Tracker BEFORE OWLCMS because under systemd OWLCMS's
-	// LaunchDaemon blocks on cmd.Wait() (foreground supervision).
-	// Tracker's LaunchDaemon starts the process and returns once the
-	// port is ready, so it must go first.
-	if resolvedTrackerVersion != "" {
-		if meta, running := shared.CheckDaemonRunning(tracker.RuntimeMetadataPath()); running {
-			log.Printf("Tracker %s is already running (PID %d, port %s)", meta.Version, meta.PID, meta.Port)
-			fmt.Printf("tracker %s already running (PID %d, port %s)\n", meta.Version, meta.PID, meta.Port)
 		} else {
-			if err := tracker.LaunchDaemon(resolvedTrackerVersion); err != nil {
-				log.Printf("ERROR: failed to launch tracker %s: %v", resolvedTrackerVersion, err)
-				fmt.Fprintf(os.Stderr, "tracker %s: %v\n", resolvedTrackerVersion, err)
-				failed = true
-			} else {
-				fmt.Printf("tracker %s started successfully\n", resolvedTrackerVersion)
+			module := &profileModule{Name: "owlcms", Version: version, MQTT: enableEmbeddedMQTT}
+			if profile.module("tracker") != nil {
+				module.Tracker = "tracker"
 			}
+			profile.Modules = append(profile.Modules, module)
 		}
 	}
 
-	if resolvedOwlcmsVersion != "" {
-		if meta, running := shared.CheckDaemonRunning(owlcms.RuntimeMetadataPath()); running {
-			log.Printf("OWLCMS %s is already running (PID %d, port %s)", meta.Version, meta.PID, meta.Port)
-			fmt.Printf("owlcms %s already running (PID %d, port %s)\n", meta.Version, meta.PID, meta.Port)
-		} else {
-			if err := owlcms.LaunchDaemon(resolvedOwlcmsVersion, enableEmbeddedMQTT); err != nil {
-				log.Printf("ERROR: failed to launch owlcms %s: %v", resolvedOwlcmsVersion, err)
-				fmt.Fprintf(os.Stderr, "owlcms %s: %v\n", resolvedOwlcmsVersion, err)
-				failed = true
-			} else {
-				fmt.Printf("owlcms %s started successfully\n", resolvedOwlcmsVersion)
-			}
-		}
+	if failed {
+		os.Exit(1)
 	}
 
-	if failed {
+	if err := launchProfileModules(profile, os.Stdout, shared.IsAutoPortEnabled()); err != nil {
+		log.Printf("ERROR: %v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 }
@@ -904,49 +1041,47 @@ func resolveRunningModuleProcess(label, metadataPath, pidFilePath, port, fallbac
 	}, nil
 }
 
-// stopHeadlessDaemons stops running OWLCMS and/or Tracker modules from the command line.
-func stopHeadlessDaemons(stopOwlcms, stopTracker bool) {
-	var failed bool
-
-	if stopOwlcms {
+// stopModule loads the environment holding the module's configured port and
+// stops it, reporting progress on out. Neither cameras nor replays has a port
+// in env.properties, so the runtime metadata and PID file are the only
+// lookups for them.
+func stopModule(module string, out io.Writer) error {
+	switch module {
+	case "owlcms":
 		if err := owlcms.InitEnv(); err != nil {
-			log.Printf("ERROR: failed to load owlcms environment: %v", err)
-			fmt.Fprintf(os.Stderr, "owlcms: failed to load environment: %v\n", err)
-			failed = true
-		} else {
-			failed = stopOneModule("owlcms", owlcms.RuntimeMetadataPath(), owlcms.PIDFilePath(), owlcms.GetPort(), owlcms.GetLastRunVersion()) || failed
+			return fmt.Errorf("owlcms: failed to load environment: %w", err)
 		}
-	}
-
-	if stopTracker {
+		return stopOneModule(out, "owlcms", owlcms.RuntimeMetadataPath(), owlcms.PIDFilePath(), owlcms.GetPort(), owlcms.GetLastRunVersion())
+	case "tracker":
 		if err := tracker.InitEnv(); err != nil {
-			log.Printf("ERROR: failed to load tracker environment: %v", err)
-			fmt.Fprintf(os.Stderr, "tracker: failed to load environment: %v\n", err)
-			failed = true
-		} else {
-			failed = stopOneModule("tracker", tracker.RuntimeMetadataPath(), tracker.PIDFilePath(), tracker.GetPort(), tracker.GetLastRunVersion()) || failed
+			return fmt.Errorf("tracker: failed to load environment: %w", err)
 		}
-	}
-
-	if failed {
-		os.Exit(1)
+		return stopOneModule(out, "tracker", tracker.RuntimeMetadataPath(), tracker.PIDFilePath(), tracker.GetPort(), tracker.GetLastRunVersion())
+	case "firmata":
+		if err := firmata.EnsureParentEnvDefaults(); err != nil {
+			return fmt.Errorf("firmata: failed to load environment: %w", err)
+		}
+		return stopOneModule(out, "firmata", firmata.RuntimeMetadataPath(), firmata.PIDFilePath(), firmata.GetPort(), firmata.GetLastRunVersion())
+	case "cameras":
+		return stopOneModule(out, "cameras", cameras.RuntimeMetadataPath(), cameras.PIDFilePath(), "", cameras.GetLastRunVersion())
+	case "replays":
+		return stopOneModule(out, "replays", replays.RuntimeMetadataPath(), replays.PIDFilePath(), "", replays.GetLastRunVersion())
+	default:
+		return fmt.Errorf("unsupported module %q", module)
 	}
 }
 
 // stopOneModule stops a single module identified by runtime metadata, PID file, or configured port.
-// Returns true on failure.
-func stopOneModule(label, metadataPath, pidFilePath, port, fallbackVersion string) bool {
+func stopOneModule(out io.Writer, label, metadataPath, pidFilePath, port, fallbackVersion string) error {
 	running, err := resolveRunningModuleProcess(label, metadataPath, pidFilePath, port, fallbackVersion)
 	if err != nil {
-		log.Printf("ERROR: failed to resolve %s process: %v", label, err)
-		fmt.Fprintf(os.Stderr, "%s: failed to resolve running process: %v\n", label, err)
-		return true
+		return fmt.Errorf("%s: failed to resolve running process: %w", label, err)
 	}
 	if running == nil {
 		log.Printf("%s is not running", label)
-		fmt.Printf("%s is not running\n", label)
+		fmt.Fprintf(out, "%s is not running\n", label)
 		_ = shared.ClearRuntimeMetadata(metadataPath)
-		return false
+		return nil
 	}
 
 	versionText := strings.TrimSpace(running.Version)
@@ -954,8 +1089,11 @@ func stopOneModule(label, metadataPath, pidFilePath, port, fallbackVersion strin
 		versionText = "unknown version"
 	}
 
+	// A supervising control panel reports the exit itself.
+	supervised := shared.IsRuntimeSupervised(metadataPath)
+
 	log.Printf("Stopping %s %s (PID %d, port %s, source %s)...", label, versionText, running.PID, running.Port, running.Source)
-	fmt.Printf("Stopping %s %s (PID %d)...\n", label, versionText, running.PID)
+	fmt.Fprintf(out, "Stopping %s %s (PID %d)...\n", label, versionText, running.PID)
 
 	var stopErr error
 	if label == "owlcms" {
@@ -964,13 +1102,14 @@ func stopOneModule(label, metadataPath, pidFilePath, port, fallbackVersion strin
 		stopErr = shared.StopPIDFileOrPortProcess(pidFilePath, running.Port)
 	}
 	if stopErr != nil {
-		log.Printf("ERROR: failed to stop %s PID %d on port %s: %v", label, running.PID, running.Port, stopErr)
-		fmt.Fprintf(os.Stderr, "%s: failed to stop PID %d on port %s: %v\n", label, running.PID, running.Port, stopErr)
-		return true
+		return fmt.Errorf("%s: failed to stop PID %d on port %s: %w", label, running.PID, running.Port, stopErr)
 	}
 
 	_ = shared.ClearRuntimeMetadata(metadataPath)
+	if !supervised {
+		shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventStopped, Module: label, Version: running.Version, Port: running.Port, PID: running.PID})
+	}
 	log.Printf("%s %s (PID %d) stopped", label, versionText, running.PID)
-	fmt.Printf("%s %s stopped\n", label, versionText)
-	return false
+	fmt.Fprintf(out, "%s %s stopped\n", label, versionText)
+	return nil
 }
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultMetricsAddress is used by --metrics without an address.
+const defaultMetricsAddress = "127.0.0.1:9464"
+
+// metricsAddressEnv is the control panel setting holding the address of the
+// metrics endpoint.
+const metricsAddressEnv = "CONTROLPANEL_METRICS_ADDRESS"
+
+const metricsPrefix = "owlcms_controlpanel_"
+
+var metricsServer *http.Server
+
+// metricsAddress returns the address given on the command line, else the
+// configured one. Empty means the endpoint is disabled.
+func metricsAddress(cliAddress string) string {
+	if address := strings.TrimSpace(cliAddress); address != "" {
+		return address
+	}
+	return shared.ControlPanelSetting(metricsAddressEnv)
+}
+
+// startMetricsServer serves /metrics on address until stopMetricsServer is called.
+func startMetricsServer(address string) error {
+	stopMetricsServer()
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil
+	}
+	if !strings.Contains(address, ":") {
+		address = "127.0.0.1:" + address
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("listen for metrics on %s: %w", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(rw http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		writeMetrics(&buf, time.Now())
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		rw.Write(buf.Bytes())
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	metricsServer = server
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("Prometheus metrics available on http://%s/metrics", listener.Addr().String())
+	return nil
+}
+
+func stopMetricsServer() {
+	if metricsServer == nil {
+		return
+	}
+	if err := metricsServer.Close(); err != nil {
+		log.Printf("Failed to close metrics endpoint: %v", err)
+	}
+	metricsServer = nil
+}
+
+// writeMetrics writes all metrics in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, now time.Time) {
+	modules := []string{"owlcms", "tracker", "firmata"}
+	if shared.GetGoos() != "darwin" {
+		modules = append(modules, "cameras", "replays")
+	}
+
+	running := map[string]*shared.RuntimeMetadata{}
+	for _, module := range modules {
+		if metadata, ok := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(module)); ok {
+			running[module] = metadata
+		}
+	}
+
+	writeMetricHeader(w, "module_up", "gauge", "Whether the module is running.")
+	for _, module := range modules {
+		up := 0
+		if running[module] != nil {
+			up = 1
+		}
+		writeMetricSample(w, "module_up", metricLabels("module", module), float64(up))
+	}
+
+	writeMetricHeader(w, "module_info", "gauge", "Version and port of the running module.")
+	for _, module := range modules {
+		if metadata := running[module]; metadata != nil {
+			writeMetricSample(w, "module_info", metricLabels("module", module, "version", metadata.Version, "port", metadata.Port), 1)
+		}
+	}
+
+	writeMetricHeader(w, "module_restarts_total", "counter", "Automatic restarts performed by this control panel.")
+	for _, module := range modules {
+		writeMetricSample(w, "module_restarts_total", metricLabels("module", module), float64(shared.GetModuleRestarts(module)))
+	}
+
+	writeMetricHeader(w, "module_uptime_seconds", "gauge", "Time since the running module was started.")
+	for _, module := range modules {
+		if started, ok := parseMetadataTime(running[module], func(m *shared.RuntimeMetadata) string { return m.StartedAt }); ok {
+			writeMetricSample(w, "module_uptime_seconds", metricLabels("module", module), now.Sub(started).Seconds())
+		}
+	}
+
+	writeMetricHeader(w, "module_readiness_seconds", "gauge", "Time the running module took to answer on its port.")
+	for _, module := range modules {
+		started, startedOK := parseMetadataTime(running[module], func(m *shared.RuntimeMetadata) string { return m.StartedAt })
+		ready, readyOK := parseMetadataTime(running[module], func(m *shared.RuntimeMetadata) string { return m.ReadyAt })
+		if startedOK && readyOK {
+			writeMetricSample(w, "module_readiness_seconds", metricLabels("module", module), ready.Sub(started).Seconds())
+		}
+	}
+
+	type processUsage struct {
+		rss int64
+		cpu float64
+	}
+	usage := map[string]processUsage{}
+	for _, module := range modules {
+		if metadata := running[module]; metadata != nil {
+			if rss, cpu, ok := shared.ReadProcessUsage(metadata.PID); ok {
+				usage[module] = processUsage{rss: rss, cpu: cpu}
+			}
+		}
+	}
+	writeMetricHeader(w, "module_resident_memory_bytes", "gauge", "Resident memory of the running module (Linux only).")
+	for _, module := range modules {
+		if u, ok := usage[module]; ok {
+			writeMetricSample(w, "module_resident_memory_bytes", metricLabels("module", module), float64(u.rss))
+		}
+	}
+	writeMetricHeader(w, "module_cpu_seconds_total", "counter", "CPU time used by the running module (Linux only).")
+	for _, module := range modules {
+		if u, ok := usage[module]; ok {
+			writeMetricSample(w, "module_cpu_seconds_total", metricLabels("module", module), u.cpu)
+		}
+	}
+
+	writeMetricHeader(w, "installed_version_info", "gauge", "Installed module versions.")
+	for _, module := range modules {
+		for _, version := range installedVersionDirectories(moduleInstallDir(module)) {
+			writeMetricSample(w, "installed_version_info", metricLabels("module", module, "version", version), 1)
+		}
+	}
+
+	writeMetricHeader(w, "backup_age_seconds", "gauge", "Time since a version of the module was last archived to a ZIP file.")
+	for _, module := range []string{"owlcms", "tracker"} {
+		installDir := owlcms.GetInstallDir()
+		if module == "tracker" {
+			installDir = tracker.GetInstallDir()
+		}
+		if when, ok := shared.GetLastBackupTime(installDir); ok {
+			writeMetricSample(w, "backup_age_seconds", metricLabels("module", module), now.Sub(when).Seconds())
+		}
+	}
+
+	downloads := shared.GetDownloadStats()
+	writeMetricHeader(w, "downloads_total", "counter", "Downloads performed by this control panel.")
+	writeMetricSample(w, "downloads_total", metricLabels("result", "success"), float64(downloads.Succeeded))
+	writeMetricSample(w, "downloads_total", metricLabels("result", "failure"), float64(downloads.Failed))
+	writeMetricHeader(w, "download_bytes_total", "counter", "Bytes downloaded by this control panel.")
+	writeMetricSample(w, "download_bytes_total", "", float64(downloads.Bytes))
+}
+
+func parseMetadataTime(metadata *shared.RuntimeMetadata, field func(*shared.RuntimeMetadata) string) (time.Time, bool) {
+	if metadata == nil || strings.TrimSpace(field(metadata)) == "" {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, field(metadata))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n", metricsPrefix, name, help)
+	fmt.Fprintf(w, "# TYPE %s%s %s\n", metricsPrefix, name, kind)
+}
+
+func writeMetricSample(w io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s%s %g\n", metricsPrefix, name, labels, value)
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricLabels formats name/value pairs as a Prometheus label set.
+func metricLabels(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], metricLabelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// showMetricsDialog edits the address of the metrics endpoint and applies it
+// immediately.
+func showMetricsDialog(w fyne.Window) {
+	addressEntry := widget.NewEntry()
+	addressEntry.SetPlaceHolder(defaultMetricsAddress)
+	addressEntry.SetText(shared.ControlPanelSetting(metricsAddressEnv))
+
+	dialog.ShowForm(
+		"Prometheus Metrics",
+		"Save",
+		"Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Listen Address", addressEntry),
+			widget.NewFormItem("", widget.NewLabel("Leave empty to disable. Use 0.0.0.0:9464 to allow scraping from other hosts.")),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+
+			address := strings.TrimSpace(addressEntry.Text)
+			if err := startMetricsServer(address); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if err := shared.SaveControlPanelSettings(map[string]string{metricsAddressEnv: address}); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save metrics address: %w", err), w)
+				return
+			}
+			if address == "" {
+				dialog.ShowInformation("Prometheus Metrics", "The metrics endpoint is disabled.", w)
+				return
+			}
+			dialog.ShowInformation("Prometheus Metrics", fmt.Sprintf("Metrics are served on http://%s/metrics", address), w)
+		},
+		w,
+	)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricLabelsEscapesValues(t *testing.T) {
+	got := metricLabels("module", "owlcms", "version", `6.0.0"beta\1`)
+	expected := `{module="owlcms",version="6.0.0\"beta\\1"}`
+	if got != expected {
+		t.Fatalf("metricLabels = %s, expected %s", got, expected)
+	}
+}
+
+func TestWriteMetricSampleUsesPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	writeMetricHeader(&buf, "module_up", "gauge", "Whether the module is running.")
+	writeMetricSample(&buf, "module_up", metricLabels("module", "tracker"), 1)
+
+	output := buf.String()
+	for _, line := range []string{
+		"# TYPE owlcms_controlpanel_module_up gauge",
+		`owlcms_controlpanel_module_up{module="tracker"} 1`,
+	} {
+		if !strings.Contains(output, line) {
+			t.Fatalf("expected %q in:\n%s", line, output)
+		}
+	}
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"controlpanel/cameras"
+	"controlpanel/firmata"
 	"controlpanel/owlcms"
 	owlcmsinstallutils "controlpanel/owlcms/installutils"
+	"controlpanel/replays"
 	"controlpanel/shared"
 	"controlpanel/tracker"
 	trackerdownloadutils "controlpanel/tracker/downloadutils"
@@ -30,12 +34,40 @@ type moduleCLICommand struct {
 	RemoveVersion    string
 	Port             string
 	LocalTrackerPort string
+	ProfilePath      string
+	AutoPort         bool
 	DaemonMode       bool
 	MQTT             bool
 }
 
+// archiveOnlyActions are only implemented for modules distributed as ZIP archives.
+var archiveOnlyActions = map[string]bool{
+	"install-zip": true,
+	"create-zip":  true,
+	"duplicate":   true,
+}
+
+func isSupportedModule(module string) bool {
+	switch module {
+	case "owlcms", "tracker", "firmata", "cameras", "replays":
+		return true
+	default:
+		return false
+	}
+}
+
+// isArchiveModule reports whether the module is installed from ZIP archives.
+func isArchiveModule(module string) bool {
+	return module == "owlcms" || module == "tracker"
+}
+
+// isVideoModule reports whether the module is one of the cameras/replays binaries.
+func isVideoModule(module string) bool {
+	return module == "cameras" || module == "replays"
+}
+
 func moduleCommandRequiresExclusiveControlPanel(cmd moduleCLICommand) bool {
-	return cmd.Action != "list" && cmd.Action != "stop" && cmd.Action != "launch"
+	return cmd.Action != "list" && cmd.Action != "stop" && cmd.Action != "launch" && cmd.Action != "show-env"
 }
 
 func parseModuleCommand(args []string) (moduleCLICommand, bool, error) {
@@ -78,6 +110,13 @@ func parseModuleCommand(args []string) (moduleCLICommand, bool, error) {
 			cmd.Module = strings.ToLower(value)
 			sawModule = true
 			i = next
+		case "--profile":
+			value, next, err := valueAfter(i, args[i])
+			if err != nil {
+				return cmd, true, err
+			}
+			cmd.ProfilePath = value
+			i = next
 		case "--launch":
 			if err := setAction("launch"); err != nil {
 				return cmd, true, err
@@ -90,6 +129,10 @@ func parseModuleCommand(args []string) (moduleCLICommand, bool, error) {
 			if err := setAction("list"); err != nil {
 				return cmd, true, err
 			}
+		case "--show-env":
+			if err := setAction("show-env"); err != nil {
+				return cmd, true, err
+			}
 		case "--install":
 			if err := setAction("install"); err != nil {
 				return cmd, true, err
@@ -183,21 +226,47 @@ func parseModuleCommand(args []string) (moduleCLICommand, bool, error) {
 			cmd.DaemonMode = true
 		case "--mqtt":
 			cmd.MQTT = true
+		case "--auto-port":
+			cmd.AutoPort = true
 		}
 	}
 
+	if cmd.ProfilePath != "" {
+		if sawModule {
+			return cmd, true, fmt.Errorf("--profile cannot be combined with --module")
+		}
+		if cmd.Action != "launch" && cmd.Action != "stop" {
+			return cmd, true, fmt.Errorf("--profile requires --launch or --stop")
+		}
+		if cmd.AutoPort && cmd.Action != "launch" {
+			return cmd, true, fmt.Errorf("--auto-port can only be used with --launch")
+		}
+		return cmd, true, nil
+	}
 	if !sawModule && !sawModuleAction {
 		return cmd, false, nil
 	}
 	if !sawModule {
-		return cmd, true, fmt.Errorf("module actions require --module owlcms or --module tracker")
+		return cmd, true, fmt.Errorf("module actions require --module owlcms, tracker, firmata, cameras or replays")
 	}
-	if cmd.Module != "owlcms" && cmd.Module != "tracker" {
+	if !isSupportedModule(cmd.Module) {
 		return cmd, true, fmt.Errorf("unsupported module %q", cmd.Module)
 	}
 	if cmd.Action == "" {
 		return cmd, true, fmt.Errorf("--module %s requires an action", cmd.Module)
 	}
+	if archiveOnlyActions[cmd.Action] && !isArchiveModule(cmd.Module) {
+		return cmd, true, fmt.Errorf("--%s is not supported for --module %s", cmd.Action, cmd.Module)
+	}
+	if cmd.Port != "" && isVideoModule(cmd.Module) {
+		return cmd, true, fmt.Errorf("--port is not supported for --module %s; edit config.toml instead", cmd.Module)
+	}
+	if cmd.AutoPort && cmd.Action != "launch" {
+		return cmd, true, fmt.Errorf("--auto-port can only be used with --launch")
+	}
+	if cmd.AutoPort && isVideoModule(cmd.Module) {
+		return cmd, true, fmt.Errorf("--auto-port is not supported for --module %s; edit config.toml instead", cmd.Module)
+	}
 	if cmd.LocalTrackerPort != "" && cmd.Module != "owlcms" {
 		return cmd, true, fmt.Errorf("--local-tracker can only be used with --module owlcms")
 	}
@@ -220,6 +289,12 @@ func resolveLocalModuleVersion(module, requested string) (string, error) {
 		return resolveLocalVersionSelector("owlcms", requested, owlcms.GetAllInstalledVersions(), owlcms.GetInstallDir())
 	case "tracker":
 		return resolveLocalVersionSelector("tracker", requested, tracker.GetAllInstalledVersions(), tracker.GetInstallDir())
+	case "firmata":
+		return resolveLocalVersionSelector("firmata", requested, firmata.GetAllInstalledVersions(), firmata.GetInstallDir())
+	case "cameras":
+		return resolveLocalVersionSelector("cameras", requested, cameras.GetAllInstalledVersions(), cameras.GetInstallDir())
+	case "replays":
+		return resolveLocalVersionSelector("replays", requested, replays.GetAllInstalledVersions(), replays.GetInstallDir())
 	default:
 		return "", fmt.Errorf("unsupported module %q", module)
 	}
@@ -240,6 +315,9 @@ func resolveLocalVersionSelector(label, requested string, allVersions []string,
 		return allVersions[1], nil
 	}
 
+	if err := checkVersionName(label, requested); err != nil {
+		return "", err
+	}
 	dir := filepath.Join(installDir, requested)
 	info, err := os.Stat(dir)
 	if err != nil {
@@ -254,6 +332,15 @@ func resolveLocalVersionSelector(label, requested string, allVersions []string,
 	return requested, nil
 }
 
+// checkVersionName rejects a requested version that is not a plain directory
+// name, so it cannot reach outside the module install directory.
+func checkVersionName(label, requested string) error {
+	if requested == "." || requested == ".." || filepath.IsAbs(requested) || strings.ContainsAny(requested, `/\`) || filepath.VolumeName(requested) != "" {
+		return fmt.Errorf("%s version %q is not a valid version name", label, requested)
+	}
+	return nil
+}
+
 func installedVersionDirectories(installDir string) []string {
 	entries, err := os.ReadDir(installDir)
 	if err != nil {
@@ -272,19 +359,30 @@ func installedVersionDirectories(installDir string) []string {
 }
 
 func executeModuleCommand(cmd moduleCLICommand, out io.Writer) error {
+	if cmd.ProfilePath != "" {
+		return executeProfileCommand(cmd, out)
+	}
 	switch cmd.Action {
 	case "list":
-		if cmd.Module == "owlcms" {
+		switch cmd.Module {
+		case "owlcms":
 			writeAvailableVersions(out, "owlcms", installedVersionDirectories(owlcms.GetInstallDir()))
-		} else {
+		case "tracker":
 			writeAvailableVersions(out, "tracker", installedVersionDirectories(tracker.GetInstallDir()))
+		case "firmata":
+			writeAvailableVersions(out, "firmata", installedVersionDirectories(firmata.GetInstallDir()))
+		case "cameras":
+			writeAvailableVersions(out, "cameras", installedVersionDirectories(cameras.GetInstallDir()))
+		case "replays":
+			writeAvailableVersions(out, "replays", installedVersionDirectories(replays.GetInstallDir()))
 		}
 		return nil
 	case "stop":
-		stopHeadlessDaemons(cmd.Module == "owlcms", cmd.Module == "tracker")
-		return nil
+		return stopModule(cmd.Module, out)
 	case "launch":
 		return executeModuleLaunch(cmd, out)
+	case "show-env":
+		return executeModuleShowEnv(cmd, out)
 	case "install":
 		return executeModuleInstall(cmd, out)
 	case "install-zip":
@@ -304,17 +402,30 @@ func executeModuleCommand(cmd moduleCLICommand, out io.Writer) error {
 	}
 }
 
+// saveModulePortForRelease claims port in the shared port registry and stores it
+// as the launch port of the module release.
+func saveModulePortForRelease(module, version, port string) error {
+	if err := shared.ClaimPort(module, version, port); err != nil {
+		return err
+	}
+	switch module {
+	case "owlcms":
+		return owlcms.SavePropertyForRelease(version, "OWLCMS_PORT", port)
+	case "tracker":
+		return tracker.SavePropertyForRelease(version, "TRACKER_PORT", port)
+	case "firmata":
+		return firmata.SavePropertyForRelease(version, "FIRMATA_PORT", port)
+	}
+	return nil
+}
+
 func executeModuleLaunch(cmd moduleCLICommand, out io.Writer) error {
 	version, err := resolveLocalModuleVersion(cmd.Module, cmd.Version)
 	if err != nil {
 		return err
 	}
 	if cmd.Port != "" {
-		if cmd.Module == "owlcms" {
-			if err := owlcms.SavePropertyForRelease(version, "OWLCMS_PORT", cmd.Port); err != nil {
-				return err
-			}
-		} else if err := tracker.SavePropertyForRelease(version, "TRACKER_PORT", cmd.Port); err != nil {
+		if err := saveModulePortForRelease(cmd.Module, version, cmd.Port); err != nil {
 			return err
 		}
 	}
@@ -323,26 +434,98 @@ func executeModuleLaunch(cmd moduleCLICommand, out io.Writer) error {
 			return err
 		}
 	}
+	// --auto-port applies to this launch only, not to the process running it.
+	autoPort := cmd.AutoPort || shared.IsAutoPortEnabled()
 
 	if cmd.DaemonMode {
-		if err := shared.SetRunAsDaemonEnabled(true); err != nil {
-			return err
-		}
-		if cmd.Module == "owlcms" {
-			if err := owlcms.LaunchDaemon(version, cmd.MQTT); err != nil {
-				return err
-			}
-		} else if err := tracker.LaunchDaemon(version); err != nil {
+		if err := launchModuleDaemon(cmd.Module, version, cmd.MQTT, autoPort); err != nil {
 			return err
 		}
 		fmt.Fprintf(out, "%s %s started successfully\n", cmd.Module, version)
+		writeModuleURLs(out, cmd.Module, version)
 		return nil
 	}
 
-	if cmd.Module == "owlcms" {
-		return owlcms.LaunchForeground(version, cmd.MQTT)
+	switch cmd.Module {
+	case "owlcms":
+		return owlcms.LaunchForeground(version, cmd.MQTT, autoPort)
+	case "firmata":
+		return firmata.LaunchForeground(version, autoPort)
+	case "cameras":
+		return cameras.LaunchForeground(version)
+	case "replays":
+		return replays.LaunchForeground(version)
+	default:
+		return tracker.LaunchForeground(version, autoPort)
+	}
+}
+
+// launchModuleDaemon starts a module version in the background. Daemon mode is
+// chosen by the call, not by CONTROLPANEL_RUN_AS_DAEMON, so a launch forwarded
+// to the GUI leaves the launch setting of the GUI alone. It is a variable so
+// tests can follow launches without starting processes.
+var launchModuleDaemon = func(module, version string, enableEmbeddedMQTT, autoPort bool) error {
+	switch module {
+	case "owlcms":
+		return owlcms.LaunchDaemon(version, enableEmbeddedMQTT, autoPort)
+	case "tracker":
+		return tracker.LaunchDaemon(version, autoPort)
+	case "firmata":
+		return firmata.LaunchDaemon(version, autoPort)
+	case "cameras":
+		return cameras.LaunchDaemon(version)
+	default:
+		return replays.LaunchDaemon(version)
+	}
+}
+
+// executeModuleShowEnv prints the variables a command-line launch of the
+// version would pass to the module process, with where each one comes from.
+func executeModuleShowEnv(cmd moduleCLICommand, out io.Writer) error {
+	version, err := resolveLocalModuleVersion(cmd.Module, cmd.Version)
+	if err != nil {
+		return err
+	}
+	// The environment loaders log every variable, unredacted.
+	log.SetOutput(io.Discard)
+
+	var vars []shared.LaunchEnvVar
+	switch cmd.Module {
+	case "owlcms":
+		vars, err = owlcms.EffectiveLaunchEnvironment(version, &cmd.MQTT)
+	case "tracker":
+		vars, err = tracker.EffectiveLaunchEnvironment(version)
+	case "firmata":
+		vars, err = firmata.EffectiveLaunchEnvironment(version)
+	case "cameras":
+		vars = shared.DescribeVideoLaunchEnv(filepath.Join(cameras.GetInstallDir(), version))
+	case "replays":
+		vars = shared.DescribeVideoLaunchEnv(filepath.Join(replays.GetInstallDir(), version))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Environment of %s %s:\n", cmd.Module, version)
+	for _, v := range vars {
+		fmt.Fprintf(out, "%s=%s  [%s]\n", v.Key, v.Value, v.DescribeOrigin())
+	}
+	return nil
+}
+
+// writeModuleURLs reports where a started module listens, using the port it
+// was actually launched on, and for OWLCMS the tracker it connects to.
+func writeModuleURLs(out io.Writer, module, version string) {
+	metadata, running := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(module))
+	if !running || metadata.Port == "" {
+		return
+	}
+	fmt.Fprintf(out, "  %s URL: http://localhost:%s\n", module, metadata.Port)
+	if module == "owlcms" {
+		if trackerURL := owlcms.GetTrackerConnectionURLForRelease(version); trackerURL != "" {
+			fmt.Fprintf(out, "  owlcms tracker connection: %s\n", trackerURL)
+		}
 	}
-	return tracker.LaunchForeground(version)
 }
 
 func executeModuleInstall(cmd moduleCLICommand, out io.Writer) error {
@@ -358,6 +541,45 @@ func executeModuleInstall(cmd moduleCLICommand, out io.Writer) error {
 		fmt.Fprintf(out, "owlcms %s installed at %s\n", result.Version, result.Path)
 		return nil
 	}
+	if cmd.Module == "firmata" {
+		target, err := firmata.ResolveInstallRelease(cmd.InstallVersion)
+		if err != nil {
+			return err
+		}
+		result, err := firmata.InstallRelease(target, target, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := firmata.EnsureJavaForRelease(result.Version); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "firmata %s installed at %s\n", result.Version, result.Path)
+		return nil
+	}
+	if cmd.Module == "cameras" {
+		target, err := cameras.ResolveInstallRelease(cmd.InstallVersion)
+		if err != nil {
+			return err
+		}
+		result, err := cameras.InstallRelease(target, target, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "cameras %s installed at %s\n", result.Version, result.Path)
+		return nil
+	}
+	if cmd.Module == "replays" {
+		target, err := replays.ResolveInstallRelease(cmd.InstallVersion)
+		if err != nil {
+			return err
+		}
+		result, err := replays.InstallRelease(target, target, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "replays %s installed at %s\n", result.Version, result.Path)
+		return nil
+	}
 
 	target, err := tracker.ResolveInstallRelease(cmd.InstallVersion)
 	if err != nil {
@@ -556,6 +778,42 @@ func executeModuleUpdate(cmd moduleCLICommand, out io.Writer) error {
 		fmt.Fprintf(out, "owlcms %s updated from %s at %s\n", result.Version, fromVersion, result.Path)
 		return nil
 	}
+	if cmd.Module == "firmata" {
+		target, err := firmata.ResolveUpdateRelease(cmd.UpdateTo, fromVersion)
+		if err != nil {
+			return err
+		}
+		result, err := firmata.UpdateRelease(fromVersion, target, nil, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "firmata %s updated from %s at %s\n", result.Version, fromVersion, result.Path)
+		return nil
+	}
+	if cmd.Module == "cameras" {
+		target, err := cameras.ResolveUpdateRelease(cmd.UpdateTo, fromVersion)
+		if err != nil {
+			return err
+		}
+		result, err := cameras.UpdateRelease(fromVersion, target, nil, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "cameras %s updated from %s at %s\n", result.Version, fromVersion, result.Path)
+		return nil
+	}
+	if cmd.Module == "replays" {
+		target, err := replays.ResolveUpdateRelease(cmd.UpdateTo, fromVersion)
+		if err != nil {
+			return err
+		}
+		result, err := replays.UpdateRelease(fromVersion, target, nil, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "replays %s updated from %s at %s\n", result.Version, fromVersion, result.Path)
+		return nil
+	}
 
 	target, err := tracker.ResolveUpdateRelease(cmd.UpdateTo, fromVersion)
 	if err != nil {
@@ -605,11 +863,19 @@ func executeModuleImport(cmd moduleCLICommand, out io.Writer) error {
 	if err != nil {
 		return err
 	}
-	if cmd.Module == "owlcms" {
-		if _, err := owlcms.ImportDataAndConfig(fromVersion, toVersion); err != nil {
-			return err
-		}
-	} else if _, err := tracker.ImportDataAndConfig(fromVersion, toVersion); err != nil {
+	switch cmd.Module {
+	case "owlcms":
+		_, err = owlcms.ImportDataAndConfig(fromVersion, toVersion)
+	case "tracker":
+		_, err = tracker.ImportDataAndConfig(fromVersion, toVersion)
+	case "firmata":
+		_, err = firmata.ImportDataAndConfig(fromVersion, toVersion)
+	case "cameras":
+		_, err = cameras.ImportDataAndConfig(fromVersion, toVersion)
+	case "replays":
+		_, err = replays.ImportDataAndConfig(fromVersion, toVersion)
+	}
+	if err != nil {
 		return err
 	}
 	fmt.Fprintf(out, "%s data/config imported from %s to %s\n", cmd.Module, fromVersion, toVersion)
@@ -621,13 +887,24 @@ func executeModuleRemove(cmd moduleCLICommand, out io.Writer) error {
 	if err != nil {
 		return err
 	}
-	if cmd.Module == "owlcms" {
-		if err := owlcms.RemoveInstalledVersion(version); err != nil {
-			return err
-		}
-	} else if err := tracker.RemoveInstalledVersion(version); err != nil {
+	switch cmd.Module {
+	case "owlcms":
+		err = owlcms.RemoveInstalledVersion(version)
+	case "tracker":
+		err = tracker.RemoveInstalledVersion(version)
+	case "firmata":
+		err = firmata.RemoveInstalledVersion(version)
+	case "cameras":
+		err = cameras.RemoveInstalledVersion(version)
+	case "replays":
+		err = replays.RemoveInstalledVersion(version)
+	}
+	if err != nil {
 		return err
 	}
+	if err := shared.ReleaseVersionPort(cmd.Module, version); err != nil {
+		log.Printf("Releasing the port of %s %s: %v", cmd.Module, version, err)
+	}
 	fmt.Fprintf(out, "%s %s removed\n", cmd.Module, version)
 	return nil
 }
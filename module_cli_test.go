@@ -175,3 +175,103 @@ func TestResolveLocalVersionSelectorAllowsSpecificNonSemverDirectory(t *testing.
 		t.Fatalf("expected exact duplicate directory, got %q", version)
 	}
 }
+
+func TestParseModuleCommandFirmataLaunchBackground(t *testing.T) {
+	cmd, handled, err := parseModuleCommand([]string{"--module", "firmata", "--launch", "--background"})
+	if err != nil {
+		t.Fatalf("parseModuleCommand returned error: %v", err)
+	}
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if cmd.Module != "firmata" || cmd.Action != "launch" || !cmd.DaemonMode {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+}
+
+func TestParseModuleCommandRejectsZipActionsForFirmata(t *testing.T) {
+	_, handled, err := parseModuleCommand([]string{"--module", "firmata", "--install-zip", "C:/Downloads/firmata.zip"})
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("expected unsupported action error, got %v", err)
+	}
+}
+
+func TestParseModuleCommandReplaysInstall(t *testing.T) {
+	cmd, handled, err := parseModuleCommand([]string{"--module", "replays", "--install"})
+	if err != nil {
+		t.Fatalf("parseModuleCommand returned error: %v", err)
+	}
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if cmd.Module != "replays" || cmd.Action != "install" || cmd.InstallVersion != "latest" {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+}
+
+func TestParseModuleCommandRejectsPortForVideoModules(t *testing.T) {
+	_, handled, err := parseModuleCommand([]string{"--module", "cameras", "--launch", "--port", "8091"})
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if err == nil || !strings.Contains(err.Error(), "--port is not supported") {
+		t.Fatalf("expected unsupported port error, got %v", err)
+	}
+}
+
+func TestParseModuleCommandProfileLaunch(t *testing.T) {
+	cmd, handled, err := parseModuleCommand([]string{"--profile", "nationals.toml", "--launch"})
+	if err != nil {
+		t.Fatalf("parseModuleCommand returned error: %v", err)
+	}
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if cmd.ProfilePath != "nationals.toml" || cmd.Action != "launch" || cmd.Module != "" {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+}
+
+func TestParseModuleCommandRejectsProfileWithModule(t *testing.T) {
+	if _, _, err := parseModuleCommand([]string{"--profile", "nationals.toml", "--module", "owlcms", "--launch"}); err == nil {
+		t.Fatal("expected --profile with --module to be rejected")
+	}
+	if _, _, err := parseModuleCommand([]string{"--profile", "nationals.toml", "--list"}); err == nil {
+		t.Fatal("expected --profile with --list to be rejected")
+	}
+}
+
+func TestParseModuleCommandAutoPortRequiresLaunch(t *testing.T) {
+	cmd, _, err := parseModuleCommand([]string{"--module", "tracker", "--launch", "--auto-port"})
+	if err != nil {
+		t.Fatalf("parseModuleCommand returned error: %v", err)
+	}
+	if !cmd.AutoPort {
+		t.Fatalf("expected auto-port to be set: %#v", cmd)
+	}
+	if _, _, err := parseModuleCommand([]string{"--module", "tracker", "--stop", "--auto-port"}); err == nil {
+		t.Fatal("expected --auto-port with --stop to be rejected")
+	}
+	if _, _, err := parseModuleCommand([]string{"--module", "replays", "--launch", "--auto-port"}); err == nil {
+		t.Fatal("expected --auto-port for replays to be rejected")
+	}
+}
+
+func TestParseModuleCommandShowEnv(t *testing.T) {
+	cmd, handled, err := parseModuleCommand([]string{"--module", "tracker", "--version", "3.4.0", "--show-env"})
+	if err != nil {
+		t.Fatalf("parseModuleCommand returned error: %v", err)
+	}
+	if !handled {
+		t.Fatal("expected command to be handled")
+	}
+	if cmd.Module != "tracker" || cmd.Action != "show-env" || cmd.Version != "3.4.0" {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+	if moduleCommandRequiresExclusiveControlPanel(cmd) {
+		t.Fatal("expected --show-env to run while the control panel is open")
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultMQTTStatusBroker is the broker embedded in OWLCMS on this machine.
+const defaultMQTTStatusBroker = "127.0.0.1:" + owlcms.EmbeddedMQTTPort
+
+const (
+	mqttStatusInterval   = 5 * time.Second
+	mqttStatusMaxBackoff = time.Minute
+)
+
+// Control panel settings of the MQTT status publisher.
+const (
+	mqttStatusBrokerKey   = "CONTROLPANEL_MQTT_BROKER"
+	mqttStatusUsernameKey = "CONTROLPANEL_MQTT_USERNAME"
+	mqttStatusPasswordKey = "CONTROLPANEL_MQTT_PASSWORD"
+	mqttStatusCommandsKey = "CONTROLPANEL_MQTT_COMMANDS"
+)
+
+// mqttStatusPasswordSecret is the secret holding the broker password saved
+// from the MQTT Status dialog.
+const mqttStatusPasswordSecret = "controlpanel-mqtt-password"
+
+// mqttStatusConfig configures publishing control panel status to a broker.
+// An empty Broker disables publishing.
+type mqttStatusConfig struct {
+	Broker   string
+	Username string
+	Password string
+	Commands bool
+}
+
+// loadMQTTStatusConfig returns the MQTT status settings of this instance. A
+// secret:<name> password is looked up in the secrets store.
+func loadMQTTStatusConfig() mqttStatusConfig {
+	settings := shared.ControlPanelSettings(mqttStatusBrokerKey, mqttStatusUsernameKey, mqttStatusPasswordKey, mqttStatusCommandsKey)
+	password, err := shared.ResolveSecretValue(settings[mqttStatusPasswordKey])
+	if err != nil {
+		log.Printf("MQTT status: %s: %v", mqttStatusPasswordKey, err)
+	}
+	commands := strings.ToLower(settings[mqttStatusCommandsKey])
+	return mqttStatusConfig{
+		Broker:   settings[mqttStatusBrokerKey],
+		Username: settings[mqttStatusUsernameKey],
+		Password: password,
+		Commands: commands == "1" || commands == "true" || commands == "yes" || commands == "on",
+	}
+}
+
+// saveMQTTStatusConfig stores the MQTT status settings with the control panel
+// settings; the password goes to the secrets store and only a reference to
+// it is written.
+func saveMQTTStatusConfig(config mqttStatusConfig) error {
+	store, err := shared.LoadSecretStore()
+	if err != nil {
+		return fmt.Errorf("secrets store: %w", err)
+	}
+	passwordRef := ""
+	if config.Password != "" {
+		if err := store.Set(mqttStatusPasswordSecret, config.Password); err != nil {
+			return err
+		}
+		passwordRef = shared.SecretRefPrefix + mqttStatusPasswordSecret
+	} else if store.Has(mqttStatusPasswordSecret) {
+		if err := store.Unset(mqttStatusPasswordSecret); err != nil {
+			return err
+		}
+	}
+	commands := "false"
+	if config.Commands {
+		commands = "true"
+	}
+	return shared.SaveControlPanelSettings(map[string]string{
+		mqttStatusBrokerKey:   strings.TrimSpace(config.Broker),
+		mqttStatusUsernameKey: strings.TrimSpace(config.Username),
+		mqttStatusPasswordKey: passwordRef,
+		mqttStatusCommandsKey: commands,
+	})
+}
+
+// mqttCommandActions are the payloads accepted on <prefix>/<module>/command.
+var mqttCommandActions = map[string]bool{
+	"launch":  true,
+	"stop":    true,
+	"restart": true,
+}
+
+// mqttStatusPublisher keeps retained status topics up to date on a broker and,
+// when allowed, runs the commands received on the command topics.
+type mqttStatusPublisher struct {
+	settings mqttStatusConfig
+	prefix   string
+	run      func(cmd moduleCLICommand, out io.Writer) error
+	commands chan mqttCommand
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+type mqttCommand struct {
+	module string
+	action string
+}
+
+var mqttStatus *mqttStatusPublisher
+
+// mqttStatusTopicPrefix returns the topic under which this instance publishes.
+func mqttStatusTopicPrefix() string {
+	return "owlcms/controlpanel/" + shared.CurrentInstanceName()
+}
+
+// mqttStatusSettings returns the saved settings, with the broker given on the
+// command line taking precedence.
+func mqttStatusSettings(cliBroker string) mqttStatusConfig {
+	settings := loadMQTTStatusConfig()
+	if broker := strings.TrimSpace(cliBroker); broker != "" {
+		settings.Broker = broker
+	}
+	return settings
+}
+
+// startMQTTStatus publishes status to the configured broker until
+// stopMQTTStatus is called. run executes the commands received from the broker.
+func startMQTTStatus(settings mqttStatusConfig, run func(cmd moduleCLICommand, out io.Writer) error) {
+	stopMQTTStatus()
+	settings.Broker = strings.TrimSpace(settings.Broker)
+	if settings.Broker == "" {
+		return
+	}
+	if !strings.Contains(settings.Broker, ":") {
+		settings.Broker = net.JoinHostPort(settings.Broker, owlcms.EmbeddedMQTTPort)
+	}
+
+	publisher := &mqttStatusPublisher{
+		settings: settings,
+		prefix:   mqttStatusTopicPrefix(),
+		run:      run,
+		commands: make(chan mqttCommand, 8),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	mqttStatus = publisher
+	go publisher.loop()
+	log.Printf("Publishing control panel status to MQTT broker %s under %s", settings.Broker, publisher.prefix)
+}
+
+// stopMQTTStatus marks this control panel offline and disconnects.
+func stopMQTTStatus() {
+	if mqttStatus == nil {
+		return
+	}
+	close(mqttStatus.stop)
+	select {
+	case <-mqttStatus.stopped:
+	case <-time.After(5 * time.Second):
+		log.Printf("MQTT status publisher did not stop in time")
+	}
+	mqttStatus = nil
+}
+
+// runHeadlessMQTTCommand runs a command received from the broker when no
+// interactive control panel is open. Launches run in the background.
+func runHeadlessMQTTCommand(cmd moduleCLICommand, out io.Writer) error {
+	if cmd.Action == "stop" {
+		return stopModule(cmd.Module, out)
+	}
+	cmd.DaemonMode = true
+	return executeModuleCommand(cmd, out)
+}
+
+func (p *mqttStatusPublisher) loop() {
+	defer close(p.stopped)
+
+	backoff := mqttStatusInterval
+	for {
+		client, err := shared.DialMQTT(shared.MQTTOptions{
+			Address:     p.settings.Broker,
+			ClientID:    fmt.Sprintf("controlpanel-%s-%d", shared.CurrentInstanceName(), os.Getpid()),
+			Username:    p.settings.Username,
+			Password:    p.settings.Password,
+			WillTopic:   p.prefix + "/online",
+			WillPayload: []byte("false"),
+		}, p.handleMessage)
+		if err != nil {
+			log.Printf("MQTT status: cannot connect to %s: %v", p.settings.Broker, err)
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > mqttStatusMaxBackoff {
+				backoff = mqttStatusMaxBackoff
+			}
+			continue
+		}
+
+		backoff = mqttStatusInterval
+		log.Printf("MQTT status: connected to %s", p.settings.Broker)
+		if !p.serve(client) {
+			return
+		}
+		log.Printf("MQTT status: lost connection to %s: %v", p.settings.Broker, client.Err())
+	}
+}
+
+// serve publishes status until the connection drops, returning true, or the
+// publisher is stopped, returning false.
+func (p *mqttStatusPublisher) serve(client *shared.MQTTClient) bool {
+	if err := client.Publish(p.prefix+"/online", []byte("true"), true); err != nil {
+		log.Printf("MQTT status: %v", err)
+	}
+	if p.settings.Commands {
+		if err := client.Subscribe(p.prefix + "/+/command"); err != nil {
+			log.Printf("MQTT status: cannot subscribe to commands: %v", err)
+		}
+	}
+
+	published := map[string]string{}
+	p.publishStatus(client, published)
+	ticker := time.NewTicker(mqttStatusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			_ = client.Publish(p.prefix+"/online", []byte("false"), true)
+			_ = client.Close()
+			return false
+		case <-client.Done():
+			return true
+		case command := <-p.commands:
+			go p.runCommand(client, command)
+		case <-ticker.C:
+			p.publishStatus(client, published)
+		}
+	}
+}
+
+// publishStatus sends the retained topics whose value changed since the last
+// call. An empty value clears the retained message.
+func (p *mqttStatusPublisher) publishStatus(client *shared.MQTTClient, published map[string]string) {
+	topics := mqttStatusTopics(p.prefix)
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+
+	for _, topic := range names {
+		value := topics[topic]
+		if previous, ok := published[topic]; ok && previous == value {
+			continue
+		}
+		if err := client.Publish(topic, []byte(value), true); err != nil {
+			log.Printf("MQTT status: cannot publish %s: %v", topic, err)
+			return
+		}
+		published[topic] = value
+	}
+}
+
+// mqttStatusTopics returns the retained status topics and their values.
+func mqttStatusTopics(prefix string) map[string]string {
+	topics := map[string]string{}
+	owlcmsVersion := ""
+	for _, status := range moduleStatuses() {
+		base := prefix + "/" + status.Module
+		topics[base+"/state"] = "down"
+		topics[base+"/version"] = ""
+		topics[base+"/port"] = ""
+		if status.Running {
+			topics[base+"/state"] = "up"
+			topics[base+"/version"] = status.Version
+			topics[base+"/port"] = status.Port
+			if status.Module == "owlcms" {
+				owlcmsVersion = status.Version
+			}
+		}
+	}
+
+	topics[prefix+"/owlcms/tracker_connection"] = ""
+	topics[prefix+"/owlcms/tracker_url"] = ""
+	if owlcmsVersion != "" {
+		connectionURL := owlcms.GetTrackerConnectionURLForRelease(owlcmsVersion)
+		topics[prefix+"/owlcms/tracker_connection"] = trackerConnectionState(connectionURL)
+		topics[prefix+"/owlcms/tracker_url"] = connectionURL
+	}
+	return topics
+}
+
+// trackerConnectionState reports whether the tracker OWLCMS sends to answers:
+// "disabled" without a connection, else "connected" or "disconnected".
+func trackerConnectionState(connectionURL string) string {
+	if connectionURL == "" {
+		return "disabled"
+	}
+	parsed, err := url.Parse(connectionURL)
+	if err != nil || parsed.Host == "" {
+		return "disconnected"
+	}
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), "80")
+	}
+	conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+	if err != nil {
+		return "disconnected"
+	}
+	conn.Close()
+	return "connected"
+}
+
+// parseMQTTCommand maps a message on <prefix>/<module>/command to a command.
+func parseMQTTCommand(prefix, topic string, payload []byte) (mqttCommand, error) {
+	rest, ok := strings.CutPrefix(topic, prefix+"/")
+	module, suffix, found := strings.Cut(rest, "/")
+	if !ok || !found || suffix != "command" {
+		return mqttCommand{}, fmt.Errorf("not a command topic: %s", topic)
+	}
+	if !isSupportedModule(module) {
+		return mqttCommand{}, fmt.Errorf("unsupported module %q", module)
+	}
+	action := strings.ToLower(strings.TrimSpace(string(payload)))
+	if !mqttCommandActions[action] {
+		return mqttCommand{}, fmt.Errorf("unsupported command %q for %s", action, module)
+	}
+	return mqttCommand{module: module, action: action}, nil
+}
+
+func (p *mqttStatusPublisher) handleMessage(topic string, payload []byte) {
+	command, err := parseMQTTCommand(p.prefix, topic, payload)
+	if err != nil {
+		log.Printf("MQTT status: ignoring message: %v", err)
+		return
+	}
+	select {
+	case p.commands <- command:
+	default:
+		log.Printf("MQTT status: too many pending commands, dropping %s %s", command.action, command.module)
+	}
+}
+
+// runCommand executes command and publishes its outcome, not retained, on
+// <prefix>/<module>/command/result.
+func (p *mqttStatusPublisher) runCommand(client *shared.MQTTClient, command mqttCommand) {
+	log.Printf("MQTT status: %s %s", command.action, command.module)
+	var output bytes.Buffer
+	err := runMQTTCommand(p.run, command, &output)
+
+	result := "ok"
+	if err != nil {
+		result = "error: " + err.Error()
+		log.Printf("MQTT status: %s %s failed: %v", command.action, command.module, err)
+	}
+	if publishErr := client.Publish(p.prefix+"/"+command.module+"/command/result", []byte(result), false); publishErr != nil {
+		log.Printf("MQTT status: cannot publish command result: %v", publishErr)
+	}
+}
+
+// runMQTTCommand turns a broker command into module commands for run. Launches
+// ask for daemon mode on the command itself; the launch setting of the
+// process running them, which may be the GUI, is left alone.
+func runMQTTCommand(run func(cmd moduleCLICommand, out io.Writer) error, command mqttCommand, out io.Writer) error {
+	if command.action != "restart" {
+		return run(moduleCLICommand{Module: command.module, Action: command.action, DaemonMode: true}, out)
+	}
+	version := ""
+	if metadata, running := shared.CheckDaemonRunning(moduleRuntimeMetadataPath(command.module)); running {
+		version = metadata.Version
+		if err := run(moduleCLICommand{Module: command.module, Action: "stop"}, out); err != nil {
+			return err
+		}
+	}
+	return run(moduleCLICommand{Module: command.module, Action: "launch", Version: version, DaemonMode: true}, out)
+}
+
+// showMQTTStatusDialog edits the MQTT status settings and restarts the
+// publisher with them.
+func showMQTTStatusDialog(w fyne.Window) {
+	settings := loadMQTTStatusConfig()
+
+	brokerEntry := widget.NewEntry()
+	brokerEntry.SetPlaceHolder(defaultMQTTStatusBroker)
+	brokerEntry.SetText(settings.Broker)
+	usernameEntry := widget.NewEntry()
+	usernameEntry.SetText(settings.Username)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetText(settings.Password)
+	commandsCheck := widget.NewCheck("Accept launch, stop and restart commands", nil)
+	commandsCheck.SetChecked(settings.Commands)
+
+	dialog.ShowForm(
+		"MQTT Status",
+		"Save",
+		"Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Broker", brokerEntry),
+			widget.NewFormItem("User Name", usernameEntry),
+			widget.NewFormItem("Password", passwordEntry),
+			widget.NewFormItem("", commandsCheck),
+			widget.NewFormItem("", widget.NewLabel("Topics are published under "+mqttStatusTopicPrefix()+".\nLeave the broker empty to disable; OWLCMS's embedded broker is "+defaultMQTTStatusBroker+".")),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+
+			updated := mqttStatusConfig{
+				Broker:   strings.TrimSpace(brokerEntry.Text),
+				Username: strings.TrimSpace(usernameEntry.Text),
+				Password: passwordEntry.Text,
+				Commands: commandsCheck.Checked,
+			}
+			if err := saveMQTTStatusConfig(updated); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save MQTT settings: %w", err), w)
+				return
+			}
+			startMQTTStatus(updated, guiMQTTCommandRunner(w))
+		},
+		w,
+	)
+}
+
+// guiMQTTCommandRunner runs commands received from the broker the same way as
+// commands forwarded from the command line, so the tabs follow them.
+func guiMQTTCommandRunner(w fyne.Window) func(cmd moduleCLICommand, out io.Writer) error {
+	return func(cmd moduleCLICommand, out io.Writer) error {
+		return runForwardedModuleCommand(cmd, out, w)
+	}
+}
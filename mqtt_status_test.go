@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"controlpanel/owlcms"
+	"controlpanel/shared"
+)
+
+func TestParseMQTTCommand(t *testing.T) {
+	prefix := "owlcms/controlpanel/main"
+
+	command, err := parseMQTTCommand(prefix, prefix+"/tracker/command", []byte(" Restart\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if command.module != "tracker" || command.action != "restart" {
+		t.Fatalf("command = %+v, want restart tracker", command)
+	}
+
+	for _, tc := range []struct {
+		topic   string
+		payload string
+	}{
+		{prefix + "/tracker/command", "install"},
+		{prefix + "/unknown/command", "stop"},
+		{prefix + "/owlcms/command/result", "ok"},
+		{"owlcms/controlpanel/other/owlcms/command", "stop"},
+	} {
+		if _, err := parseMQTTCommand(prefix, tc.topic, []byte(tc.payload)); err == nil {
+			t.Errorf("parseMQTTCommand(%q, %q) accepted", tc.topic, tc.payload)
+		}
+	}
+}
+
+func TestTrackerConnectionStateDisabledWithoutURL(t *testing.T) {
+	if got := trackerConnectionState(""); got != "disabled" {
+		t.Fatalf("trackerConnectionState(\"\") = %q, want disabled", got)
+	}
+}
+
+func TestMQTTRestartLeavesDaemonSettingAlone(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	owlcmsDir := t.TempDir()
+	t.Cleanup(resetInstallDirsForTest)
+	owlcms.SetInstallDir(owlcmsDir)
+	mustMkdir(t, owlcmsDir, "65.0.0")
+	t.Setenv(shared.RunAsDaemonEnv, "false")
+
+	var launched []string
+	previous := launchModuleDaemon
+	launchModuleDaemon = func(module, version string, enableEmbeddedMQTT, autoPort bool) error {
+		launched = append(launched, module+" "+version)
+		return nil
+	}
+	t.Cleanup(func() { launchModuleDaemon = previous })
+
+	for _, action := range []string{"launch", "restart"} {
+		if err := runMQTTCommand(executeModuleCommand, mqttCommand{module: "owlcms", action: action}, io.Discard); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	if strings.Join(launched, ",") != "owlcms 65.0.0,owlcms 65.0.0" {
+		t.Fatalf("expected two background launches of owlcms 65.0.0, got %v", launched)
+	}
+	if value := os.Getenv(shared.RunAsDaemonEnv); value != "false" {
+		t.Fatalf("expected %s to stay false, got %q", shared.RunAsDaemonEnv, value)
+	}
+}
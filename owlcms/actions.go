@@ -30,6 +30,15 @@ func ensureReleaseCatalog(includePrereleases bool) ([]string, error) {
 	return allReleases, nil
 }
 
+// ListAvailableReleases returns the releases published on GitHub, newest first.
+func ListAvailableReleases() ([]string, error) {
+	releases, err := ensureReleaseCatalog(true)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), releases...), nil
+}
+
 // ResolveInstallRelease resolves a GitHub release selector for a clean install.
 func ResolveInstallRelease(selector string) (string, error) {
 	selector = strings.TrimSpace(selector)
@@ -59,6 +68,24 @@ func ResolveUpdateRelease(selector, fromVersion string) (string, error) {
 	return getMostRecentStableRelease()
 }
 
+// EnsureJavaForRelease returns the Java runtime required by a release,
+// downloading it into the shared runtime directory when missing.
+func EnsureJavaForRelease(version string) (string, error) {
+	temurinVersion := GetTemurinVersionForRelease(version)
+	javaPath, err := shared.FindJavaForVersion(temurinVersion, shared.GetGoos)
+	if err == nil {
+		return javaPath, nil
+	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return "", err
+	}
+	log.Printf("Java %s not found locally, downloading from Temurin", temurinVersion)
+	if err := shared.DownloadAndInstallJava(temurinVersion, nil, nil, shared.GetGoos); err != nil {
+		return "", fmt.Errorf("installing Java %s: %w", temurinVersion, err)
+	}
+	return shared.FindJavaForVersion(temurinVersion, shared.GetGoos)
+}
+
 // InstallRelease downloads and extracts a clean OWLCMS release.
 func InstallRelease(downloadVersion, installVersion string, progress shared.ProgressCallback) (ActionResult, error) {
 	downloadVersion = strings.TrimSpace(downloadVersion)
@@ -108,6 +135,7 @@ func InstallRelease(downloadVersion, installVersion string, progress shared.Prog
 		return ActionResult{}, fmt.Errorf("failed to create release env.properties: %w", err)
 	}
 
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventInstalled, Module: "owlcms", Version: installVersion})
 	return ActionResult{Version: installVersion, Path: extractPath}, nil
 }
 
@@ -187,6 +215,7 @@ func UpdateRelease(existingVersion, targetVersion string, progress shared.Progre
 	}
 	result.LocalFilesCopied = true
 	success = true
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventUpdateInstalled, Module: "owlcms", Version: targetInstallVersion, Detail: "updated from " + existingVersion})
 	return result, nil
 }
 
@@ -115,6 +115,14 @@ func GetPortForRelease(releaseVersion string) string {
 	return getPortFromProperties(merged)
 }
 
+// selectFreePortForRelease moves releaseVersion to the next free port when
+// its port is busy and returns the port to launch on.
+func selectFreePortForRelease(releaseVersion string) (string, error) {
+	return shared.SelectFreeModulePort("owlcms", releaseVersion, GetPortForRelease(releaseVersion), func(port string) error {
+		return SavePropertyForRelease(releaseVersion, "OWLCMS_PORT", port)
+	})
+}
+
 // GetReleaseEnvPath returns the version-specific env.properties path for the
 // selected OWLCMS release.
 func GetReleaseEnvPath(releaseVersion string) string {
@@ -204,6 +212,16 @@ func ConfigureTrackerConnectionForReleaseURL(releaseVersion, baseURL, trackerPor
 	return SavePropertyForRelease(releaseVersion, trackerConnectionEnv, trackerConnectionURL(baseURL, trackerPort))
 }
 
+// ConfigureTrackerConnectionForReleaseFromURL stores a complete tracker
+// websocket URL such as ws://tracker.local:8096/ws for the selected release.
+func ConfigureTrackerConnectionForReleaseFromURL(releaseVersion, connectionURL string) error {
+	baseURL, port, ok := trackerConnectionSettings(connectionURL)
+	if !ok {
+		return fmt.Errorf("tracker connection URL must look like ws://host:port/ws")
+	}
+	return ConfigureTrackerConnectionForReleaseURL(releaseVersion, baseURL, port)
+}
+
 // DisableTrackerConnectionForRelease writes an explicit blank release override so
 // the selected release clears any shared default tracker connection.
 func DisableTrackerConnectionForRelease(releaseVersion string) error {
@@ -230,33 +248,74 @@ func loadReleaseProperties(releaseVersion string) (*properties.Properties, error
 // GetTrackerConnectionPortForRelease returns the configured local tracker port
 // from OWLCMS_VIDEODATA for the selected release, or empty when disabled.
 func GetTrackerConnectionPortForRelease(releaseVersion string) string {
+	_, port, ok := trackerConnectionForRelease(releaseVersion)
+	if !ok {
+		return ""
+	}
+	return port
+}
+
+// GetTrackerConnectionURLForRelease returns the tracker websocket URL used by
+// the selected release, or empty when disabled.
+func GetTrackerConnectionURLForRelease(releaseVersion string) string {
+	baseURL, port, ok := trackerConnectionForRelease(releaseVersion)
+	if !ok {
+		return ""
+	}
+	return trackerConnectionURL(baseURL, port)
+}
+
+// trackerConnectionForRelease returns the OWLCMS_VIDEODATA endpoint for the
+// selected release. An explicit blank release value disables the connection.
+func trackerConnectionForRelease(releaseVersion string) (string, string, bool) {
 	releaseProps, err := loadReleaseProperties(releaseVersion)
 	if err == nil && releaseProps != nil {
 		if value, ok := releaseProps.Get(trackerConnectionEnv); ok {
-			if strings.TrimSpace(value) == "" {
-				return ""
-			}
-			port, ok := trackerConnectionPort(value)
-			if !ok {
-				return ""
-			}
-			return port
+			return trackerConnectionSettings(value)
 		}
 	}
 
 	merged, err := loadEnvironmentForReleaseProps(releaseVersion)
 	if err != nil || merged == nil {
-		return ""
+		return "", "", false
 	}
 	value, ok := merged.Get(trackerConnectionEnv)
 	if !ok {
-		return ""
+		return "", "", false
 	}
-	port, ok := trackerConnectionPort(value)
+	return trackerConnectionSettings(value)
+}
+
+// rewireTrackerConnectionForRelease follows a local tracker that was moved to
+// another port because its configured one was busy: when the tracker runtime
+// metadata shows it running on a different port than the one the release
+// connects to on this machine, the release connection is updated to match.
+func rewireTrackerConnectionForRelease(releaseVersion string) {
+	baseURL, configuredPort, ok := trackerConnectionForRelease(releaseVersion)
 	if !ok {
-		return ""
+		return
 	}
-	return port
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return
+	}
+	switch parsed.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+	default:
+		return
+	}
+
+	metadata, running := shared.CheckDaemonRunning(tracker.RuntimeMetadataPath())
+	if !running || metadata.Port == "" || metadata.Port == configuredPort {
+		return
+	}
+
+	if err := ConfigureTrackerConnectionForReleaseURL(releaseVersion, baseURL, metadata.Port); err != nil {
+		log.Printf("Failed to move OWLCMS %s tracker connection to port %s: %v", releaseVersion, metadata.Port, err)
+		return
+	}
+	log.Printf("Tracker is running on port %s instead of %s; OWLCMS %s now connects to %s",
+		metadata.Port, configuredPort, releaseVersion, trackerConnectionURL(baseURL, metadata.Port))
 }
 
 func loadPropertiesFromFile(envFilePath string) (*properties.Properties, error) {
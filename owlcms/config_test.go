@@ -1,8 +1,10 @@
 package owlcms
 
 import (
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -214,3 +216,61 @@ func TestDefaultTrackerConnectionRetainsEndpointWhenDisabled(t *testing.T) {
 		t.Fatalf("expected disabled default to clear OWLCMS_VIDEODATA, got %q", string(content))
 	}
 }
+
+func TestSelectFreePortForReleaseStoresNextFreePort(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+	installDir := t.TempDir()
+	previousDir := GetInstallDir()
+	SetInstallDir(installDir)
+	t.Cleanup(func() {
+		SetInstallDir(previousDir)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen on test port: %v", err)
+	}
+	defer listener.Close()
+	busyPort := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	if err := os.WriteFile(filepath.Join(installDir, "env.properties"), []byte("TEMURIN_VERSION=jdk-25\n"), 0o644); err != nil {
+		t.Fatalf("write shared env: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(installDir, "65.0.0"), 0o755); err != nil {
+		t.Fatalf("mkdir release dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(installDir, "65.0.0", "env.properties"), []byte("OWLCMS_PORT="+busyPort+"\n"), 0o644); err != nil {
+		t.Fatalf("write release env: %v", err)
+	}
+
+	port, err := selectFreePortForRelease("65.0.0")
+	if err != nil {
+		t.Fatalf("selectFreePortForRelease returned error: %v", err)
+	}
+	if port == busyPort {
+		t.Fatalf("expected a port other than busy port %s", busyPort)
+	}
+	if got := GetPortForRelease("65.0.0"); got != port {
+		t.Fatalf("expected stored release port %s, got %q", port, got)
+	}
+}
+
+func TestGetTrackerConnectionURLForReleaseReadsStoredURL(t *testing.T) {
+	installDir := t.TempDir()
+	previousDir := GetInstallDir()
+	SetInstallDir(installDir)
+	t.Cleanup(func() {
+		SetInstallDir(previousDir)
+	})
+
+	if err := os.MkdirAll(filepath.Join(installDir, "65.0.0"), 0o755); err != nil {
+		t.Fatalf("mkdir release dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(installDir, "65.0.0", "env.properties"), []byte("OWLCMS_VIDEODATA=ws://127.0.0.1:18123/ws\n"), 0o644); err != nil {
+		t.Fatalf("write release env: %v", err)
+	}
+
+	if got := GetTrackerConnectionURLForRelease("65.0.0"); got != "ws://127.0.0.1:18123/ws" {
+		t.Fatalf("expected stored tracker URL, got %q", got)
+	}
+}
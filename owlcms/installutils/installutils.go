@@ -388,5 +388,11 @@ func CreateZipArchive(sourceDir, zipPath string, progressCallback func(float64))
 		return nil
 	})
 
+	if err == nil {
+		if recordErr := shared.RecordBackup(filepath.Dir(sourceDir)); recordErr != nil {
+			log.Printf("Failed to record backup time: %v", recordErr)
+		}
+		shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventBackupCreated, Module: "owlcms", Version: filepath.Base(sourceDir), Detail: zipPath})
+	}
 	return err
 }
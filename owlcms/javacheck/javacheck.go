@@ -32,9 +32,10 @@ func FindLocalJava() (string, error) {
 	return FindLocalJavaForVersion(temurinVersion)
 }
 
-// FindLocalJavaForVersion finds local Java installation for a specific Temurin version
+// FindLocalJavaForVersion finds the Java installation for a specific Temurin version
+// allowed by the runtime policy of the instance
 func FindLocalJavaForVersion(temurinVersion string) (string, error) {
-	return shared.FindLocalJavaForVersion(temurinVersion, shared.GetGoos)
+	return shared.FindJavaForVersion(temurinVersion, shared.GetGoos)
 }
 
 // CheckJavaHeadless is a headless-friendly version of CheckJava that does not depend on Fyne.
@@ -46,6 +47,9 @@ func CheckJavaHeadless() error {
 	} else {
 		log.Printf("*** Local Java not found at %s: %v\n", javaPath, err)
 	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return err
+	}
 
 	fmt.Println("Suitable Java not found. Downloading from Temurin...")
 	log.Println("Downloading a local copy of the Java language runtime.")
@@ -121,6 +125,9 @@ func CheckJava(statusLabel *widget.Label) error {
 	} else {
 		log.Printf("*** Local Java not found at %s: %v\n", javaPath, err)
 	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return err
+	}
 
 	fmt.Println("Suitable Java not found. Downloading from Temurin...")
 	statusLabel.SetText("Downloading a local copy of the Java language runtime.")
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -170,10 +171,16 @@ type owlcmsLaunchParams struct {
 const daemonMainClass = "app.owlcms.MainWrapper"
 const embeddedMQTTEnv = "OWLCMS_ENABLEEMBEDDEDMQTT"
 
+// EmbeddedMQTTPort is the port of the MQTT broker embedded in OWLCMS.
+const EmbeddedMQTTPort = "1883"
+
 var owlcmsGoos = shared.GetGoos
 
+// shouldUseOwlcmsDaemonWrapper reports whether a detached daemon launch runs
+// through MainWrapper. It only depends on the platform and systemd, not on the
+// GUI daemon setting of the calling process.
 func shouldUseOwlcmsDaemonWrapper() bool {
-	return owlcmsGoos() == "linux" && shared.IsRunAsDaemonEnabled() && !shared.IsRunningUnderSystemd()
+	return owlcmsGoos() == "linux" && !shared.IsRunningUnderSystemd()
 }
 
 func setEnvValue(env []string, key, value string) []string {
@@ -257,6 +264,9 @@ func prepareOwlcmsLaunch(version string, embeddedMQTTOverride *bool) (*owlcmsLau
 	if _, err := os.Stat(jarPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("owlcms.jar not found in %s", versionDir)
 	}
+	if err := shared.CheckConfigBeforeLaunch("owlcms", installDir, version); err != nil {
+		return nil, err
+	}
 
 	mergedEnv, err := loadEnvironmentForReleaseProps(version)
 	if err != nil {
@@ -271,27 +281,19 @@ func prepareOwlcmsLaunch(version string, embeddedMQTTOverride *bool) (*owlcmsLau
 
 	targetPort := getPortFromProperties(mergedEnv)
 
-	env := shared.NormalizeChildProcessEnv(os.Environ())
-	lv := shared.GetLauncherVersionSemver()
-	env = shared.UpsertEnv(env, "OWLCMS_LAUNCHER", lv)
-	env = shared.UpsertEnv(env, "OWLCMS_CONTROLPANEL", lv)
+	overrides := owlcmsCommandLineOverrides(embeddedMQTTOverride)
 	effectiveEnv := cloneProperties(mergedEnv)
-	if embeddedMQTTOverride != nil {
-		value := "false"
-		if *embeddedMQTTOverride {
-			value = "true"
-		}
-		effectiveEnv.Set(embeddedMQTTEnv, value)
+	for key, value := range overrides {
+		effectiveEnv.Set(key, value)
 	}
 	logEffectiveOwlcmsEnvironment(version, effectiveEnv)
-	env = applyOwlcmsPropertiesToEnv(env, mergedEnv)
-	if embeddedMQTTOverride != nil {
-		value := "false"
-		if *embeddedMQTTOverride {
-			value = "true"
-		}
-		log.Printf("   %s=%s (command-line override)", embeddedMQTTEnv, value)
-		env = setEnvValue(env, embeddedMQTTEnv, value)
+	for key, value := range overrides {
+		log.Printf("   %s=%s (command-line override)", key, value)
+	}
+
+	env, err := shared.InjectSecrets(buildOwlcmsEnv(mergedEnv, overrides))
+	if err != nil {
+		return nil, err
 	}
 
 	return &owlcmsLaunchParams{
@@ -303,6 +305,46 @@ func prepareOwlcmsLaunch(version string, embeddedMQTTOverride *bool) (*owlcmsLau
 	}, nil
 }
 
+// owlcmsCommandLineOverrides returns the variables set by launch options
+// rather than by env.properties.
+func owlcmsCommandLineOverrides(embeddedMQTTOverride *bool) map[string]string {
+	overrides := map[string]string{}
+	if embeddedMQTTOverride != nil {
+		overrides[embeddedMQTTEnv] = strconv.FormatBool(*embeddedMQTTOverride)
+	}
+	return overrides
+}
+
+// buildOwlcmsEnv returns the environment of the OWLCMS process for the
+// merged env.properties of a version.
+func buildOwlcmsEnv(mergedEnv *properties.Properties, overrides map[string]string) []string {
+	env := shared.NormalizeChildProcessEnv(os.Environ())
+	lv := shared.GetLauncherVersionSemver()
+	env = shared.UpsertEnv(env, "OWLCMS_LAUNCHER", lv)
+	env = shared.UpsertEnv(env, "OWLCMS_CONTROLPANEL", lv)
+	env = applyOwlcmsPropertiesToEnv(env, mergedEnv)
+	for key, value := range overrides {
+		env = setEnvValue(env, key, value)
+	}
+	return env
+}
+
+// EffectiveLaunchEnvironment returns the variables a launch of version would
+// pass to OWLCMS, with where each one comes from.
+func EffectiveLaunchEnvironment(version string, embeddedMQTTOverride *bool) ([]shared.LaunchEnvVar, error) {
+	versionDir := filepath.Join(installDir, version)
+	if _, err := os.Stat(versionDir); err != nil {
+		return nil, fmt.Errorf("version directory not found: %s", versionDir)
+	}
+	mergedEnv, err := loadEnvironmentForReleaseProps(version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment: %w", err)
+	}
+	overrides := owlcmsCommandLineOverrides(embeddedMQTTOverride)
+	env := buildOwlcmsEnv(mergedEnv, overrides)
+	return shared.DescribeLaunchEnv(env, filepath.Join(installDir, "env.properties"), filepath.Join(versionDir, "env.properties"), overrides), nil
+}
+
 func buildOwlcmsCommand(params *owlcmsLaunchParams, useDaemonWrapper bool) *exec.Cmd {
 	if useDaemonWrapper {
 		return exec.Command(params.JavaPath, "-cp", params.JarPath, daemonMainClass)
@@ -320,6 +362,8 @@ func recordOwlcmsStart(pid int, version, port string, daemon bool) *shared.Runti
 
 	SaveLastRunVersion(version)
 
+	emitOwlcmsEvent(shared.EventStarted, version, port, pid, "")
+
 	metadata, err := shared.WriteRuntimeMetadata(runtimeMetadataPath(), pid, version, port, daemon)
 	if err != nil {
 		log.Printf("Failed to write OWLCMS runtime metadata: %v", err)
@@ -328,6 +372,11 @@ func recordOwlcmsStart(pid int, version, port string, daemon bool) *shared.Runti
 	return metadata
 }
 
+// emitOwlcmsEvent reports a lifecycle event of an OWLCMS process.
+func emitOwlcmsEvent(event, version, port string, pid int, detail string) {
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: event, Module: "owlcms", Version: version, Port: port, PID: pid, Detail: detail})
+}
+
 // SaveLastRunVersion persists the version so that --owlcms previous can find it.
 func SaveLastRunVersion(version string) {
 	p := filepath.Join(installDir, "last-version.txt")
@@ -350,10 +399,17 @@ func GetLastRunVersion() string {
 // Under systemd it stays in the foreground, waits on the process, and restarts
 // on non-zero exit (same supervision as the interactive launcher).
 // Otherwise it detaches the child and returns once the port is ready.
-func LaunchDaemon(version string, enableEmbeddedMQTT bool) error {
+// With autoPort, a busy port is replaced by the next free one.
+func LaunchDaemon(version string, enableEmbeddedMQTT, autoPort bool) error {
 	log.Printf("LaunchDaemon: starting OWLCMS %s headlessly (systemd=%v, INVOCATION_ID=%q)",
 		version, shared.IsRunningUnderSystemd(), os.Getenv("INVOCATION_ID"))
 
+	if autoPort {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			return err
+		}
+		rewireTrackerConnectionForRelease(version)
+	}
 	params, err := prepareOwlcmsLaunch(version, &enableEmbeddedMQTT)
 	if err != nil {
 		return err
@@ -377,8 +433,15 @@ func LaunchDaemon(version string, enableEmbeddedMQTT bool) error {
 }
 
 // LaunchForeground starts OWLCMS from the command line and blocks until it exits.
-func LaunchForeground(version string, enableEmbeddedMQTT bool) error {
+// With autoPort, a busy port is replaced by the next free one.
+func LaunchForeground(version string, enableEmbeddedMQTT, autoPort bool) error {
 	log.Printf("LaunchForeground: starting OWLCMS %s", version)
+	if autoPort {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			return err
+		}
+		rewireTrackerConnectionForRelease(version)
+	}
 	params, err := prepareOwlcmsLaunch(version, &enableEmbeddedMQTT)
 	if err != nil {
 		return err
@@ -413,7 +476,9 @@ func launchSupervisedForeground(version string, params *owlcmsLaunchParams, daem
 
 	pid := cmd.Process.Pid
 	activeRuntime = recordOwlcmsStart(pid, version, params.TargetPort, daemon)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 	log.Printf("LaunchSupervisedForeground: OWLCMS %s (PID %d), waiting for port %s...", version, pid, params.TargetPort)
+	shared.NotifySystemd(fmt.Sprintf("STATUS=OWLCMS %s starting on port %s", version, params.TargetPort))
 
 	// Wait for the port to come up before declaring success.
 	deadline := time.Now().Add(60 * time.Second)
@@ -429,14 +494,31 @@ func launchSupervisedForeground(version string, params *owlcmsLaunchParams, daem
 		time.Sleep(500 * time.Millisecond)
 	}
 
-	if ready {
+	announceReady := func() {
 		log.Printf("LaunchSupervisedForeground: OWLCMS %s ready on port %s (PID %d)", version, params.TargetPort, pid)
+		shared.MarkRuntimeReady(runtimeMetadataPath())
+		emitOwlcmsEvent(shared.EventReady, version, params.TargetPort, pid, "")
+		shared.NotifySystemdReady("OWLCMS", version, params.TargetPort)
 		fmt.Printf("owlcms %s started successfully\n", version)
 	}
+	if ready {
+		announceReady()
+	} else if shared.IsProcessRunning(pid) {
+		// systemd allows more time than this wait; READY=1 is still due
+		// when the port opens.
+		log.Printf("LaunchSupervisedForeground: OWLCMS %s not answering on port %s yet, still waiting", version, params.TargetPort)
+		go shared.AwaitPortReady(params.TargetPort, pid, announceReady)
+	}
+	stopWatchdog := shared.StartSystemdWatchdog("OWLCMS", func(timeout time.Duration) error {
+		return shared.ProbeHTTP(params.TargetPort, timeout)
+	})
 
 	// Block until the process exits.
 	waitErr := cmd.Wait()
+	stopWatchdog()
+	shared.NotifySystemd("STOPPING=1")
 	clearRuntimeState()
+	emitOwlcmsEvent(shared.ExitEvent(waitErr, false), version, params.TargetPort, pid, shared.EventDetail(waitErr))
 
 	if waitErr == nil {
 		log.Printf("LaunchSupervisedForeground: OWLCMS %s exited normally (code 0)", version)
@@ -477,6 +559,8 @@ func launchDaemonDetached(version string, params *owlcmsLaunchParams) error {
 	for time.Now().Before(deadline) {
 		if shared.CheckPort(params.TargetPort) == nil {
 			log.Printf("LaunchDaemon: OWLCMS %s ready on port %s (PID %d)", version, params.TargetPort, pid)
+			shared.MarkRuntimeReady(runtimeMetadataPath())
+			emitOwlcmsEvent(shared.EventReady, version, params.TargetPort, pid, "")
 			return nil
 		}
 		if !shared.IsProcessRunning(pid) {
@@ -553,6 +637,15 @@ func launchOwlcms(version string, launchButton, stopBtn *widget.Button) error {
 		return err
 	}
 
+	if shared.IsAutoPortEnabled() {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			goBackToMainScreen()
+			releaseJavaLock()
+			return err
+		}
+		rewireTrackerConnectionForRelease(version)
+	}
+
 	params, err := prepareOwlcmsLaunch(version, nil)
 	if err != nil {
 		goBackToMainScreen()
@@ -657,6 +750,7 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 
 		javaPID = cmd.Process.Pid
 		activeRuntime = recordOwlcmsStart(javaPID, version, targetPort, false)
+		shared.MarkRuntimeSupervised(runtimeMetadataPath())
 
 		log.Printf("Launching OWLCMS %s (PID: %d), waiting for port %s...\n", version, javaPID, targetPort)
 		statusLabel.SetText(fmt.Sprintf("Starting OWLCMS %s (PID: %d), waiting for port %s.\nFull startup can take up to 30 seconds.", version, javaPID, targetPort))
@@ -692,6 +786,7 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 			if err := <-monitorChan; err != nil {
 				if killedByUs || stopInProgress.Load() {
 					log.Printf("OWLCMS process %d stopped before readiness during intentional stop: %v", pid, err)
+					emitOwlcmsEvent(shared.EventStopped, version, targetPort, pid, shared.EventDetail(err))
 					restoreOwlcmsStoppedUI(version, stopBtn, launchButton, fmt.Sprintf("OWLCMS %s (PID: %d) was stopped before becoming ready", version, pid))
 					return
 				}
@@ -699,6 +794,7 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 				log.Printf("OWLCMS process %d failed to start properly: %v\n", pid, err)
 				currentProcess = nil
 				clearRuntimeState()
+				emitOwlcmsEvent(shared.EventCrashed, version, targetPort, pid, shared.EventDetail(err))
 				fyne.Do(func() {
 					statusLabel.SetText(fmt.Sprintf("OWLCMS process %d failed to start properly", pid))
 					stopBtn.Hide()
@@ -712,6 +808,8 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 			}
 
 			log.Printf("OWLCMS process %d is ready (port %s responding)\n", pid, targetPort)
+			shared.MarkRuntimeReady(runtimeMetadataPath())
+			emitOwlcmsEvent(shared.EventReady, version, targetPort, pid, "")
 			url := fmt.Sprintf("http://localhost:%s", targetPort)
 			fyne.Do(func() {
 				statusLabel.SetText(fmt.Sprintf("OWLCMS running (PID: %d) on port %s", pid, targetPort))
@@ -742,6 +840,9 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 			if logRestartDecision(version, pid, err, retryCount, maxRestartRetries) {
 				attemptNum := retryCount + 1
 				log.Printf("OWLCMS %s (PID: %d) exited unexpectedly (%v); restarting in %s (attempt %d/%d)\n", version, pid, err, restartDelay, attemptNum, maxRestartRetries)
+				shared.RecordModuleRestart("owlcms")
+				emitOwlcmsEvent(shared.EventCrashed, version, targetPort, pid, shared.EventDetail(err))
+				emitOwlcmsEvent(shared.EventRestarted, version, targetPort, pid, fmt.Sprintf("attempt %d/%d", attemptNum, maxRestartRetries))
 				currentProcess = nil
 				fyne.Do(func() {
 					setOwlcmsTabModeRunning()
@@ -775,6 +876,7 @@ func continueOwlcmsLaunch(version string, params *owlcmsLaunchParams, launchButt
 				exitMessage = fmt.Sprintf("OWLCMS %s (PID: %d) exited normally", version, pid)
 			}
 
+			emitOwlcmsEvent(shared.ExitEvent(err, killedByUs), version, targetPort, pid, shared.EventDetail(err))
 			currentProcess = nil
 			killedByUs = false
 			stopInProgress.Store(false)
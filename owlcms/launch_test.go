@@ -14,7 +14,7 @@ import (
 )
 
 func TestShouldUseOwlcmsDaemonWrapperForDetachedDaemonMode(t *testing.T) {
-	t.Setenv("CONTROLPANEL_RUN_AS_DAEMON", "true")
+	t.Setenv("CONTROLPANEL_RUN_AS_DAEMON", "false")
 	t.Setenv("INVOCATION_ID", "")
 	withOwlcmsGoos(t, "linux")
 
@@ -353,7 +353,11 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 	})
 	trackerToggleItem.Label = "Default Tracker Connection"
 
-	optionsMenuItems := []*fyne.MenuItem{setPortItem, trackerToggleItem}
+	settingsItem := fyne.NewMenuItem("All Default Settings", func() {
+		showSettingsEditor(w, "")
+	})
+
+	optionsMenuItems := []*fyne.MenuItem{setPortItem, trackerToggleItem, fyne.NewMenuItemSeparator(), settingsItem}
 
 	optionsMenu := shared.CreateMenuButton("Options", optionsMenuItems)
 
@@ -565,6 +569,10 @@ func showPortNumberDialog(w fyne.Window) {
 				return
 			}
 
+			if err := shared.ClaimPort("owlcms", "", newPort); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
 			if err := SaveProperty("OWLCMS_PORT", newPort); err != nil {
 				dialog.ShowError(fmt.Errorf("failed to save OWLCMS port: %w", err), w)
 				return
@@ -612,6 +620,10 @@ func showPortNumberDialogForVersion(w fyne.Window, version string) {
 				return
 			}
 
+			if err := shared.ClaimPort("owlcms", version, newPort); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
 			if err := SavePropertyForRelease(version, "OWLCMS_PORT", newPort); err != nil {
 				dialog.ShowError(fmt.Errorf("failed to save OWLCMS port: %w", err), w)
 				return
@@ -624,6 +636,48 @@ func showPortNumberDialogForVersion(w fyne.Window, version string) {
 	d.Show()
 }
 
+// showSettingsEditor edits the shared env.properties, or the one of version
+// when it is set.
+func showSettingsEditor(w fyne.Window, version string) {
+	editor := shared.EnvSettingsEditor{
+		Title:         "OWLCMS Default Settings",
+		Module:        "owlcms",
+		ParentEnvPath: filepath.Join(installDir, "env.properties"),
+		Save:          SaveProperty,
+		Remove:        DeleteProperty,
+		OnSaved: func(_ []string) {
+			if err := InitEnv(); err != nil {
+				log.Printf("Failed to reload env.properties: %v", err)
+			}
+		},
+	}
+	if version != "" {
+		if err := EnsureReleaseEnvFromParent(version); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to initialize release env.properties: %w", err), w)
+			return
+		}
+		editor.Title = fmt.Sprintf("OWLCMS %s Settings", version)
+		editor.ReleaseEnvPath = filepath.Join(installDir, version, "env.properties")
+		editor.Save = func(key, value string) error {
+			return SavePropertyForRelease(version, key, value)
+		}
+		editor.Remove = func(key string) error {
+			return DeletePropertyForRelease(version, key)
+		}
+		editor.OnSaved = nil
+	}
+	save := editor.Save
+	editor.Save = func(key, value string) error {
+		if key == "OWLCMS_PORT" && value != "" {
+			if err := shared.ClaimPort("owlcms", version, value); err != nil {
+				return err
+			}
+		}
+		return save(key, value)
+	}
+	shared.ShowEnvSettingsEditor(w, editor)
+}
+
 func refreshAvailableVersions(w fyne.Window) {
 	// Reset release-related state to mirror a fresh app start.
 	showPrereleases = false
@@ -802,6 +856,16 @@ func reconnectOwlcmsRuntime() bool {
 	return true
 }
 
+// ReconnectRuntime attaches the tab to a process started outside of it, such
+// as by a launch profile, unless the tab already tracks a running process.
+// Must be called on the UI thread.
+func ReconnectRuntime() {
+	if IsRunning() {
+		return
+	}
+	reconnectOwlcmsRuntime()
+}
+
 // checkJava checks for Java and downloads it if not found
 func checkJava(statusLabel *widget.Label) error {
 	statusLabel.SetText("Checking for the Java language runtime.")
@@ -1112,3 +1176,14 @@ func StopRunningProcess(w fyne.Window) {
 		stopProcess(currentVersion, stopButton, downloadContainer, versionContainer, statusLabel, w)
 	}
 }
+
+// showEffectiveSettings lists the variables a launch of version passes to
+// OWLCMS, with where each one comes from.
+func showEffectiveSettings(w fyne.Window, version string) {
+	vars, err := EffectiveLaunchEnvironment(version, nil)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	shared.ShowLaunchEnvironment(w, fmt.Sprintf("OWLCMS %s Effective Settings", version), vars)
+}
@@ -238,6 +238,13 @@ func createVersionOptionsButton(w fyne.Window, version string, buttonContainer *
 		fyne.NewMenuItem("Tracker Connection", func() {
 			showTrackerConnectionDialogForVersion(w, version)
 		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("All Settings", func() {
+			showSettingsEditor(w, version)
+		}),
+		fyne.NewMenuItem("Effective Settings", func() {
+			showEffectiveSettings(w, version)
+		}),
 	}
 
 	buttonContainer.Add(container.NewPadded(shared.CreateMenuButton("Options", menuItems)))
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"github.com/sqweek/dialog"
+)
+
+// selectProfileFile shows a native sqweek file chooser on Windows and macOS.
+func selectProfileFile(_ fyne.Window, cb func(path string, err error)) {
+	// Run in a goroutine because sqweek blocks the thread.
+	go func() {
+		path, err := dialog.File().Filter("Launch profiles", "toml", "properties").Title("Select Launch Profile").Load()
+		if err != nil {
+			// If user cancelled, return nil error and empty path
+			if err == dialog.ErrCancelled {
+				cb("", nil)
+				return
+			}
+			cb("", err)
+			return
+		}
+		cb(path, nil)
+	}()
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"controlpanel/shared"
 
@@ -17,6 +18,8 @@ import (
 var (
 	camerasPIDFile = filepath.Join(getInstallDir(), "cameras.pid")
 	replaysPIDFile = filepath.Join(getInstallDir(), "replays.pid")
+	// activeRuntime is the recorded replays process, whether launched or reattached.
+	activeRuntime *shared.RuntimeMetadata
 )
 
 func camerasInstallDir() string {
@@ -70,9 +73,13 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 	if _, err := os.Stat(exePath); os.IsNotExist(err) {
 		return fmt.Errorf("cameras binary not found: %s", exePath)
 	}
+	if err := shared.CheckConfigBeforeLaunch("cameras", camerasInstallDir(), version); err != nil {
+		return err
+	}
 
 	// Ensure FFmpeg is available (download if needed)
-	if _, err := shared.EnsureFFmpegPrerequisite(w); err != nil {
+	ffmpegPath, err := shared.EnsureFFmpegPrerequisite(w)
+	if err != nil {
 		return fmt.Errorf("FFmpeg prerequisite: %w", err)
 	}
 
@@ -98,7 +105,12 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 		return fmt.Errorf("failed to reset cameras log: %w", err)
 	}
 
-	cmd.Env = shared.BuildVideoLaunchEnv(versionDir)
+	env, err := shared.InjectSecrets(shared.BuildVideoLaunchEnv(versionDir))
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	camerasFFmpegWarning = shared.CheckVideoFFmpeg(ffmpegPath, cmd.Env)
 
 	log.Printf("Starting cameras %s: %s", version, exePath)
 	if err := cmd.Start(); err != nil {
@@ -112,9 +124,10 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 	if err := os.WriteFile(camerasPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
 		log.Printf("Failed to write cameras PID file: %v", err)
 	}
+	emitVideoEvent("cameras", shared.EventStarted, version, "", pid, "")
 
 	if statusLabel != nil {
-		statusLabel.SetText(fmt.Sprintf("Cameras %s running (PID: %d)", version, pid))
+		statusLabel.SetText(runningStatusText("Cameras", version, pid, camerasFFmpegWarning))
 	}
 	cameraStopButton.SetText(fmt.Sprintf("Stop Cameras %s", version))
 	cameraStopButton.Show()
@@ -145,6 +158,7 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 			log.Printf("Cameras %s (PID: %d) exited normally\n", version, pid)
 		}
 
+		emitVideoEvent("cameras", shared.ExitEvent(err, killedByUs), version, "", pid, shared.EventDetail(err))
 		camerasProcess = nil
 		killedByUs = false
 		os.Remove(camerasPIDFile)
@@ -161,7 +175,7 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 			checkForNewerVersion()
 		} else {
 			if statusLabel != nil {
-				statusLabel.SetText(fmt.Sprintf("Replays %s running (PID: %d)", replaysVersion, replaysProcess.Process.Pid))
+				statusLabel.SetText(runningStatusText("Replays", replaysVersion, replaysProcess.Process.Pid, replaysFFmpegWarning))
 			}
 		}
 	}()
@@ -169,19 +183,193 @@ func launchCameras(version string, _ *widget.Button, w fyne.Window) error {
 	return nil
 }
 
-func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
+func runtimeMetadataPath() string {
+	return filepath.Join(installDir, "replays-run.json")
+}
+
+// RuntimeMetadataPath returns the replays runtime metadata path.
+func RuntimeMetadataPath() string {
+	return runtimeMetadataPath()
+}
+
+// PIDFilePath returns the replays PID file path.
+func PIDFilePath() string {
+	return replaysPIDFile
+}
+
+func clearRuntimeState() {
+	activeRuntime = nil
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear replays runtime metadata: %v", err)
+	}
+}
+
+// GetLastRunVersion returns the version recorded in the runtime metadata, or empty string.
+func GetLastRunVersion() string {
+	metadata, err := shared.LoadRuntimeMetadata(runtimeMetadataPath())
+	if err != nil || metadata == nil {
+		return ""
+	}
+	return metadata.Version
+}
+
+func recordReplaysStart(pid int, version, port string, daemon bool) *shared.RuntimeMetadata {
+	if err := os.WriteFile(replaysPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		log.Printf("Failed to write replays PID file: %v", err)
+	}
+	emitVideoEvent("replays", shared.EventStarted, version, port, pid, "")
+	metadata, err := shared.WriteRuntimeMetadata(runtimeMetadataPath(), pid, version, port, daemon)
+	if err != nil {
+		log.Printf("Failed to write replays runtime metadata: %v", err)
+		return nil
+	}
+	return metadata
+}
+
+// emitVideoEvent reports a lifecycle event of a cameras or replays process.
+func emitVideoEvent(module, event, version, port string, pid int, detail string) {
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: event, Module: module, Version: version, Port: port, PID: pid, Detail: detail})
+}
+
+// prepareHeadlessLaunch initializes env.properties and checks that neither a
+// previous replays process nor another program holds the configured port.
+func prepareHeadlessLaunch(version string) (*exec.Cmd, string, error) {
+	if err := InitEnv(); err != nil {
+		return nil, "", fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	port := getPortForRelease(version)
+	if pid, _, err := shared.ResolvePIDFromFileOrPort(replaysPIDFile, ""); err == nil && pid > 0 {
+		return nil, "", fmt.Errorf("replays is already running (PID %d)", pid)
+	}
+	if port != "" && shared.CheckPort(port) == nil {
+		return nil, "", fmt.Errorf("port %s is already in use", port)
+	}
+	cmd, err := prepareReplaysLaunch(version, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return cmd, port, nil
+}
+
+// waitForReplaysStartup waits for the configured port, or only checks that the
+// process survives its first seconds when config.toml has no port.
+func waitForReplaysStartup(version, port string, pid int) error {
+	timeout := 3 * time.Second
+	if port != "" {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if port != "" && shared.CheckPort(port) == nil {
+			return nil
+		}
+		if !shared.IsProcessRunning(pid) {
+			return fmt.Errorf("replays %s (PID %d) exited during startup", version, pid)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if port != "" {
+		return fmt.Errorf("timed out waiting for replays %s on port %s", version, port)
+	}
+	return nil
+}
+
+// LaunchDaemon starts replays headlessly (no UI) in daemon mode.
+// FFmpeg is downloaded first if it is not installed.
+func LaunchDaemon(version string) error {
+	log.Printf("LaunchDaemon: starting replays %s headlessly", version)
+
+	cmd, port, err := prepareHeadlessLaunch(version)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureNoConsoleWindow(cmd)
+	shared.ConfigureDetachedDaemonProcess(cmd, true)
+
+	logPath := filepath.Join(cmd.Dir, "logs", "replays-console.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Printf("Warning: could not open replays console log: %v", err)
+	} else {
+		// Pass the file descriptor directly so the child does not hold a pipe
+		// to a control panel that exits right after the launch.
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+	}
+
+	log.Printf("LaunchDaemon: command %v in %s", cmd.Args, cmd.Dir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start replays %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordReplaysStart(pid, version, port, true)
+	if err := waitForReplaysStartup(version, port, pid); err != nil {
+		return err
+	}
+	emitVideoEvent("replays", shared.EventReady, version, port, pid, "")
+	log.Printf("LaunchDaemon: replays %s running (PID %d)", version, pid)
+	return nil
+}
+
+// LaunchForeground starts replays from the command line and blocks until it exits.
+func LaunchForeground(version string) error {
+	log.Printf("LaunchForeground: starting replays %s", version)
+
+	cmd, port, err := prepareHeadlessLaunch(version)
+	if err != nil {
+		return err
+	}
+	shared.ConfigureNoConsoleWindow(cmd)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	log.Printf("LaunchForeground: command %v in %s", cmd.Args, cmd.Dir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start replays %s: %w", version, err)
+	}
+
+	pid := cmd.Process.Pid
+	recordReplaysStart(pid, version, port, false)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
+	if err := waitForReplaysStartup(version, port, pid); err != nil {
+		log.Printf("LaunchForeground: %v", err)
+	} else {
+		emitVideoEvent("replays", shared.EventReady, version, port, pid, "")
+		fmt.Printf("replays %s started successfully\n", version)
+	}
+
+	waitErr := cmd.Wait()
+	emitVideoEvent("replays", shared.ExitEvent(waitErr, false), version, port, pid, shared.EventDetail(waitErr))
+	os.Remove(replaysPIDFile)
+	if err := shared.ClearRuntimeMetadata(runtimeMetadataPath()); err != nil {
+		log.Printf("Failed to clear replays runtime metadata: %v", err)
+	}
+	if waitErr == nil {
+		log.Printf("LaunchForeground: replays %s exited normally", version)
+		return nil
+	}
+	return waitErr
+}
+
+// prepareReplaysLaunch checks the replays binary, provisions FFmpeg and runs the
+// extract bootstrap when needed. A nil window skips the FFmpeg progress dialog.
+func prepareReplaysLaunch(version string, w fyne.Window) (*exec.Cmd, error) {
 	versionDir := filepath.Join(installDir, version)
 	configDir := versionDir
 	exePath := filepath.Join(versionDir, replaysExeName())
-	targetPort := getPortForRelease(version)
 
 	if _, err := os.Stat(exePath); os.IsNotExist(err) {
-		return fmt.Errorf("replays binary not found: %s", exePath)
+		return nil, fmt.Errorf("replays binary not found: %s", exePath)
+	}
+	if err := shared.CheckConfigBeforeLaunch("replays", installDir, version); err != nil {
+		return nil, err
 	}
 
 	// Ensure FFmpeg is available (download if needed)
-	if _, err := shared.EnsureFFmpegPrerequisite(w); err != nil {
-		return fmt.Errorf("FFmpeg prerequisite: %w", err)
+	ffmpegPath, err := shared.EnsureFFmpegPrerequisite(w)
+	if err != nil {
+		return nil, fmt.Errorf("FFmpeg prerequisite: %w", err)
 	}
 
 	// Make executable on Linux
@@ -194,7 +382,7 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 	if shared.ShouldRunVideoExtract(versionDir, "replays") {
 		log.Printf("Running replays extract bootstrap for %s", versionDir)
 		if err := shared.RunVideoExtractBootstrap(exePath, versionDir); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -203,10 +391,27 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 
 	logPath := filepath.Join(versionDir, "logs", "replays.log")
 	if err := shared.ResetLogFile(logPath); err != nil {
-		return fmt.Errorf("failed to reset replays log: %w", err)
+		return nil, fmt.Errorf("failed to reset replays log: %w", err)
+	}
+
+	env, err := shared.InjectSecrets(shared.BuildVideoLaunchEnv(versionDir))
+	if err != nil {
+		return nil, err
 	}
+	cmd.Env = env
+	replaysFFmpegWarning = shared.CheckVideoFFmpeg(ffmpegPath, cmd.Env)
+	return cmd, nil
+}
 
-	cmd.Env = shared.BuildVideoLaunchEnv(versionDir)
+func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
+	versionDir := filepath.Join(installDir, version)
+	targetPort := getPortForRelease(version)
+	cmd, err := prepareReplaysLaunch(version, w)
+	if err != nil {
+		return err
+	}
+	keepRunning := GetKeepRunning()
+	shared.ConfigureDetachedDaemonProcess(cmd, keepRunning)
 
 	if targetPort != "" && shared.CheckPort(targetPort) == nil {
 		log.Printf("Replays port %s is in use, attempting to free it...", targetPort)
@@ -218,7 +423,7 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 		}
 	}
 
-	log.Printf("Starting replays %s: %s", version, exePath)
+	log.Printf("Starting replays %s: %s", version, cmd.Path)
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start replays %s: %w", version, err)
 	}
@@ -227,12 +432,11 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 	replaysVersion = version
 
 	pid := cmd.Process.Pid
-	if err := os.WriteFile(replaysPIDFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
-		log.Printf("Failed to write replays PID file: %v", err)
-	}
+	activeRuntime = recordReplaysStart(pid, version, targetPort, keepRunning)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 
 	if statusLabel != nil {
-		statusLabel.SetText(fmt.Sprintf("Replays %s running (PID: %d)", version, pid))
+		statusLabel.SetText(runningStatusText("Replays", version, pid, replaysFFmpegWarning))
 	}
 	replaysStopButton.SetText(fmt.Sprintf("Stop Replays %s", version))
 	replaysStopButton.Show()
@@ -256,9 +460,11 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 			log.Printf("Replays %s (PID: %d) exited normally\n", version, pid)
 		}
 
+		emitVideoEvent("replays", shared.ExitEvent(err, killedByUs), version, targetPort, pid, shared.EventDetail(err))
 		replaysProcess = nil
 		killedByUs = false
 		os.Remove(replaysPIDFile)
+		clearRuntimeState()
 		replaysStopButton.Hide()
 		updateStopContainer()
 
@@ -272,7 +478,7 @@ func launchReplays(version string, _ *widget.Button, w fyne.Window) error {
 			checkForNewerVersion()
 		} else {
 			if statusLabel != nil {
-				statusLabel.SetText(fmt.Sprintf("Cameras %s running (PID: %d)", camerasVersion, camerasProcess.Process.Pid))
+				statusLabel.SetText(runningStatusText("Cameras", camerasVersion, camerasProcess.Process.Pid, camerasFFmpegWarning))
 			}
 		}
 	}()
@@ -305,6 +511,41 @@ func configureCamerasRunLinks(version, versionDir string) {
 	}
 }
 
+func restoreReplaysRunningUI(version string, pid int) {
+	replaysVersion = version
+	if statusLabel != nil {
+		statusLabel.SetText(runningStatusText("Replays", version, pid, replaysFFmpegWarning))
+		statusLabel.Show()
+	}
+	replaysStopButton.SetText(fmt.Sprintf("Stop Replays %s", version))
+	replaysStopButton.Show()
+	updateStopContainer()
+	setVideoTabModeRunning()
+	configureReplaysRunLinks(version, filepath.Join(installDir, version))
+}
+
+func reconnectReplaysRuntime() bool {
+	metadata, running := shared.CheckDaemonRunning(runtimeMetadataPath())
+	if !running {
+		clearRuntimeState()
+		return false
+	}
+
+	activeRuntime = metadata
+	restoreReplaysRunningUI(metadata.Version, metadata.PID)
+	return true
+}
+
+// ReconnectRuntime attaches the tab to a process started outside of it, such
+// as by a launch profile, unless the tab already tracks a running process.
+// Must be called on the UI thread.
+func ReconnectRuntime() {
+	if IsRunning() {
+		return
+	}
+	reconnectReplaysRuntime()
+}
+
 func configureReplaysRunLinks(version, versionDir string) {
 	if replaysDirLink != nil {
 		replaysDirLink.SetText(fmt.Sprintf("Open Replays %s configuration directory", version))
@@ -330,6 +571,16 @@ func configureReplaysRunLinks(version, versionDir string) {
 	}
 }
 
+// runningStatusText describes a running video process in the tab, with the
+// FFmpeg warning found when it was launched.
+func runningStatusText(module, version string, pid int, ffmpegWarning string) string {
+	text := fmt.Sprintf("%s %s running (PID: %d)", module, version, pid)
+	if ffmpegWarning != "" {
+		text += "\nWarning: " + ffmpegWarning
+	}
+	return text
+}
+
 func hideAllRunLinks() {
 	if appDirLink != nil {
 		appDirLink.Hide()
@@ -54,6 +54,9 @@ func stopReplaysProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 		statusLabel.SetText(fmt.Sprintf("Stopping replays %s...", curVersion))
 	}
 	port := getPortForRelease(curVersion)
+	if activeRuntime != nil && activeRuntime.Port != "" {
+		port = activeRuntime.Port
+	}
 	if port == "" {
 		port = runtimeReplaysPort()
 	}
@@ -77,8 +80,10 @@ func stopReplaysProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 	if statusLabel != nil {
 		statusLabel.SetText(fmt.Sprintf("Replays %s stopped", curVersion))
 	}
+	attached := curProcess == nil
 	replaysProcess = nil
 	os.Remove(replaysPIDFile)
+	clearRuntimeState()
 	if stopBtn != nil {
 		stopBtn.Hide()
 	}
@@ -87,6 +92,11 @@ func stopReplaysProcess(curProcess *exec.Cmd, curVersion string, stopBtn *widget
 	downloadContainer.Show()
 	versionContainer.Show()
 	hideAllRunLinks()
+	if attached {
+		// No wait goroutine restores the UI for a reattached process.
+		killedByUs = false
+		setVideoTabMode(w)
+	}
 }
 
 func killLockingProcess() error {
@@ -107,7 +117,7 @@ func updateStopContainer() {
 	if stopContainer == nil {
 		return
 	}
-	if camerasProcess == nil && replaysProcess == nil {
+	if camerasProcess == nil && replaysProcess == nil && activeRuntime == nil {
 		stopContainer.Hide()
 	} else {
 		stopContainer.Show()
@@ -25,6 +25,8 @@ var (
 	replaysProcess            *exec.Cmd
 	camerasVersion            string
 	replaysVersion            string
+	camerasFFmpegWarning      string
+	replaysFFmpegWarning      string
 	killedByUs                bool
 	statusLabel               *widget.Label
 	cameraStopButton          *widget.Button
@@ -32,6 +34,7 @@ var (
 	versionContainer          *fyne.Container
 	stopContainer             *fyne.Container
 	singleOrMultiVersionLabel *widget.Label
+	ffmpegLabel               *widget.Label
 	downloadContainer         *fyne.Container
 	downloadsShown            bool
 	appDirLink                *widget.Hyperlink
@@ -48,7 +51,18 @@ var (
 
 // IsRunning returns true if any video process (cameras or replays) is running
 func IsRunning() bool {
-	return camerasProcess != nil || replaysProcess != nil
+	return camerasProcess != nil || replaysProcess != nil || activeRuntime != nil
+}
+
+// IsLocalProcessRunning returns true when a running process should stop with this control panel.
+func IsLocalProcessRunning() bool {
+	return IsRunning() && !IsDaemonRunning()
+}
+
+// IsDaemonRunning returns true when the replays process was started to keep
+// running after the control panel closes.
+func IsDaemonRunning() bool {
+	return activeRuntime != nil && activeRuntime.Daemon
 }
 
 // StopRunningProcess stops all running video processes
@@ -60,6 +74,9 @@ func StopRunningProcess(w fyne.Window) {
 	if replaysProcess != nil && replaysProcess.Process != nil {
 		log.Println("Stopping Replays process")
 		stopReplaysProcess(replaysProcess, replaysVersion, replaysStopButton, w)
+	} else if activeRuntime != nil {
+		log.Println("Stopping attached Replays process")
+		stopReplaysProcess(nil, replaysVersion, replaysStopButton, w)
 	}
 }
 
@@ -82,6 +99,13 @@ func HandleSignalCleanup() {
 		}
 		replaysProcess = nil
 	}
+	if activeRuntime != nil && replaysProcess == nil {
+		log.Printf("Forcefully stopping attached Replays (PID: %d)", activeRuntime.PID)
+		if err := shared.ForcefullyKillPID(activeRuntime.PID); err != nil {
+			log.Printf("Failed to kill attached Replays process %d: %v", activeRuntime.PID, err)
+		}
+	}
+	clearRuntimeState()
 	os.Remove(camerasPIDFile)
 	os.Remove(replaysPIDFile)
 }
@@ -141,6 +165,8 @@ func CreateTab(w fyne.Window) *fyne.Container {
 		}
 	}
 	singleOrMultiVersionLabel = widget.NewLabel("")
+	ffmpegLabel = widget.NewLabel("")
+	ffmpegLabel.Wrapping = fyne.TextWrapWord
 
 	// Wire stop button actions
 	cameraStopButton.OnTapped = func() {}
@@ -162,7 +188,7 @@ func CreateTab(w fyne.Window) *fyne.Container {
 	topSpacer.SetMinSize(fyne.NewSize(1, 8))
 
 	topInstallContent = container.NewVBox()
-	topVersionContent = container.NewVBox(menuBar, topSpacer)
+	topVersionContent = container.NewVBox(menuBar, topSpacer, ffmpegLabel)
 	topRunContent = container.NewVBox(stopContainer)
 	topModeStack = container.NewStack(topInstallContent, topVersionContent, topRunContent)
 
@@ -267,6 +293,9 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 		fyne.NewMenuItem("Refresh Available Versions", func() {
 			refreshAvailableVersions(w)
 		}),
+		fyne.NewMenuItem("Probe FFmpeg Capabilities", func() {
+			refreshFFmpegCapabilities(true)
+		}),
 		fyne.NewMenuItemSeparator(),
 		fyne.NewMenuItem("Uninstall Replays", func() {
 			uninstallAll()
@@ -274,6 +303,17 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 	}
 	fileMenu := shared.CreateMenuButton("Files", fileMenuItems)
 
+	keepRunningItem := fyne.NewMenuItem("Keep Running When Control Panel Closes", nil)
+	keepRunningItem.Checked = GetKeepRunning()
+	keepRunningItem.Action = func() {
+		enabled := !keepRunningItem.Checked
+		if err := SetKeepRunning(enabled); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save setting: %w", err), w)
+			return
+		}
+		keepRunningItem.Checked = enabled
+	}
+
 	processMenuItems := []*fyne.MenuItem{
 		fyne.NewMenuItem("Open Version Directory", func() {
 			if err := shared.OpenFileExplorer(installDir); err != nil {
@@ -287,6 +327,8 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 				dialog.ShowInformation("Success", "Successfully killed running Replays processes", w)
 			}
 		}),
+		fyne.NewMenuItemSeparator(),
+		keepRunningItem,
 	}
 	processMenu := shared.CreateMenuButton("Processes", processMenuItems)
 
@@ -322,6 +364,11 @@ func refreshAvailableVersions(w fyne.Window) {
 
 func initializeTab(w fyne.Window) {
 	fyne.Do(func() {
+		if reconnectReplaysRuntime() {
+			log.Println("Replays tab reattached to existing runtime")
+			return
+		}
+
 		if len(getAllInstalledVersions()) == 0 {
 			setVideoTabModeUninstalled(w)
 		} else {
@@ -347,6 +394,7 @@ func setVideoTabModeInstalled(w fyne.Window) {
 	recomputeVersionList(w)
 	checkForNewerVersion()
 	showVersionListMode()
+	refreshFFmpegCapabilities(false)
 	cameraStopButton.Hide()
 	replaysStopButton.Hide()
 	statusLabel.Hide()
@@ -354,6 +402,25 @@ func setVideoTabModeInstalled(w fyne.Window) {
 	log.Printf("Video UI Mode: Installed (%d versions)", len(getAllInstalledVersions()))
 }
 
+// refreshFFmpegCapabilities shows what the shared FFmpeg can do, probing it
+// again when force is set or the binary changed since the last probe.
+func refreshFFmpegCapabilities(force bool) {
+	go func() {
+		text := "FFmpeg is not installed yet; it is downloaded on first launch."
+		if ffmpegPath := shared.FindLocalFFmpeg(); ffmpegPath != "" {
+			caps, err := shared.LoadFFmpegCapabilities(ffmpegPath, shared.VideoConfigDir(""), force)
+			if err != nil {
+				text = fmt.Sprintf("FFmpeg capabilities unknown: %v", err)
+			} else {
+				text = caps.Summary()
+			}
+		}
+		fyne.Do(func() {
+			ffmpegLabel.SetText(text)
+		})
+	}()
+}
+
 func setVideoTabMode(w fyne.Window) {
 	if len(getAllInstalledVersions()) == 0 {
 		setVideoTabModeUninstalled(w)
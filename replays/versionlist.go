@@ -150,6 +150,7 @@ func createVersionList(w fyne.Window) *widget.List {
 
 			createReplaysLaunchButton(w, version, buttonContainer)
 			createFilesButton(version, w, buttonContainer)
+			createVersionOptionsButton(w, version, buttonContainer)
 			if len(allReleases) > 0 {
 				createUpdateButton(version, w, buttonContainer)
 			}
@@ -238,6 +239,17 @@ func createFilesButton(version string, w fyne.Window, buttonContainer *fyne.Cont
 	buttonContainer.Add(container.NewPadded(filesButton))
 }
 
+func createVersionOptionsButton(w fyne.Window, version string, buttonContainer *fyne.Container) {
+	menuItems := []*fyne.MenuItem{
+		fyne.NewMenuItem("Effective Settings", func() {
+			vars := shared.DescribeVideoLaunchEnv(filepath.Join(installDir, version))
+			shared.ShowLaunchEnvironment(w, fmt.Sprintf("Replays %s Effective Settings", version), vars)
+		}),
+	}
+
+	buttonContainer.Add(container.NewPadded(shared.CreateMenuButton("Options", menuItems)))
+}
+
 func createUpdateButton(version string, w fyne.Window, buttonContainer *fyne.Container) {
 	updateButton := widget.NewButton("Update", nil)
 
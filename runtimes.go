@@ -0,0 +1,697 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"github.com/magiconair/properties"
+)
+
+// runtimeVersionKeys are the env.properties keys that choose a runtime.
+var runtimeVersionKeys = map[string]string{
+	shared.RuntimeJava: "TEMURIN_VERSION",
+	shared.RuntimeNode: "NODE_VERSION",
+}
+
+// runtimeRequirement is a module version that needs a runtime.
+type runtimeRequirement struct {
+	Kind     string
+	Required string // TEMURIN_VERSION or NODE_VERSION; empty takes the newest
+	User     string // for example "owlcms 64.0.1 [records]"
+}
+
+// runRuntimesCommand executes --runtimes list|install|remove|verify|clean|pin|policy.
+func runRuntimesCommand(opts cliOptions, out io.Writer) error {
+	action := "list"
+	var args []string
+	if len(opts.runtimeCmd) > 0 {
+		action = strings.ToLower(opts.runtimeCmd[0])
+		args = opts.runtimeCmd[1:]
+	}
+	if action == "policy" {
+		return runRuntimePolicyCommand(args, out)
+	}
+	if len(args) > 0 && !shared.IsRuntimeKind(strings.ToLower(args[0])) {
+		return fmt.Errorf("unknown runtime %q; use java, node or ffmpeg", args[0])
+	}
+	if len(args) > 0 {
+		args[0] = strings.ToLower(args[0])
+	}
+	if action != "install" {
+		// The runtime finders log every candidate they look at.
+		log.SetOutput(io.Discard)
+	}
+
+	switch action {
+	case "list":
+		return printRuntimes(out)
+	case "install":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("--runtimes install expects java|node|ffmpeg [version]")
+		}
+		return installRuntime(args[0], optionalArg(args, 1), out)
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("--runtimes remove expects java|node|ffmpeg <version>")
+		}
+		return removeRuntime(args[0], args[1], opts.yes, out)
+	case "verify":
+		if len(args) > 1 {
+			return fmt.Errorf("--runtimes verify expects at most one runtime kind")
+		}
+		return verifyRuntimes(optionalArg(args, 0), out)
+	case "clean":
+		if len(args) > 1 {
+			return fmt.Errorf("--runtimes clean expects at most one runtime kind")
+		}
+		return cleanupRuntimes(optionalArg(args, 0), opts.dryRun, out)
+	case "pin":
+		if len(args) < 2 || len(args) > 4 {
+			return fmt.Errorf("--runtimes pin expects java|node <version> [module] [module-version]")
+		}
+		return pinRuntime(args[0], args[1], optionalArg(args, 2), optionalArg(args, 3), out)
+	default:
+		return fmt.Errorf("unknown --runtimes action %q; use list, install, remove, verify, clean, pin or policy", action)
+	}
+}
+
+func optionalArg(args []string, index int) string {
+	if index < len(args) {
+		return strings.TrimSpace(args[index])
+	}
+	return ""
+}
+
+// runtimeDirInstances lists the instances sharing this runtime directory: the
+// instances found next to the default install directory, and those recorded
+// in the instance registry of the runtime directory wherever they live.
+func runtimeDirInstances() ([]*instancePaths, error) {
+	discovered, err := discoverInstances()
+	if err != nil {
+		return nil, err
+	}
+	runtimeDir := filepath.Clean(shared.GetRuntimeDir())
+	registered, err := shared.RegisteredInstances(runtimeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []*instancePaths
+	seen := map[string]bool{}
+	for _, instance := range discovered {
+		if filepath.Clean(instance.RuntimeDir) != runtimeDir {
+			continue
+		}
+		seen[filepath.Clean(instance.Paths.ControlPanelDir)] = true
+		instances = append(instances, instance.Paths)
+	}
+	for _, instance := range registered {
+		dir := filepath.Clean(instance.ControlPanelDir)
+		if seen[dir] {
+			continue
+		}
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		seen[dir] = true
+		instances = append(instances, &instancePaths{
+			InstanceName:    instance.Name,
+			ControlPanelDir: instance.ControlPanelDir,
+			OwlcmsDir:       instance.OwlcmsDir,
+			TrackerDir:      instance.TrackerDir,
+		})
+	}
+	return instances, nil
+}
+
+func instanceSuffix(name string) string {
+	if isMainInstance(name) {
+		return ""
+	}
+	return " [" + name + "]"
+}
+
+// collectRuntimeRequirements lists the module versions of every instance
+// sharing this runtime directory, with the runtime each one asks for.
+func collectRuntimeRequirements() ([]runtimeRequirement, error) {
+	instances, err := runtimeDirInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	var requirements []runtimeRequirement
+	for _, instance := range instances {
+		suffix := instanceSuffix(instance.InstanceName)
+		requirements = append(requirements, releaseRuntimeRequirements(instance.OwlcmsDir, "owlcms", shared.RuntimeJava, "jdk-25", suffix)...)
+		requirements = append(requirements, releaseRuntimeRequirements(instance.TrackerDir, "tracker", shared.RuntimeNode, "", suffix)...)
+	}
+
+	// Firmata, cameras and replays are shared by all instances.
+	requirements = append(requirements, releaseRuntimeRequirements(firmata.GetInstallDir(), "firmata", shared.RuntimeJava, "jdk-25", "")...)
+	for _, module := range []struct {
+		name string
+		dir  string
+	}{{"cameras", cameras.GetInstallDir()}, {"replays", replays.GetInstallDir()}} {
+		for _, version := range installedVersionDirectories(module.dir) {
+			requirements = append(requirements, runtimeRequirement{Kind: shared.RuntimeFFmpeg, User: module.name + " " + version})
+		}
+	}
+	return requirements, nil
+}
+
+// releaseRuntimeRequirements reads the runtime version key of every version
+// of a module, falling back to the module's env.properties and then to fallback.
+func releaseRuntimeRequirements(moduleDir, module, kind, fallback, suffix string) []runtimeRequirement {
+	var requirements []runtimeRequirement
+	for _, version := range installedVersionDirectories(moduleDir) {
+		required := fallback
+		props, err := shared.MergeEnvironmentProperties(
+			filepath.Join(moduleDir, "env.properties"),
+			filepath.Join(moduleDir, version, "env.properties"),
+		)
+		if err == nil {
+			if value, ok := props.Get(runtimeVersionKeys[kind]); ok && strings.TrimSpace(value) != "" {
+				required = strings.TrimSpace(value)
+			}
+		}
+		requirements = append(requirements, runtimeRequirement{Kind: kind, Required: required, User: module + " " + version + suffix})
+	}
+	return requirements
+}
+
+// resolveRuntimeRequirement returns the installed runtime a launch would
+// select for the requirement, or nil when it would have to download one.
+func resolveRuntimeRequirement(runtimes []shared.InstalledRuntime, requirement runtimeRequirement) *shared.InstalledRuntime {
+	var executable string
+	switch requirement.Kind {
+	case shared.RuntimeJava:
+		executable, _ = shared.FindLocalJavaForVersion(requirement.Required, shared.GetGoos)
+	case shared.RuntimeNode:
+		executable, _ = shared.FindLocalNodeForVersion(requirement.Required, shared.GetGoos)
+	case shared.RuntimeFFmpeg:
+		executable = shared.FindLocalFFmpeg()
+	}
+	if executable == "" {
+		return nil
+	}
+	return shared.RuntimeContaining(runtimes, executable)
+}
+
+// runtimeUsage maps each installed runtime path to the module versions and
+// running processes that use it, and returns the requirements that no
+// installed runtime satisfies.
+func runtimeUsage(runtimes []shared.InstalledRuntime) (map[string][]string, []runtimeRequirement, error) {
+	requirements, err := collectRuntimeRequirements()
+	if err != nil {
+		return nil, nil, err
+	}
+	return runtimeUsers(runtimes, requirements)
+}
+
+func runtimeUsers(runtimes []shared.InstalledRuntime, requirements []runtimeRequirement) (map[string][]string, []runtimeRequirement, error) {
+	users, err := runningRuntimeUsers(runtimes)
+	if err != nil {
+		return nil, nil, err
+	}
+	var missing []runtimeRequirement
+	for _, requirement := range requirements {
+		if runtime := resolveRuntimeRequirement(runtimes, requirement); runtime != nil {
+			users[runtime.Path] = append(users[runtime.Path], requirement.User)
+		} else {
+			missing = append(missing, requirement)
+		}
+	}
+	return users, missing, nil
+}
+
+// runningRuntimeUsers maps the runtime paths that running OWLCMS, Tracker and
+// firmata processes execute from to those processes. A process keeps running
+// on an older build after a newer one is installed, and must not lose it.
+func runningRuntimeUsers(runtimes []shared.InstalledRuntime) (map[string][]string, error) {
+	instances, err := runtimeDirInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	type runningModule struct {
+		name         string
+		metadataPath string
+		suffix       string
+	}
+	modules := []runningModule{{name: "firmata", metadataPath: firmata.RuntimeMetadataPath()}}
+	for _, instance := range instances {
+		suffix := instanceSuffix(instance.InstanceName)
+		modules = append(modules,
+			runningModule{name: "owlcms", metadataPath: filepath.Join(instance.ControlPanelDir, "owlcms-run.json"), suffix: suffix},
+			runningModule{name: "tracker", metadataPath: filepath.Join(instance.TrackerDir, "tracker-run.json"), suffix: suffix},
+		)
+	}
+
+	users := map[string][]string{}
+	for _, module := range modules {
+		metadata, ok := shared.CheckDaemonRunning(module.metadataPath)
+		if !ok {
+			continue
+		}
+		executable, err := shared.ProcessExecutable(metadata.PID)
+		if err != nil || executable == "" {
+			continue
+		}
+		if runtime := shared.RuntimeContaining(runtimes, executable); runtime != nil {
+			users[runtime.Path] = append(users[runtime.Path], "running "+module.name+" "+metadata.Version+module.suffix)
+		}
+	}
+	return users, nil
+}
+
+// collectCleanupUsage gathers the runtime usage of every instance sharing the
+// runtime directory for the cleanup of obsolete Java and Node.js versions.
+func collectCleanupUsage() (shared.RuntimeUsage, error) {
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return shared.RuntimeUsage{}, err
+	}
+	requirements, err := collectRuntimeRequirements()
+	if err != nil {
+		return shared.RuntimeUsage{}, err
+	}
+	users, _, err := runtimeUsers(runtimes, requirements)
+	if err != nil {
+		return shared.RuntimeUsage{}, err
+	}
+	usage := shared.RuntimeUsage{Required: map[string][]string{}, UsedBy: users}
+	for _, requirement := range requirements {
+		if requirement.Required != "" {
+			usage.Required[requirement.Kind] = append(usage.Required[requirement.Kind], requirement.Required)
+		}
+	}
+	return usage, nil
+}
+
+// cleanupRuntimes removes the Java and Node.js builds no instance uses, or
+// with dryRun lists them, as the File menu cleanup entries do.
+func cleanupRuntimes(kind string, dryRun bool, out io.Writer) error {
+	if kind == shared.RuntimeFFmpeg {
+		return fmt.Errorf("FFmpeg has a single shared build and needs no cleanup")
+	}
+	usage, err := collectCleanupUsage()
+	if err != nil {
+		return err
+	}
+	var results []string
+	if kind == "" || kind == shared.RuntimeJava {
+		removed, err := shared.CleanupObsoleteJavaVersions(owlcms.GetInstallDir(), firmata.GetInstallDir(), usage, dryRun, nil, nil)
+		results = append(results, removed...)
+		if err != nil {
+			printCleanupResults(results, out)
+			return err
+		}
+	}
+	if kind == "" || kind == shared.RuntimeNode {
+		removed, err := shared.CleanupObsoleteNodeVersions(usage, dryRun, nil, nil)
+		results = append(results, removed...)
+		if err != nil {
+			printCleanupResults(results, out)
+			return err
+		}
+	}
+	printCleanupResults(results, out)
+	return nil
+}
+
+func printCleanupResults(results []string, out io.Writer) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No obsolete runtimes found")
+	}
+	for _, result := range results {
+		fmt.Fprintln(out, result)
+	}
+}
+
+func describeRequirement(requirement runtimeRequirement) string {
+	if requirement.Required == "" {
+		return requirement.Kind
+	}
+	return requirement.Kind + " " + requirement.Required
+}
+
+func printRuntimes(out io.Writer) error {
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return err
+	}
+	users, missing, err := runtimeUsage(runtimes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Runtime directory: %s\n", shared.GetRuntimeDir())
+	if policy, err := shared.LoadRuntimePolicy(); err != nil {
+		fmt.Fprintf(out, "Runtime policy: %v\n", err)
+	} else if policy.Mode != shared.RuntimePolicyBundled {
+		fmt.Fprintf(out, "Runtime policy: %s (see --runtimes policy)\n", policy.Mode)
+	}
+	if len(runtimes) == 0 {
+		fmt.Fprintln(out, "No runtimes installed")
+	} else {
+		fmt.Fprintf(out, "%-7s %-28s %10s  %s\n", "RUNTIME", "VERSION", "SIZE", "USED BY")
+		for _, runtime := range runtimes {
+			usedBy := "unused"
+			if list := users[runtime.Path]; len(list) > 0 {
+				usedBy = strings.Join(list, ", ")
+			}
+			if runtime.Executable == "" {
+				usedBy = "incomplete, no executable found"
+			}
+			fmt.Fprintf(out, "%-7s %-28s %10s  %s\n", runtime.Kind, runtime.Version, shared.FormatSize(shared.RuntimeSize(runtime)), usedBy)
+		}
+	}
+	for _, requirement := range missing {
+		fmt.Fprintf(out, "Not installed: %s, needed by %s (downloaded at launch)\n", describeRequirement(requirement), requirement.User)
+	}
+	return nil
+}
+
+func installRuntime(kind, version string, out io.Writer) error {
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return err
+	}
+	installed := func(version string) bool {
+		for _, runtime := range runtimes {
+			if runtime.Kind == kind && runtime.Version == version && runtime.Executable != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	var executable string
+	switch kind {
+	case shared.RuntimeJava:
+		if version == "" {
+			version = "jdk-25"
+		}
+		if installed(version) {
+			fmt.Fprintf(out, "java %s is already installed\n", version)
+			return nil
+		}
+		fmt.Fprintf(out, "Downloading Java %s from Temurin...\n", version)
+		if err := shared.DownloadAndInstallJava(version, nil, nil, shared.GetGoos); err != nil {
+			return err
+		}
+		executable, _ = shared.FindLocalJavaForVersion(version, shared.GetGoos)
+	case shared.RuntimeNode:
+		if version == "" || strings.EqualFold(version, "latest") {
+			latest, err := shared.FindLatestNodeRelease("")
+			if err != nil {
+				return err
+			}
+			version = latest
+		}
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		if installed(version) {
+			fmt.Fprintf(out, "node %s is already installed\n", version)
+			return nil
+		}
+		fmt.Fprintf(out, "Downloading Node.js %s...\n", version)
+		if executable, err = shared.DownloadAndInstallNode(version, nil); err != nil {
+			return err
+		}
+	case shared.RuntimeFFmpeg:
+		if version != "" {
+			return fmt.Errorf("FFmpeg is always installed from the latest build; omit the version")
+		}
+		if existing := shared.FindLocalFFmpeg(); existing != "" {
+			fmt.Fprintf(out, "ffmpeg is already installed at %s\n", existing)
+			return nil
+		}
+		fmt.Fprintln(out, "Downloading FFmpeg...")
+		if executable, err = shared.DownloadAndInstallFFmpeg(nil, nil); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Installed %s %s at %s\n", kind, version, executable)
+	return nil
+}
+
+func removeRuntime(kind, version string, force bool, out io.Writer) error {
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return err
+	}
+	var target *shared.InstalledRuntime
+	for i := range runtimes {
+		if runtimes[i].Kind == kind && runtimes[i].Version == version {
+			target = &runtimes[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%s %s is not installed in %s", kind, version, shared.GetRuntimeDir())
+	}
+
+	users, _, err := runtimeUsage(runtimes)
+	if err != nil {
+		return err
+	}
+	if list := users[target.Path]; len(list) > 0 && !force {
+		return fmt.Errorf("%s %s is used by %s; add --yes to remove it anyway", kind, version, strings.Join(list, ", "))
+	}
+	if err := shared.RemoveRuntime(kind, version); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed %s %s (%s)\n", kind, version, target.Path)
+	return nil
+}
+
+func verifyRuntimes(kind string, out io.Writer) error {
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return err
+	}
+	_, missing, err := runtimeUsage(runtimes)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, runtime := range runtimes {
+		if kind != "" && runtime.Kind != kind {
+			continue
+		}
+		version, err := shared.VerifyRuntime(runtime)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAILED  %s %s: %v\n", runtime.Kind, runtime.Version, err)
+			continue
+		}
+		fmt.Fprintf(out, "OK      %s %s: %s\n", runtime.Kind, runtime.Version, version)
+	}
+	for _, requirement := range missing {
+		if kind != "" && requirement.Kind != kind {
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "MISSING %s, needed by %s\n", describeRequirement(requirement), requirement.User)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d runtime problem(s) found", failed)
+	}
+	return nil
+}
+
+// pinRuntime sets TEMURIN_VERSION or NODE_VERSION for one version of a
+// module, or for all of its versions when moduleVersion is empty.
+func pinRuntime(kind, version, module, moduleVersion string, out io.Writer) error {
+	key, ok := runtimeVersionKeys[kind]
+	if !ok {
+		return fmt.Errorf("FFmpeg has a single shared build and cannot be pinned")
+	}
+	if module == "" {
+		module = "owlcms"
+		if kind == shared.RuntimeNode {
+			module = "tracker"
+		}
+	}
+	module = strings.ToLower(module)
+
+	var moduleDir string
+	var saveParent func() error
+	var saveRelease func(string) error
+	switch {
+	case kind == shared.RuntimeJava && module == "owlcms":
+		if major, err := shared.ExtractMajorVersion(version); err != nil || major < 25 {
+			return fmt.Errorf("OWLCMS requires Java 25 or later, got %q", version)
+		}
+		moduleDir = owlcms.GetInstallDir()
+		saveParent = func() error { return owlcms.SaveProperty(key, version) }
+		saveRelease = func(release string) error { return owlcms.SavePropertyForRelease(release, key, version) }
+	case kind == shared.RuntimeJava && module == "firmata":
+		if _, err := shared.ExtractMajorVersion(version); err != nil {
+			return fmt.Errorf("invalid Java version %q: %w", version, err)
+		}
+		moduleDir = firmata.GetInstallDir()
+		saveParent = func() error {
+			if err := firmata.EnsureParentEnvDefaults(); err != nil {
+				return err
+			}
+			return shared.SavePropertyToFile(filepath.Join(moduleDir, "env.properties"), key, version)
+		}
+		saveRelease = func(release string) error { return firmata.SavePropertyForRelease(release, key, version) }
+	case kind == shared.RuntimeNode && module == "tracker":
+		if _, _, _, err := shared.ExtractNodeVersion(version); err != nil {
+			return fmt.Errorf("invalid Node.js version %q: %w", version, err)
+		}
+		moduleDir = tracker.GetInstallDir()
+		saveParent = func() error { return tracker.SaveProperty(key, version) }
+		saveRelease = func(release string) error { return tracker.SavePropertyForRelease(release, key, version) }
+	default:
+		return fmt.Errorf("%s is not used by %s", kind, module)
+	}
+
+	var releases []string
+	if moduleVersion != "" {
+		if info, err := os.Stat(filepath.Join(moduleDir, moduleVersion)); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s version %q is not installed", module, moduleVersion)
+		}
+		releases = []string{moduleVersion}
+	} else {
+		if err := saveParent(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Set %s=%s in %s\n", key, version, filepath.Join(moduleDir, "env.properties"))
+		// Versions with their own value would otherwise keep it.
+		for _, release := range installedVersionDirectories(moduleDir) {
+			props, err := shared.MergeEnvironmentProperties("", filepath.Join(moduleDir, release, "env.properties"))
+			if err != nil {
+				return err
+			}
+			if _, ok := props.Get(key); ok {
+				releases = append(releases, release)
+			}
+		}
+	}
+	sort.Strings(releases)
+	for _, release := range releases {
+		if err := saveRelease(release); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Set %s=%s for %s %s\n", key, version, module, release)
+	}
+
+	runtimes, err := shared.ListInstalledRuntimes()
+	if err != nil {
+		return err
+	}
+	if resolveRuntimeRequirement(runtimes, runtimeRequirement{Kind: kind, Required: version}) == nil {
+		fmt.Fprintf(out, "%s %s is not installed yet; it is downloaded at the next launch, or now with --runtimes install %s %s\n", kind, version, kind, version)
+	}
+	return nil
+}
+
+// runRuntimePolicyCommand shows or sets the runtime policy of the instance:
+//
+//	policy
+//	policy <bundled|prefer-system|system|path> [java <executable>] [node <executable>]
+func runRuntimePolicyCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return printRuntimePolicy(out)
+	}
+	mode, err := shared.ParseRuntimePolicyMode(args[0])
+	if err != nil {
+		return err
+	}
+	if len(args)%2 != 1 {
+		return fmt.Errorf("--runtimes policy expects a mode followed by java|node <executable> pairs")
+	}
+
+	values := map[string]string{shared.RuntimePolicyKey: mode}
+	for i := 1; i < len(args); i += 2 {
+		executable, err := filepath.Abs(args[i+1])
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(executable); err != nil || info.IsDir() {
+			return fmt.Errorf("%s is not an executable file", args[i+1])
+		}
+		switch strings.ToLower(args[i]) {
+		case shared.RuntimeJava:
+			values[shared.RuntimeJavaPathKey] = executable
+		case shared.RuntimeNode:
+			values[shared.RuntimeNodePathKey] = executable
+		default:
+			return fmt.Errorf("--runtimes policy sets java or node executables, not %q", args[i])
+		}
+	}
+
+	dir := shared.GetControlPanelInstallDir()
+	if err := shared.EnsureDir0755(dir); err != nil {
+		return err
+	}
+	props, err := loadControlPanelEnv(dir)
+	if err != nil {
+		return err
+	}
+	if props == nil {
+		props = properties.NewProperties()
+	}
+	for key, value := range values {
+		props.Set(key, value)
+	}
+	if err := writeFileAtomically(controlPanelEnvPath(dir), []byte(props.String()), 0644); err != nil {
+		return err
+	}
+	return printRuntimePolicy(out)
+}
+
+// printRuntimePolicy shows the runtime policy and the Java and Node.js that
+// launches would use under it.
+func printRuntimePolicy(out io.Writer) error {
+	policy, err := shared.LoadRuntimePolicy()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s=%s (%s)\n", shared.RuntimePolicyKey, policy.Mode, controlPanelEnvPath(shared.GetControlPanelInstallDir()))
+	if policy.JavaPath != "" {
+		fmt.Fprintf(out, "%s=%s\n", shared.RuntimeJavaPathKey, policy.JavaPath)
+	}
+	if policy.NodePath != "" {
+		fmt.Fprintf(out, "%s=%s\n", shared.RuntimeNodePathKey, policy.NodePath)
+	}
+	if !policy.AllowsDownloads() {
+		fmt.Fprintln(out, "Missing runtimes are not downloaded")
+	}
+
+	// OWLCMS and firmata require Java 25; the Tracker's NODE_VERSION is optional.
+	javaVersion := "jdk-25"
+	nodeVersion := ""
+	if props, err := shared.MergeEnvironmentProperties(filepath.Join(tracker.GetInstallDir(), "env.properties"), ""); err == nil {
+		nodeVersion = strings.TrimSpace(props.GetString(runtimeVersionKeys[shared.RuntimeNode], ""))
+	}
+	if javaPath, err := shared.FindJavaForVersion(javaVersion, shared.GetGoos); err != nil {
+		fmt.Fprintf(out, "Java for %s: %v\n", javaVersion, err)
+	} else {
+		fmt.Fprintf(out, "Java for %s: %s\n", javaVersion, javaPath)
+	}
+	nodeLabel := strings.TrimSpace("Node.js " + nodeVersion)
+	if nodePath, err := shared.FindNodeForVersion(nodeVersion, shared.GetGoos); err != nil {
+		fmt.Fprintf(out, "%s: %v\n", nodeLabel, err)
+	} else {
+		fmt.Fprintf(out, "%s: %s\n", nodeLabel, nodePath)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseRuntimesCommand(t *testing.T) {
+	opts := parseCLIOptions([]string{"--runtimes"})
+	if len(opts.runtimeCmd) != 1 || opts.runtimeCmd[0] != "list" {
+		t.Fatalf("expected --runtimes to default to list, got %v", opts.runtimeCmd)
+	}
+
+	opts = parseCLIOptions([]string{"--instance", "records", "--runtimes", "pin", "java", "jdk-25.0.1+8", "owlcms", "64.0.1", "--yes"})
+	if strings.Join(opts.runtimeCmd, " ") != "pin java jdk-25.0.1+8 owlcms 64.0.1" {
+		t.Fatalf("unexpected runtime command %v", opts.runtimeCmd)
+	}
+	if opts.instanceArg != "records" || !opts.yes {
+		t.Fatalf("expected instance and --yes to be kept, got %+v", opts)
+	}
+}
+
+func TestRuntimesCommandRejectsInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{"upgrade"},
+		{"install", "python"},
+		{"remove", "node"},
+		{"clean", "ffmpeg"},
+		{"pin", "ffmpeg", "latest"},
+		{"pin", "java", "jdk-21", "owlcms"},
+		{"pin", "node", "v22.11.0", "owlcms"},
+		{"policy", "managed"},
+		{"policy", "path", "java"},
+	} {
+		if err := runRuntimesCommand(cliOptions{runtimeCmd: args}, io.Discard); err == nil {
+			t.Fatalf("expected --runtimes %v to be rejected", args)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"controlpanel/cameras"
+	"controlpanel/firmata"
+	"controlpanel/owlcms"
+	"controlpanel/replays"
+	"controlpanel/shared"
+	"controlpanel/tracker"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// runSecretCommand executes --secret list|set|unset. A set without a value
+// reads it from the first line of in, so that it stays out of the shell
+// history.
+func runSecretCommand(opts cliOptions, in io.Reader, out io.Writer) error {
+	action := "list"
+	var args []string
+	if len(opts.secretCmd) > 0 {
+		action = strings.ToLower(opts.secretCmd[0])
+		args = opts.secretCmd[1:]
+	}
+	store, err := shared.LoadSecretStore()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		if len(args) > 0 {
+			return fmt.Errorf("--secret list takes no arguments")
+		}
+		return printSecrets(store, out)
+	case "set":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("--secret set expects <name> [value]")
+		}
+		value := optionalArg(args, 1)
+		if value == "" {
+			line, err := bufio.NewReader(in).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+		if err := store.Set(args[0], value); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Stored secret %q; refer to it as %s%s in env.properties\n", args[0], shared.SecretRefPrefix, args[0])
+		return nil
+	case "unset":
+		if len(args) != 1 {
+			return fmt.Errorf("--secret unset expects <name>")
+		}
+		if err := store.Unset(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed secret %q\n", args[0])
+		if users := secretReferences()[args[0]]; len(users) > 0 {
+			fmt.Fprintf(out, "Launches of %s will fail until it is set again\n", strings.Join(users, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown --secret action %q; use list, set or unset", action)
+	}
+}
+
+// printSecrets lists the secret names, never their values, with the
+// env.properties settings that refer to them.
+func printSecrets(store *shared.SecretStore, out io.Writer) error {
+	fmt.Fprintf(out, "Secrets store: %s (%s)\n", shared.SecretsPath(), store.Backend())
+	references := secretReferences()
+	names := store.Names()
+	for name := range references {
+		if !store.Has(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(out, "No secrets stored")
+		return nil
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "%-24s %s\n", "NAME", "USED BY")
+	for _, name := range names {
+		usedBy := "unused"
+		if users := references[name]; len(users) > 0 {
+			usedBy = strings.Join(users, ", ")
+		}
+		if !store.Has(name) {
+			usedBy += " (not set)"
+		}
+		fmt.Fprintf(out, "%-24s %s\n", name, usedBy)
+	}
+	return nil
+}
+
+// secretReferences maps each secret name referred to in the env.properties
+// of the modules to the settings that refer to it, for example
+// "owlcms 65.0.0 OWLCMS_DBPASSWORD".
+func secretReferences() map[string][]string {
+	references := map[string][]string{}
+	for _, module := range []struct {
+		name string
+		dir  string
+	}{
+		{"owlcms", owlcms.GetInstallDir()},
+		{"tracker", tracker.GetInstallDir()},
+		{"firmata", firmata.GetInstallDir()},
+		{"cameras", cameras.GetInstallDir()},
+		{"replays", replays.GetInstallDir()},
+	} {
+		layers := []struct{ label, path string }{{module.name, filepath.Join(module.dir, "env.properties")}}
+		for _, version := range installedVersionDirectories(module.dir) {
+			layers = append(layers, struct{ label, path string }{module.name + " " + version, filepath.Join(module.dir, version, "env.properties")})
+		}
+		for _, layer := range layers {
+			props, err := shared.MergeEnvironmentProperties(layer.path, "")
+			if err != nil {
+				continue
+			}
+			for _, key := range props.Keys() {
+				value, _ := props.Get(key)
+				if name, ok := shared.ParseSecretRef(value); ok {
+					references[name] = append(references[name], layer.label+" "+key)
+				}
+			}
+		}
+	}
+	return references
+}
+
+// showSecretsDialog lists the secrets of the instance and sets or removes
+// them. Values are never shown.
+func showSecretsDialog(w fyne.Window) {
+	store, err := shared.LoadSecretStore()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	references := secretReferences()
+
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.RemoveAll()
+		names := store.Names()
+		if len(names) == 0 {
+			list.Add(widget.NewLabel("No secrets stored."))
+		}
+		for _, name := range names {
+			usedBy := "unused"
+			if users := references[name]; len(users) > 0 {
+				usedBy = strings.Join(users, ", ")
+			}
+			removeButton := widget.NewButton("Remove", func() {
+				dialog.ShowConfirm("Remove Secret", fmt.Sprintf("Remove secret %q?", name), func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := store.Unset(name); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					refresh()
+				}, w)
+			})
+			label := widget.NewLabel(fmt.Sprintf("%s  (%s)", name, usedBy))
+			label.Truncation = fyne.TextTruncateEllipsis
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(
+				widget.NewButton("Change", func() { showSetSecretDialog(w, store, name, refresh) }),
+				removeButton,
+			), label))
+		}
+		list.Refresh()
+	}
+	refresh()
+
+	header := widget.NewLabel(fmt.Sprintf("Secrets are kept in %s (%s) and are not included in instance exports. "+
+		"Refer to a secret in env.properties as %s<name>; the value is only passed to the module when it is launched.",
+		shared.SecretsPath(), store.Backend(), shared.SecretRefPrefix))
+	header.Wrapping = fyne.TextWrapWord
+	addButton := widget.NewButton("Add Secret", func() { showSetSecretDialog(w, store, "", refresh) })
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(560, 240))
+
+	d := dialog.NewCustom("Secrets", "Close", container.NewBorder(header, container.NewHBox(addButton), nil, nil, scroll), w)
+	d.Resize(fyne.NewSize(640, 440))
+	d.Show()
+}
+
+// showSetSecretDialog asks for the value of a new secret, or of name.
+func showSetSecretDialog(w fyne.Window, store *shared.SecretStore, name string, onSaved func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(name)
+	nameEntry.SetPlaceHolder("db-password")
+	if name != "" {
+		nameEntry.Disable()
+	}
+	valueEntry := widget.NewPasswordEntry()
+
+	dialog.ShowForm("Set Secret", "Save", "Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Value", valueEntry),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := store.Set(strings.TrimSpace(nameEntry.Text), valueEntry.Text); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			onSaved()
+		},
+		w,
+	)
+}
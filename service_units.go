@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"controlpanel/shared"
+)
+
+// serviceModules are the modules that get a systemd unit, in start order:
+// OWLCMS is ordered after the tracker it sends results to.
+var serviceModules = []string{"tracker", "owlcms"}
+
+// serviceScope selects user units (systemctl --user) or system units.
+type serviceScope struct {
+	System bool
+}
+
+// runSystemctl runs systemctl and returns its combined output. Tests replace it.
+var runSystemctl = func(args ...string) (string, error) {
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// defaultServiceScope installs system units when running as root and user
+// units otherwise.
+func defaultServiceScope(userFlag, systemFlag bool) (serviceScope, error) {
+	if userFlag && systemFlag {
+		return serviceScope{}, fmt.Errorf("--user and --system cannot be combined")
+	}
+	if systemFlag {
+		return serviceScope{System: true}, nil
+	}
+	if userFlag {
+		return serviceScope{}, nil
+	}
+	return serviceScope{System: os.Geteuid() == 0}, nil
+}
+
+func (s serviceScope) systemctl(args ...string) (string, error) {
+	if !s.System {
+		args = append([]string{"--user"}, args...)
+	}
+	return runSystemctl(args...)
+}
+
+// unitDir is where the unit files of the scope are installed.
+func (s serviceScope) unitDir() string {
+	if s.System {
+		return "/etc/systemd/system"
+	}
+	configDir := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configDir, "systemd", "user")
+}
+
+func (s serviceScope) label() string {
+	if s.System {
+		return "system"
+	}
+	return "user"
+}
+
+// serviceUnitName names the unit of module for instance, for example
+// controlpanel-records-owlcms.service.
+func serviceUnitName(instance, module string) string {
+	return fmt.Sprintf("controlpanel-%s-%s.service", instance, module)
+}
+
+// renderServiceUnit returns the unit file running module of instance in the
+// foreground, so that the control panel supervises it and systemd restarts
+// the control panel if it fails.
+func renderServiceUnit(scope serviceScope, executable, instance, module, username string) string {
+	var b strings.Builder
+	description := "OWLCMS"
+	if module == "tracker" {
+		description = "OWLCMS Tracker"
+	}
+
+	fmt.Fprintf(&b, "# Generated by controlpanel --install-service; changes are lost when it runs again.\n")
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s (control panel instance %s)\n", description, instance)
+	if scope.System {
+		fmt.Fprintf(&b, "Wants=network-online.target\n")
+		fmt.Fprintf(&b, "After=network-online.target\n")
+	}
+	if module == "owlcms" {
+		tracker := serviceUnitName(instance, "tracker")
+		fmt.Fprintf(&b, "Wants=%s\n", tracker)
+		fmt.Fprintf(&b, "After=%s\n", tracker)
+	}
+	fmt.Fprintf(&b, "\n[Service]\n")
+	// The control panel reports readiness once the module answers on its port,
+	// and pings the watchdog only while it keeps answering HTTP requests.
+	fmt.Fprintf(&b, "Type=notify\n")
+	fmt.Fprintf(&b, "TimeoutStartSec=180\n")
+	fmt.Fprintf(&b, "WatchdogSec=90\n")
+	if scope.System && username != "" {
+		fmt.Fprintf(&b, "User=%s\n", username)
+	}
+	fmt.Fprintf(&b, "ExecStart=%s --instance %s --module %s --launch\n", systemdQuote(executable), instance, module)
+	fmt.Fprintf(&b, "Restart=on-failure\n")
+	fmt.Fprintf(&b, "RestartSec=5\n")
+	// Java exits with 143 after running its shutdown hooks on SIGTERM.
+	fmt.Fprintf(&b, "SuccessExitStatus=143 SIGTERM\n")
+	fmt.Fprintf(&b, "TimeoutStopSec=60\n")
+	fmt.Fprintf(&b, "\n[Install]\n")
+	if scope.System {
+		fmt.Fprintf(&b, "WantedBy=multi-user.target\n")
+	} else {
+		fmt.Fprintf(&b, "WantedBy=default.target\n")
+	}
+	return b.String()
+}
+
+// systemdQuote quotes a path for ExecStart when it contains spaces.
+func systemdQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\"\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func requireSystemd() error {
+	if shared.GetGoos() != "linux" {
+		return fmt.Errorf("services are only supported on Linux with systemd")
+	}
+	return nil
+}
+
+// installServices writes and enables the units of the selected instance.
+func installServices(scope serviceScope, out io.Writer) error {
+	if err := requireSystemd(); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate control panel executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	username := ""
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	if scope.System && username == "root" {
+		// The instance directories belong to the user whose HOME they are in.
+		if sudoUser := strings.TrimSpace(os.Getenv("SUDO_USER")); sudoUser != "" {
+			username = sudoUser
+		}
+	}
+
+	instance := shared.CurrentInstanceName()
+	dir := scope.unitDir()
+	if err := shared.EnsureDir0755(dir); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	var units []string
+	for _, module := range serviceModules {
+		unit := serviceUnitName(instance, module)
+		path := filepath.Join(dir, unit)
+		if err := writeFileAtomically(path, []byte(renderServiceUnit(scope, executable, instance, module, username)), 0644); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		fmt.Fprintf(out, "Wrote %s\n", path)
+		units = append(units, unit)
+	}
+
+	if output, err := scope.systemctl("daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload: %v: %s", err, output)
+	}
+	if output, err := scope.systemctl(append([]string{"enable"}, units...)...); err != nil {
+		return fmt.Errorf("systemctl enable: %v: %s", err, output)
+	}
+
+	prefix := "systemctl"
+	if !scope.System {
+		prefix = "systemctl --user"
+	}
+	fmt.Fprintf(out, "Enabled %s services for instance %q. Start them with:\n", scope.label(), instance)
+	fmt.Fprintf(out, "    %s start %s\n", prefix, serviceUnitName(instance, "owlcms"))
+	if !scope.System {
+		fmt.Fprintf(out, "User services only start at boot when lingering is on: loginctl enable-linger %s\n", username)
+	}
+	return nil
+}
+
+// uninstallServices stops, disables and removes the units of the selected instance.
+func uninstallServices(scope serviceScope, out io.Writer) error {
+	if err := requireSystemd(); err != nil {
+		return err
+	}
+	instance := shared.CurrentInstanceName()
+	dir := scope.unitDir()
+
+	var units []string
+	for _, module := range serviceModules {
+		unit := serviceUnitName(instance, module)
+		if _, err := os.Stat(filepath.Join(dir, unit)); err == nil {
+			units = append(units, unit)
+		}
+	}
+	if len(units) == 0 {
+		return fmt.Errorf("no %s services installed for instance %q in %s", scope.label(), instance, dir)
+	}
+
+	if output, err := scope.systemctl(append([]string{"disable", "--now"}, units...)...); err != nil {
+		return fmt.Errorf("systemctl disable: %v: %s", err, output)
+	}
+	for _, unit := range units {
+		path := filepath.Join(dir, unit)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		fmt.Fprintf(out, "Removed %s\n", path)
+	}
+	if output, err := scope.systemctl("daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload: %v: %s", err, output)
+	}
+	return nil
+}
+
+// printServiceStatus shows whether the units of the selected instance are
+// installed, enabled and active.
+func printServiceStatus(scope serviceScope, out io.Writer) error {
+	if err := requireSystemd(); err != nil {
+		return err
+	}
+	instance := shared.CurrentInstanceName()
+	dir := scope.unitDir()
+
+	fmt.Fprintf(out, "%-40s %-10s %-10s %s\n", "UNIT", "INSTALLED", "ENABLED", "ACTIVE")
+	for _, module := range serviceModules {
+		unit := serviceUnitName(instance, module)
+		installed := "no"
+		enabled, active := "-", "-"
+		if _, err := os.Stat(filepath.Join(dir, unit)); err == nil {
+			installed = "yes"
+			// is-enabled and is-active exit non-zero for disabled or inactive
+			// units; their output is still the state.
+			enabled, _ = scope.systemctl("is-enabled", unit)
+			active, _ = scope.systemctl("is-active", unit)
+		}
+		fmt.Fprintf(out, "%-40s %-10s %-10s %s\n", unit, installed, enabled, active)
+	}
+	return nil
+}
+
+// runServiceCommand executes --install-service, --uninstall-service or --service-status.
+func runServiceCommand(opts cliOptions, out io.Writer) error {
+	scope, err := defaultServiceScope(opts.userScope, opts.systemScope)
+	if err != nil {
+		return err
+	}
+	switch opts.service {
+	case "install":
+		return installServices(scope, out)
+	case "uninstall":
+		return uninstallServices(scope, out)
+	default:
+		return printServiceStatus(scope, out)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"controlpanel/shared"
+)
+
+func TestRenderServiceUnitOrdersOwlcmsAfterTracker(t *testing.T) {
+	unit := renderServiceUnit(serviceScope{System: true}, "/opt/owlcms/controlpanel", "records", "owlcms", "owlcms")
+	for _, want := range []string{
+		"Wants=controlpanel-records-tracker.service\n",
+		"After=controlpanel-records-tracker.service\n",
+		"User=owlcms\n",
+		"ExecStart=/opt/owlcms/controlpanel --instance records --module owlcms --launch\n",
+		"Type=notify\n",
+		"WatchdogSec=90\n",
+		"Restart=on-failure\n",
+		"WantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("owlcms unit missing %q:\n%s", want, unit)
+		}
+	}
+
+	tracker := renderServiceUnit(serviceScope{}, "/home/me/my apps/controlpanel", "records", "tracker", "me")
+	if strings.Contains(tracker, "controlpanel-records-owlcms.service") || strings.Contains(tracker, "User=") {
+		t.Errorf("tracker user unit has owlcms ordering or a User= line:\n%s", tracker)
+	}
+	if !strings.Contains(tracker, `ExecStart="/home/me/my apps/controlpanel" --instance records --module tracker --launch`) {
+		t.Errorf("tracker unit does not quote the executable:\n%s", tracker)
+	}
+	if !strings.Contains(tracker, "WantedBy=default.target\n") {
+		t.Errorf("user unit not wanted by default.target:\n%s", tracker)
+	}
+}
+
+func TestInstallAndUninstallUserServices(t *testing.T) {
+	if shared.GetGoos() != "linux" {
+		t.Skip("services are only supported on Linux")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CONTROLPANEL_INSTANCE", "records")
+
+	var calls []string
+	previous := runSystemctl
+	runSystemctl = func(args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		return "", nil
+	}
+	defer func() { runSystemctl = previous }()
+
+	scope := serviceScope{}
+	var out bytes.Buffer
+	if err := installServices(scope, &out); err != nil {
+		t.Fatalf("installServices: %v", err)
+	}
+	for _, module := range serviceModules {
+		if _, err := os.Stat(filepath.Join(scope.unitDir(), serviceUnitName("records", module))); err != nil {
+			t.Fatalf("unit for %s not written: %v", module, err)
+		}
+	}
+	wantEnable := "--user enable controlpanel-records-tracker.service controlpanel-records-owlcms.service"
+	if len(calls) != 2 || calls[0] != "--user daemon-reload" || calls[1] != wantEnable {
+		t.Fatalf("systemctl calls = %q", calls)
+	}
+
+	calls = nil
+	if err := uninstallServices(scope, &out); err != nil {
+		t.Fatalf("uninstallServices: %v", err)
+	}
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "--user disable --now") {
+		t.Fatalf("systemctl calls = %q", calls)
+	}
+	entries, _ := os.ReadDir(scope.unitDir())
+	if len(entries) != 0 {
+		t.Fatalf("units left after uninstall: %v", entries)
+	}
+	if err := uninstallServices(scope, &out); err == nil {
+		t.Fatal("uninstall without units succeeded")
+	}
+}
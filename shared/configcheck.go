@@ -0,0 +1,415 @@
+package shared
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/magiconair/properties"
+)
+
+// Severities of a ConfigIssue.
+const (
+	ConfigError   = "error"
+	ConfigWarning = "warning"
+)
+
+// ConfigIssue is a problem found in an env.properties or config.toml file.
+type ConfigIssue struct {
+	File     string
+	Line     int // 0 when the issue is not tied to a line
+	Severity string
+	Message  string
+}
+
+func (i ConfigIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+}
+
+// SortConfigIssues orders issues by file, then line.
+func SortConfigIssues(issues []ConfigIssue) {
+	sort.SliceStable(issues, func(a, b int) bool {
+		if issues[a].File != issues[b].File {
+			return issues[a].File < issues[b].File
+		}
+		return issues[a].Line < issues[b].Line
+	})
+}
+
+// CountConfigErrors returns the number of issues of error severity.
+func CountConfigErrors(issues []ConfigIssue) int {
+	count := 0
+	for _, issue := range issues {
+		if issue.Severity == ConfigError {
+			count++
+		}
+	}
+	return count
+}
+
+// envEntry is one key of an env.properties file and the line it starts on.
+type envEntry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// readEnvEntries reads the entries of an env.properties file in order,
+// including repeated keys. A missing file has no entries.
+func readEnvEntries(path string) ([]envEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []envEntry
+	var logical strings.Builder
+	start := 0
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if logical.Len() == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+				continue
+			}
+			start = lineNumber
+		}
+		logical.WriteString(line)
+		logical.WriteString("\n")
+		// An odd number of trailing backslashes continues the line.
+		if (len(line)-len(strings.TrimRight(line, `\`)))%2 == 1 {
+			continue
+		}
+
+		props, err := properties.LoadString(logical.String())
+		logical.Reset()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, start, err)
+		}
+		for _, key := range props.Keys() {
+			value, _ := props.Get(key)
+			entries = append(entries, envEntry{Key: key, Value: value, Line: start})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// CheckEnvFile checks the known settings of module in an env.properties file
+// against EnvSchema, and reports repeated keys, likely misspellings, secrets
+// in plain text and references to secrets that are not set.
+func CheckEnvFile(module, path string) ([]ConfigIssue, error) {
+	entries, err := readEnvEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []ConfigIssue
+	var secrets *SecretStore
+	firstLine := map[string]int{}
+	for _, entry := range entries {
+		if line, seen := firstLine[entry.Key]; seen {
+			issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigWarning,
+				Message: fmt.Sprintf("%s is already set on line %d; this later value wins", entry.Key, line)})
+		} else {
+			firstLine[entry.Key] = entry.Line
+		}
+
+		if name, isRef := ParseSecretRef(entry.Value); isRef {
+			if secrets == nil {
+				if secrets, err = LoadSecretStore(); err != nil {
+					return nil, err
+				}
+			}
+			if !secrets.Has(name) {
+				issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigError,
+					Message: fmt.Sprintf("%s refers to secret %q, which is not set", entry.Key, name)})
+			}
+			continue
+		}
+		if IsSecretEnvKey(entry.Key) && strings.TrimSpace(entry.Value) != "" {
+			issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigWarning,
+				Message: fmt.Sprintf("%s is stored in plain text; move it to the secrets store with --secret set and %s=%s<name>", entry.Key, entry.Key, SecretRefPrefix)})
+		}
+
+		if module != ControlPanelModule {
+			if _, ok := LookupEnvSetting(ControlPanelModule, entry.Key); ok {
+				issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigWarning,
+					Message: fmt.Sprintf("%s is a control panel setting; it has no effect here and is passed to %s; set it in %s", entry.Key, module, ControlPanelEnvPath())})
+				continue
+			}
+		}
+		if setting, ok := LookupEnvSetting(module, entry.Key); ok {
+			if err := setting.Validate(entry.Value); err != nil {
+				issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigError, Message: err.Error()})
+			}
+			continue
+		}
+		if suggestion := closestEnvKey(module, entry.Key); suggestion != "" {
+			issues = append(issues, ConfigIssue{File: path, Line: entry.Line, Severity: ConfigWarning,
+				Message: fmt.Sprintf("unknown key %s; did you mean %s?", entry.Key, suggestion)})
+		}
+	}
+	return issues, nil
+}
+
+// closestEnvKey returns the known key of module that key is a likely
+// misspelling of, or "" when there is none. Other keys are passed to the
+// module, which may understand them.
+func closestEnvKey(module, key string) string {
+	upper := strings.ToUpper(key)
+	best, bestDistance := "", 3
+	for _, setting := range EnvSettingsForModule(module) {
+		if upper == setting.Key {
+			return setting.Key
+		}
+		if distance := editDistance(upper, setting.Key); distance < bestDistance {
+			best, bestDistance = setting.Key, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// EnvValueLocation is where the effective value of a key is set.
+type EnvValueLocation struct {
+	Value string
+	File  string // empty when the schema default applies
+	Line  int
+}
+
+// LocateEnvValue returns the effective value of key for a module version:
+// the last entry of the release file, then of the parent file, then the
+// schema default.
+func LocateEnvValue(module, key, parentEnvPath, releaseEnvPath string) (EnvValueLocation, error) {
+	for _, path := range []string{releaseEnvPath, parentEnvPath} {
+		if path == "" {
+			continue
+		}
+		entries, err := readEnvEntries(path)
+		if err != nil {
+			return EnvValueLocation{}, err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Key == key {
+				return EnvValueLocation{Value: strings.TrimSpace(entries[i].Value), File: path, Line: entries[i].Line}, nil
+			}
+		}
+	}
+	setting, _ := LookupEnvSetting(module, key)
+	return EnvValueLocation{Value: setting.Default}, nil
+}
+
+// CheckModuleVersionConfig checks the release env.properties of a version of
+// owlcms, tracker or firmata; the shared one is checked once with
+// CheckEnvFile. With checkRuntimes, it also reports a runtime version that is
+// neither installed nor allowed to be downloaded.
+func CheckModuleVersionConfig(module, moduleDir, version string, checkRuntimes bool) ([]ConfigIssue, error) {
+	parentEnvPath := filepath.Join(moduleDir, "env.properties")
+	releaseEnvPath := filepath.Join(moduleDir, version, "env.properties")
+	issues, err := CheckEnvFile(module, releaseEnvPath)
+	if err != nil {
+		return nil, err
+	}
+	if !checkRuntimes {
+		return issues, nil
+	}
+
+	key := "TEMURIN_VERSION"
+	if module == "tracker" {
+		key = "NODE_VERSION"
+	}
+	location, err := LocateEnvValue(module, key, parentEnvPath, releaseEnvPath)
+	if err != nil {
+		return nil, err
+	}
+	setting, _ := LookupEnvSetting(module, key)
+	if setting.Validate(location.Value) != nil {
+		// Already reported by CheckEnvFile for the file it is in.
+		return issues, nil
+	}
+
+	var findErr error
+	if module == "tracker" {
+		_, findErr = FindNodeForVersion(location.Value, GetGoos)
+	} else {
+		_, findErr = FindJavaForVersion(location.Value, GetGoos)
+	}
+	if findErr != nil {
+		if err := CheckRuntimeDownloadAllowed(findErr); err != nil {
+			file := location.File
+			if file == "" {
+				file = releaseEnvPath
+			}
+			issues = append(issues, ConfigIssue{File: file, Line: location.Line, Severity: ConfigError,
+				Message: fmt.Sprintf("%s %s cannot run: %v", module, version, err)})
+		}
+	}
+	return issues, nil
+}
+
+// CheckVideoConfigTOML checks that a cameras or replays config.toml is well
+// formed and that a port setting is a valid port. The TOML decoder stops at
+// the first syntax error, so at most one is reported.
+func CheckVideoConfigTOML(path string) ([]ConfigIssue, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string]any
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		var parseErr toml.ParseError
+		if !errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return []ConfigIssue{{File: path, Line: parseErr.Position.Line, Severity: ConfigError, Message: parseErr.Message}}, nil
+	}
+
+	var issues []ConfigIssue
+	if value, ok := config["port"]; ok {
+		var port int64
+		switch value := value.(type) {
+		case int64:
+			port = value
+		case string:
+			port, _ = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		}
+		if port < 1 || port > 65535 {
+			issues = append(issues, ConfigIssue{File: path, Line: topLevelTOMLKeyLine(string(content), "port"), Severity: ConfigError,
+				Message: fmt.Sprintf("port must be between 1 and 65535, not %v", value)})
+		}
+	}
+	return issues, nil
+}
+
+// topLevelTOMLKeyLine returns the line that sets key before the first table
+// header, or 0. The decoder does not report where a key is set.
+func topLevelTOMLKeyLine(content, key string) int {
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			return 0
+		}
+		name, _, found := strings.Cut(line, "=")
+		if found && strings.Trim(strings.TrimSpace(name), `"'`) == key {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ModulePort is the port a module version is configured to use.
+type ModulePort struct {
+	Module  string
+	Version string
+	Port    string
+	File    string
+	Line    int
+}
+
+// CheckPortConflicts reports versions of different modules configured on the
+// same port, since they are meant to run together, and ports that the port
+// registry gives to another instance.
+func CheckPortConflicts(ports []ModulePort, registry *PortRegistry, instance string) []ConfigIssue {
+	var issues []ConfigIssue
+	reported := map[string]bool{}
+	for i, port := range ports {
+		for _, other := range ports[:i] {
+			if other.Port != port.Port || other.Module == port.Module {
+				continue
+			}
+			pair := port.Module + " " + other.Module + " " + port.Port
+			if reported[pair] {
+				continue
+			}
+			reported[pair] = true
+			issues = append(issues, ConfigIssue{File: port.File, Line: port.Line, Severity: ConfigError,
+				Message: fmt.Sprintf("%s %s uses port %s, like %s %s", port.Module, port.Version, port.Port, other.Module, other.Version)})
+		}
+		if registry == nil {
+			continue
+		}
+		if ownerInstance, ownerModule := registry.Owner(port.Port, instance, port.Module); ownerInstance != "" && ownerInstance != instance {
+			issues = append(issues, ConfigIssue{File: port.File, Line: port.Line, Severity: ConfigWarning,
+				Message: fmt.Sprintf("%s %s uses port %s, which is allocated to %s", port.Module, port.Version, port.Port, DescribePortOwner(ownerInstance, ownerModule))})
+		}
+	}
+	return issues
+}
+
+// CheckConfigBeforeLaunch checks the configuration files of a module version
+// before it starts: env.properties for owlcms, tracker and firmata, and
+// config.toml for cameras and replays. Warnings are logged; errors stop the
+// launch.
+func CheckConfigBeforeLaunch(module, moduleDir, version string) error {
+	var issues []ConfigIssue
+	var err error
+	switch module {
+	case "cameras", "replays":
+		issues, err = CheckVideoConfigTOML(filepath.Join(moduleDir, version, "config.toml"))
+	default:
+		issues, err = CheckEnvFile(module, filepath.Join(moduleDir, "env.properties"))
+		if err == nil {
+			var release []ConfigIssue
+			release, err = CheckModuleVersionConfig(module, moduleDir, version, false)
+			issues = append(issues, release...)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("checking %s %s configuration: %w", module, version, err)
+	}
+
+	var failures []string
+	for _, issue := range issues {
+		if issue.Severity == ConfigError {
+			failures = append(failures, issue.String())
+		} else {
+			log.Printf("Configuration %s", issue)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s %s has configuration errors:\n%s", module, version, strings.Join(failures, "\n"))
+	}
+	return nil
+}
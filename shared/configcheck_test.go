@@ -0,0 +1,117 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEnvFileReportsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.properties")
+	content := "# OWLCMS settings\n" +
+		"OWLCMS_PORT=80a\n" +
+		"OWLCMS_RESETMODE=true\n" +
+		"JAVA_OPTIONS=-Xmx1024m \\\n" +
+		"    -Dfile.encoding=UTF-8\n" +
+		"OWLCMS_RESETMODE=false\n" +
+		"OWLCMS_PROT=8080\n" +
+		"SOMETHING_ELSE=1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := CheckEnvFile("owlcms", path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, strings.TrimPrefix(issue.String(), path+":"))
+	}
+	want := []string{
+		`2: error: OWLCMS_PORT must be a port between 1 and 65535, not "80a"`,
+		"6: warning: OWLCMS_RESETMODE is already set on line 3; this later value wins",
+		"7: warning: unknown key OWLCMS_PROT; did you mean OWLCMS_PORT?",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+	if CountConfigErrors(issues) != 1 {
+		t.Fatalf("expected one error, got %d", CountConfigErrors(issues))
+	}
+}
+
+func TestCheckVideoConfigTOML(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		line    int
+		message string
+	}{
+		{"port = 70000\n", 1, "port must be between 1 and 65535"},
+		{"# replays\nport = \"80a\"\n", 2, "port must be between 1 and 65535"},
+		{"port = 8091\ntitle = \"Jury replays\n", 2, "strings cannot contain newlines"},
+		{"mode = auto\n", 1, `expected value but found "auto"`},
+		{"[[cameras]]\nname = \"left\"\n[[cameras]]\nname = \"right\"\nargs = [\n  \"-f\", \"v4l2\",\n]\nname = \"again\"\n", 8, "cameras.name"},
+		{"port = 8091\n[[cameras]]\nname = \"left\"\nport = 0\n", 0, ""},
+	} {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		issues, err := CheckVideoConfigTOML(path)
+		if err != nil {
+			t.Fatalf("check %q: %v", test.content, err)
+		}
+		if test.message == "" {
+			if len(issues) != 0 {
+				t.Fatalf("%q: expected no issues, got %+v", test.content, issues)
+			}
+			continue
+		}
+		if len(issues) != 1 || issues[0].Line != test.line || issues[0].Severity != ConfigError || !strings.Contains(issues[0].Message, test.message) {
+			t.Fatalf("%q: expected line %d %q, got %+v", test.content, test.line, test.message, issues)
+		}
+	}
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	registry := &PortRegistry{Instances: map[string]map[string]string{"records": {"tracker": "8096"}}}
+	ports := []ModulePort{
+		{Module: "owlcms", Version: "64.0.0", Port: "8080", File: "owlcms/64.0.0/env.properties", Line: 3},
+		{Module: "owlcms", Version: "65.0.0", Port: "8080", File: "owlcms/65.0.0/env.properties", Line: 3},
+		{Module: "tracker", Version: "3.4.0", Port: "8080", File: "tracker/3.4.0/env.properties", Line: 1},
+		{Module: "tracker", Version: "3.5.0", Port: "8096", File: "tracker/env.properties", Line: 1},
+	}
+
+	issues := CheckPortConflicts(ports, registry, "")
+	if len(issues) != 2 {
+		t.Fatalf("expected two issues, got %+v", issues)
+	}
+	if issues[0].Severity != ConfigError || !strings.Contains(issues[0].Message, "tracker 3.4.0 uses port 8080, like owlcms 64.0.0") {
+		t.Fatalf("unexpected conflict %+v", issues[0])
+	}
+	if issues[1].Severity != ConfigWarning || !strings.Contains(issues[1].Message, `instance "records"`) {
+		t.Fatalf("unexpected registry issue %+v", issues[1])
+	}
+}
+
+func TestCheckEnvFileReportsControlPanelSettings(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "env.properties")
+	if err := os.WriteFile(path, []byte("CONTROLPANEL_AUTO_PORT=true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := CheckEnvFile("owlcms", path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Severity != ConfigWarning || !strings.Contains(issues[0].Message, ControlPanelEnvPath()) {
+		t.Fatalf("expected a misplaced setting warning, got %+v", issues)
+	}
+
+	if issues, err := CheckEnvFile(ControlPanelModule, path); err != nil || len(issues) != 0 {
+		t.Fatalf("expected no issues in the control panel env, got %+v %v", issues, err)
+	}
+}
@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/magiconair/properties"
+)
+
+// ControlPanelModule names the settings of the control panel itself in
+// EnvSchema. They are kept in the env.properties of the control panel
+// directory of the instance, which, unlike the env.properties of a module,
+// is never passed to a module process.
+const ControlPanelModule = "controlpanel"
+
+// ControlPanelEnvPath returns the env.properties of the control panel
+// directory of the current instance.
+func ControlPanelEnvPath() string {
+	return filepath.Join(GetControlPanelInstallDir(), "env.properties")
+}
+
+// ControlPanelSetting returns a control panel setting, read from the process
+// environment first and then from ControlPanelEnvPath.
+func ControlPanelSetting(key string) string {
+	return ControlPanelSettings(key)[key]
+}
+
+// ControlPanelSettings is ControlPanelSetting for several keys, reading the
+// file once.
+func ControlPanelSettings(keys ...string) map[string]string {
+	props, err := MergeEnvironmentProperties(ControlPanelEnvPath(), "")
+	if err != nil {
+		log.Printf("Reading control panel settings: %v", err)
+		props = properties.NewProperties()
+	}
+	settings := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			settings[key] = value
+		} else {
+			settings[key] = strings.TrimSpace(props.GetString(key, ""))
+		}
+	}
+	return settings
+}
+
+// SaveControlPanelSettings stores settings in ControlPanelEnvPath. An empty
+// value removes the key.
+func SaveControlPanelSettings(settings map[string]string) error {
+	path := ControlPanelEnvPath()
+	props := properties.NewProperties()
+	if err := overlayPropertiesFromFile(props, path); err != nil {
+		return err
+	}
+	for key, value := range settings {
+		if value == "" {
+			props.Delete(key)
+		} else {
+			props.Set(key, value)
+		}
+	}
+
+	if err := EnsureDir0755(filepath.Dir(path)); err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(props.String()), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
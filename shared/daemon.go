@@ -13,6 +13,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gofrs/flock"
 	psnet "github.com/shirou/gopsutil/net"
 )
 
@@ -25,6 +26,11 @@ type RuntimeMetadata struct {
 	Daemon            bool   `json:"daemon"`
 	ProcessStartTicks uint64 `json:"processStartTicks"`
 	StartedAt         string `json:"startedAt"`
+	ReadyAt           string `json:"readyAt,omitempty"`
+	SupervisorPID     int    `json:"supervisorPid,omitempty"`
+	// Instance is the control panel instance that started the module, which
+	// tells instances apart for the modules they share a directory for.
+	Instance string `json:"instance,omitempty"`
 }
 
 const RunAsDaemonEnv = "CONTROLPANEL_RUN_AS_DAEMON"
@@ -82,25 +88,88 @@ func WriteRuntimeMetadata(filePath string, pid int, version, port string, daemon
 		Port:              strings.TrimSpace(port),
 		Daemon:            daemon,
 		ProcessStartTicks: startTicks,
-		StartedAt:         time.Now().UTC().Format(time.RFC3339),
+		StartedAt:         time.Now().UTC().Format(time.RFC3339Nano),
+		Instance:          CurrentInstanceName(),
 	}
 
+	lock := flock.New(filePath + ".lock")
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("lock runtime metadata: %w", err)
+	}
+	defer lock.Unlock()
+	if err := saveRuntimeMetadata(filePath, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// MarkRuntimeReady records when the module recorded in filePath started
+// answering on its port, so that readiness latency can be reported.
+func MarkRuntimeReady(filePath string) {
+	err := updateRuntimeMetadata(filePath, func(metadata *RuntimeMetadata) {
+		metadata.ReadyAt = time.Now().UTC().Format(time.RFC3339Nano)
+	})
+	if err != nil {
+		log.Printf("Failed to mark readiness in %s: %v", filePath, err)
+	}
+}
+
+// MarkRuntimeSupervised records that this process waits on the module recorded
+// in filePath and reports its exit, so that other processes stopping it do not
+// report the exit a second time.
+func MarkRuntimeSupervised(filePath string) {
+	err := updateRuntimeMetadata(filePath, func(metadata *RuntimeMetadata) {
+		metadata.SupervisorPID = os.Getpid()
+	})
+	if err != nil {
+		log.Printf("Failed to mark supervision in %s: %v", filePath, err)
+	}
+}
+
+// updateRuntimeMetadata applies update to the metadata in filePath under a
+// file lock, so that the launcher marking readiness and the supervisor
+// marking supervision do not lose each other's field.
+func updateRuntimeMetadata(filePath string, update func(*RuntimeMetadata)) error {
+	lock := flock.New(filePath + ".lock")
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("lock runtime metadata: %w", err)
+	}
+	defer lock.Unlock()
+
+	metadata, err := LoadRuntimeMetadata(filePath)
+	if err != nil {
+		return err
+	}
+	update(metadata)
+	return saveRuntimeMetadata(filePath, metadata)
+}
+
+// IsRuntimeSupervised reports whether a live process waits on the module
+// recorded in filePath.
+func IsRuntimeSupervised(filePath string) bool {
+	metadata, err := LoadRuntimeMetadata(filePath)
+	if err != nil {
+		return false
+	}
+	return metadata.SupervisorPID > 0 && IsProcessRunning(metadata.SupervisorPID)
+}
+
+func saveRuntimeMetadata(filePath string, metadata *RuntimeMetadata) error {
 	content, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("marshal runtime metadata: %w", err)
+		return fmt.Errorf("marshal runtime metadata: %w", err)
 	}
 
 	tempPath := filePath + ".tmp"
 	if err := os.WriteFile(tempPath, content, 0644); err != nil {
-		return nil, fmt.Errorf("write runtime metadata temp file: %w", err)
+		return fmt.Errorf("write runtime metadata temp file: %w", err)
 	}
 
 	if err := os.Rename(tempPath, filePath); err != nil {
 		_ = os.Remove(tempPath)
-		return nil, fmt.Errorf("rename runtime metadata temp file: %w", err)
+		return fmt.Errorf("rename runtime metadata temp file: %w", err)
 	}
-
-	return metadata, nil
+	return nil
 }
 
 // ClearRuntimeMetadata removes the runtime metadata file if present.
@@ -171,6 +240,25 @@ func ReadProcessStartTicks(pid int) (uint64, error) {
 	return startTicks, nil
 }
 
+// ProcessExecutable returns the Linux /proc executable path for a PID.
+// On non-Linux platforms it returns "" with no error so callers can degrade gracefully.
+func ProcessExecutable(pid int) (string, error) {
+	if pid <= 0 {
+		return "", fmt.Errorf("invalid PID %d", pid)
+	}
+
+	if GetGoos() != "linux" {
+		return "", nil
+	}
+
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", fmt.Errorf("read /proc exe for pid %d: %w", pid, err)
+	}
+	// A runtime deleted while running is reported with this suffix.
+	return strings.TrimSuffix(exe, " (deleted)"), nil
+}
+
 // PIDMatchesStartTicks validates that the current process for a PID is the same one
 // that was originally recorded in runtime metadata.
 func PIDMatchesStartTicks(pid int, expected uint64) bool {
@@ -239,6 +327,19 @@ func waitForProcessExit(pid int, timeout time.Duration) bool {
 	return !IsProcessRunning(pid)
 }
 
+// AwaitPortReady polls port until it answers, then calls onReady, or until the
+// process pid exits. Launchers use it once their startup wait is over, so that
+// a slow module is still reported ready.
+func AwaitPortReady(port string, pid int, onReady func()) {
+	for IsProcessRunning(pid) {
+		if CheckPort(port) == nil {
+			onReady()
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
 // SignalStopPID asks a process to exit using only catchable or non-forced
 // mechanisms. It deliberately does not escalate to force-kill because a forced
 // kill prevents the child from choosing its own clean exit code.
@@ -589,12 +690,13 @@ func CheckPort(port string) error {
 // CheckDaemonRunning loads runtime metadata and checks whether the recorded
 // daemon is still alive by validating its PID (with start-ticks on Linux).
 // It does not adopt an arbitrary process listening on the recorded port.
+// The port may be empty for modules such as cameras that do not listen on one.
 func CheckDaemonRunning(metadataPath string) (*RuntimeMetadata, bool) {
 	metadata, err := LoadRuntimeMetadata(metadataPath)
 	if err != nil || metadata == nil {
 		return nil, false
 	}
-	if strings.TrimSpace(metadata.Version) == "" || strings.TrimSpace(metadata.Port) == "" {
+	if strings.TrimSpace(metadata.Version) == "" {
 		return nil, false
 	}
 
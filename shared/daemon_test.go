@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestCheckPortDetectsNonHTTPListener(t *testing.T) {
@@ -23,6 +25,7 @@ func TestCheckPortDetectsNonHTTPListener(t *testing.T) {
 }
 
 func TestCheckDaemonRunningDetectsRecordedProcess(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTANCE", "records")
 	metadataPath := filepath.Join(t.TempDir(), "runtime.json")
 	metadata, err := WriteRuntimeMetadata(metadataPath, os.Getpid(), "test", "8080", true)
 	if err != nil {
@@ -36,6 +39,24 @@ func TestCheckDaemonRunningDetectsRecordedProcess(t *testing.T) {
 	if got == nil || got.PID != metadata.PID {
 		t.Fatalf("CheckDaemonRunning returned metadata %+v, want PID %d", got, metadata.PID)
 	}
+	if got.Instance != "records" {
+		t.Fatalf("CheckDaemonRunning returned instance %q, want records", got.Instance)
+	}
+}
+
+func TestCheckDaemonRunningAcceptsMetadataWithoutPort(t *testing.T) {
+	metadataPath := filepath.Join(t.TempDir(), "runtime.json")
+	if _, err := WriteRuntimeMetadata(metadataPath, os.Getpid(), "test", "", true); err != nil {
+		t.Fatalf("write runtime metadata: %v", err)
+	}
+
+	got, running := CheckDaemonRunning(metadataPath)
+	if !running || got == nil {
+		t.Fatal("CheckDaemonRunning did not detect a recorded process without a port")
+	}
+	if got.Port != "" {
+		t.Fatalf("CheckDaemonRunning returned port %q, want empty", got.Port)
+	}
 }
 
 func TestCheckDaemonRunningDoesNotAdoptForeignPortOwner(t *testing.T) {
@@ -64,3 +85,64 @@ func TestCheckDaemonRunningDoesNotAdoptForeignPortOwner(t *testing.T) {
 		t.Fatalf("CheckDaemonRunning adopted foreign port owner: metadata=%+v running=%v", got, running)
 	}
 }
+
+func TestAwaitPortReadyReportsALatePort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	ready := make(chan struct{})
+	go AwaitPortReady(port, os.Getpid(), func() { close(ready) })
+
+	time.Sleep(1500 * time.Millisecond)
+	select {
+	case <-ready:
+		t.Fatal("reported ready before the port opened")
+	default:
+	}
+
+	listener, err = net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Skipf("port %s was taken meanwhile: %v", port, err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the port opening was not reported")
+	}
+}
+
+func TestConcurrentRuntimeMarksKeepBothFields(t *testing.T) {
+	metadataPath := filepath.Join(t.TempDir(), "runtime.json")
+	for round := 0; round < 20; round++ {
+		if _, err := WriteRuntimeMetadata(metadataPath, os.Getpid(), "test", "8080", true); err != nil {
+			t.Fatalf("write runtime metadata: %v", err)
+		}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); MarkRuntimeReady(metadataPath) }()
+		go func() { defer wg.Done(); MarkRuntimeSupervised(metadataPath) }()
+		wg.Wait()
+
+		metadata, err := LoadRuntimeMetadata(metadataPath)
+		if err != nil {
+			t.Fatalf("load runtime metadata: %v", err)
+		}
+		if metadata.ReadyAt == "" || metadata.SupervisorPID != os.Getpid() {
+			t.Fatalf("round %d lost a field: %+v", round, metadata)
+		}
+	}
+}
@@ -19,9 +19,14 @@ import (
 type ProgressCallback func(downloaded, total int64)
 
 // DownloadArchive downloads a file and reports progress through the callback. It also accepts a cancel channel.
-func DownloadArchive(url, destPath string, progress ProgressCallback, cancel <-chan bool) error {
+func DownloadArchive(url, destPath string, progress ProgressCallback, cancel <-chan bool) (err error) {
 	log.Printf("Attempting to download from URL: %s\n", url)
 
+	var downloaded int64
+	defer func() {
+		RecordDownload(downloaded, err)
+	}()
+
 	client := &http.Client{}
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -62,7 +67,7 @@ func DownloadArchive(url, destPath string, progress ProgressCallback, cancel <-c
 		Cancel:   cancel, // Pass the cancel channel to the counter
 	}
 
-	_, err = io.Copy(out, io.TeeReader(resp.Body, counter))
+	downloaded, err = io.Copy(out, io.TeeReader(resp.Body, counter))
 	if err != nil {
 		return fmt.Errorf("failed to copy data: %w", err)
 	}
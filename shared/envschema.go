@@ -0,0 +1,214 @@
+package shared
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/magiconair/properties"
+)
+
+// Value types of the env.properties settings in EnvSchema.
+const (
+	EnvTypeString = "string"
+	EnvTypeInt    = "int"
+	EnvTypePort   = "port"
+	EnvTypeBool   = "bool"
+	EnvTypeEnum   = "enum"
+	EnvTypeURL    = "url"
+	EnvTypeJava   = "java" // a TEMURIN_VERSION such as jdk-25
+	EnvTypeNode   = "node" // a NODE_VERSION such as v22.11.0
+)
+
+// EnvSetting describes a known env.properties key.
+type EnvSetting struct {
+	Key         string
+	Modules     []string // owlcms, tracker, firmata or ControlPanelModule
+	Type        string
+	Default     string // what applies when the key is not set; empty when nothing does
+	Choices     []string
+	Description string
+}
+
+// EnvSchema lists the env.properties keys the control panel and the modules
+// understand. Other keys are passed to the module unchanged.
+var EnvSchema = []EnvSetting{
+	{Key: "OWLCMS_PORT", Modules: []string{"owlcms"}, Type: EnvTypePort, Default: "8080",
+		Description: "HTTP port of OWLCMS."},
+	{Key: "TEMURIN_VERSION", Modules: []string{"owlcms", "firmata"}, Type: EnvTypeJava, Default: "jdk-25",
+		Description: "Temurin Java build the module runs on; a later build of the same or a newer major version is used when installed."},
+	{Key: "JAVA_OPTIONS", Modules: []string{"owlcms", "firmata"}, Type: EnvTypeString,
+		Description: "Options for the Java virtual machine, for example -Xmx1024m."},
+	{Key: "OWLCMS_INITIALDATA", Modules: []string{"owlcms"}, Type: EnvTypeString,
+		Description: "Data loaded into a new or reset database, for example LARGEGROUP_DEMO."},
+	{Key: "OWLCMS_RESETMODE", Modules: []string{"owlcms"}, Type: EnvTypeBool, Default: "false",
+		Description: "Recreate the database on every start."},
+	{Key: "OWLCMS_MEMORYMODE", Modules: []string{"owlcms"}, Type: EnvTypeBool, Default: "false",
+		Description: "Keep the database in memory only; nothing is saved."},
+	{Key: "OWLCMS_FEATURESWITCHES", Modules: []string{"owlcms"}, Type: EnvTypeString,
+		Description: "Comma-separated feature switches that override those of the database."},
+	{Key: "OWLCMS_ENABLEEMBEDDEDMQTT", Modules: []string{"owlcms"}, Type: EnvTypeBool,
+		Description: "Start the MQTT broker embedded in OWLCMS for refereeing devices; OWLCMS decides when empty."},
+	{Key: "OWLCMS_VIDEODATA", Modules: []string{"owlcms"}, Type: EnvTypeURL,
+		Description: "WebSocket URL of the Tracker that OWLCMS sends competition data to; empty disables the connection."},
+	{Key: "CONTROLPANEL_TRACKER_URL", Modules: []string{"owlcms"}, Type: EnvTypeURL, Default: "ws://localhost/ws",
+		Description: "Base URL of the Tracker connection offered by the control panel."},
+	{Key: "CONTROLPANEL_TRACKER_PORT", Modules: []string{"owlcms"}, Type: EnvTypePort, Default: "8096",
+		Description: "Tracker port of the connection offered by the control panel."},
+	{Key: "CONTROLPANEL_TRACKER_CONNECTION_ENABLED_BY_DEFAULT", Modules: []string{"owlcms"}, Type: EnvTypeBool,
+		Description: "Connect new OWLCMS versions to the local Tracker."},
+	{Key: RunAsDaemonEnv, Modules: []string{"owlcms", "tracker"}, Type: EnvTypeBool, Default: "false",
+		Description: "On Linux, leave OWLCMS and the Tracker running after the control panel exits."},
+	{Key: AutoPortEnv, Modules: []string{ControlPanelModule}, Type: EnvTypeBool, Default: "false",
+		Description: "Move a module to the next free port when its port is in use."},
+	{Key: "CONTROLPANEL_MQTT_BROKER", Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "host:port of the broker the control panel publishes module status to; disabled when empty."},
+	{Key: "CONTROLPANEL_MQTT_USERNAME", Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "User name for the MQTT status broker."},
+	{Key: "CONTROLPANEL_MQTT_PASSWORD", Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "Password for the MQTT status broker, normally a secret:<name> reference."},
+	{Key: "CONTROLPANEL_MQTT_COMMANDS", Modules: []string{ControlPanelModule}, Type: EnvTypeBool, Default: "false",
+		Description: "Accept launch, stop and restart commands from the MQTT status broker."},
+	{Key: "CONTROLPANEL_METRICS_ADDRESS", Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "host:port serving the Prometheus /metrics endpoint; disabled when empty."},
+	{Key: EventCommandEnv, Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "Command run for each lifecycle event."},
+	{Key: EventWebhookEnv, Modules: []string{ControlPanelModule}, Type: EnvTypeURL,
+		Description: "URL that lifecycle events are posted to."},
+	{Key: EventFileEnv, Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "File that lifecycle events are appended to, one JSON object per line."},
+	{Key: EventFilterEnv, Modules: []string{ControlPanelModule}, Type: EnvTypeString,
+		Description: "Comma-separated events sent to the outputs; empty sends all of them."},
+	{Key: "TRACKER_PORT", Modules: []string{"tracker"}, Type: EnvTypePort, Default: "8096",
+		Description: "HTTP port of the Tracker."},
+	{Key: "NODE_VERSION", Modules: []string{"tracker"}, Type: EnvTypeNode,
+		Description: "Node.js build the Tracker runs on; the release default applies when empty."},
+	{Key: "FIRMATA_PORT", Modules: []string{"firmata"}, Type: EnvTypePort, Default: "8090",
+		Description: "HTTP port of firmata."},
+	{Key: "FIRMATA_MQTTSERVER", Modules: []string{"firmata"}, Type: EnvTypeString,
+		Description: "Host of the MQTT broker used by the refereeing devices, usually the OWLCMS host."},
+	{Key: "FIRMATA_MQTTPORT", Modules: []string{"firmata"}, Type: EnvTypePort, Default: "1883",
+		Description: "Port of the MQTT broker used by the refereeing devices."},
+	{Key: "FIRMATA_KEEP_RUNNING", Modules: []string{"firmata"}, Type: EnvTypeBool, Default: "false",
+		Description: "Leave firmata running when the control panel closes."},
+}
+
+// EnvSettingsForModule returns the settings of EnvSchema used by module.
+func EnvSettingsForModule(module string) []EnvSetting {
+	var settings []EnvSetting
+	for _, setting := range EnvSchema {
+		if containsString(setting.Modules, module) {
+			settings = append(settings, setting)
+		}
+	}
+	return settings
+}
+
+// LookupEnvSetting returns the setting of module named key.
+func LookupEnvSetting(module, key string) (EnvSetting, bool) {
+	for _, setting := range EnvSettingsForModule(module) {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return EnvSetting{}, false
+}
+
+// Validate checks value against the type of the setting. An empty value
+// leaves the default in place and is always valid.
+func (s EnvSetting) Validate(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	switch s.Type {
+	case EnvTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be a whole number, not %q", s.Key, value)
+		}
+	case EnvTypePort:
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("%s must be a port between 1 and 65535, not %q", s.Key, value)
+		}
+	case EnvTypeBool:
+		if _, ok := parseEnvBool(value); !ok {
+			return fmt.Errorf("%s must be true or false, not %q", s.Key, value)
+		}
+	case EnvTypeEnum:
+		for _, choice := range s.Choices {
+			if strings.EqualFold(value, choice) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of %s, not %q", s.Key, strings.Join(s.Choices, ", "), value)
+	case EnvTypeURL:
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%s must be a URL such as ws://host:port/ws, not %q", s.Key, value)
+		}
+	case EnvTypeJava:
+		if !strings.HasPrefix(value, "jdk-") {
+			return fmt.Errorf("%s must be a Temurin version such as jdk-25, not %q", s.Key, value)
+		}
+		if _, err := ExtractMajorVersion(value); err != nil {
+			return fmt.Errorf("%s must be a Temurin version such as jdk-25, not %q", s.Key, value)
+		}
+	case EnvTypeNode:
+		if _, _, _, err := ExtractNodeVersion(value); err != nil {
+			return fmt.Errorf("%s must be a Node.js version such as v22.11.0, not %q", s.Key, value)
+		}
+	}
+	return nil
+}
+
+func parseEnvBool(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true, true
+	case "0", "false", "no", "off":
+		return false, true
+	}
+	return false, false
+}
+
+// Layers an effective env.properties value comes from.
+const (
+	EnvOriginRelease = "release"
+	EnvOriginParent  = "parent"
+	EnvOriginDefault = "default"
+)
+
+// EnvSettingValue is the effective value of a setting for a module version.
+type EnvSettingValue struct {
+	EnvSetting
+	Value  string
+	Origin string
+}
+
+// ResolveEnvSettings returns the value of every setting of module and the
+// layer it comes from: the release env.properties overlays the parent one,
+// which overlays the schema default. releaseEnvPath is empty for the parent
+// file alone.
+func ResolveEnvSettings(module, parentEnvPath, releaseEnvPath string) ([]EnvSettingValue, error) {
+	parent := properties.NewProperties()
+	if err := overlayPropertiesFromFile(parent, parentEnvPath); err != nil {
+		return nil, err
+	}
+	release := properties.NewProperties()
+	if err := overlayPropertiesFromFile(release, releaseEnvPath); err != nil {
+		return nil, err
+	}
+
+	var values []EnvSettingValue
+	for _, setting := range EnvSettingsForModule(module) {
+		value := EnvSettingValue{EnvSetting: setting, Value: setting.Default, Origin: EnvOriginDefault}
+		if v, ok := release.Get(setting.Key); ok {
+			value.Value, value.Origin = v, EnvOriginRelease
+		} else if v, ok := parent.Get(setting.Key); ok {
+			value.Value, value.Origin = v, EnvOriginParent
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
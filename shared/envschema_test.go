@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveEnvSettingsReportsLayer(t *testing.T) {
+	dir := t.TempDir()
+	parent := filepath.Join(dir, "env.properties")
+	release := filepath.Join(dir, "2.1.0", "env.properties")
+	if err := os.MkdirAll(filepath.Dir(release), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(parent, []byte("TRACKER_PORT=8097\nNODE_VERSION=v22.11.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(release, []byte("TRACKER_PORT=8098\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := ResolveEnvSettings("tracker", parent, release)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	got := map[string]EnvSettingValue{}
+	for _, value := range values {
+		got[value.Key] = value
+	}
+	if v := got["TRACKER_PORT"]; v.Value != "8098" || v.Origin != EnvOriginRelease {
+		t.Fatalf("expected release TRACKER_PORT, got %+v", v)
+	}
+	if v := got["NODE_VERSION"]; v.Value != "v22.11.0" || v.Origin != EnvOriginParent {
+		t.Fatalf("expected parent NODE_VERSION, got %+v", v)
+	}
+	if v := got[RunAsDaemonEnv]; v.Value != "false" || v.Origin != EnvOriginDefault {
+		t.Fatalf("expected default daemon setting, got %+v", v)
+	}
+	if _, ok := got["OWLCMS_PORT"]; ok {
+		t.Fatal("expected OWLCMS settings to be left out of the tracker")
+	}
+}
+
+func TestEnvSettingValidate(t *testing.T) {
+	cases := []struct {
+		key, value string
+		valid      bool
+	}{
+		{"OWLCMS_PORT", "8080", true},
+		{"OWLCMS_PORT", "80a", false},
+		{"OWLCMS_PORT", "70000", false},
+		{"OWLCMS_PORT", "", true},
+		{"OWLCMS_RESETMODE", "Yes", true},
+		{"OWLCMS_RESETMODE", "maybe", false},
+		{"TEMURIN_VERSION", "jdk-25.0.1+8", true},
+		{"TEMURIN_VERSION", "25", false},
+		{"OWLCMS_VIDEODATA", "ws://localhost:8096/ws", true},
+		{"OWLCMS_VIDEODATA", "localhost:8096", false},
+	}
+	for _, c := range cases {
+		setting, ok := LookupEnvSetting("owlcms", c.key)
+		if !ok {
+			t.Fatalf("%s is not in the owlcms schema", c.key)
+		}
+		if err := setting.Validate(c.value); (err == nil) != c.valid {
+			t.Fatalf("Validate(%s=%q) = %v, want valid=%v", c.key, c.value, err, c.valid)
+		}
+	}
+}
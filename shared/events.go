@@ -0,0 +1,286 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Module lifecycle events.
+const (
+	EventStarted         = "started"
+	EventReady           = "ready"
+	EventStopped         = "stopped"
+	EventCrashed         = "crashed"
+	EventRestarted       = "restarted"
+	EventInstalled       = "installed"
+	EventUpdateInstalled = "update-installed"
+	EventBackupCreated   = "backup-created"
+)
+
+// AllEvents lists the lifecycle events in the order they are documented.
+var AllEvents = []string{
+	EventStarted,
+	EventReady,
+	EventStopped,
+	EventCrashed,
+	EventRestarted,
+	EventInstalled,
+	EventUpdateInstalled,
+	EventBackupCreated,
+}
+
+// Control panel settings naming the outputs that receive lifecycle events.
+// They are kept with the control panel settings of the instance, so that
+// every module reports to them, and never reach a module process.
+const (
+	EventCommandEnv = "CONTROLPANEL_EVENT_COMMAND"
+	EventWebhookEnv = "CONTROLPANEL_EVENT_WEBHOOK"
+	EventFileEnv    = "CONTROLPANEL_EVENT_FILE"
+	EventFilterEnv  = "CONTROLPANEL_EVENTS"
+)
+
+// EventHookKeys are the settings that configure lifecycle event outputs.
+var EventHookKeys = []string{EventCommandEnv, EventWebhookEnv, EventFileEnv, EventFilterEnv}
+
+const (
+	eventCommandTimeout = 30 * time.Second
+	eventWebhookTimeout = 10 * time.Second
+)
+
+// ModuleEvent is one lifecycle event. It is the JSON body posted to the
+// webhook and the line appended to the events file.
+type ModuleEvent struct {
+	Event    string `json:"event"`
+	Module   string `json:"module"`
+	Version  string `json:"version,omitempty"`
+	Port     string `json:"port,omitempty"`
+	PID      int    `json:"pid,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance"`
+	Time     string `json:"time"`
+}
+
+// EventHooks are the configured outputs for lifecycle events. Empty fields
+// are disabled; an empty Events list selects every event.
+type EventHooks struct {
+	Command    string
+	WebhookURL string
+	File       string
+	Events     []string
+}
+
+var (
+	pendingEvents  sync.WaitGroup
+	eventFileMutex sync.Mutex
+)
+
+// CurrentEventHooks returns the event outputs configured for this instance.
+func CurrentEventHooks() EventHooks {
+	settings := ControlPanelSettings(EventHookKeys...)
+	return EventHooks{
+		Command:    settings[EventCommandEnv],
+		WebhookURL: settings[EventWebhookEnv],
+		File:       settings[EventFileEnv],
+		Events:     ParseEventFilter(settings[EventFilterEnv]),
+	}
+}
+
+// SaveEventHooks stores the event outputs in the control panel settings.
+func SaveEventHooks(hooks EventHooks) error {
+	return SaveControlPanelSettings(map[string]string{
+		EventCommandEnv: strings.TrimSpace(hooks.Command),
+		EventWebhookEnv: strings.TrimSpace(hooks.WebhookURL),
+		EventFileEnv:    strings.TrimSpace(hooks.File),
+		EventFilterEnv:  strings.Join(hooks.Events, ","),
+	})
+}
+
+// ParseEventFilter splits a comma-separated event list, ignoring blanks.
+func ParseEventFilter(value string) []string {
+	var events []string
+	for _, event := range strings.Split(value, ",") {
+		if event = strings.ToLower(strings.TrimSpace(event)); event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Enabled reports whether at least one output is configured.
+func (h EventHooks) Enabled() bool {
+	return h.Command != "" || h.WebhookURL != "" || h.File != ""
+}
+
+// Wants reports whether event passes the configured event filter.
+func (h EventHooks) Wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, wanted := range h.Events {
+		if wanted == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitEvent classifies the end of a module process. Exits requested by the
+// user, clean exits and stop signals are "stopped"; anything else is "crashed".
+func ExitEvent(waitErr error, stoppedByUser bool) string {
+	if stoppedByUser || !ShouldRestartProcess(waitErr) {
+		return EventStopped
+	}
+	return EventCrashed
+}
+
+// EventDetail describes err for the Detail field; nil gives an empty string.
+func EventDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// EmitModuleEvent sends event to the configured outputs in the background.
+// Use FlushEvents before the process exits so that deliveries are not lost.
+func EmitModuleEvent(event ModuleEvent) {
+	hooks := CurrentEventHooks()
+	if !hooks.Enabled() || !hooks.Wants(event.Event) {
+		return
+	}
+	if event.Instance == "" {
+		event.Instance = CurrentInstanceName()
+	}
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	pendingEvents.Add(1)
+	go func() {
+		defer pendingEvents.Done()
+		deliverModuleEvent(hooks, event)
+	}()
+}
+
+// FlushEvents waits up to timeout for events still being delivered and
+// reports whether all of them finished.
+func FlushEvents(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		pendingEvents.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		log.Printf("Gave up waiting for lifecycle events after %s", timeout)
+		return false
+	}
+}
+
+func deliverModuleEvent(hooks EventHooks, event ModuleEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event for %s: %v", event.Event, event.Module, err)
+		return
+	}
+
+	if hooks.File != "" {
+		if err := appendEventLine(hooks.File, body); err != nil {
+			log.Printf("Failed to append %s event for %s to %s: %v", event.Event, event.Module, hooks.File, err)
+		}
+	}
+	if hooks.WebhookURL != "" {
+		if err := postEventWebhook(hooks.WebhookURL, body); err != nil {
+			log.Printf("Failed to post %s event for %s: %v", event.Event, event.Module, err)
+		}
+	}
+	if hooks.Command != "" {
+		if err := runEventCommand(hooks.Command, event, body); err != nil {
+			log.Printf("Event command failed for %s event of %s: %v", event.Event, event.Module, err)
+		}
+	}
+}
+
+func appendEventLine(path string, body []byte) error {
+	eventFileMutex.Lock()
+	defer eventFileMutex.Unlock()
+
+	if err := EnsureDir0755(filepath.Dir(path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(body, '\n'))
+	return err
+}
+
+func postEventWebhook(url string, body []byte) error {
+	client := &http.Client{Timeout: eventWebhookTimeout}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return nil
+}
+
+// EventEnvironment returns the variables describing event that are passed to
+// the event command.
+func EventEnvironment(event ModuleEvent) []string {
+	pid := ""
+	if event.PID > 0 {
+		pid = strconv.Itoa(event.PID)
+	}
+	return []string{
+		"CONTROLPANEL_EVENT=" + event.Event,
+		"CONTROLPANEL_EVENT_MODULE=" + event.Module,
+		"CONTROLPANEL_EVENT_VERSION=" + event.Version,
+		"CONTROLPANEL_EVENT_PORT=" + event.Port,
+		"CONTROLPANEL_EVENT_PID=" + pid,
+		"CONTROLPANEL_EVENT_DETAIL=" + event.Detail,
+		"CONTROLPANEL_EVENT_INSTANCE=" + event.Instance,
+		"CONTROLPANEL_EVENT_TIME=" + event.Time,
+	}
+}
+
+// runEventCommand runs the command through the platform shell with the event
+// in its environment and the JSON event on its standard input.
+func runEventCommand(command string, event ModuleEvent, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), eventCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if GetGoos() == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	ConfigureNoConsoleWindow(cmd)
+	cmd.Env = append(os.Environ(), EventEnvironment(event)...)
+	cmd.Stdin = bytes.NewReader(body)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitModuleEventAppendsToEventsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "events.jsonl")
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	t.Setenv(EventCommandEnv, "")
+	t.Setenv(EventWebhookEnv, "")
+	t.Setenv(EventFileEnv, path)
+	t.Setenv(EventFilterEnv, "crashed, ready")
+	t.Setenv("CONTROLPANEL_INSTANCE", "fop2")
+
+	EmitModuleEvent(ModuleEvent{Event: EventStarted, Module: "owlcms", Version: "64.0.0"})
+	EmitModuleEvent(ModuleEvent{Event: EventCrashed, Module: "owlcms", Version: "64.0.0", PID: 42, Detail: "exit status 1"})
+	if !FlushEvents(5 * time.Second) {
+		t.Fatal("events were not delivered in time")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading events file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the crashed event, got %q", content)
+	}
+	var event ModuleEvent
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("decoding event: %v", err)
+	}
+	if event.Event != EventCrashed || event.Instance != "fop2" || event.PID != 42 || event.Time == "" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestEventHooksAreReadFromControlPanelSettings(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	for _, key := range EventHookKeys {
+		t.Setenv(key, "")
+	}
+
+	saved := EventHooks{File: "/var/log/owlcms/events.jsonl", Events: []string{EventInstalled, EventCrashed}}
+	if err := SaveEventHooks(saved); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	hooks := CurrentEventHooks()
+	if hooks.File != saved.File || strings.Join(hooks.Events, ",") != "installed,crashed" || hooks.Command != "" {
+		t.Fatalf("unexpected hooks %+v", hooks)
+	}
+
+	t.Setenv(EventFileEnv, "/tmp/events.jsonl")
+	if hooks := CurrentEventHooks(); hooks.File != "/tmp/events.jsonl" {
+		t.Fatalf("expected the environment to take precedence, got %+v", hooks)
+	}
+}
+
+func TestExitEventTreatsUserStopsAndCleanExitsAsStopped(t *testing.T) {
+	if got := ExitEvent(nil, false); got != EventStopped {
+		t.Fatalf("clean exit: got %q", got)
+	}
+	if got := ExitEvent(os.ErrClosed, true); got != EventStopped {
+		t.Fatalf("stop by user: got %q", got)
+	}
+}
+
+func TestEventEnvironmentDescribesEvent(t *testing.T) {
+	env := EventEnvironment(ModuleEvent{Event: EventReady, Module: "tracker", Port: "8096", PID: 7})
+	joined := strings.Join(env, "\n")
+	for _, want := range []string{"CONTROLPANEL_EVENT=ready", "CONTROLPANEL_EVENT_MODULE=tracker", "CONTROLPANEL_EVENT_PORT=8096", "CONTROLPANEL_EVENT_PID=7"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing %s in %q", want, joined)
+		}
+	}
+}
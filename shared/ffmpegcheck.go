@@ -159,6 +159,7 @@ func DownloadAndInstallFFmpeg(progressCallback func(downloaded, total int64), ca
 // EnsureFFmpegPrerequisite checks for FFmpeg in the shared directory,
 // downloads it if missing, and falls back to the system PATH only when
 // the download is not possible (e.g. unsupported platform).
+// A nil window skips the progress dialog for command-line use.
 func EnsureFFmpegPrerequisite(w fyne.Window) (string, error) {
 	log.Println("FFmpeg check: looking for bundled FFmpeg in shared directory")
 	ffmpegDir := GetSharedFFmpegDir()
@@ -187,25 +188,31 @@ func EnsureFFmpegPrerequisite(w fyne.Window) (string, error) {
 	log.Printf("FFmpeg check: will download bundled FFmpeg from %s", downloadURL)
 
 	cancel := make(chan bool)
-	progressBar := widget.NewProgressBar()
-	progressDialog := dialog.NewCustom("Installing FFmpeg", "Cancel", progressBar, w)
-	progressDialog.SetOnClosed(func() {
-		select {
-		case cancel <- true:
-		default:
-		}
-	})
-	progressDialog.Show()
-	progressBar.SetValue(0.01)
+	var progressBar *widget.ProgressBar
+	var progressDialog dialog.Dialog
+	if w != nil {
+		progressBar = widget.NewProgressBar()
+		progressDialog = dialog.NewCustom("Installing FFmpeg", "Cancel", progressBar, w)
+		progressDialog.SetOnClosed(func() {
+			select {
+			case cancel <- true:
+			default:
+			}
+		})
+		progressDialog.Show()
+		progressBar.SetValue(0.01)
+	}
 
 	path, err := DownloadAndInstallFFmpeg(func(downloaded, total int64) {
-		if total > 0 {
+		if total > 0 && progressBar != nil {
 			progressBar.SetValue(float64(downloaded) / float64(total))
 		}
 	}, cancel)
 
-	progressBar.SetValue(1.0)
-	progressDialog.Hide()
+	if progressDialog != nil {
+		progressBar.SetValue(1.0)
+		progressDialog.Hide()
+	}
 
 	if err != nil {
 		// Download failed — try system PATH as last resort.
@@ -0,0 +1,458 @@
+package shared
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+const ffmpegCapabilitiesFile = "ffmpeg-capabilities.json"
+
+// FFmpegCapabilities is what an ffmpeg binary reports it can do. It is cached
+// in VideoConfigDir and probed again when the binary changes.
+type FFmpegCapabilities struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Version  string    `json:"version"`
+	Encoders []string  `json:"encoders"`
+	HWAccels []string  `json:"hwaccels"`
+	Demuxers []string  `json:"demuxers"`
+	Devices  []string  `json:"devices"`
+	ProbedAt time.Time `json:"probedAt"`
+}
+
+// FFmpegCapabilitiesPath returns the probe cache file for configDir.
+func FFmpegCapabilitiesPath(configDir string) string {
+	return filepath.Join(configDir, ffmpegCapabilitiesFile)
+}
+
+// LoadFFmpegCapabilities returns the capabilities of ffmpegPath, from the
+// cache in configDir when the binary has not changed, and probes it otherwise.
+// force always probes.
+func LoadFFmpegCapabilities(ffmpegPath, configDir string, force bool) (*FFmpegCapabilities, error) {
+	info, err := os.Stat(ffmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	cachePath := FFmpegCapabilitiesPath(configDir)
+	if !force {
+		if content, err := os.ReadFile(cachePath); err == nil {
+			var cached FFmpegCapabilities
+			if err := json.Unmarshal(content, &cached); err == nil &&
+				cached.Path == ffmpegPath && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+				return &cached, nil
+			}
+		}
+	}
+
+	caps, err := ProbeFFmpeg(ffmpegPath)
+	if err != nil {
+		return nil, err
+	}
+	caps.Size = info.Size()
+	caps.ModTime = info.ModTime()
+	if err := saveFFmpegCapabilities(cachePath, caps); err != nil {
+		log.Printf("Failed to cache FFmpeg capabilities: %v", err)
+	}
+	return caps, nil
+}
+
+func saveFFmpegCapabilities(cachePath string, caps *FFmpegCapabilities) error {
+	if err := EnsureDir0755(filepath.Dir(cachePath)); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(caps, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal ffmpeg capabilities: %w", err)
+	}
+	tempPath := cachePath + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0644); err != nil {
+		return fmt.Errorf("write ffmpeg capabilities temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("rename ffmpeg capabilities temp file: %w", err)
+	}
+	return nil
+}
+
+// ProbeFFmpeg runs ffmpegPath to list its version, encoders, hardware
+// accelerations and demuxers, including input devices.
+func ProbeFFmpeg(ffmpegPath string) (*FFmpegCapabilities, error) {
+	log.Printf("Probing FFmpeg capabilities of %s", ffmpegPath)
+	caps := &FFmpegCapabilities{Path: ffmpegPath, ProbedAt: time.Now()}
+
+	output, err := runFFmpegProbe(ffmpegPath, "-version")
+	if err != nil {
+		return nil, err
+	}
+	caps.Version = parseFFmpegVersion(output)
+
+	if output, err = runFFmpegProbe(ffmpegPath, "-encoders"); err != nil {
+		return nil, err
+	}
+	caps.Encoders = parseFFmpegEncoders(output)
+
+	if output, err = runFFmpegProbe(ffmpegPath, "-hwaccels"); err != nil {
+		return nil, err
+	}
+	caps.HWAccels = parseFFmpegHWAccels(output)
+
+	if output, err = runFFmpegProbe(ffmpegPath, "-demuxers"); err != nil {
+		return nil, err
+	}
+	caps.Demuxers, caps.Devices = parseFFmpegDemuxers(output)
+	return caps, nil
+}
+
+func runFFmpegProbe(ffmpegPath, option string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", option)
+	cmd.Env = withFFmpegLibraryPath(os.Environ(), ffmpegPath)
+	ConfigureNoConsoleWindow(cmd)
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%s %s did not answer within 30s", ffmpegPath, option)
+	}
+	if err != nil {
+		return "", fmt.Errorf("running %s %s: %w", ffmpegPath, option, err)
+	}
+	return string(output), nil
+}
+
+// parseFFmpegVersion returns the version from "ffmpeg version 7.1-full_build ...".
+func parseFFmpegVersion(output string) string {
+	fields := strings.Fields(firstLine(output))
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return strings.TrimSpace(firstLine(output))
+}
+
+// parseFFmpegEncoders reads the names after the " ------" separator of -encoders.
+func parseFFmpegEncoders(output string) []string {
+	var names []string
+	for _, line := range linesAfterSeparator(output) {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			names = append(names, fields[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFFmpegHWAccels reads the names below "Hardware acceleration methods:".
+func parseFFmpegHWAccels(output string) []string {
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		names = append(names, line)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFFmpegDemuxers reads the formats of -demuxers. The flag columns come
+// first: D for demuxing and d for a device; comma-separated aliases are split.
+func parseFFmpegDemuxers(output string) (demuxers, devices []string) {
+	for _, line := range linesAfterSeparator(output) {
+		if len(line) < 5 {
+			continue
+		}
+		flags := line[:4]
+		fields := strings.Fields(line[4:])
+		if len(fields) == 0 || !strings.Contains(flags, "D") {
+			continue
+		}
+		for _, name := range strings.Split(fields[0], ",") {
+			demuxers = append(demuxers, name)
+			if strings.Contains(flags, "d") {
+				devices = append(devices, name)
+			}
+		}
+	}
+	sort.Strings(demuxers)
+	sort.Strings(devices)
+	return demuxers, devices
+}
+
+// linesAfterSeparator returns the table rows below the first line made only
+// of dashes.
+func linesAfterSeparator(output string) []string {
+	var rows []string
+	inTable := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !inTable {
+			trimmed := strings.TrimSpace(line)
+			inTable = trimmed != "" && strings.Trim(trimmed, "-") == ""
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			rows = append(rows, line)
+		}
+	}
+	return rows
+}
+
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	return line
+}
+
+// HasEncoder reports whether name is one of the encoders.
+func (c *FFmpegCapabilities) HasEncoder(name string) bool {
+	return containsString(c.Encoders, name)
+}
+
+// HasHWAccel reports whether name is one of the hardware accelerations.
+func (c *FFmpegCapabilities) HasHWAccel(name string) bool {
+	return containsString(c.HWAccels, name)
+}
+
+// HasDemuxer reports whether name is one of the input formats or devices.
+func (c *FFmpegCapabilities) HasDemuxer(name string) bool {
+	return containsString(c.Demuxers, name)
+}
+
+// Summary describes the capabilities that matter for cameras and replays.
+func (c *FFmpegCapabilities) Summary() string {
+	var h264 []string
+	for _, encoder := range c.Encoders {
+		if strings.Contains(encoder, "264") {
+			h264 = append(h264, encoder)
+		}
+	}
+	lines := []string{fmt.Sprintf("FFmpeg %s (%s)", c.Version, c.Path)}
+	lines = append(lines, "Hardware acceleration: "+listOrNone(c.HWAccels))
+	lines = append(lines, "H.264 encoders: "+listOrNone(h264))
+	lines = append(lines, "Input devices: "+listOrNone(c.Devices))
+	return strings.Join(lines, "\n")
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVideoFFmpeg probes ffmpegPath and validates the ffmpeg.toml of the
+// VIDEO_CONFIGDIR in env, the environment of the cameras or replays process.
+// The validation is a heuristic and a failed probe does not mean FFmpeg is
+// unusable, so nothing found here blocks a launch: the findings are logged and
+// returned as a warning for the tab to show. No findings return "".
+func CheckVideoFFmpeg(ffmpegPath string, env []string) string {
+	configDir := envValue(env, "VIDEO_CONFIGDIR")
+	if configDir == "" {
+		configDir = VideoConfigDir("")
+	}
+	var warning string
+	caps, err := LoadFFmpegCapabilities(ffmpegPath, configDir, false)
+	if err != nil {
+		warning = fmt.Sprintf("could not probe FFmpeg: %v", err)
+	} else if problems, err := ValidateFFmpegConfig(filepath.Join(configDir, "ffmpeg.toml"), caps); err != nil {
+		warning = fmt.Sprintf("could not check ffmpeg.toml: %v", err)
+	} else if len(problems) > 0 {
+		warning = fmt.Sprintf("ffmpeg.toml may ask for features FFmpeg %s does not have:\n%s", caps.Version, strings.Join(problems, "\n"))
+	}
+	if warning != "" {
+		log.Printf("Warning: %s", warning)
+	}
+	return warning
+}
+
+func envValue(env []string, key string) string {
+	prefix := key + "="
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], prefix) {
+			return strings.TrimPrefix(env[i], prefix)
+		}
+	}
+	return ""
+}
+
+// Values that select FFmpeg's own default rather than a named feature.
+var ffmpegDefaultValues = map[string]bool{"": true, "auto": true, "none": true, "copy": true, "cpu": true, "software": true}
+
+// ValidateFFmpegConfig checks the encoders, hardware accelerations and input
+// formats named in ffmpegToml against caps. Settings are recognised by key
+// (encoder, hwaccel, input_format) and by the -c:v, -hwaccel and -f options
+// of argument strings. Sections for another operating system are skipped. A
+// missing file has nothing to validate.
+func ValidateFFmpegConfig(ffmpegToml string, caps *FFmpegCapabilities) ([]string, error) {
+	var config map[string]interface{}
+	metadata, err := toml.DecodeFile(ffmpegToml, &config)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, key := range metadata.Keys() {
+		section := key[:len(key)-1].String()
+		if isOtherOSSection(section) {
+			continue
+		}
+		values := tomlStrings(tomlValue(config, key))
+		if len(values) == 0 {
+			continue
+		}
+		location := key[len(key)-1]
+		if section != "" {
+			location = "[" + section + "] " + location
+		}
+		report := func(what, value string) {
+			problems = append(problems, fmt.Sprintf("%s: %s %q is not available", location, what, value))
+		}
+		checkFFmpegSetting(key[len(key)-1], values, caps, report)
+	}
+	return problems, nil
+}
+
+// checkFFmpegSetting reports the features named by the string values of key
+// that caps lacks.
+func checkFFmpegSetting(key string, values []string, caps *FFmpegCapabilities, report func(what, value string)) {
+	lowerKey := strings.ToLower(key)
+	for _, value := range values {
+		if ffmpegDefaultValues[strings.ToLower(value)] || strings.ContainsAny(value, " ${}%") {
+			continue
+		}
+		switch {
+		case strings.Contains(lowerKey, "hwaccel"):
+			if !caps.HasHWAccel(value) {
+				report("hardware acceleration", value)
+			}
+		case strings.Contains(lowerKey, "encoder"):
+			if !caps.HasEncoder(value) {
+				report("encoder", value)
+			}
+		case lowerKey == "input_format" || lowerKey == "inputformat" || lowerKey == "demuxer":
+			if !caps.HasDemuxer(value) {
+				report("input format", value)
+			}
+		}
+	}
+
+	var args []string
+	for _, value := range values {
+		args = append(args, strings.Fields(value)...)
+	}
+	lastInput := -1
+	for i, arg := range args {
+		if arg == "-i" {
+			lastInput = i
+		}
+	}
+	for i := 0; i+1 < len(args); i++ {
+		option, value := args[i], args[i+1]
+		if ffmpegDefaultValues[strings.ToLower(value)] || strings.ContainsAny(value, "${}%") {
+			continue
+		}
+		switch {
+		case option == "-hwaccel":
+			if !caps.HasHWAccel(value) {
+				report("hardware acceleration", value)
+			}
+		case isFFmpegCodecOption(option) && i > lastInput:
+			// Codec options before the last -i choose decoders, which are not probed.
+			if !caps.HasEncoder(value) {
+				report("encoder", value)
+			}
+		case option == "-f" && i < lastInput:
+			if !caps.HasDemuxer(value) {
+				report("input format", value)
+			}
+		}
+	}
+}
+
+func isFFmpegCodecOption(option string) bool {
+	switch option {
+	case "-c:v", "-codec:v", "-vcodec", "-c:a", "-codec:a", "-acodec":
+		return true
+	}
+	return false
+}
+
+// isOtherOSSection reports whether a section such as [windows] or
+// [encoders.darwin] applies to another operating system.
+func isOtherOSSection(section string) bool {
+	current := GetGoos()
+	for _, part := range strings.Split(strings.ToLower(section), ".") {
+		part = strings.Trim(part, `"' `)
+		if part == "macos" || part == "mac" {
+			part = "darwin"
+		}
+		switch part {
+		case "windows", "linux", "darwin":
+			if part != current {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tomlValue returns the value of key in a decoded TOML document, or nil when
+// a part of the key is not a table, as in an array of tables.
+func tomlValue(config map[string]interface{}, key toml.Key) interface{} {
+	var value interface{} = config
+	for _, part := range key {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = table[part]
+	}
+	return value
+}
+
+// tomlStrings returns a decoded TOML string, or the strings of an array.
+// Other values yield nothing.
+func tomlStrings(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
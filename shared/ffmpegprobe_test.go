@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fakeFFmpegScript = `#!/bin/sh
+case "$2" in
+-version) echo "ffmpeg version 7.1-test Copyright (c) 2000-2024" ;;
+-encoders) cat <<'EOF'
+Encoders:
+ V..... = Video
+ ------
+ V....D libx264              libx264 H.264 / AVC
+ V....D h264_vaapi           H.264/AVC (VAAPI)
+ A....D aac                  AAC (Advanced Audio Coding)
+EOF
+;;
+-hwaccels) printf 'Hardware acceleration methods:\nvaapi\n\n' ;;
+-demuxers) cat <<'EOF'
+File formats:
+ D. = Demuxing supported
+ ..d = Is a device
+ ---
+ D  mov,mp4,m4a      QuickTime / MOV
+ D d v4l2            Video4Linux2 device grab
+EOF
+;;
+esac
+`
+
+func TestProbeAndValidateFFmpegConfig(t *testing.T) {
+	if GetGoos() == "windows" {
+		t.Skip("uses a shell script as ffmpeg")
+	}
+	dir := t.TempDir()
+	ffmpegPath := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpegPath, []byte(fakeFFmpegScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configDir := filepath.Join(dir, "video_config", "ffmpeg")
+
+	caps, err := LoadFFmpegCapabilities(ffmpegPath, configDir, false)
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if caps.Version != "7.1-test" || !caps.HasEncoder("h264_vaapi") || !caps.HasHWAccel("vaapi") ||
+		!caps.HasDemuxer("mp4") || strings.Join(caps.Devices, ",") != "v4l2" {
+		t.Fatalf("unexpected capabilities %+v", caps)
+	}
+	if _, err := os.Stat(FFmpegCapabilitiesPath(configDir)); err != nil {
+		t.Fatalf("expected probe to be cached: %v", err)
+	}
+
+	otherOS := "windows"
+	if GetGoos() == "windows" {
+		otherOS = "linux"
+	}
+	toml := `encoder = "h264_nvenc"
+hwaccel = "auto"
+
+[` + GetGoos() + `]
+input_args = ["-hwaccel", "cuda", "-f", "v4l2", "-i", "/dev/video0"]
+output_args = "-c:v libx264 -f mpegts"
+
+[other.` + otherOS + `]
+encoder = "h264_qsv"
+`
+	if err := os.WriteFile(filepath.Join(configDir, "ffmpeg.toml"), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := ValidateFFmpegConfig(filepath.Join(configDir, "ffmpeg.toml"), caps)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if len(problems) != 2 || !strings.Contains(problems[0], `encoder "h264_nvenc"`) ||
+		!strings.Contains(problems[1], `input_args: hardware acceleration "cuda"`) {
+		t.Fatalf("unexpected problems %q", problems)
+	}
+
+	warning := CheckVideoFFmpeg(ffmpegPath, []string{"VIDEO_CONFIGDIR=" + configDir})
+	if !strings.Contains(warning, "h264_nvenc") {
+		t.Fatalf("expected launch check to warn about h264_nvenc, got %q", warning)
+	}
+
+	if err := os.WriteFile(filepath.Join(configDir, "ffmpeg.toml"), []byte("encoder = \"h264_nvenc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	warning = CheckVideoFFmpeg(ffmpegPath, []string{"VIDEO_CONFIGDIR=" + configDir})
+	if !strings.Contains(warning, "could not check ffmpeg.toml") {
+		t.Fatalf("expected a malformed ffmpeg.toml to be a warning, got %q", warning)
+	}
+}
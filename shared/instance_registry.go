@@ -0,0 +1,114 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gofrs/flock"
+)
+
+// RegisteredInstance is an instance recorded in the instance registry of the
+// runtime directory it uses.
+type RegisteredInstance struct {
+	Name            string `json:"-"`
+	ControlPanelDir string `json:"controlPanelDir"`
+	OwlcmsDir       string `json:"owlcmsDir"`
+	TrackerDir      string `json:"trackerDir"`
+}
+
+// instanceRegistry records the instances that use a runtime directory, so
+// that the runtimes they need are known wherever their directories are.
+type instanceRegistry struct {
+	Instances map[string]RegisteredInstance `json:"instances"`
+}
+
+// InstanceRegistryPathIn returns the instance registry of runtimeDir.
+func InstanceRegistryPathIn(runtimeDir string) string {
+	return filepath.Join(runtimeDir, "instances.json")
+}
+
+func loadInstanceRegistry(path string) (*instanceRegistry, error) {
+	registry := &instanceRegistry{Instances: map[string]RegisteredInstance{}}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, fmt.Errorf("read instance registry: %w", err)
+	}
+	if err := json.Unmarshal(content, registry); err != nil {
+		return nil, fmt.Errorf("parse instance registry %s: %w", path, err)
+	}
+	if registry.Instances == nil {
+		registry.Instances = map[string]RegisteredInstance{}
+	}
+	return registry, nil
+}
+
+// RegisteredInstances returns the instances recorded in the registry of
+// runtimeDir, sorted by name.
+func RegisteredInstances(runtimeDir string) ([]RegisteredInstance, error) {
+	registry, err := loadInstanceRegistry(InstanceRegistryPathIn(runtimeDir))
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]RegisteredInstance, 0, len(registry.Instances))
+	for name, instance := range registry.Instances {
+		instance.Name = name
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances, nil
+}
+
+// RegisterInstance records instance in the registry of runtimeDir, replacing
+// an earlier entry of the same name.
+func RegisterInstance(runtimeDir string, instance RegisteredInstance) error {
+	return updateInstanceRegistry(InstanceRegistryPathIn(runtimeDir), func(registry *instanceRegistry) {
+		registry.Instances[instance.Name] = instance
+	})
+}
+
+// UnregisterInstance removes the instance name from the registry of runtimeDir.
+func UnregisterInstance(runtimeDir, name string) error {
+	return updateInstanceRegistry(InstanceRegistryPathIn(runtimeDir), func(registry *instanceRegistry) {
+		delete(registry.Instances, name)
+	})
+}
+
+// updateInstanceRegistry applies update to the registry at path under a file
+// lock, as updatePortRegistryFile does for ports.
+func updateInstanceRegistry(path string, update func(*instanceRegistry)) error {
+	if err := EnsureDir0755(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("creating instance registry directory: %w", err)
+	}
+
+	lock := flock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("lock instance registry: %w", err)
+	}
+	defer lock.Unlock()
+
+	registry, err := loadInstanceRegistry(path)
+	if err != nil {
+		return err
+	}
+	update(registry)
+
+	content, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal instance registry: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0644); err != nil {
+		return fmt.Errorf("write instance registry temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("replace instance registry: %w", err)
+	}
+	return nil
+}
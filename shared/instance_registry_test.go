@@ -0,0 +1,36 @@
+package shared
+
+import (
+	"testing"
+)
+
+func TestInstanceRegistryTracksRegisteredInstances(t *testing.T) {
+	runtimeDir := t.TempDir()
+
+	records := RegisteredInstance{Name: "records", ControlPanelDir: "/srv/records/controlpanel", OwlcmsDir: "/srv/records/owlcms", TrackerDir: "/srv/records/tracker"}
+	if err := RegisterInstance(runtimeDir, records); err != nil {
+		t.Fatalf("register records: %v", err)
+	}
+	if err := RegisterInstance(runtimeDir, RegisteredInstance{Name: "club", ControlPanelDir: "/srv/club/controlpanel"}); err != nil {
+		t.Fatalf("register club: %v", err)
+	}
+
+	instances, err := RegisteredInstances(runtimeDir)
+	if err != nil {
+		t.Fatalf("list instances: %v", err)
+	}
+	if len(instances) != 2 || instances[0].Name != "club" || instances[1] != records {
+		t.Fatalf("unexpected instances: %+v", instances)
+	}
+
+	if err := UnregisterInstance(runtimeDir, "club"); err != nil {
+		t.Fatalf("unregister club: %v", err)
+	}
+	instances, err = RegisteredInstances(runtimeDir)
+	if err != nil {
+		t.Fatalf("list instances: %v", err)
+	}
+	if len(instances) != 1 || instances[0].Name != "records" {
+		t.Fatalf("expected only records to remain, got %+v", instances)
+	}
+}
@@ -88,6 +88,9 @@ func CheckAndInstallJava(requiredVersion string, statusLabel *widget.Label, w fy
 
 // DownloadAndInstallJava downloads and installs a specific Java version
 func DownloadAndInstallJava(temurinVersion string, statusLabel *widget.Label, w fyne.Window, goosFunc func() string) error {
+	if err := CheckRuntimeDownloadAllowed(fmt.Errorf("Java %s is not installed", temurinVersion)); err != nil {
+		return err
+	}
 	if statusLabel != nil {
 		statusLabel.SetText("Downloading required Java version...")
 		statusLabel.Refresh()
@@ -277,8 +280,10 @@ func scanEnvPropertiesForJavaVersions(owlcmsInstallDir, firmataInstallDir string
 
 // CleanupObsoleteJavaVersions scans env.properties files in the control panel,
 // finds the highest required Java version, ensures it's installed,
-// removes older control panel Java versions, and removes legacy bundled Java
-func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, statusLabel *widget.Label, w fyne.Window) ([]string, error) {
+// removes the control panel Java versions no instance uses, and removes legacy bundled Java.
+// usage adds the requirements and builds of the other instances sharing the
+// runtime directory; with dryRun, the results describe what would be done.
+func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, usage RuntimeUsage, dryRun bool, statusLabel *widget.Label, w fyne.Window) ([]string, error) {
 	runtimeDir := GetRuntimeDir()
 	javaBaseDir := filepath.Join(runtimeDir, "java")
 
@@ -291,6 +296,7 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 	if err != nil {
 		return nil, fmt.Errorf("scanning env.properties files: %w", err)
 	}
+	requiredVersions = appendUnique(requiredVersions, usage.Required[RuntimeJava]...)
 
 	// Step 2: Determine the highest major version required
 	highestMajor := 0
@@ -310,7 +316,7 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 	}
 
 	// Step 3: Check if the required Java version (or newer) exists in control panel
-	if _, err := os.Stat(javaBaseDir); os.IsNotExist(err) {
+	if _, err := os.Stat(javaBaseDir); os.IsNotExist(err) && !dryRun {
 		// No Java directory exists, need to install
 		if err := EnsureDir0755(javaBaseDir); err != nil {
 			return nil, fmt.Errorf("creating java directory: %w", err)
@@ -332,7 +338,9 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 	}
 
 	// If required Java not found, attempt to download it
-	if !hasRequiredJava {
+	if !hasRequiredJava && dryRun {
+		removed = append(removed, fmt.Sprintf("Would download Java %d", highestMajor))
+	} else if !hasRequiredJava {
 		// Determine the version string to download
 		versionToDownload := fmt.Sprintf("jdk-%d", highestMajor)
 
@@ -358,61 +366,15 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 		}
 	}
 
-	// Step 4: Remove all Java versions older than the highest major version
-	if entries, err := os.ReadDir(javaBaseDir); err == nil {
-		versionsByMajor := make(map[int][]string)
-
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			versionName := entry.Name()
-			majorVer, err := ExtractMajorVersion(versionName)
-			if err != nil {
-				continue
-			}
-			versionsByMajor[majorVer] = append(versionsByMajor[majorVer], versionName)
-		}
-
-		// Remove all versions of older major versions
-		for majorVer, versions := range versionsByMajor {
-			if majorVer < highestMajor {
-				for _, version := range versions {
-					versionPath := filepath.Join(javaBaseDir, version)
-					if err := os.RemoveAll(versionPath); err != nil {
-						return removed, fmt.Errorf("removing %s: %w", version, err)
-					}
-					removed = append(removed, fmt.Sprintf("%s (superseded by Java %d)", version, highestMajor))
-				}
-			}
-		}
-
-		// For the highest major version, keep only the latest patch version
-		if versions, exists := versionsByMajor[highestMajor]; exists && len(versions) > 1 {
-			// Sort to find the latest version
-			sortedVersions := make([]string, len(versions))
-			copy(sortedVersions, versions)
-
-			// Sort using CompareJDKVersions (latest first)
-			for i := 0; i < len(sortedVersions); i++ {
-				for j := i + 1; j < len(sortedVersions); j++ {
-					if CompareJDKVersions(sortedVersions[j], sortedVersions[i]) {
-						sortedVersions[i], sortedVersions[j] = sortedVersions[j], sortedVersions[i]
-					}
-				}
-			}
-
-			latestVersion := sortedVersions[0]
-
-			// Remove all older patch versions
-			for _, version := range sortedVersions[1:] {
-				versionPath := filepath.Join(javaBaseDir, version)
-				if err := os.RemoveAll(versionPath); err != nil {
-					return removed, fmt.Errorf("removing %s: %w", version, err)
-				}
-				removed = append(removed, fmt.Sprintf("%s (kept latest: %s)", version, latestVersion))
-			}
-		}
+	// Step 4: Remove the Java versions that no instance sharing the runtime directory uses
+	plan, err := PlanRuntimeCleanup(RuntimeJava, usage)
+	if err != nil {
+		return removed, err
+	}
+	planned, err := removePlannedRuntimes(plan, dryRun)
+	removed = append(removed, planned...)
+	if err != nil {
+		return removed, err
 	}
 
 	// Step 5: Remove legacy Java from owlcms and firmata directories
@@ -421,7 +383,9 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 		filepath.Join(owlcmsInstallDir, "java"),
 	}
 	for _, javaDir := range owlcmsJavaDirs {
-		if _, err := os.Stat(javaDir); err == nil {
+		if _, err := os.Stat(javaDir); err == nil && dryRun {
+			removed = append(removed, fmt.Sprintf("Would remove legacy Java from owlcms (%s)", filepath.Base(javaDir)))
+		} else if err == nil {
 			if err := os.RemoveAll(javaDir); err != nil {
 				return removed, fmt.Errorf("removing legacy owlcms java: %w", err)
 			}
@@ -434,7 +398,9 @@ func CleanupObsoleteJavaVersions(owlcmsInstallDir, firmataInstallDir string, sta
 		filepath.Join(firmataInstallDir, "java"),
 	}
 	for _, javaDir := range firmataJavaDirs {
-		if _, err := os.Stat(javaDir); err == nil {
+		if _, err := os.Stat(javaDir); err == nil && dryRun {
+			removed = append(removed, fmt.Sprintf("Would remove legacy Java from firmata (%s)", filepath.Base(javaDir)))
+		} else if err == nil {
 			if err := os.RemoveAll(javaDir); err != nil {
 				return removed, fmt.Errorf("removing legacy firmata java: %w", err)
 			}
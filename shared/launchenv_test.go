@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeLaunchEnvOrigins(t *testing.T) {
+	dir := t.TempDir()
+	parent := filepath.Join(dir, "env.properties")
+	release := filepath.Join(dir, "65.0.0", "env.properties")
+	if err := os.MkdirAll(filepath.Dir(release), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(parent, []byte("TEMURIN_VERSION=jdk-25\nOWLCMS_PORT=8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(release, []byte("OWLCMS_PORT=8081\nOWLCMS_ENABLEEMBEDDEDMQTT=true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONTROLPANEL_TEST_HOME", "/home/owlcms")
+	t.Setenv("CONTROLPANEL_MQTT_PASSWORD", "hunter2")
+
+	env := []string{
+		"CONTROLPANEL_TEST_HOME=/home/owlcms",
+		"CONTROLPANEL_MQTT_PASSWORD=hunter2",
+		"OWLCMS_CONTROLPANEL=3.2.0",
+		"OWLCMS_PORT=8080",
+		"OWLCMS_PORT=8081",
+		"TEMURIN_VERSION=jdk-25",
+		"OWLCMS_ENABLEEMBEDDEDMQTT=false",
+		"OWLCMS_VIDEODATA=ws://owlcms:s3cret@localhost:8096/ws",
+	}
+	vars := DescribeLaunchEnv(env, parent, release, map[string]string{"OWLCMS_ENABLEEMBEDDEDMQTT": "false"})
+	got := map[string]LaunchEnvVar{}
+	for _, v := range vars {
+		got[v.Key] = v
+	}
+	if len(got) != 7 {
+		t.Fatalf("expected 7 variables, got %+v", vars)
+	}
+
+	expected := map[string]LaunchEnvVar{
+		"CONTROLPANEL_TEST_HOME":     {Value: "/home/owlcms", Origin: LaunchEnvOS},
+		"CONTROLPANEL_MQTT_PASSWORD": {Value: RedactedValue, Origin: LaunchEnvOS, Secret: true},
+		"OWLCMS_CONTROLPANEL":        {Value: "3.2.0", Origin: LaunchEnvControlPanel},
+		"OWLCMS_PORT":                {Value: "8081", Origin: LaunchEnvRelease},
+		"TEMURIN_VERSION":            {Value: "jdk-25", Origin: LaunchEnvParent},
+		"OWLCMS_ENABLEEMBEDDEDMQTT":  {Value: "false", Origin: LaunchEnvCommandLine, Shadows: LaunchEnvRelease},
+		"OWLCMS_VIDEODATA":           {Value: "ws://owlcms:" + RedactedValue + "@localhost:8096/ws", Origin: LaunchEnvControlPanel},
+	}
+	for key, want := range expected {
+		want.Key = key
+		if got[key] != want {
+			t.Fatalf("%s: got %+v, want %+v", key, got[key], want)
+		}
+	}
+}
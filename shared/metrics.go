@@ -0,0 +1,133 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clockTicksPerSecond is the USER_HZ value Linux uses for /proc CPU times.
+const clockTicksPerSecond = 100
+
+var (
+	downloadsSucceeded atomic.Int64
+	downloadsFailed    atomic.Int64
+	downloadedBytes    atomic.Int64
+
+	moduleRestartsMutex sync.Mutex
+	moduleRestarts      = map[string]int{}
+)
+
+// DownloadStats are the download counters of this control panel process.
+type DownloadStats struct {
+	Succeeded int64
+	Failed    int64
+	Bytes     int64
+}
+
+// RecordDownload counts a finished download and the bytes it transferred.
+func RecordDownload(bytes int64, err error) {
+	downloadedBytes.Add(bytes)
+	if err != nil {
+		downloadsFailed.Add(1)
+		return
+	}
+	downloadsSucceeded.Add(1)
+}
+
+// GetDownloadStats returns the download counters of this process.
+func GetDownloadStats() DownloadStats {
+	return DownloadStats{
+		Succeeded: downloadsSucceeded.Load(),
+		Failed:    downloadsFailed.Load(),
+		Bytes:     downloadedBytes.Load(),
+	}
+}
+
+// RecordModuleRestart counts an automatic restart of module by this process.
+func RecordModuleRestart(module string) {
+	moduleRestartsMutex.Lock()
+	defer moduleRestartsMutex.Unlock()
+	moduleRestarts[module]++
+}
+
+// GetModuleRestarts returns how many times this process restarted module.
+func GetModuleRestarts(module string) int {
+	moduleRestartsMutex.Lock()
+	defer moduleRestartsMutex.Unlock()
+	return moduleRestarts[module]
+}
+
+func lastBackupPath(installDir string) string {
+	return filepath.Join(installDir, "last-backup.txt")
+}
+
+// RecordBackup notes that a version of the module installed in installDir
+// was just archived.
+func RecordBackup(installDir string) error {
+	return os.WriteFile(lastBackupPath(installDir), []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// GetLastBackupTime returns when a version of the module installed in
+// installDir was last archived.
+func GetLastBackupTime(installDir string) (time.Time, bool) {
+	content, err := os.ReadFile(lastBackupPath(installDir))
+	if err != nil {
+		return time.Time{}, false
+	}
+	when, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return when, true
+}
+
+// ReadProcessUsage returns the resident memory and the CPU time used so far by
+// a PID. It is only supported on Linux; ok is false elsewhere.
+func ReadProcessUsage(pid int) (rssBytes int64, cpuSeconds float64, ok bool) {
+	if pid <= 0 || GetGoos() != "linux" {
+		return 0, 0, false
+	}
+
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, false
+	}
+	statmFields := strings.Fields(string(statm))
+	if len(statmFields) < 2 {
+		return 0, 0, false
+	}
+	residentPages, err := strconv.ParseInt(statmFields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, false
+	}
+	statLine := strings.TrimSpace(string(stat))
+	idx := strings.LastIndex(statLine, ")")
+	if idx == -1 || idx+2 >= len(statLine) {
+		return 0, 0, false
+	}
+	fields := strings.Fields(statLine[idx+2:])
+	if len(fields) <= 12 {
+		return 0, 0, false
+	}
+	userTicks, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	systemTicks, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return residentPages * int64(os.Getpagesize()), float64(userTicks+systemTicks) / clockTicksPerSecond, true
+}
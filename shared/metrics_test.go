@@ -0,0 +1,40 @@
+package shared
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRecordBackupIsReadBack(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := GetLastBackupTime(dir); ok {
+		t.Fatal("expected no backup before RecordBackup")
+	}
+	if err := RecordBackup(dir); err != nil {
+		t.Fatalf("RecordBackup returned error: %v", err)
+	}
+	if _, ok := GetLastBackupTime(dir); !ok {
+		t.Fatal("expected backup time after RecordBackup")
+	}
+}
+
+func TestRecordDownloadCountsFailuresSeparately(t *testing.T) {
+	before := GetDownloadStats()
+	RecordDownload(100, nil)
+	RecordDownload(10, errors.New("broken"))
+	after := GetDownloadStats()
+	if after.Succeeded-before.Succeeded != 1 || after.Failed-before.Failed != 1 || after.Bytes-before.Bytes != 110 {
+		t.Fatalf("unexpected download stats: before %+v after %+v", before, after)
+	}
+}
+
+func TestReadProcessUsageOfCurrentProcess(t *testing.T) {
+	if GetGoos() != "linux" {
+		t.Skip("process usage is only read on Linux")
+	}
+	rss, _, ok := ReadProcessUsage(os.Getpid())
+	if !ok || rss <= 0 {
+		t.Fatalf("expected resident memory for current process, got %d (ok=%v)", rss, ok)
+	}
+}
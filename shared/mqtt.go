@@ -0,0 +1,323 @@
+package shared
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types used by MQTTClient.
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttSubscribe  = 8
+	mqttPingreq    = 12
+	mqttDisconnect = 14
+)
+
+// mqttMaxRemainingLength is the largest body a packet length can encode.
+const mqttMaxRemainingLength = 268435455
+
+// MQTTOptions describe the connection to a broker.
+type MQTTOptions struct {
+	Address     string
+	ClientID    string
+	Username    string
+	Password    string
+	WillTopic   string
+	WillPayload []byte
+	KeepAlive   time.Duration
+}
+
+// MQTTMessageHandler receives messages of subscribed topics. It runs on the
+// reading goroutine and must not block.
+type MQTTMessageHandler func(topic string, payload []byte)
+
+// MQTTClient is a minimal MQTT 3.1.1 client: QoS 0 publishing with an optional
+// retained flag, QoS 0 subscriptions and keep-alive pings. It is enough to
+// talk to the broker embedded in OWLCMS without an external dependency.
+type MQTTClient struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+	handler   MQTTMessageHandler
+
+	writeMutex sync.Mutex
+	nextID     uint16
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// DialMQTT connects to the broker and waits for it to accept the session.
+func DialMQTT(opts MQTTOptions, handler MQTTMessageHandler) (*MQTTClient, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", opts.Address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &MQTTClient{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: opts.KeepAlive,
+		handler:   handler,
+		done:      make(chan struct{}),
+	}
+	if err := client.writePacket(mqttConnect<<4, encodeMQTTConnect(opts)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send MQTT connect: %w", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	header, body, err := readMQTTPacket(client.reader)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read MQTT connack: %w", err)
+	}
+	if header>>4 != mqttConnack || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected MQTT packet type %d instead of connack", header>>4)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("MQTT broker refused the connection (%s)", mqttConnackReason(body[1]))
+	}
+
+	go client.readLoop()
+	go client.pingLoop()
+	return client, nil
+}
+
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("code %d", code)
+	}
+}
+
+// Publish sends payload to topic with QoS 0.
+func (c *MQTTClient) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(mqttPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+	return c.writePacket(header, body)
+}
+
+// Subscribe asks for the messages of topic with QoS 0.
+func (c *MQTTClient) Subscribe(topic string) error {
+	c.writeMutex.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.writeMutex.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	body = appendMQTTString(body, topic)
+	body = append(body, 0)
+	return c.writePacket(mqttSubscribe<<4|0x02, body)
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *MQTTClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, once Done is closed.
+func (c *MQTTClient) Err() error {
+	<-c.done
+	return c.err
+}
+
+// Close disconnects cleanly; the broker does not publish the will message.
+func (c *MQTTClient) Close() error {
+	err := c.writePacket(mqttDisconnect<<4, nil)
+	c.shutdown(errors.New("closed"))
+	return err
+}
+
+func (c *MQTTClient) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+func (c *MQTTClient) writePacket(header byte, body []byte) error {
+	if len(body) > mqttMaxRemainingLength {
+		return fmt.Errorf("MQTT packet too large (%d bytes)", len(body))
+	}
+	packet := append([]byte{header}, encodeMQTTLength(len(body))...)
+	packet = append(packet, body...)
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+func (c *MQTTClient) readLoop() {
+	for {
+		// The broker answers our pings, so silence past 1.5 keep-alive
+		// periods means the connection is gone.
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		header, body, err := readMQTTPacket(c.reader)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		if header>>4 != mqttPublish {
+			continue
+		}
+
+		qos := (header >> 1) & 0x03
+		topic, rest, err := readMQTTString(body)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		if qos > 0 {
+			if len(rest) < 2 {
+				c.shutdown(fmt.Errorf("MQTT publish without packet identifier"))
+				return
+			}
+			if qos == 1 {
+				_ = c.writePacket(mqttPuback<<4, rest[:2])
+			}
+			rest = rest[2:]
+		}
+		if c.handler != nil {
+			c.handler(topic, rest)
+		}
+	}
+}
+
+func (c *MQTTClient) pingLoop() {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writePacket(mqttPingreq<<4, nil); err != nil {
+				c.shutdown(err)
+				return
+			}
+		}
+	}
+}
+
+func encodeMQTTConnect(opts MQTTOptions) []byte {
+	flags := byte(0x02) // clean session
+	if opts.WillTopic != "" {
+		flags |= 0x04 | 0x20 // will, retained, QoS 0
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendMQTTString(body, opts.ClientID)
+	if opts.WillTopic != "" {
+		body = appendMQTTString(body, opts.WillTopic)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(opts.WillPayload)))
+		body = append(body, opts.WillPayload...)
+	}
+	if opts.Username != "" {
+		body = appendMQTTString(body, opts.Username)
+		if opts.Password != "" {
+			body = appendMQTTString(body, opts.Password)
+		}
+	}
+	return body
+}
+
+func encodeMQTTLength(length int) []byte {
+	var encoded []byte
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		encoded = append(encoded, digit)
+		if length == 0 {
+			return encoded
+		}
+	}
+}
+
+func appendMQTTString(buf []byte, value string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	return append(buf, value...)
+}
+
+func readMQTTString(buf []byte) (string, []byte, error) {
+	if len(buf) < 2 {
+		return "", nil, fmt.Errorf("truncated MQTT string")
+	}
+	length := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+length {
+		return "", nil, fmt.Errorf("truncated MQTT string")
+	}
+	return string(buf[2 : 2+length]), buf[2+length:], nil
+}
+
+func readMQTTPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for multiplier := 1; ; multiplier *= 128 {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if multiplier > 128*128 {
+			return 0, nil, fmt.Errorf("malformed MQTT remaining length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeMQTTLength(t *testing.T) {
+	cases := map[int][]byte{
+		0:         {0x00},
+		127:       {0x7f},
+		128:       {0x80, 0x01},
+		16383:     {0xff, 0x7f},
+		2097152:   {0x80, 0x80, 0x80, 0x01},
+		268435455: {0xff, 0xff, 0xff, 0x7f},
+	}
+	for length, want := range cases {
+		if got := encodeMQTTLength(length); !bytes.Equal(got, want) {
+			t.Errorf("encodeMQTTLength(%d) = %x, want %x", length, got, want)
+		}
+	}
+}
+
+func TestMQTTClientPublishesAndReceives(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	type packet struct {
+		header byte
+		body   []byte
+	}
+	packets := make(chan packet, 8)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			header, body, err := readMQTTPacket(reader)
+			if err != nil {
+				close(packets)
+				return
+			}
+			packets <- packet{header, body}
+			switch header >> 4 {
+			case mqttConnect:
+				_, _ = conn.Write([]byte{mqttConnack << 4, 2, 0, 0})
+			case mqttSubscribe:
+				// Deliver one QoS 0 message on the subscribed topic.
+				message := appendMQTTString(nil, "owlcms/controlpanel/main/owlcms/command")
+				message = append(message, "stop"...)
+				_, _ = conn.Write(append(append([]byte{mqttPublish << 4}, encodeMQTTLength(len(message))...), message...))
+			}
+		}
+	}()
+
+	received := make(chan string, 1)
+	client, err := DialMQTT(MQTTOptions{
+		Address:     listener.Addr().String(),
+		ClientID:    "test",
+		Username:    "user",
+		Password:    "secret",
+		WillTopic:   "owlcms/controlpanel/main/online",
+		WillPayload: []byte("false"),
+	}, func(topic string, payload []byte) {
+		received <- topic + "=" + string(payload)
+	})
+	if err != nil {
+		t.Fatalf("DialMQTT: %v", err)
+	}
+
+	connect := <-packets
+	if connect.header>>4 != mqttConnect {
+		t.Fatalf("first packet type = %d, want connect", connect.header>>4)
+	}
+	if flags := connect.body[7]; flags != 0xe6 {
+		t.Fatalf("connect flags = %#x, want user, password, retained will and clean session", flags)
+	}
+
+	if err := client.Publish("owlcms/controlpanel/main/online", []byte("true"), true); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	publish := <-packets
+	if publish.header != mqttPublish<<4|0x01 {
+		t.Fatalf("publish header = %#x, want retained QoS 0 publish", publish.header)
+	}
+	topic, payload, err := readMQTTString(publish.body)
+	if err != nil || topic != "owlcms/controlpanel/main/online" || string(payload) != "true" {
+		t.Fatalf("publish = %q %q %v", topic, payload, err)
+	}
+
+	if err := client.Subscribe("owlcms/controlpanel/main/+/command"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case message := <-received:
+		if message != "owlcms/controlpanel/main/owlcms/command=stop" {
+			t.Fatalf("received %q", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	<-client.Done()
+}
+
+func TestDialMQTTReportsRefusal(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err := readMQTTPacket(bufio.NewReader(conn)); err == nil {
+			_, _ = conn.Write([]byte{mqttConnack << 4, 2, 0, 4})
+		}
+	}()
+
+	_, err = DialMQTT(MQTTOptions{Address: listener.Addr().String(), ClientID: "test"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("DialMQTT error = %v, want refusal", err)
+	}
+}
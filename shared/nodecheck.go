@@ -342,6 +342,9 @@ func scanEnvPropertiesForNodeVersions(trackerInstallDir string) ([]string, error
 
 // DownloadAndInstallNode downloads and installs a Node.js version
 func DownloadAndInstallNode(version string, progressCallback func(downloaded, total int64)) (string, error) {
+	if err := CheckRuntimeDownloadAllowed(fmt.Errorf("Node.js %s is not installed", version)); err != nil {
+		return "", err
+	}
 	goos := GetGoos()
 	goarch := GetGoarch()
 
@@ -444,8 +447,10 @@ func DownloadAndInstallNode(version string, progressCallback func(downloaded, to
 }
 
 // CleanupObsoleteNodeVersions scans for NODE_VERSION requirements, ensures they're met,
-// then removes older control panel Node versions and bundled Node from tracker releases
-func CleanupObsoleteNodeVersions(statusLabel *widget.Label, w fyne.Window) ([]string, error) {
+// then removes the control panel Node versions no instance uses and bundled Node from tracker releases.
+// usage adds the requirements and builds of the other instances sharing the
+// runtime directory; with dryRun, the results describe what would be done.
+func CleanupObsoleteNodeVersions(usage RuntimeUsage, dryRun bool, statusLabel *widget.Label, w fyne.Window) ([]string, error) {
 	var removed []string
 
 	// Step 1: Find all required Node versions from env.properties files in control panel structure
@@ -454,6 +459,7 @@ func CleanupObsoleteNodeVersions(statusLabel *widget.Label, w fyne.Window) ([]st
 	if err != nil {
 		return nil, fmt.Errorf("scanning env.properties files: %w", err)
 	}
+	requiredVersions = appendUnique(requiredVersions, usage.Required[RuntimeNode]...)
 
 	// Step 2: Determine the highest major.minor.patch version required
 	var highestMajor, highestMinor, highestPatch int
@@ -492,7 +498,9 @@ func CleanupObsoleteNodeVersions(statusLabel *widget.Label, w fyne.Window) ([]st
 	}
 
 	// Step 4: If we don't have required Node, download the latest LTS
-	if !hasRequiredNode && (highestMajor > 0 || len(requiredVersions) == 0) {
+	if !hasRequiredNode && (highestMajor > 0 || len(requiredVersions) == 0) && dryRun {
+		removed = append(removed, "Would download the required Node.js version")
+	} else if !hasRequiredNode && (highestMajor > 0 || len(requiredVersions) == 0) {
 		if statusLabel != nil {
 			statusLabel.SetText("Downloading required Node.js version...")
 			statusLabel.Refresh()
@@ -516,44 +524,19 @@ func CleanupObsoleteNodeVersions(statusLabel *widget.Label, w fyne.Window) ([]st
 		}
 	}
 
-	// Step 5: Clean up versioned Node installations in control panel
-	if _, err := os.Stat(nodeBaseDir); err == nil {
-		versionDirs, err := os.ReadDir(nodeBaseDir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read node directory: %w", err)
-		}
-
-		if len(versionDirs) > 1 {
-			// Collect versions
-			var versions []string
-			for _, dir := range versionDirs {
-				if dir.IsDir() {
-					versions = append(versions, dir.Name())
-				}
-			}
-
-			// Sort to find the latest
-			sort.Slice(versions, func(i, j int) bool {
-				return CompareNodeVersions(versions[i], versions[j])
-			})
-
-			// Keep the first (latest), remove the rest
-			for _, version := range versions[1:] {
-				versionPath := filepath.Join(nodeBaseDir, version)
-
-				if statusLabel != nil {
-					statusLabel.SetText(fmt.Sprintf("Removing Node.js %s...", version))
-					statusLabel.Refresh()
-				}
-
-				log.Printf("Removing Node.js version: %s\n", version)
-				if err := os.RemoveAll(versionPath); err != nil {
-					log.Printf("Warning: failed to remove %s: %v\n", version, err)
-				} else {
-					removed = append(removed, fmt.Sprintf("Node.js %s", version))
-				}
-			}
-		}
+	// Step 5: Remove the Node versions that no instance sharing the runtime directory uses
+	if statusLabel != nil && !dryRun {
+		statusLabel.SetText("Removing unused Node.js versions...")
+		statusLabel.Refresh()
+	}
+	plan, err := PlanRuntimeCleanup(RuntimeNode, usage)
+	if err != nil {
+		return nil, err
+	}
+	planned, err := removePlannedRuntimes(plan, dryRun)
+	removed = append(removed, planned...)
+	if err != nil {
+		return removed, err
 	}
 
 	// Second, remove bundled node.exe/node files from tracker release directories
@@ -578,7 +561,9 @@ func CleanupObsoleteNodeVersions(statusLabel *widget.Label, w fyne.Window) ([]st
 
 				// Use defensive search to find any bundled Node executable
 				nodePath := findNodeExecutable(versionPath, nodeExe)
-				if nodePath != "" {
+				if nodePath != "" && dryRun {
+					removed = append(removed, fmt.Sprintf("Would remove bundled Node from tracker %s", dir.Name()))
+				} else if nodePath != "" {
 					if statusLabel != nil {
 						statusLabel.SetText(fmt.Sprintf("Removing bundled Node from tracker %s...", dir.Name()))
 						statusLabel.Refresh()
@@ -0,0 +1,344 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/flock"
+)
+
+// PortBlockSize separates the port blocks offered to named instances: the
+// main instance uses 8080/8096, the next one 8180/8196, and so on.
+const PortBlockSize = 100
+
+// MainInstanceName is the instance used when CONTROLPANEL_INSTANCE is unset.
+const MainInstanceName = "owlcms"
+
+// DefaultModulePorts are the main instance ports that make up a port block.
+// Only the modules each instance installs for itself are part of a block;
+// see SharedModulePorts for the others.
+var DefaultModulePorts = map[string]int{
+	"owlcms":  8080,
+	"tracker": 8096,
+}
+
+// SharedModulePorts are the default ports of the modules installed once and
+// used by every instance. They are not part of a port block: the default port
+// of each is reserved for it in every instance, and the port claimed for one
+// of its versions is shared by all instances rather than owned by one. The
+// cameras and replays ports live in their config.toml and are not registered.
+var SharedModulePorts = map[string]int{
+	"firmata": 8090,
+}
+
+// AllInstances is the owner reported for a port reserved by SharedModulePorts.
+const AllInstances = "*"
+
+// DescribePortOwner names the holder of a port returned by Owner.
+func DescribePortOwner(instance, module string) string {
+	if instance == AllInstances {
+		return module + ", which all instances share"
+	}
+	return fmt.Sprintf("%s of instance %q", module, instance)
+}
+
+// PortRegistry records the ports claimed by each instance sharing a runtime
+// directory, so that two instances never configure the same port. The ports
+// of an instance are keyed by module for its shared env.properties and by
+// module/version for a version that overrides it.
+type PortRegistry struct {
+	Instances map[string]map[string]string `json:"instances"`
+}
+
+// PortAllocation is one registry entry. Version is empty for the port of
+// the shared env.properties of the module.
+type PortAllocation struct {
+	Instance string
+	Module   string
+	Version  string
+	Port     string
+}
+
+// portRegistryKey returns the registry key of the port of module, or of one
+// of its versions.
+func portRegistryKey(module, version string) string {
+	if version = strings.TrimSpace(version); version != "" {
+		return module + "/" + version
+	}
+	return module
+}
+
+// describePortRegistryKey returns the module of a registry key, followed by
+// the version when there is one.
+func describePortRegistryKey(key string) string {
+	module, version, _ := strings.Cut(key, "/")
+	return strings.TrimSpace(module + " " + version)
+}
+
+// PortRegistryPath returns the registry file shared by all instances using the runtime directory.
+func PortRegistryPath() string {
+	return PortRegistryPathIn(GetRuntimeDir())
+}
+
+// PortRegistryPathIn returns the registry file of runtimeDir, which may belong
+// to an instance other than the selected one.
+func PortRegistryPathIn(runtimeDir string) string {
+	return filepath.Join(runtimeDir, "port-registry.json")
+}
+
+// CurrentInstanceName returns the instance selected for this process.
+func CurrentInstanceName() string {
+	if instance := strings.TrimSpace(os.Getenv("CONTROLPANEL_INSTANCE")); instance != "" {
+		return instance
+	}
+	return MainInstanceName
+}
+
+// LoadPortRegistry reads the registry; a missing file is a registry holding
+// only the reserved ports of the main instance.
+func LoadPortRegistry() (*PortRegistry, error) {
+	return loadPortRegistry(PortRegistryPath())
+}
+
+func loadPortRegistry(path string) (*PortRegistry, error) {
+	registry := &PortRegistry{Instances: map[string]map[string]string{}}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read port registry: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, registry); err != nil {
+			return nil, fmt.Errorf("parse port registry %s: %w", path, err)
+		}
+	}
+	if registry.Instances == nil {
+		registry.Instances = map[string]map[string]string{}
+	}
+	reserveMainInstancePorts(registry)
+	return registry, nil
+}
+
+// reserveMainInstancePorts gives the main instance DefaultModulePorts until it
+// claims ports of its own: it works without ever being initialized, so its
+// ports would otherwise be free for the first other instance.
+func reserveMainInstancePorts(registry *PortRegistry) {
+	if len(registry.Instances[MainInstanceName]) > 0 {
+		return
+	}
+	ports := make(map[string]string, len(DefaultModulePorts))
+	for module, port := range DefaultModulePorts {
+		ports[module] = strconv.Itoa(port)
+	}
+	registry.Instances[MainInstanceName] = ports
+}
+
+// updatePortRegistry applies update to the registry under a file lock so that
+// concurrent control panels do not overwrite each other's claims.
+func updatePortRegistry(update func(*PortRegistry) error) error {
+	return updatePortRegistryFile(PortRegistryPath(), update)
+}
+
+// updatePortRegistryFile is updatePortRegistry for the registry at path.
+func updatePortRegistryFile(path string, update func(*PortRegistry) error) error {
+	if err := EnsureDir0755(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("creating port registry directory: %w", err)
+	}
+
+	lock := flock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("lock port registry: %w", err)
+	}
+	defer lock.Unlock()
+
+	registry, err := loadPortRegistry(path)
+	if err != nil {
+		return err
+	}
+	if err := update(registry); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal port registry: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0644); err != nil {
+		return fmt.Errorf("write port registry temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("replace port registry: %w", err)
+	}
+	return nil
+}
+
+// Owner returns the instance and module holding port, other than module of
+// instance itself: the versions of a module never run at the same time, so
+// they may share a port. A module of SharedModulePorts shares its ports with
+// itself in every instance, and its default port is held by it, with
+// AllInstances as the instance. Both are empty when the port is available;
+// the module is followed by the version that claimed the port, if any.
+func (r *PortRegistry) Owner(port, instance, module string) (string, string) {
+	port = strings.TrimSpace(port)
+	_, shared := SharedModulePorts[module]
+	for ownerInstance, keys := range r.Instances {
+		for key, ownerPort := range keys {
+			if ownerPort != port {
+				continue
+			}
+			if ownerModule, _, _ := strings.Cut(key, "/"); ownerModule == module && (ownerInstance == instance || shared) {
+				continue
+			}
+			return ownerInstance, describePortRegistryKey(key)
+		}
+	}
+	for sharedModule, sharedPort := range SharedModulePorts {
+		if sharedModule != module && strconv.Itoa(sharedPort) == port {
+			return AllInstances, sharedModule
+		}
+	}
+	return "", ""
+}
+
+// Allocations lists every claimed port, sorted by instance then module.
+func (r *PortRegistry) Allocations() []PortAllocation {
+	var allocations []PortAllocation
+	for instance, keys := range r.Instances {
+		for key, port := range keys {
+			module, version, _ := strings.Cut(key, "/")
+			allocations = append(allocations, PortAllocation{Instance: instance, Module: module, Version: version, Port: port})
+		}
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		if allocations[i].Instance != allocations[j].Instance {
+			return allocations[i].Instance < allocations[j].Instance
+		}
+		if allocations[i].Module != allocations[j].Module {
+			return allocations[i].Module < allocations[j].Module
+		}
+		return allocations[i].Version < allocations[j].Version
+	})
+	return allocations
+}
+
+func (r *PortRegistry) claim(instance, module, version, port string) error {
+	if ownerInstance, ownerModule := r.Owner(port, instance, module); ownerInstance != "" {
+		return fmt.Errorf("port %s is already allocated to %s", port, DescribePortOwner(ownerInstance, ownerModule))
+	}
+	if r.Instances[instance] == nil {
+		r.Instances[instance] = map[string]string{}
+	}
+	r.Instances[instance][portRegistryKey(module, version)] = port
+	return nil
+}
+
+// ClaimPort records port for module of the current instance: the port of
+// its shared env.properties when version is empty, otherwise the port of
+// that version. It fails when another module or instance holds the port.
+func ClaimPort(module, version, port string) error {
+	return ClaimInstancePort(CurrentInstanceName(), module, version, port)
+}
+
+// ClaimInstancePort records port for module, or a version of it, of instance.
+func ClaimInstancePort(instance, module, version, port string) error {
+	port = strings.TrimSpace(port)
+	if port == "" {
+		return fmt.Errorf("port is required")
+	}
+	return updatePortRegistry(func(registry *PortRegistry) error {
+		return registry.claim(instance, module, version, port)
+	})
+}
+
+// ReleaseVersionPort removes the port claimed by a version of module of the
+// current instance, once that version is removed.
+func ReleaseVersionPort(module, version string) error {
+	if strings.TrimSpace(version) == "" {
+		return nil
+	}
+	return updatePortRegistry(func(registry *PortRegistry) error {
+		delete(registry.Instances[CurrentInstanceName()], portRegistryKey(module, version))
+		return nil
+	})
+}
+
+// IsPortClaimedByOther returns true when port belongs to another module or
+// instance than module of the current instance.
+func IsPortClaimedByOther(module, port string) bool {
+	registry, err := LoadPortRegistry()
+	if err != nil {
+		return false
+	}
+	ownerInstance, _ := registry.Owner(port, CurrentInstanceName(), module)
+	return ownerInstance != ""
+}
+
+// AllocatePortBlock claims the first block of DefaultModulePorts, shifted by a
+// multiple of PortBlockSize, in which no port belongs to another instance.
+func AllocatePortBlock(instance string) (map[string]string, error) {
+	highest := 0
+	for _, port := range DefaultModulePorts {
+		if port > highest {
+			highest = port
+		}
+	}
+
+	var block map[string]string
+	err := updatePortRegistry(func(registry *PortRegistry) error {
+		for offset := 0; highest+offset <= 65535; offset += PortBlockSize {
+			candidate := make(map[string]string, len(DefaultModulePorts))
+			free := true
+			for module, port := range DefaultModulePorts {
+				candidate[module] = strconv.Itoa(port + offset)
+				if ownerInstance, _ := registry.Owner(candidate[module], instance, module); ownerInstance != "" {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+			for module, port := range candidate {
+				if err := registry.claim(instance, module, "", port); err != nil {
+					return err
+				}
+			}
+			block = candidate
+			return nil
+		}
+		return fmt.Errorf("no free port block left for instance %q", instance)
+	})
+	return block, err
+}
+
+// RenameInstancePorts moves the ports claimed by oldInstance to newInstance in
+// the registry at registryPath.
+func RenameInstancePorts(registryPath, oldInstance, newInstance string) error {
+	return updatePortRegistryFile(registryPath, func(registry *PortRegistry) error {
+		modules, ok := registry.Instances[oldInstance]
+		if !ok {
+			return nil
+		}
+		if _, taken := registry.Instances[newInstance]; taken {
+			return fmt.Errorf("instance %q already has ports in the registry", newInstance)
+		}
+		registry.Instances[newInstance] = modules
+		delete(registry.Instances, oldInstance)
+		return nil
+	})
+}
+
+// ReleaseInstancePorts removes every port claimed by instance from the
+// registry at registryPath.
+func ReleaseInstancePorts(registryPath, instance string) error {
+	return updatePortRegistryFile(registryPath, func(registry *PortRegistry) error {
+		delete(registry.Instances, instance)
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllocatePortBlockSkipsBlocksOfOtherInstances(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+
+	mainBlock, err := AllocatePortBlock(MainInstanceName)
+	if err != nil {
+		t.Fatalf("allocate main block: %v", err)
+	}
+	if mainBlock["owlcms"] != "8080" || mainBlock["tracker"] != "8096" {
+		t.Fatalf("unexpected main block: %v", mainBlock)
+	}
+
+	recordsBlock, err := AllocatePortBlock("records")
+	if err != nil {
+		t.Fatalf("allocate records block: %v", err)
+	}
+	if recordsBlock["owlcms"] != "8180" || recordsBlock["tracker"] != "8196" {
+		t.Fatalf("unexpected records block: %v", recordsBlock)
+	}
+
+	again, err := AllocatePortBlock(MainInstanceName)
+	if err != nil {
+		t.Fatalf("reallocate main block: %v", err)
+	}
+	if again["owlcms"] != "8080" {
+		t.Fatalf("expected main instance to keep its block, got %v", again)
+	}
+}
+
+func TestClaimInstancePortRejectsPortOfAnotherInstance(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+
+	if err := ClaimInstancePort("records", "owlcms", "", "8180"); err != nil {
+		t.Fatalf("claim records port: %v", err)
+	}
+	if err := ClaimInstancePort(MainInstanceName, "tracker", "", "8180"); err == nil {
+		t.Fatal("expected claim of a port held by another instance to fail")
+	}
+	if err := ClaimInstancePort("records", "owlcms", "", "8181"); err != nil {
+		t.Fatalf("moving records owlcms port: %v", err)
+	}
+	if err := ClaimInstancePort(MainInstanceName, "tracker", "", "8180"); err != nil {
+		t.Fatalf("expected released port to be claimable: %v", err)
+	}
+
+	registry, err := LoadPortRegistry()
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	allocations := registry.Allocations()
+	if len(allocations) != 3 || allocations[0].Instance != MainInstanceName || allocations[1].Port != "8180" || allocations[2].Port != "8181" {
+		t.Fatalf("unexpected allocations: %+v", allocations)
+	}
+}
+
+func TestRenameAndReleaseInstancePorts(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+
+	if _, err := AllocatePortBlock("records"); err != nil {
+		t.Fatalf("allocate records block: %v", err)
+	}
+	if err := RenameInstancePorts(PortRegistryPath(), "records", "masters"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	registry, err := LoadPortRegistry()
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	if _, ok := registry.Instances["records"]; ok {
+		t.Fatalf("records still has ports: %v", registry.Instances)
+	}
+	if registry.Instances["masters"]["owlcms"] != "8180" {
+		t.Fatalf("masters did not keep the block: %v", registry.Instances)
+	}
+
+	if err := ReleaseInstancePorts(PortRegistryPath(), "masters"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	registry, err = LoadPortRegistry()
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	if len(registry.Instances) != 1 || registry.Instances[MainInstanceName]["owlcms"] != "8080" {
+		t.Fatalf("expected only the main instance reservation, got %v", registry.Instances)
+	}
+}
+
+func TestMainInstanceReservesDefaultPortsUntilItClaimsItsOwn(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+
+	if err := ClaimInstancePort("records", "owlcms", "", "8080"); err == nil {
+		t.Fatal("expected the default port of the uninitialized main instance to be reserved")
+	}
+	if err := ClaimInstancePort(MainInstanceName, "owlcms", "", "9080"); err != nil {
+		t.Fatalf("claim main port: %v", err)
+	}
+	if err := ClaimInstancePort("records", "owlcms", "", "8080"); err != nil {
+		t.Fatalf("expected the default port to be free once main claims its own: %v", err)
+	}
+}
+
+func TestReleaseClaimKeepsTheSharedPort(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+	t.Setenv("CONTROLPANEL_INSTANCE", "records")
+
+	if err := ClaimPort("owlcms", "", "8180"); err != nil {
+		t.Fatalf("claim shared port: %v", err)
+	}
+	if err := ClaimPort("owlcms", "65.0.0", "8181"); err != nil {
+		t.Fatalf("claim release port: %v", err)
+	}
+	if err := ClaimInstancePort("masters", "tracker", "", "8180"); err == nil {
+		t.Fatal("expected the shared port to stay claimed after a release override")
+	}
+	if err := ClaimInstancePort("masters", "tracker", "", "8181"); err == nil {
+		t.Fatal("expected the release port to be claimed")
+	}
+	if err := ClaimPort("owlcms", "66.0.0", "8180"); err != nil {
+		t.Fatalf("versions of a module may share a port: %v", err)
+	}
+
+	if err := ReleaseVersionPort("owlcms", "65.0.0"); err != nil {
+		t.Fatalf("release version port: %v", err)
+	}
+	registry, err := LoadPortRegistry()
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	ports := registry.Instances["records"]
+	if ports["owlcms"] != "8180" || ports["owlcms/66.0.0"] != "8180" || ports["owlcms/65.0.0"] != "" {
+		t.Fatalf("unexpected records ports: %v", ports)
+	}
+	if owner, module := registry.Owner("8180", "masters", "tracker"); owner != "records" || (module != "owlcms" && module != "owlcms 66.0.0") {
+		t.Fatalf("unexpected owner %q %q", owner, module)
+	}
+}
+
+func TestSharedModulePortsAreReservedAndSharedByInstances(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+
+	err := ClaimInstancePort("records", "owlcms", "", "8090")
+	if err == nil || !strings.Contains(err.Error(), "firmata, which all instances share") {
+		t.Fatalf("expected the firmata default port to be refused, got %v", err)
+	}
+
+	if err := ClaimInstancePort("records", "firmata", "2.1.0", "8091"); err != nil {
+		t.Fatalf("claim firmata port: %v", err)
+	}
+	if err := ClaimInstancePort(MainInstanceName, "firmata", "2.1.0", "8091"); err != nil {
+		t.Fatalf("expected the shared firmata install to keep its port in another instance: %v", err)
+	}
+	if err := ClaimInstancePort(MainInstanceName, "tracker", "", "8091"); err == nil {
+		t.Fatal("expected the firmata port to be refused to the tracker")
+	}
+}
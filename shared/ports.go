@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// AutoPortEnv enables launching on the next free port instead of stopping
+// whatever already listens on the configured one. It is a control panel
+// setting.
+const AutoPortEnv = "CONTROLPANEL_AUTO_PORT"
+
+// autoPortOverride holds the free-port choice of the current process, set
+// by --auto-port or the menu toggle; nil defers to the control panel setting.
+var autoPortOverride atomic.Pointer[bool]
+
+// maxPortSearch bounds how many ports FindFreePort tries above the configured one.
+const maxPortSearch = 100
+
+// IsAutoPortEnabled returns true when busy ports should be replaced by the next free one.
+func IsAutoPortEnabled() bool {
+	if enabled := autoPortOverride.Load(); enabled != nil {
+		return *enabled
+	}
+	value := strings.ToLower(ControlPanelSetting(AutoPortEnv))
+	return value == "1" || value == "true" || value == "yes" || value == "on"
+}
+
+// SetAutoPortEnabled changes the free-port choice of the current process
+// only, so runtime behavior can change immediately.
+func SetAutoPortEnabled(enabled bool) error {
+	autoPortOverride.Store(&enabled)
+	return nil
+}
+
+// SaveAutoPortEnabled persists the free-port setting of the instance and
+// applies it to the current process.
+func SaveAutoPortEnabled(enabled bool) error {
+	if err := SaveControlPanelSettings(map[string]string{AutoPortEnv: strconv.FormatBool(enabled)}); err != nil {
+		return err
+	}
+	return SetAutoPortEnabled(enabled)
+}
+
+// FindFreePort returns port when nothing listens on it and it can be bound,
+// otherwise the first such port above it.
+func FindFreePort(port string) (string, error) {
+	return findFreePort(port, nil)
+}
+
+// FindFreeModulePort is FindFreePort that also skips ports the shared port
+// registry has allocated to other modules or instances.
+func FindFreeModulePort(module, port string) (string, error) {
+	return findFreePort(port, func(candidate string) bool {
+		return IsPortClaimedByOther(module, candidate)
+	})
+}
+
+// SelectFreeModulePort keeps the current port of a module version when it is
+// free. Otherwise it claims the next free port for the version, stores it
+// with save, leaving the program on the busy port alone, and returns the
+// port to launch on.
+func SelectFreeModulePort(module, version, current string, save func(port string) error) (string, error) {
+	port, err := FindFreeModulePort(module, current)
+	if err != nil {
+		return "", err
+	}
+	if port == current {
+		return current, nil
+	}
+	if err := ClaimPort(module, version, port); err != nil {
+		return "", err
+	}
+	if err := save(port); err != nil {
+		return "", err
+	}
+	log.Printf("Port %s is in use; %s will use port %s", current, module, port)
+	return port, nil
+}
+
+func findFreePort(port string, reserved func(string) bool) (string, error) {
+	start, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || start < 1 || start > 65535 {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	for candidate := start; candidate <= 65535 && candidate < start+maxPortSearch; candidate++ {
+		candidatePort := strconv.Itoa(candidate)
+		if reserved != nil && reserved(candidatePort) {
+			continue
+		}
+		if CheckPort(candidatePort) == nil {
+			continue
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort("", candidatePort))
+		if err != nil {
+			continue
+		}
+		listener.Close()
+		return candidatePort, nil
+	}
+	return "", fmt.Errorf("no free port found between %d and %d", start, start+maxPortSearch-1)
+}
@@ -0,0 +1,75 @@
+package shared
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestFindFreePortSkipsListeningPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen on test port: %v", err)
+	}
+	defer listener.Close()
+
+	busy := listener.Addr().(*net.TCPAddr).Port
+	port, err := FindFreePort(strconv.Itoa(busy))
+	if err != nil {
+		t.Fatalf("FindFreePort returned error: %v", err)
+	}
+	if port == strconv.Itoa(busy) {
+		t.Fatalf("FindFreePort returned busy port %s", port)
+	}
+	if got, _ := strconv.Atoi(port); got <= busy {
+		t.Fatalf("expected a port above %d, got %s", busy, port)
+	}
+}
+
+func TestFindFreePortRejectsInvalidPort(t *testing.T) {
+	for _, port := range []string{"", "abc", "0", "70000"} {
+		if _, err := FindFreePort(port); err == nil {
+			t.Fatalf("expected error for %q", port)
+		}
+	}
+}
+
+func TestFindFreeModulePortSkipsPortsOfOtherInstances(t *testing.T) {
+	t.Setenv("RUNTIME_DIR", t.TempDir())
+	t.Setenv("CONTROLPANEL_INSTANCE", "")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen on test port: %v", err)
+	}
+	start := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	if err := ClaimInstancePort("records", "owlcms", "", strconv.Itoa(start)); err != nil {
+		t.Fatalf("claim records port: %v", err)
+	}
+	port, err := FindFreeModulePort("owlcms", strconv.Itoa(start))
+	if err != nil {
+		t.Fatalf("FindFreeModulePort returned error: %v", err)
+	}
+	if port == strconv.Itoa(start) {
+		t.Fatalf("FindFreeModulePort returned port %s allocated to another instance", port)
+	}
+}
+
+func TestAutoPortSettingIsStoredForTheControlPanel(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	t.Setenv(AutoPortEnv, "")
+	t.Cleanup(func() { autoPortOverride.Store(nil) })
+
+	if err := SaveAutoPortEnabled(true); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	autoPortOverride.Store(nil)
+	if !IsAutoPortEnabled() {
+		t.Fatal("expected the stored setting to enable free ports")
+	}
+	if err := SetAutoPortEnabled(false); err != nil || IsAutoPortEnabled() {
+		t.Fatalf("expected the process choice to win, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+//go:build linux
+
+package shared
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StartZombieReaper reaps the exited children of this process that nobody
+// waits for, as PID 1 of a container must: processes orphaned by OWLCMS or
+// the Tracker are reparented to it. Children for which managed returns true
+// are left to their own cmd.Wait(). The returned function stops the reaper.
+func StartZombieReaper(managed func(pid int) bool) func() {
+	sigChan := make(chan os.Signal, 8)
+	signal.Notify(sigChan, syscall.SIGCHLD)
+	stop := make(chan struct{})
+
+	go func() {
+		// SIGCHLD is coalesced, so also sweep periodically.
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			for _, pid := range zombieChildren(os.Getpid()) {
+				if managed(pid) {
+					continue
+				}
+				var status syscall.WaitStatus
+				if reaped, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil); err == nil && reaped == pid {
+					log.Printf("Reaped orphaned process %d (exit status %d)", pid, status.ExitStatus())
+				}
+			}
+			select {
+			case <-stop:
+				return
+			case <-sigChan:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigChan)
+		close(stop)
+	}
+}
+
+// zombieChildren lists the children of parent that have exited but were not
+// waited for yet.
+func zombieChildren(parent int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var zombies []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		state, ppid, err := readProcessStateAndParent(pid)
+		if err == nil && state == "Z" && ppid == parent {
+			zombies = append(zombies, pid)
+		}
+	}
+	return zombies
+}
+
+func readProcessStateAndParent(pid int) (string, int, error) {
+	content, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", 0, err
+	}
+	statLine := strings.TrimSpace(string(content))
+	idx := strings.LastIndex(statLine, ")")
+	if idx == -1 || idx+2 >= len(statLine) {
+		return "", 0, fmt.Errorf("unexpected /proc stat format for pid %d", pid)
+	}
+	fields := strings.Fields(statLine[idx+2:])
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("missing parent in /proc stat for pid %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("parse parent for pid %d: %w", pid, err)
+	}
+	return fields[0], ppid, nil
+}
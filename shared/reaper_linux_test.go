@@ -0,0 +1,39 @@
+//go:build linux
+
+package shared
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStartZombieReaperSkipsManagedChildren(t *testing.T) {
+	orphan := exec.Command("true")
+	if err := orphan.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	managed := exec.Command("true")
+	if err := managed.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	managedPID := managed.Process.Pid
+
+	stop := StartZombieReaper(func(pid int) bool { return pid == managedPID })
+	defer stop()
+
+	deadline := time.Now().Add(45 * time.Second)
+	for time.Now().Before(deadline) {
+		state, _, err := readProcessStateAndParent(orphan.Process.Pid)
+		if err != nil || state != "Z" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if state, _, err := readProcessStateAndParent(orphan.Process.Pid); err == nil && state == "Z" {
+		t.Fatal("unmanaged zombie was not reaped")
+	}
+	if err := managed.Wait(); err != nil {
+		t.Fatalf("managed child was reaped by the reaper: %v", err)
+	}
+}
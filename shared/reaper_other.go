@@ -0,0 +1,9 @@
+//go:build !linux
+
+package shared
+
+// StartZombieReaper does nothing outside Linux, where containers do not run
+// the control panel as PID 1.
+func StartZombieReaper(managed func(pid int) bool) func() {
+	return func() {}
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// RuntimeUsage is what the module versions of every instance sharing the
+// runtime directory need, so that a cleanup started from one instance keeps
+// the builds the others run on.
+type RuntimeUsage struct {
+	Required map[string][]string // TEMURIN_VERSION and NODE_VERSION values by runtime kind
+	UsedBy   map[string][]string // module versions and running processes by build path
+}
+
+// RuntimeCleanupItem is a runtime build that cleanup keeps or removes, and why.
+type RuntimeCleanupItem struct {
+	Runtime InstalledRuntime
+	Remove  bool
+	Reason  string
+}
+
+// PlanRuntimeCleanup decides which builds of kind a cleanup removes. The
+// newest complete build is kept, as the launchers pick it for any required
+// version it satisfies, and so is every build in usage.UsedBy.
+func PlanRuntimeCleanup(kind string, usage RuntimeUsage) ([]RuntimeCleanupItem, error) {
+	runtimes, err := ListInstalledRuntimes()
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *InstalledRuntime
+	for i := range runtimes {
+		if runtimes[i].Kind == kind && runtimes[i].Executable != "" {
+			newest = &runtimes[i]
+			break
+		}
+	}
+
+	var plan []RuntimeCleanupItem
+	for _, runtime := range runtimes {
+		if runtime.Kind != kind {
+			continue
+		}
+		item := RuntimeCleanupItem{Runtime: runtime}
+		users := usage.UsedBy[runtime.Path]
+		switch {
+		case len(users) > 0:
+			item.Reason = "used by " + strings.Join(users, ", ")
+		case newest != nil && runtime.Path == newest.Path:
+			item.Reason = "newest " + kind + " build"
+		case runtime.Executable == "":
+			item.Remove = true
+			item.Reason = "incomplete, no executable found"
+		default:
+			item.Remove = true
+			item.Reason = fmt.Sprintf("superseded by %s and not used by any instance", newest.Version)
+		}
+		plan = append(plan, item)
+	}
+	return plan, nil
+}
+
+// removePlannedRuntimes deletes the builds the plan removes, or only
+// describes them when dryRun is set.
+func removePlannedRuntimes(plan []RuntimeCleanupItem, dryRun bool) ([]string, error) {
+	var removed []string
+	for _, item := range plan {
+		if !item.Remove {
+			continue
+		}
+		if dryRun {
+			removed = append(removed, fmt.Sprintf("Would remove %s %s (%s)", item.Runtime.Kind, item.Runtime.Version, item.Reason))
+			continue
+		}
+		log.Printf("Removing %s %s: %s\n", item.Runtime.Kind, item.Runtime.Version, item.Reason)
+		if err := os.RemoveAll(item.Runtime.Path); err != nil {
+			return removed, fmt.Errorf("removing %s: %w", item.Runtime.Version, err)
+		}
+		removed = append(removed, fmt.Sprintf("%s %s (%s)", item.Runtime.Kind, item.Runtime.Version, item.Reason))
+	}
+	return removed, nil
+}
+
+// appendUnique adds the values of extra that are not in values yet.
+func appendUnique(values []string, extra ...string) []string {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[value] = true
+	}
+	for _, value := range extra {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
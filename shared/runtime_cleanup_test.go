@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanRuntimeCleanupKeepsNewestAndUsedBuilds(t *testing.T) {
+	if GetGoos() == "windows" {
+		t.Skip("executable names differ on Windows")
+	}
+	runtimeDir := t.TempDir()
+	t.Setenv("RUNTIME_DIR", runtimeDir)
+
+	for _, version := range []string{"jdk-25.0.1+8", "jdk-25.0.0+36", "jdk-21.0.5+11"} {
+		writeRuntimeFile(t, filepath.Join(runtimeDir, "java", version, version, "bin", "java"), 1)
+	}
+	if err := os.MkdirAll(filepath.Join(runtimeDir, "java", "jdk-17"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	running := filepath.Join(runtimeDir, "java", "jdk-25.0.0+36")
+
+	plan, err := PlanRuntimeCleanup(RuntimeJava, RuntimeUsage{UsedBy: map[string][]string{
+		running: {"running owlcms 64.0.1 [records]"},
+	}})
+	if err != nil {
+		t.Fatalf("plan cleanup: %v", err)
+	}
+
+	decisions := map[string]RuntimeCleanupItem{}
+	for _, item := range plan {
+		decisions[item.Runtime.Version] = item
+	}
+	if item := decisions["jdk-25.0.1+8"]; item.Remove {
+		t.Fatalf("expected newest build to be kept: %+v", item)
+	}
+	if item := decisions["jdk-25.0.0+36"]; item.Remove || !strings.Contains(item.Reason, "[records]") {
+		t.Fatalf("expected build used by another instance to be kept: %+v", item)
+	}
+	if item := decisions["jdk-21.0.5+11"]; !item.Remove || !strings.Contains(item.Reason, "jdk-25.0.1+8") {
+		t.Fatalf("expected unused older build to be removed: %+v", item)
+	}
+	if item := decisions["jdk-17"]; !item.Remove || !strings.Contains(item.Reason, "incomplete") {
+		t.Fatalf("expected incomplete build to be removed: %+v", item)
+	}
+
+	dryRun, err := removePlannedRuntimes(plan, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(dryRun) != 2 || !strings.HasPrefix(dryRun[0], "Would remove") {
+		t.Fatalf("unexpected dry run %v", dryRun)
+	}
+	if _, err := os.Stat(filepath.Join(runtimeDir, "java", "jdk-21.0.5+11")); err != nil {
+		t.Fatalf("dry run must not remove anything: %v", err)
+	}
+
+	if _, err := removePlannedRuntimes(plan, false); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	remaining, err := ListInstalledRuntimes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected the newest and the running build to remain, got %+v", remaining)
+	}
+}
@@ -0,0 +1,256 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Settings of the runtime policy, read from the process environment first and
+// then from the env.properties of the instance's control panel directory.
+const (
+	RuntimePolicyKey   = "RUNTIME_POLICY"
+	RuntimeJavaPathKey = "RUNTIME_JAVA_PATH"
+	RuntimeNodePathKey = "RUNTIME_NODE_PATH"
+)
+
+// Runtime policy modes.
+const (
+	// RuntimePolicyBundled runs on the builds of the runtime directory,
+	// downloading them when missing. This is the default.
+	RuntimePolicyBundled = "bundled"
+	// RuntimePolicyPreferSystem runs on the system Java or Node.js when it is
+	// recent enough, and on the runtime directory otherwise.
+	RuntimePolicyPreferSystem = "prefer-system"
+	// RuntimePolicySystem runs only on the system Java or Node.js and never downloads.
+	RuntimePolicySystem = "system"
+	// RuntimePolicyPath runs only on RUNTIME_JAVA_PATH and RUNTIME_NODE_PATH and never downloads.
+	RuntimePolicyPath = "path"
+)
+
+// RuntimePolicyModes lists the runtime policy modes.
+var RuntimePolicyModes = []string{RuntimePolicyBundled, RuntimePolicyPreferSystem, RuntimePolicySystem, RuntimePolicyPath}
+
+// RuntimePolicy chooses where OWLCMS, firmata and the Tracker find Java and Node.js.
+type RuntimePolicy struct {
+	Mode     string
+	JavaPath string
+	NodePath string
+}
+
+// ParseRuntimePolicyMode validates a policy mode; empty means bundled.
+func ParseRuntimePolicyMode(value string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(value))
+	if mode == "" {
+		return RuntimePolicyBundled, nil
+	}
+	for _, known := range RuntimePolicyModes {
+		if mode == known {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s %q; use %s", RuntimePolicyKey, value, strings.Join(RuntimePolicyModes, ", "))
+}
+
+// LoadRuntimePolicy returns the runtime policy of the current instance.
+func LoadRuntimePolicy() (RuntimePolicy, error) {
+	props, err := MergeEnvironmentProperties(filepath.Join(GetControlPanelInstallDir(), "env.properties"), "")
+	if err != nil {
+		return RuntimePolicy{Mode: RuntimePolicyBundled}, err
+	}
+	value := func(key string) string {
+		if fromEnv := strings.TrimSpace(os.Getenv(key)); fromEnv != "" {
+			return fromEnv
+		}
+		return strings.TrimSpace(props.GetString(key, ""))
+	}
+
+	mode, err := ParseRuntimePolicyMode(value(RuntimePolicyKey))
+	if err != nil {
+		return RuntimePolicy{Mode: RuntimePolicyBundled}, err
+	}
+	return RuntimePolicy{Mode: mode, JavaPath: value(RuntimeJavaPathKey), NodePath: value(RuntimeNodePathKey)}, nil
+}
+
+// AllowsDownloads reports whether missing runtimes may be downloaded.
+func (p RuntimePolicy) AllowsDownloads() bool {
+	return p.Mode == RuntimePolicyBundled || p.Mode == RuntimePolicyPreferSystem
+}
+
+// CheckRuntimeDownloadAllowed returns cause, explaining why no runtime was
+// found, when the runtime policy of the instance forbids downloading one.
+func CheckRuntimeDownloadAllowed(cause error) error {
+	policy, err := LoadRuntimePolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.AllowsDownloads() {
+		return fmt.Errorf("%w; %s=%s does not allow downloading a runtime", cause, RuntimePolicyKey, policy.Mode)
+	}
+	return nil
+}
+
+// FindJavaForVersion returns the Java executable for a Temurin version allowed
+// by the runtime policy of the instance. System and explicit Java must be at
+// least the major version of temurinVersion.
+func FindJavaForVersion(temurinVersion string, goosFunc func() string) (string, error) {
+	policy, err := LoadRuntimePolicy()
+	if err != nil {
+		return "", err
+	}
+
+	switch policy.Mode {
+	case RuntimePolicyPreferSystem:
+		if javaPath, err := FindSystemJava(goosFunc); err == nil {
+			err = checkJavaCompatible(javaPath, temurinVersion)
+			if err == nil {
+				log.Printf("*** Using system Java at: %s\n", javaPath)
+				return javaPath, nil
+			}
+			log.Printf("*** Not using system Java: %v\n", err)
+		}
+		return FindLocalJavaForVersion(temurinVersion, goosFunc)
+	case RuntimePolicySystem:
+		javaPath, err := FindSystemJava(goosFunc)
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: %w (set JAVA_HOME or add java to PATH)", RuntimePolicyKey, policy.Mode, err)
+		}
+		return javaPath, checkJavaCompatible(javaPath, temurinVersion)
+	case RuntimePolicyPath:
+		if policy.JavaPath == "" {
+			return "", fmt.Errorf("%s=%s requires %s", RuntimePolicyKey, policy.Mode, RuntimeJavaPathKey)
+		}
+		if _, err := os.Stat(policy.JavaPath); err != nil {
+			return "", fmt.Errorf("%s: %w", RuntimeJavaPathKey, err)
+		}
+		return policy.JavaPath, checkJavaCompatible(policy.JavaPath, temurinVersion)
+	}
+	return FindLocalJavaForVersion(temurinVersion, goosFunc)
+}
+
+// checkJavaCompatible verifies that javaPath is at least the major version of temurinVersion.
+func checkJavaCompatible(javaPath, temurinVersion string) error {
+	required, err := ExtractMajorVersion(temurinVersion)
+	if err != nil {
+		required = 0
+	}
+	// javaw has no console output.
+	versionPath := javaPath
+	if strings.HasSuffix(versionPath, "javaw.exe") {
+		versionPath = strings.TrimSuffix(versionPath, "javaw.exe") + "java.exe"
+	} else if strings.HasSuffix(versionPath, "javaw") {
+		versionPath = strings.TrimSuffix(versionPath, "javaw") + "java"
+	}
+	actual, err := GetJavaVersion(versionPath)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", javaPath, err)
+	}
+	if actual < required {
+		return fmt.Errorf("%s is Java %d, but TEMURIN_VERSION %s requires Java %d or later", javaPath, actual, temurinVersion, required)
+	}
+	return nil
+}
+
+// FindSystemNode finds node on the PATH.
+func FindSystemNode() (string, error) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		return "", fmt.Errorf("node executable not found")
+	}
+	return nodePath, nil
+}
+
+// FindNodeForVersion returns the Node.js executable allowed by the runtime
+// policy of the instance. System and explicit Node.js must be at least version
+// when it is set.
+func FindNodeForVersion(version string, goosFunc func() string) (string, error) {
+	policy, err := LoadRuntimePolicy()
+	if err != nil {
+		return "", err
+	}
+
+	switch policy.Mode {
+	case RuntimePolicyPreferSystem:
+		if nodePath, err := FindSystemNode(); err == nil {
+			err = checkNodeCompatible(nodePath, version)
+			if err == nil {
+				log.Printf("*** Using system Node.js at: %s\n", nodePath)
+				return nodePath, nil
+			}
+			log.Printf("*** Not using system Node.js: %v\n", err)
+		}
+		return FindLocalNodeForVersion(version, goosFunc)
+	case RuntimePolicySystem:
+		nodePath, err := FindSystemNode()
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: %w (add node to PATH)", RuntimePolicyKey, policy.Mode, err)
+		}
+		return nodePath, checkNodeCompatible(nodePath, version)
+	case RuntimePolicyPath:
+		if policy.NodePath == "" {
+			return "", fmt.Errorf("%s=%s requires %s", RuntimePolicyKey, policy.Mode, RuntimeNodePathKey)
+		}
+		if _, err := os.Stat(policy.NodePath); err != nil {
+			return "", fmt.Errorf("%s: %w", RuntimeNodePathKey, err)
+		}
+		return policy.NodePath, checkNodeCompatible(policy.NodePath, version)
+	}
+	return FindLocalNodeForVersion(version, goosFunc)
+}
+
+// checkNodeCompatible verifies that nodePath is at least NODE_VERSION.
+func checkNodeCompatible(nodePath, version string) error {
+	cmd := exec.Command(nodePath, "--version")
+	ConfigureNoConsoleWindow(cmd)
+	done := make(chan struct{})
+	var output []byte
+	var err error
+	go func() {
+		output, err = cmd.Output()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		if cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+		<-done
+		return fmt.Errorf("%s --version did not answer within 30s", nodePath)
+	}
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", nodePath, err)
+	}
+	actual := strings.TrimSpace(string(output))
+	if !NodeVersionAtLeast(actual, version) {
+		return fmt.Errorf("%s is Node.js %s, but NODE_VERSION requires %s or later", nodePath, actual, version)
+	}
+	return nil
+}
+
+// NodeVersionAtLeast reports whether actual is required or later. An empty or
+// unparsable requirement is met by any version.
+func NodeVersionAtLeast(actual, required string) bool {
+	if strings.TrimSpace(required) == "" {
+		return true
+	}
+	reqMajor, reqMinor, reqPatch, err := ExtractNodeVersion(required)
+	if err != nil {
+		return true
+	}
+	major, minor, patch, err := ExtractNodeVersion(actual)
+	if err != nil {
+		return false
+	}
+	if major != reqMajor {
+		return major > reqMajor
+	}
+	if minor != reqMinor {
+		return minor > reqMinor
+	}
+	return patch >= reqPatch
+}
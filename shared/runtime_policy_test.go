@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRuntimePolicyReadsControlPanelEnvAndProcessOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONTROLPANEL_INSTALLDIR", dir)
+	t.Setenv(RuntimePolicyKey, "")
+	t.Setenv(RuntimeJavaPathKey, "")
+	t.Setenv(RuntimeNodePathKey, "")
+
+	policy, err := LoadRuntimePolicy()
+	if err != nil || policy.Mode != RuntimePolicyBundled || !policy.AllowsDownloads() {
+		t.Fatalf("expected bundled default, got %+v, %v", policy, err)
+	}
+
+	content := "RUNTIME_POLICY=System\nRUNTIME_JAVA_PATH=/opt/jdk/bin/java\n"
+	if err := os.WriteFile(filepath.Join(dir, "env.properties"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = LoadRuntimePolicy()
+	if err != nil || policy.Mode != RuntimePolicySystem || policy.JavaPath != "/opt/jdk/bin/java" || policy.AllowsDownloads() {
+		t.Fatalf("expected system policy from env.properties, got %+v, %v", policy, err)
+	}
+	if err := CheckRuntimeDownloadAllowed(os.ErrNotExist); err == nil || !strings.Contains(err.Error(), "RUNTIME_POLICY=system") {
+		t.Fatalf("expected downloads to be refused, got %v", err)
+	}
+
+	t.Setenv(RuntimePolicyKey, "prefer-system")
+	if policy, err = LoadRuntimePolicy(); err != nil || policy.Mode != RuntimePolicyPreferSystem {
+		t.Fatalf("expected process environment to override, got %+v, %v", policy, err)
+	}
+
+	t.Setenv(RuntimePolicyKey, "managed")
+	if _, err := LoadRuntimePolicy(); err == nil {
+		t.Fatal("expected unknown policy to be rejected")
+	}
+}
+
+func TestFindNodeForVersionChecksExplicitPath(t *testing.T) {
+	if GetGoos() == "windows" {
+		t.Skip("uses a shell script as node")
+	}
+	dir := t.TempDir()
+	t.Setenv("CONTROLPANEL_INSTALLDIR", dir)
+	node := filepath.Join(dir, "node")
+	if err := os.WriteFile(node, []byte("#!/bin/sh\necho v20.11.1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(RuntimePolicyKey, RuntimePolicyPath)
+	t.Setenv(RuntimeNodePathKey, node)
+
+	if path, err := FindNodeForVersion("v20.11.0", GetGoos); err != nil || path != node {
+		t.Fatalf("expected explicit node to satisfy v20.11.0, got %q, %v", path, err)
+	}
+	if _, err := FindNodeForVersion("v22.0.0", GetGoos); err == nil || !strings.Contains(err.Error(), "v20.11.1") {
+		t.Fatalf("expected explicit node to be too old for v22, got %v", err)
+	}
+
+	t.Setenv(RuntimeNodePathKey, "")
+	if _, err := FindNodeForVersion("", GetGoos); err == nil || !strings.Contains(err.Error(), RuntimeNodePathKey) {
+		t.Fatalf("expected missing RUNTIME_NODE_PATH to be reported, got %v", err)
+	}
+}
+
+func TestNodeVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		actual, required string
+		want             bool
+	}{
+		{"v22.11.0", "", true},
+		{"v22.11.0", "v22.11.0", true},
+		{"v22.11.0", "22.2.0", true},
+		{"v20.18.0", "v22.0.0", false},
+		{"v22.1.9", "v22.2.0", false},
+		{"garbage", "v18.0.0", false},
+	}
+	for _, c := range cases {
+		if got := NodeVersionAtLeast(c.actual, c.required); got != c.want {
+			t.Fatalf("NodeVersionAtLeast(%q, %q) = %v, want %v", c.actual, c.required, got, c.want)
+		}
+	}
+}
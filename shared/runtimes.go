@@ -0,0 +1,224 @@
+package shared
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runtime kinds, named after their directory under GetRuntimeDir().
+const (
+	RuntimeJava   = "java"
+	RuntimeNode   = "node"
+	RuntimeFFmpeg = "ffmpeg"
+)
+
+// RuntimeKinds lists the runtime kinds in display order.
+var RuntimeKinds = []string{RuntimeJava, RuntimeNode, RuntimeFFmpeg}
+
+// InstalledRuntime is one Java, Node.js or FFmpeg build in the runtime directory.
+type InstalledRuntime struct {
+	Kind       string
+	Version    string // directory name under <runtime dir>/<kind>
+	Path       string
+	Executable string // empty when the build is incomplete
+}
+
+// IsRuntimeKind reports whether kind is java, node or ffmpeg.
+func IsRuntimeKind(kind string) bool {
+	for _, k := range RuntimeKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ListInstalledRuntimes returns the runtimes installed under GetRuntimeDir(),
+// Java first, newest version first within a kind.
+func ListInstalledRuntimes() ([]InstalledRuntime, error) {
+	var runtimes []InstalledRuntime
+	for _, kind := range RuntimeKinds {
+		baseDir := filepath.Join(GetRuntimeDir(), kind)
+		entries, err := os.ReadDir(baseDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("read %s: %w", baseDir, err)
+		}
+		var found []InstalledRuntime
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(baseDir, entry.Name())
+			found = append(found, InstalledRuntime{
+				Kind:       kind,
+				Version:    entry.Name(),
+				Path:       path,
+				Executable: runtimeExecutable(kind, path),
+			})
+		}
+		sort.Slice(found, func(i, j int) bool {
+			switch kind {
+			case RuntimeJava:
+				return CompareJDKVersions(found[i].Version, found[j].Version)
+			case RuntimeNode:
+				return CompareNodeVersions(found[i].Version, found[j].Version)
+			}
+			return found[i].Version > found[j].Version
+		})
+		runtimes = append(runtimes, found...)
+	}
+	return runtimes, nil
+}
+
+// runtimeExecutable finds java, node or ffmpeg inside one runtime build.
+func runtimeExecutable(kind, dir string) string {
+	windows := GetGoos() == "windows"
+	switch kind {
+	case RuntimeJava:
+		exe := "java"
+		if windows && !IsWSL() {
+			exe = "javaw.exe"
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if !entry.IsDir() || (!strings.HasPrefix(entry.Name(), "jdk") && !strings.HasPrefix(entry.Name(), "jre")) {
+				continue
+			}
+			for _, candidate := range []string{
+				filepath.Join(dir, entry.Name(), "bin", exe),
+				filepath.Join(dir, entry.Name(), "Contents", "Home", "bin", exe),
+			} {
+				if _, err := os.Stat(candidate); err == nil {
+					return candidate
+				}
+			}
+		}
+	case RuntimeNode:
+		exe := "node"
+		if windows {
+			exe = "node.exe"
+		}
+		return findNodeExecutable(dir, exe)
+	case RuntimeFFmpeg:
+		exe := "ffmpeg"
+		if windows {
+			exe = "ffmpeg.exe"
+		}
+		candidate := filepath.Join(dir, "bin", exe)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// RuntimeContaining returns the runtime whose directory holds executable, as
+// returned by FindLocalJavaForVersion, FindLocalNodeForVersion or FindLocalFFmpeg.
+func RuntimeContaining(runtimes []InstalledRuntime, executable string) *InstalledRuntime {
+	executable = filepath.Clean(executable)
+	for i := range runtimes {
+		if strings.HasPrefix(executable, filepath.Clean(runtimes[i].Path)+string(filepath.Separator)) {
+			return &runtimes[i]
+		}
+	}
+	return nil
+}
+
+// RuntimeSize returns the disk usage of a runtime build in bytes.
+func RuntimeSize(runtime InstalledRuntime) int64 {
+	var size int64
+	_ = filepath.WalkDir(runtime.Path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// FormatSize renders a byte count as B, KB, MB or GB.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+// VerifyRuntime runs the runtime's executable to print its version and
+// returns the first line of the output.
+func VerifyRuntime(runtime InstalledRuntime) (string, error) {
+	if runtime.Executable == "" {
+		return "", fmt.Errorf("no %s executable in %s", runtime.Kind, runtime.Path)
+	}
+	arg := "-version"
+	if runtime.Kind == RuntimeNode {
+		arg = "--version"
+	}
+	executable := runtime.Executable
+	if runtime.Kind == RuntimeJava && strings.HasSuffix(executable, "javaw.exe") {
+		// javaw has no console output.
+		executable = strings.TrimSuffix(executable, "javaw.exe") + "java.exe"
+	}
+
+	cmd := exec.Command(executable, arg)
+	ConfigureNoConsoleWindow(cmd)
+	done := make(chan struct{})
+	var output []byte
+	var err error
+	go func() {
+		output, err = cmd.CombinedOutput()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		if cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+		<-done
+		return "", fmt.Errorf("%s did not answer within 30s", executable)
+	}
+	first := strings.TrimSpace(strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)[0])
+	if err != nil {
+		return first, fmt.Errorf("%s %s: %w", executable, arg, err)
+	}
+	return first, nil
+}
+
+// RemoveRuntime deletes one runtime build from the runtime directory.
+func RemoveRuntime(kind, version string) error {
+	version = strings.TrimSpace(version)
+	if !IsRuntimeKind(kind) {
+		return fmt.Errorf("unknown runtime %q (use java, node or ffmpeg)", kind)
+	}
+	if version == "" || version == "." || version == ".." || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("invalid %s version %q", kind, version)
+	}
+	dir := filepath.Join(GetRuntimeDir(), kind, version)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s %s is not installed in %s", kind, version, filepath.Dir(dir))
+	}
+	return os.RemoveAll(dir)
+}
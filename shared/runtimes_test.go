@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRuntimeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListInstalledRuntimesFindsBuildsNewestFirst(t *testing.T) {
+	if GetGoos() == "windows" {
+		t.Skip("executable names differ on Windows")
+	}
+	runtimeDir := t.TempDir()
+	t.Setenv("RUNTIME_DIR", runtimeDir)
+
+	writeRuntimeFile(t, filepath.Join(runtimeDir, "java", "jdk-21.0.5+11", "jdk-21.0.5+11", "bin", "java"), 10)
+	writeRuntimeFile(t, filepath.Join(runtimeDir, "java", "jdk-25.0.1+8", "jdk-25.0.1+8", "bin", "java"), 10)
+	writeRuntimeFile(t, filepath.Join(runtimeDir, "node", "v22.11.0", "node-v22.11.0-linux-x64", "bin", "node"), 10)
+	if err := os.MkdirAll(filepath.Join(runtimeDir, "node", "v20.18.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	runtimes, err := ListInstalledRuntimes()
+	if err != nil {
+		t.Fatalf("list runtimes: %v", err)
+	}
+	if len(runtimes) != 4 {
+		t.Fatalf("expected 4 runtimes, got %+v", runtimes)
+	}
+	if runtimes[0].Version != "jdk-25.0.1+8" || runtimes[1].Version != "jdk-21.0.5+11" {
+		t.Fatalf("expected Java newest first, got %s, %s", runtimes[0].Version, runtimes[1].Version)
+	}
+	if runtimes[2].Kind != RuntimeNode || runtimes[2].Version != "v22.11.0" || runtimes[2].Executable == "" {
+		t.Fatalf("unexpected node runtime %+v", runtimes[2])
+	}
+	if runtimes[3].Executable != "" {
+		t.Fatalf("expected incomplete node runtime without executable, got %+v", runtimes[3])
+	}
+
+	exe := filepath.Join(runtimeDir, "java", "jdk-21.0.5+11", "jdk-21.0.5+11", "bin", "java")
+	if found := RuntimeContaining(runtimes, exe); found == nil || found.Version != "jdk-21.0.5+11" {
+		t.Fatalf("expected %s to belong to jdk-21.0.5+11, got %+v", exe, found)
+	}
+	if found := RuntimeContaining(runtimes, "/usr/bin/java"); found != nil {
+		t.Fatalf("expected system java to match no runtime, got %+v", found)
+	}
+	if size := RuntimeSize(runtimes[0]); size != 10 {
+		t.Fatalf("expected size 10, got %d", size)
+	}
+}
+
+func TestRemoveRuntimeRejectsPathsOutsideTheRuntimeDir(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("RUNTIME_DIR", runtimeDir)
+	writeRuntimeFile(t, filepath.Join(runtimeDir, "node", "v22.11.0", "bin", "node"), 1)
+
+	for _, version := range []string{"", "..", "../java", "v22.11.0/bin"} {
+		if err := RemoveRuntime(RuntimeNode, version); err == nil {
+			t.Fatalf("expected version %q to be rejected", version)
+		}
+	}
+	if err := RemoveRuntime("python", "3.12"); err == nil {
+		t.Fatal("expected unknown runtime kind to be rejected")
+	}
+	if err := RemoveRuntime(RuntimeNode, "v22.11.0"); err != nil {
+		t.Fatalf("remove runtime: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(runtimeDir, "node", "v22.11.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected runtime directory to be removed, got %v", err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		512:               "512 B",
+		2048:              "2.0 KB",
+		350 * 1024 * 1024: "350.0 MB",
+		3 << 30:           "3.0 GB",
+	} {
+		if got := FormatSize(bytes); got != want {
+			t.Fatalf("FormatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
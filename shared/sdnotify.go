@@ -0,0 +1,121 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SdNotify sends a state such as "READY=1" to systemd over NOTIFY_SOCKET, as
+// Type=notify units expect. It does nothing when the socket is not set.
+func SdNotify(state string) error {
+	socket := strings.TrimSpace(os.Getenv("NOTIFY_SOCKET"))
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// Abstract namespace socket.
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("connect to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("notify systemd: %w", err)
+	}
+	return nil
+}
+
+// NotifySystemd sends state to systemd and logs a failure instead of
+// returning it, since a missed notification must not stop the module.
+func NotifySystemd(state string) {
+	if err := SdNotify(state); err != nil {
+		log.Printf("systemd notify %q failed: %v", state, err)
+	}
+}
+
+// NotifySystemdReady reports that module is serving on port, so that units
+// depending on it start only now.
+func NotifySystemdReady(module, version, port string) {
+	NotifySystemd(fmt.Sprintf("READY=1\nSTATUS=%s %s ready on port %s", module, version, port))
+}
+
+// SystemdWatchdogInterval returns the WatchdogSec of the unit, or zero when
+// the watchdog is off or meant for another process.
+func SystemdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("WATCHDOG_USEC")), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := strings.TrimSpace(os.Getenv("WATCHDOG_PID")); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// StartSystemdWatchdog pings the systemd watchdog at half its interval for as
+// long as healthy succeeds, so that systemd restarts a module that still runs
+// but no longer answers. The returned function stops the pings.
+func StartSystemdWatchdog(module string, healthy func(timeout time.Duration) error) func() {
+	interval := SystemdWatchdogInterval()
+	if interval <= 0 {
+		return func() {}
+	}
+	probeTimeout := interval / 4
+	if probeTimeout > 10*time.Second {
+		probeTimeout = 10 * time.Second
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := healthy(probeTimeout); err != nil {
+					log.Printf("%s health probe failed, withholding systemd watchdog ping: %v", module, err)
+					NotifySystemd(fmt.Sprintf("STATUS=%s not responding: %v", module, err))
+					continue
+				}
+				NotifySystemd("WATCHDOG=1")
+			}
+		}
+	}()
+	log.Printf("systemd watchdog enabled for %s, interval %v", module, interval)
+
+	stopped := false
+	return func() {
+		if !stopped {
+			stopped = true
+			close(stop)
+		}
+	}
+}
+
+// ProbeHTTP checks that the server on the local port answers an HTTP request
+// within timeout. Any response counts: a hung JVM still accepts connections
+// but never answers.
+func ProbeHTTP(port string, timeout time.Duration) error {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Head("http://" + net.JoinHostPort("localhost", strings.TrimSpace(port)) + "/")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,84 @@
+package shared
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSdNotifyWritesToNotifySocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("systemd notify sockets only exist on Linux")
+	}
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	NotifySystemdReady("OWLCMS", "64.0.0", "8080")
+
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got, want := string(buf[:n]), "READY=1\nSTATUS=OWLCMS 64.0.0 ready on port 8080"; got != want {
+		t.Fatalf("notification = %q, want %q", got, want)
+	}
+}
+
+func TestSdNotifyWithoutSocketDoesNothing(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := SdNotify("READY=1"); err != nil {
+		t.Fatalf("SdNotify without socket: %v", err)
+	}
+}
+
+func TestSystemdWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "90000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := SystemdWatchdogInterval(); got != 90*time.Second {
+		t.Fatalf("interval = %v, want 90s", got)
+	}
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if got := SystemdWatchdogInterval(); got != 0 {
+		t.Fatalf("interval for another PID = %v, want 0", got)
+	}
+	t.Setenv("WATCHDOG_USEC", "")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := SystemdWatchdogInterval(); got != 0 {
+		t.Fatalf("interval without watchdog = %v, want 0", got)
+	}
+}
+
+func TestProbeHTTPAcceptsAnyResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	if err := ProbeHTTP(port, 2*time.Second); err != nil {
+		t.Fatalf("ProbeHTTP: %v", err)
+	}
+
+	// A listener that never answers is what a hung JVM looks like.
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer silent.Close()
+	_, port, _ = net.SplitHostPort(silent.Addr().String())
+	if err := ProbeHTTP(port, 300*time.Millisecond); err == nil {
+		t.Fatal("ProbeHTTP succeeded against a silent listener")
+	}
+}
@@ -0,0 +1,327 @@
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/magiconair/properties"
+)
+
+// SecretsFileName is the secrets store of an instance, in its control panel
+// directory. It is left out of instance exports.
+const SecretsFileName = "secrets.properties"
+
+// SecretsBackendKey chooses where secret values are kept, read from the
+// process environment first and then from the env.properties of the
+// instance's control panel directory.
+const SecretsBackendKey = "CONTROLPANEL_SECRETS_BACKEND"
+
+// Secret storage backends.
+const (
+	// SecretsBackendFile keeps the values in SecretsFileName, readable only
+	// by its owner. This is the default.
+	SecretsBackendFile = "file"
+	// SecretsBackendKeyring keeps the values in the keyring of the operating
+	// system; SecretsFileName then only lists the names.
+	SecretsBackendKeyring = "keyring"
+)
+
+// SecretRefPrefix starts an env.properties value that refers to a secret,
+// as in OWLCMS_DBPASSWORD=secret:db-password. The value is only looked up
+// when a module is launched.
+const SecretRefPrefix = "secret:"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateSecretName checks that name can be used in a secret reference.
+func ValidateSecretName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q; use letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// ParseSecretRef returns the secret name of a secret:<name> value.
+func ParseSecretRef(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, SecretRefPrefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(value, SecretRefPrefix)
+	if ValidateSecretName(name) != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// SecretsPath returns the secrets store of the current instance.
+func SecretsPath() string {
+	return filepath.Join(GetControlPanelInstallDir(), SecretsFileName)
+}
+
+// SecretStore holds the secrets of an instance.
+type SecretStore struct {
+	path    string
+	backend string
+	service string // keyring service of the instance
+	props   *properties.Properties
+}
+
+// LoadSecretStore opens the secrets store of the current instance.
+func LoadSecretStore() (*SecretStore, error) {
+	backend, err := secretsBackend()
+	if err != nil {
+		return nil, err
+	}
+	path := SecretsPath()
+	// Secret values are kept verbatim: ${...} is not expanded.
+	loader := &properties.Loader{Encoding: properties.UTF8, DisableExpansion: true, IgnoreMissing: true}
+	props, err := loader.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return &SecretStore{
+		path:    path,
+		backend: backend,
+		service: "owlcms-controlpanel/" + CurrentInstanceName(),
+		props:   props,
+	}, nil
+}
+
+func secretsBackend() (string, error) {
+	value := strings.TrimSpace(os.Getenv(SecretsBackendKey))
+	if value == "" {
+		props, err := MergeEnvironmentProperties(filepath.Join(GetControlPanelInstallDir(), "env.properties"), "")
+		if err != nil {
+			return "", err
+		}
+		value = strings.TrimSpace(props.GetString(SecretsBackendKey, ""))
+	}
+	switch strings.ToLower(value) {
+	case "", SecretsBackendFile:
+		return SecretsBackendFile, nil
+	case SecretsBackendKeyring:
+		return SecretsBackendKeyring, nil
+	}
+	return "", fmt.Errorf("unknown %s %q; use %s or %s", SecretsBackendKey, value, SecretsBackendFile, SecretsBackendKeyring)
+}
+
+// Backend returns where the values of the store are kept.
+func (s *SecretStore) Backend() string {
+	return s.backend
+}
+
+// Names returns the names of the stored secrets, sorted.
+func (s *SecretStore) Names() []string {
+	names := s.props.Keys()
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a secret named name is stored.
+func (s *SecretStore) Has(name string) bool {
+	_, ok := s.props.Get(name)
+	return ok
+}
+
+// Get returns the value of the secret name.
+func (s *SecretStore) Get(name string) (string, error) {
+	value, ok := s.props.Get(name)
+	if !ok {
+		return "", fmt.Errorf("secret %q is not set; set it with --secret set %s", name, name)
+	}
+	if s.backend == SecretsBackendKeyring {
+		return keyringGet(s.service, name)
+	}
+	return value, nil
+}
+
+// Set stores value as the secret name.
+func (s *SecretStore) Set(name, value string) error {
+	if err := ValidateSecretName(name); err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("secret %q cannot be empty; use --secret unset to remove it", name)
+	}
+	if s.backend == SecretsBackendKeyring {
+		if err := keyringSet(s.service, name, value); err != nil {
+			return err
+		}
+		value = ""
+	}
+	s.props.Set(name, value)
+	return s.save()
+}
+
+// Unset removes the secret name.
+func (s *SecretStore) Unset(name string) error {
+	if !s.Has(name) {
+		return fmt.Errorf("secret %q is not set", name)
+	}
+	if s.backend == SecretsBackendKeyring {
+		if err := keyringDelete(s.service, name); err != nil {
+			return err
+		}
+	}
+	s.props.Delete(name)
+	return s.save()
+}
+
+// save rewrites the store, readable only by its owner.
+func (s *SecretStore) save() error {
+	if err := EnsureDir0755(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	var content bytes.Buffer
+	content.WriteString("# Secrets of the control panel instance; referenced as secret:<name> in env.properties.\n")
+	if _, err := s.props.Write(&content, properties.UTF8); err != nil {
+		return err
+	}
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, content.Bytes(), 0600); err != nil {
+		return fmt.Errorf("write %s: %w", s.path, err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(tempPath, 0600); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replace %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// ResolveSecretValue returns the secret a secret:<name> value refers to, and
+// any other value unchanged.
+func ResolveSecretValue(value string) (string, error) {
+	name, isRef := ParseSecretRef(value)
+	if !isRef {
+		return value, nil
+	}
+	store, err := LoadSecretStore()
+	if err != nil {
+		return "", fmt.Errorf("secrets store: %w", err)
+	}
+	return store.Get(name)
+}
+
+// InjectSecrets replaces the secret:<name> values of a launch environment by
+// the secrets they refer to. The store is only opened when env refers to one.
+func InjectSecrets(env []string) ([]string, error) {
+	var store *SecretStore
+	for i, entry := range env {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		name, isRef := ParseSecretRef(value)
+		if !isRef {
+			continue
+		}
+		if store == nil {
+			var err error
+			if store, err = LoadSecretStore(); err != nil {
+				return nil, fmt.Errorf("secrets store: %w", err)
+			}
+		}
+		secret, err := store.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		env[i] = key + "=" + secret
+	}
+	return env, nil
+}
+
+// The keyring backend drives the secret-tool command on Linux and the
+// security command on macOS.
+
+func keyringSet(service, name, value string) error {
+	switch GetGoos() {
+	case "linux":
+		cmd := exec.Command("secret-tool", "store", "--label="+service+" "+name, "service", service, "name", name)
+		cmd.Stdin = strings.NewReader(value)
+		return runKeyringCommand(cmd)
+	case "darwin":
+		cmd, err := macKeyringSetCommand(service, name, value)
+		if err != nil {
+			return err
+		}
+		if err := runKeyringCommand(cmd); err != nil {
+			return err
+		}
+		// security -i reports a failed command on its output, not in its exit
+		// status, so the value is read back to confirm it was stored.
+		stored, err := keyringGet(service, name)
+		if err != nil || stored != value {
+			return fmt.Errorf("secret %q was not stored in the keychain", name)
+		}
+		return nil
+	}
+	return errKeyringUnsupported()
+}
+
+// macKeyringSetCommand stores a password with security -i, which reads its
+// commands from stdin and needs no terminal, so the value never appears in
+// the argument list of a process.
+func macKeyringSetCommand(service, name, value string) (*exec.Cmd, error) {
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return nil, fmt.Errorf("secret %q cannot contain line breaks in the keychain", name)
+	}
+	line := strings.Join([]string{"add-generic-password", "-U", "-s", securityQuote(service), "-a", securityQuote(name), "-w", securityQuote(value)}, " ")
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(line + "\n")
+	return cmd, nil
+}
+
+// securityQuote quotes an argument of a security -i command line.
+func securityQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func keyringGet(service, name string) (string, error) {
+	var cmd *exec.Cmd
+	switch GetGoos() {
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "name", name)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", name, "-w")
+	default:
+		return "", errKeyringUnsupported()
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("secret %q not found in the keyring: %w", name, err)
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+func keyringDelete(service, name string) error {
+	switch GetGoos() {
+	case "linux":
+		return runKeyringCommand(exec.Command("secret-tool", "clear", "service", service, "name", name))
+	case "darwin":
+		return runKeyringCommand(exec.Command("security", "delete-generic-password", "-s", service, "-a", name))
+	}
+	return errKeyringUnsupported()
+}
+
+func runKeyringCommand(cmd *exec.Cmd) error {
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w (%s)", filepath.Base(cmd.Path), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func errKeyringUnsupported() error {
+	return fmt.Errorf("%s=%s is only supported on Linux and macOS", SecretsBackendKey, SecretsBackendKeyring)
+}
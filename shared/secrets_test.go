@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretStoreInjectsReferences(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONTROLPANEL_INSTALLDIR", dir)
+	t.Setenv(SecretsBackendKey, "")
+
+	store, err := LoadSecretStore()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := store.Set("db-password", "p@ss${word}"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := store.Set("bad name", "x"); err == nil {
+		t.Fatal("expected an invalid name to be rejected")
+	}
+	info, err := os.Stat(filepath.Join(dir, SecretsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetGoos() != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("secrets store mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	env, err := InjectSecrets([]string{"OWLCMS_DBPASSWORD=secret:db-password", "OWLCMS_PORT=8080"})
+	if err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	if strings.Join(env, " ") != "OWLCMS_DBPASSWORD=p@ss${word} OWLCMS_PORT=8080" {
+		t.Fatalf("unexpected environment %q", env)
+	}
+
+	if err := store.Unset("db-password"); err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+	if _, err := InjectSecrets([]string{"OWLCMS_DBPASSWORD=secret:db-password"}); err == nil || !strings.Contains(err.Error(), "OWLCMS_DBPASSWORD") {
+		t.Fatalf("expected a missing secret to stop the launch, got %v", err)
+	}
+}
+
+func TestResolveSecretValue(t *testing.T) {
+	t.Setenv("CONTROLPANEL_INSTALLDIR", t.TempDir())
+	t.Setenv(SecretsBackendKey, "")
+
+	store, err := LoadSecretStore()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := store.Set("mqtt-password", "hunter2"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if value, err := ResolveSecretValue("secret:mqtt-password"); err != nil || value != "hunter2" {
+		t.Fatalf("resolve reference: %q %v", value, err)
+	}
+	if value, err := ResolveSecretValue("plain"); err != nil || value != "plain" {
+		t.Fatalf("resolve plain value: %q %v", value, err)
+	}
+	if _, err := ResolveSecretValue("secret:missing"); err == nil {
+		t.Fatal("expected a missing secret to be reported")
+	}
+}
+
+func TestMacKeyringSetKeepsTheValueOutOfTheArguments(t *testing.T) {
+	cmd, err := macKeyringSetCommand("owlcms-controlpanel", "mqtt", `pa ss"w\rd`)
+	if err != nil {
+		t.Fatalf("build command: %v", err)
+	}
+	if strings.Join(cmd.Args, " ") != "security -i" {
+		t.Fatalf("expected the value to stay out of the arguments, got %q", cmd.Args)
+	}
+	input, err := io.ReadAll(cmd.Stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `add-generic-password -U -s "owlcms-controlpanel" -a "mqtt" -w "pa ss\"w\\rd"` + "\n"
+	if string(input) != want {
+		t.Fatalf("stdin = %q, want %q", input, want)
+	}
+
+	if _, err := macKeyringSetCommand("owlcms-controlpanel", "mqtt", "two\nlines"); err == nil {
+		t.Fatal("expected a value with a line break to be refused")
+	}
+}
@@ -0,0 +1,192 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// EnvSettingsEditor edits the EnvSchema settings of one env.properties layer
+// of a module: the parent file, or the release file of a version.
+type EnvSettingsEditor struct {
+	Title          string
+	Module         string
+	ParentEnvPath  string
+	ReleaseEnvPath string // empty to edit the parent file
+	// Save writes a value to the edited layer, through the module's
+	// SaveProperty or SavePropertyForRelease.
+	Save func(key, value string) error
+	// Remove deletes a key from the edited layer so that the lower layer
+	// applies again. When nil, a cleared value is saved as empty.
+	Remove func(key string) error
+	// OnSaved is called after changes were written.
+	OnSaved func(changed []string)
+}
+
+// ShowEnvSettingsEditor shows every known setting of the module with its
+// value and the layer it comes from, and saves the edited values after
+// validating them against the schema.
+func ShowEnvSettingsEditor(w fyne.Window, editor EnvSettingsEditor) {
+	values, err := ResolveEnvSettings(editor.Module, editor.ParentEnvPath, editor.ReleaseEnvPath)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	editedLayer := EnvOriginParent
+	if editor.ReleaseEnvPath != "" {
+		editedLayer = EnvOriginRelease
+	}
+
+	type settingRow struct {
+		value   EnvSettingValue
+		entry   *widget.SelectEntry
+		initial string
+	}
+	var rows []settingRow
+	form := widget.NewForm()
+	for _, value := range values {
+		choices := value.Choices
+		if value.Type == EnvTypeBool {
+			choices = []string{"true", "false"}
+		}
+		entry := widget.NewSelectEntry(choices)
+		initial := ""
+		if value.Origin == editedLayer {
+			initial = value.Value
+		} else if value.Value != "" {
+			entry.SetPlaceHolder(value.Value)
+		}
+		entry.SetText(initial)
+
+		item := widget.NewFormItem(value.Key, entry)
+		item.HintText = value.Description + " " + describeEnvOrigin(value, editedLayer)
+		form.AppendItem(item)
+		rows = append(rows, settingRow{value: value, entry: entry, initial: initial})
+	}
+
+	file := editor.ParentEnvPath
+	if editor.ReleaseEnvPath != "" {
+		file = editor.ReleaseEnvPath
+	}
+	header := widget.NewLabel(fmt.Sprintf("Editing %s. Greyed values come from a lower layer and apply while the field is empty.", file))
+	header.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(form)
+	scroll.SetMinSize(fyne.NewSize(700, 450))
+
+	d := dialog.NewCustomConfirm(editor.Title, "Save", "Cancel", container.NewBorder(header, nil, nil, nil, scroll), func(ok bool) {
+		if !ok {
+			return
+		}
+		var problems []string
+		for _, row := range rows {
+			if text := strings.TrimSpace(row.entry.Text); text != row.initial {
+				if err := row.value.Validate(text); err != nil {
+					problems = append(problems, err.Error())
+				}
+			}
+		}
+		if len(problems) > 0 {
+			dialog.ShowError(fmt.Errorf("settings not saved:\n%s", strings.Join(problems, "\n")), w)
+			return
+		}
+
+		var changed []string
+		for _, row := range rows {
+			text := strings.TrimSpace(row.entry.Text)
+			if text == row.initial {
+				continue
+			}
+			var err error
+			if text == "" && editor.Remove != nil {
+				err = editor.Remove(row.value.Key)
+			} else {
+				err = editor.Save(row.value.Key, text)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save %s: %w", row.value.Key, err), w)
+				return
+			}
+			changed = append(changed, row.value.Key)
+		}
+		if len(changed) == 0 {
+			return
+		}
+		if editor.OnSaved != nil {
+			editor.OnSaved(changed)
+		}
+		dialog.ShowInformation(editor.Title, fmt.Sprintf("Saved %s. Restart the module to apply the changes.", strings.Join(changed, ", ")), w)
+	}, w)
+	d.Resize(fyne.NewSize(760, 600))
+	d.Show()
+}
+
+// describeEnvOrigin explains where the effective value of a setting comes
+// from, relative to the layer being edited.
+func describeEnvOrigin(value EnvSettingValue, editedLayer string) string {
+	switch {
+	case value.Origin == editedLayer:
+		return "(set here)"
+	case value.Origin == EnvOriginParent:
+		return "(inherited from the shared env.properties)"
+	case value.Value != "":
+		return "(default)"
+	}
+	return "(not set)"
+}
+
+// ShowLaunchEnvironment lists the variables a launch passes to a module
+// process, with where each one comes from, as described by DescribeLaunchEnv.
+func ShowLaunchEnvironment(w fyne.Window, title string, vars []LaunchEnvVar) {
+	filter := widget.NewEntry()
+	filter.SetPlaceHolder("Filter by name or value")
+	shown := vars
+
+	table := widget.NewTable(
+		func() (int, int) { return len(shown) + 1, 3 },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, object fyne.CanvasObject) {
+			label := object.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText([]string{"Variable", "Value", "Origin"}[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			v := shown[id.Row-1]
+			switch id.Col {
+			case 0:
+				label.SetText(v.Key)
+			case 1:
+				label.SetText(v.Value)
+			default:
+				label.SetText(v.DescribeOrigin())
+			}
+		},
+	)
+	table.SetColumnWidth(0, 260)
+	table.SetColumnWidth(1, 360)
+	table.SetColumnWidth(2, 260)
+
+	filter.OnChanged = func(text string) {
+		text = strings.ToLower(strings.TrimSpace(text))
+		shown = nil
+		for _, v := range vars {
+			if text == "" || strings.Contains(strings.ToLower(v.Key+"="+v.Value), text) {
+				shown = append(shown, v)
+			}
+		}
+		table.Refresh()
+	}
+
+	d := dialog.NewCustom(title, "Close", container.NewBorder(filter, nil, nil, nil, table), w)
+	d.Resize(fyne.NewSize(940, 620))
+	d.Show()
+}
@@ -0,0 +1,210 @@
+package shared
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VideoActionResult describes the installed cameras or replays version
+// affected by a non-UI action.
+type VideoActionResult struct {
+	Version      string
+	Path         string
+	ConfigCopied bool
+}
+
+// VideoModuleActions installs, updates, imports and removes the versions of
+// a video module (cameras or replays) without any UI.
+type VideoModuleActions struct {
+	Module            string // "cameras" or "replays", used in messages and events
+	InstallDir        string
+	DownloadURLPrefix string // followed by /<version>/<BinaryName>
+	BinaryName        string // the platform binary of the release
+	// ConfigArtifacts are the files and directories of a version directory
+	// that hold its configuration, copied on update and import.
+	ConfigArtifacts []string
+	// ExtractConfig writes the editable config files of a fresh install.
+	ExtractConfig func(binaryPath, versionDir string) error
+	// EnsureFFmpeg makes sure FFmpeg is available after an install.
+	EnsureFFmpeg func() error
+}
+
+// download fetches the platform binary of version into versionDir.
+func (m VideoModuleActions) download(version, versionDir string, progress ProgressCallback, cancel <-chan bool) (string, error) {
+	url := fmt.Sprintf("%s/%s/%s", m.DownloadURLPrefix, version, m.BinaryName)
+	binaryPath := filepath.Join(versionDir, m.BinaryName)
+	log.Printf("Downloading %s from: %s", m.Module, url)
+	if err := DownloadArchive(url, binaryPath, progress, cancel); err != nil {
+		return "", fmt.Errorf("%s download failed: %w", m.Module, err)
+	}
+	if GetGoos() != "windows" {
+		os.Chmod(binaryPath, 0755)
+	}
+	return binaryPath, nil
+}
+
+// updateTargetVersion keeps the build suffix of existingVersion on the
+// target, so that a custom build is updated to a custom build.
+func (m VideoModuleActions) updateTargetVersion(existingVersion, targetVersion string) string {
+	targetBaseVersion, _ := ParseVersionWithBuild(targetVersion)
+	existingBuild := GetCurrentBuildString(existingVersion)
+	if existingBuild == "" {
+		return targetVersion
+	}
+	resolvedBuild := ResolveCollisionForBuild(m.InstallDir, targetBaseVersion, existingBuild)
+	return fmt.Sprintf("%s+%s", targetBaseVersion, resolvedBuild)
+}
+
+// copyConfig copies the config artifacts of srcDir that exist to destDir.
+func (m VideoModuleActions) copyConfig(srcDir, destDir string) error {
+	copiedAny := false
+	for _, name := range m.ConfigArtifacts {
+		src := filepath.Join(srcDir, name)
+		info, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		dst := filepath.Join(destDir, name)
+		if info.IsDir() {
+			err = CopyDir(src, dst)
+		} else {
+			err = CopyFile(src, dst)
+		}
+		if err != nil {
+			return err
+		}
+		copiedAny = true
+	}
+	if !copiedAny {
+		return fmt.Errorf("no config artifacts found in %s", srcDir)
+	}
+	return nil
+}
+
+// InstallRelease downloads the binary of downloadVersion as installVersion,
+// extracts its editable config files and makes sure FFmpeg is available.
+func (m VideoModuleActions) InstallRelease(downloadVersion, installVersion string, progress ProgressCallback) (VideoActionResult, error) {
+	downloadVersion = strings.TrimSpace(downloadVersion)
+	installVersion = strings.TrimSpace(installVersion)
+	if downloadVersion == "" {
+		return VideoActionResult{}, fmt.Errorf("download version is required")
+	}
+	if installVersion == "" {
+		installVersion = downloadVersion
+	}
+
+	versionDir := filepath.Join(m.InstallDir, installVersion)
+	if _, err := os.Stat(versionDir); err == nil {
+		return VideoActionResult{}, fmt.Errorf("%s version %q already exists", m.Module, installVersion)
+	} else if !os.IsNotExist(err) {
+		return VideoActionResult{}, fmt.Errorf("checking install directory %s: %w", versionDir, err)
+	}
+	if err := EnsureDir0755(versionDir); err != nil {
+		return VideoActionResult{}, fmt.Errorf("creating version directory: %w", err)
+	}
+
+	binaryPath, err := m.download(downloadVersion, versionDir, progress, nil)
+	if err != nil {
+		_ = os.RemoveAll(versionDir)
+		return VideoActionResult{}, err
+	}
+	if err := m.ExtractConfig(binaryPath, versionDir); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return VideoActionResult{}, fmt.Errorf("failed to extract %s config files: %w", m.Module, err)
+	}
+	if err := m.EnsureFFmpeg(); err != nil {
+		return VideoActionResult{}, fmt.Errorf("FFmpeg installation failed: %w", err)
+	}
+
+	EmitModuleEvent(ModuleEvent{Event: EventInstalled, Module: m.Module, Version: installVersion})
+	return VideoActionResult{Version: installVersion, Path: versionDir}, nil
+}
+
+// UpdateRelease downloads targetVersion and copies the configuration from
+// existingVersion.
+func (m VideoModuleActions) UpdateRelease(existingVersion, targetVersion string, progress ProgressCallback, cancel <-chan bool) (VideoActionResult, error) {
+	existingVersion = strings.TrimSpace(existingVersion)
+	targetVersion = strings.TrimSpace(targetVersion)
+	if existingVersion == "" {
+		return VideoActionResult{}, fmt.Errorf("source version is required")
+	}
+	if targetVersion == "" {
+		return VideoActionResult{}, fmt.Errorf("target version is required")
+	}
+
+	existingDir := filepath.Join(m.InstallDir, existingVersion)
+	if info, err := os.Stat(existingDir); err != nil {
+		return VideoActionResult{}, fmt.Errorf("source version %q not found: %w", existingVersion, err)
+	} else if !info.IsDir() {
+		return VideoActionResult{}, fmt.Errorf("source version %q is not a directory", existingVersion)
+	}
+
+	targetInstallVersion := m.updateTargetVersion(existingVersion, targetVersion)
+	newVersionDir := filepath.Join(m.InstallDir, targetInstallVersion)
+	if _, err := os.Stat(newVersionDir); err == nil {
+		return VideoActionResult{}, fmt.Errorf("target install version %q already exists", targetInstallVersion)
+	} else if !os.IsNotExist(err) {
+		return VideoActionResult{}, fmt.Errorf("checking target install directory: %w", err)
+	}
+	if err := EnsureDir0755(newVersionDir); err != nil {
+		return VideoActionResult{}, fmt.Errorf("creating version directory: %w", err)
+	}
+
+	if _, err := m.download(targetVersion, newVersionDir, progress, cancel); err != nil {
+		_ = os.RemoveAll(newVersionDir)
+		return VideoActionResult{}, err
+	}
+
+	result := VideoActionResult{Version: targetInstallVersion, Path: newVersionDir}
+	if err := m.copyConfig(existingDir, newVersionDir); err != nil {
+		log.Printf("No config to copy from %s: %v", existingDir, err)
+	} else {
+		result.ConfigCopied = true
+	}
+	EmitModuleEvent(ModuleEvent{Event: EventUpdateInstalled, Module: m.Module, Version: targetInstallVersion, Detail: "updated from " + existingVersion})
+	return result, nil
+}
+
+// ImportDataAndConfig copies the config artifacts of sourceVersion to
+// destVersion.
+func (m VideoModuleActions) ImportDataAndConfig(sourceVersion, destVersion string) (VideoActionResult, error) {
+	sourceVersion = strings.TrimSpace(sourceVersion)
+	destVersion = strings.TrimSpace(destVersion)
+	sourceDir := filepath.Join(m.InstallDir, sourceVersion)
+	destDir := filepath.Join(m.InstallDir, destVersion)
+	if info, err := os.Stat(sourceDir); err != nil {
+		return VideoActionResult{}, fmt.Errorf("source version %q does not exist: %w", sourceVersion, err)
+	} else if !info.IsDir() {
+		return VideoActionResult{}, fmt.Errorf("source version %q is not a directory", sourceVersion)
+	}
+	if info, err := os.Stat(destDir); err != nil {
+		return VideoActionResult{}, fmt.Errorf("destination version %q does not exist: %w", destVersion, err)
+	} else if !info.IsDir() {
+		return VideoActionResult{}, fmt.Errorf("destination version %q is not a directory", destVersion)
+	}
+
+	if err := m.copyConfig(sourceDir, destDir); err != nil {
+		return VideoActionResult{}, err
+	}
+	return VideoActionResult{Version: destVersion, Path: destDir, ConfigCopied: true}, nil
+}
+
+// RemoveInstalledVersion removes an installed version directory.
+func (m VideoModuleActions) RemoveInstalledVersion(version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("version is required")
+	}
+	dir := filepath.Join(m.InstallDir, version)
+	if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("%s version %q is not installed: %w", m.Module, version, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s version %q is not a directory", m.Module, version)
+	}
+	return os.RemoveAll(dir)
+}
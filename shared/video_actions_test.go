@@ -0,0 +1,150 @@
+package shared
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestVideoActions serves a fake replays binary for every version and
+// extracts a config.toml and a config/ directory on install.
+func newTestVideoActions(t *testing.T) (VideoModuleActions, *[]string) {
+	t.Helper()
+	var downloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads = append(downloads, r.URL.Path)
+		if strings.Contains(r.URL.Path, "/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("binary " + r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	actions := VideoModuleActions{
+		Module:            "replays",
+		InstallDir:        t.TempDir(),
+		DownloadURLPrefix: server.URL + "/download",
+		BinaryName:        "replays_linux_amd64",
+		ConfigArtifacts:   []string{"config.toml", "config"},
+		ExtractConfig: func(binaryPath, versionDir string) error {
+			if err := os.WriteFile(filepath.Join(versionDir, "config.toml"), []byte("port = 8091\n"), 0o644); err != nil {
+				return err
+			}
+			return os.MkdirAll(filepath.Join(versionDir, "config"), 0o755)
+		},
+		EnsureFFmpeg: func() error { return nil },
+	}
+	return actions, &downloads
+}
+
+func TestVideoInstallRelease(t *testing.T) {
+	actions, downloads := newTestVideoActions(t)
+
+	result, err := actions.InstallRelease("2.0.0", "", nil)
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if result.Version != "2.0.0" || result.Path != filepath.Join(actions.InstallDir, "2.0.0") {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if (*downloads)[0] != "/download/2.0.0/replays_linux_amd64" {
+		t.Fatalf("unexpected download %v", *downloads)
+	}
+	binary, err := os.ReadFile(filepath.Join(result.Path, "replays_linux_amd64"))
+	if err != nil || !strings.Contains(string(binary), "2.0.0") {
+		t.Fatalf("binary not downloaded: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.Path, "config.toml")); err != nil {
+		t.Fatalf("config not extracted: %v", err)
+	}
+
+	if _, err := actions.InstallRelease("2.0.0", "2.0.0", nil); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an already exists error, got %v", err)
+	}
+}
+
+func TestVideoInstallReleaseCleansUpOnFailure(t *testing.T) {
+	actions, _ := newTestVideoActions(t)
+	if _, err := actions.InstallRelease("missing", "", nil); err == nil {
+		t.Fatal("expected a download error")
+	}
+	if _, err := os.Stat(filepath.Join(actions.InstallDir, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("expected the version directory to be removed, got %v", err)
+	}
+
+	actions.ExtractConfig = func(string, string) error { return errors.New("bad binary") }
+	if _, err := actions.InstallRelease("2.0.0", "", nil); err == nil || !strings.Contains(err.Error(), "bad binary") {
+		t.Fatalf("expected an extract error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(actions.InstallDir, "2.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected the version directory to be removed, got %v", err)
+	}
+}
+
+func TestVideoUpdateReleaseCopiesConfig(t *testing.T) {
+	actions, _ := newTestVideoActions(t)
+	oldDir := filepath.Join(actions.InstallDir, "1.9.0")
+	if err := os.MkdirAll(filepath.Join(oldDir, "config", "cameras"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "config.toml"), []byte("port = 9000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "config", "cameras", "left.toml"), []byte("name = \"left\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := actions.UpdateRelease("1.9.0", "2.0.0", nil, nil)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result.Version != "2.0.0" || !result.ConfigCopied {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	config, err := os.ReadFile(filepath.Join(result.Path, "config.toml"))
+	if err != nil || string(config) != "port = 9000\n" {
+		t.Fatalf("config.toml not copied: %q %v", config, err)
+	}
+	if _, err := os.Stat(filepath.Join(result.Path, "config", "cameras", "left.toml")); err != nil {
+		t.Fatalf("config/ not copied: %v", err)
+	}
+
+	if _, err := actions.UpdateRelease("1.8.0", "2.0.1", nil, nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected a missing source error, got %v", err)
+	}
+}
+
+func TestVideoImportDataAndConfig(t *testing.T) {
+	actions, _ := newTestVideoActions(t)
+	for _, version := range []string{"1.9.0", "2.0.0", "2.1.0"} {
+		if err := os.MkdirAll(filepath.Join(actions.InstallDir, version), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(actions.InstallDir, "1.9.0", "config.toml"), []byte("port = 9000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := actions.ImportDataAndConfig("1.9.0", "2.0.0")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if !result.ConfigCopied {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(actions.InstallDir, "2.0.0", "config.toml")); err != nil {
+		t.Fatalf("config.toml not imported: %v", err)
+	}
+
+	if _, err := actions.ImportDataAndConfig("2.1.0", "2.0.0"); err == nil || !strings.Contains(err.Error(), "no config artifacts") {
+		t.Fatalf("expected an empty source error, got %v", err)
+	}
+	if _, err := actions.ImportDataAndConfig("1.9.0", "3.0.0"); err == nil || !strings.Contains(err.Error(), "destination") {
+		t.Fatalf("expected a missing destination error, got %v", err)
+	}
+}
@@ -30,17 +30,7 @@ func BuildVideoLaunchEnv(versionDir string) []string {
 	// Export the shared FFmpeg path so child processes find it directly.
 	if ffmpegPath := FindLocalFFmpeg(); ffmpegPath != "" {
 		env = UpsertEnv(env, "VIDEO_FFMPEG_PATH", ffmpegPath)
-		// For Linux shared builds, prepend the bundled lib/ to LD_LIBRARY_PATH.
-		if GetGoos() == "linux" {
-			libDir := filepath.Join(filepath.Dir(filepath.Dir(ffmpegPath)), "lib")
-			if st, err := os.Stat(libDir); err == nil && st.IsDir() {
-				if existing := os.Getenv("LD_LIBRARY_PATH"); existing != "" {
-					env = UpsertEnv(env, "LD_LIBRARY_PATH", fmt.Sprintf("%s:%s", libDir, existing))
-				} else {
-					env = UpsertEnv(env, "LD_LIBRARY_PATH", libDir)
-				}
-			}
-		}
+		env = withFFmpegLibraryPath(env, ffmpegPath)
 	}
 
 	parentEnvPath := filepath.Join(filepath.Dir(versionDir), "env.properties")
@@ -52,6 +42,29 @@ func BuildVideoLaunchEnv(versionDir string) []string {
 	return env
 }
 
+// DescribeVideoLaunchEnv returns the variables a cameras or replays launch
+// from versionDir would pass to the process, with where each one comes from.
+func DescribeVideoLaunchEnv(versionDir string) []LaunchEnvVar {
+	return DescribeLaunchEnv(BuildVideoLaunchEnv(versionDir),
+		filepath.Join(filepath.Dir(versionDir), "env.properties"), filepath.Join(versionDir, "env.properties"), nil)
+}
+
+// withFFmpegLibraryPath prepends the bundled lib/ of a Linux shared FFmpeg
+// build to LD_LIBRARY_PATH.
+func withFFmpegLibraryPath(env []string, ffmpegPath string) []string {
+	if GetGoos() != "linux" {
+		return env
+	}
+	libDir := filepath.Join(filepath.Dir(filepath.Dir(ffmpegPath)), "lib")
+	if st, err := os.Stat(libDir); err != nil || !st.IsDir() {
+		return env
+	}
+	if existing := os.Getenv("LD_LIBRARY_PATH"); existing != "" {
+		return UpsertEnv(env, "LD_LIBRARY_PATH", fmt.Sprintf("%s:%s", libDir, existing))
+	}
+	return UpsertEnv(env, "LD_LIBRARY_PATH", libDir)
+}
+
 // ShouldRunVideoExtract determines whether launchers should run --extractConfig preflight.
 // app must be "replays" or "cameras".
 // FFmpeg availability is handled separately by EnsureFFmpegPrerequisite.
@@ -29,6 +29,15 @@ func ensureReleaseCatalog() ([]string, error) {
 	return allReleases, nil
 }
 
+// ListAvailableReleases returns the releases published on GitHub, newest first.
+func ListAvailableReleases() ([]string, error) {
+	releases, err := ensureReleaseCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), releases...), nil
+}
+
 // ResolveInstallRelease resolves a GitHub release selector for a clean install.
 func ResolveInstallRelease(selector string) (string, error) {
 	selector = strings.TrimSpace(selector)
@@ -104,6 +113,7 @@ func InstallRelease(downloadVersion, installVersion string, downloadProgress, ex
 		return ActionResult{}, fmt.Errorf("extraction failed: %w", err)
 	}
 
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventInstalled, Module: "tracker", Version: installVersion})
 	return ActionResult{Version: installVersion, Path: extractPath}, nil
 }
 
@@ -175,6 +185,7 @@ func UpdateRelease(existingVersion, targetVersion string, downloadProgress, extr
 	}
 
 	success = true
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: shared.EventUpdateInstalled, Module: "tracker", Version: targetInstallVersion, Detail: "updated from " + existingVersion})
 	return result, nil
 }
 
@@ -249,3 +260,32 @@ func RemoveInstalledVersion(version string) error {
 	}
 	return os.RemoveAll(dir)
 }
+
+// EnsureNodeForRelease returns the Node.js runtime required by a release,
+// downloading it into the shared runtime directory when missing.
+func EnsureNodeForRelease(version string) (string, error) {
+	var required string
+	if props, err := loadEnvironmentForReleaseProps(version); err != nil {
+		return "", err
+	} else if props != nil {
+		required = strings.TrimSpace(props.GetString("NODE_VERSION", ""))
+	}
+	nodePath, err := shared.FindNodeForVersion(required, shared.GetGoos)
+	if err == nil {
+		return nodePath, nil
+	}
+	if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+		return "", err
+	}
+
+	target := required
+	if target == "" {
+		latest, err := shared.FindLatestNodeRelease("")
+		if err != nil {
+			return "", fmt.Errorf("finding latest Node.js release: %w", err)
+		}
+		target = latest
+	}
+	log.Printf("Node.js %s not found locally, downloading", target)
+	return shared.DownloadAndInstallNode(target, nil)
+}
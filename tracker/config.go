@@ -77,6 +77,14 @@ func GetPortForRelease(releaseVersion string) string {
 	return strings.TrimSpace(port)
 }
 
+// selectFreePortForRelease moves releaseVersion to the next free port when
+// its port is busy and returns the port to launch on.
+func selectFreePortForRelease(releaseVersion string) (string, error) {
+	return shared.SelectFreeModulePort("tracker", releaseVersion, GetPortForRelease(releaseVersion), func(port string) error {
+		return SavePropertyForRelease(releaseVersion, "TRACKER_PORT", port)
+	})
+}
+
 // GetRunAsDaemon returns true if the control panel should leave OWLCMS and Tracker
 // running after window or terminal closure on Linux.
 func GetRunAsDaemon() bool {
@@ -16,9 +16,14 @@ import (
 
 // DownloadArchive downloads a zip file from the given URL and saves it to the specified path.
 // Includes progress callback support.
-func DownloadArchive(url, destPath string, progressCallback func(downloaded, total int64), cancelChan chan struct{}) error {
+func DownloadArchive(url, destPath string, progressCallback func(downloaded, total int64), cancelChan chan struct{}) (err error) {
 	log.Printf("Attempting to download from URL: %s\n", url)
 
+	var downloaded int64
+	defer func() {
+		shared.RecordDownload(downloaded, err)
+	}()
+
 	client := &http.Client{
 		Timeout: 300 * time.Second, // Set a longer timeout for larger files
 	}
@@ -43,7 +48,6 @@ func DownloadArchive(url, destPath string, progressCallback func(downloaded, tot
 	totalSize := resp.ContentLength
 
 	// Create a progress writer
-	var downloaded int64
 	buf := make([]byte, 32*1024) // 32KB buffer
 	for {
 		// Check for cancellation
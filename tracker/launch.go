@@ -16,6 +16,7 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/gofrs/flock"
+	"github.com/magiconair/properties"
 )
 
 func configureTailLogLink(version, appDir string) {
@@ -98,6 +99,9 @@ func prepareTrackerLaunch(version string) (*trackerLaunchParams, error) {
 	if _, err := os.Stat(mainScript); os.IsNotExist(err) {
 		return nil, fmt.Errorf("start-with-ws.js not found in %s", versionDir)
 	}
+	if err := shared.CheckConfigBeforeLaunch("tracker", installDir, version); err != nil {
+		return nil, err
+	}
 
 	if err := LoadEnvironmentForRelease(version); err != nil {
 		return nil, fmt.Errorf("failed to initialize environment: %w", err)
@@ -122,21 +126,9 @@ func prepareTrackerLaunch(version string) (*trackerLaunchParams, error) {
 		}
 	}
 
-	// Build environment
-	env := shared.NormalizeChildProcessEnv(os.Environ())
-	lv := shared.GetLauncherVersionSemver()
-	env = append(env, fmt.Sprintf("TRACKER_LAUNCHER=%s", lv))
-	env = append(env, fmt.Sprintf("OWLCMS_CONTROLPANEL=%s", lv))
-	env = shared.UpsertEnv(env, "PORT", targetPort)
-	if environment != nil {
-		for _, key := range environment.Keys() {
-			value, _ := environment.Get(key)
-			log.Printf("   %s=%s", key, value)
-			if key == "TRACKER_PORT" {
-				continue
-			}
-			env = append(env, fmt.Sprintf("%s=%s", key, value))
-		}
+	env, err := shared.InjectSecrets(buildTrackerEnv(environment, targetPort))
+	if err != nil {
+		return nil, err
 	}
 
 	var requiredNodeVersion string
@@ -156,6 +148,42 @@ func prepareTrackerLaunch(version string) (*trackerLaunchParams, error) {
 	}, nil
 }
 
+// buildTrackerEnv returns the environment of the tracker process for the
+// merged env.properties of a version.
+func buildTrackerEnv(props *properties.Properties, targetPort string) []string {
+	env := shared.NormalizeChildProcessEnv(os.Environ())
+	lv := shared.GetLauncherVersionSemver()
+	env = append(env, fmt.Sprintf("TRACKER_LAUNCHER=%s", lv))
+	env = append(env, fmt.Sprintf("OWLCMS_CONTROLPANEL=%s", lv))
+	env = shared.UpsertEnv(env, "PORT", targetPort)
+	if props != nil {
+		for _, key := range props.Keys() {
+			value, _ := props.Get(key)
+			log.Printf("   %s=%s", key, value)
+			if key == "TRACKER_PORT" {
+				continue
+			}
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	return env
+}
+
+// EffectiveLaunchEnvironment returns the variables a launch of version would
+// pass to the tracker, with where each one comes from.
+func EffectiveLaunchEnvironment(version string) ([]shared.LaunchEnvVar, error) {
+	versionDir := filepath.Join(installDir, version)
+	if _, err := os.Stat(versionDir); err != nil {
+		return nil, fmt.Errorf("version directory not found: %s", versionDir)
+	}
+	merged, err := loadEnvironmentForReleaseProps(version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	}
+	env := buildTrackerEnv(merged, GetPortForRelease(version))
+	return shared.DescribeLaunchEnv(env, filepath.Join(installDir, "env.properties"), filepath.Join(versionDir, "env.properties"), nil), nil
+}
+
 // recordTrackerStart writes the PID file and runtime metadata after a successful cmd.Start().
 func recordTrackerStart(pid int, version, port string, daemon bool) *shared.RuntimeMetadata {
 	if err := os.WriteFile(pidFilePath, []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
@@ -166,6 +194,8 @@ func recordTrackerStart(pid int, version, port string, daemon bool) *shared.Runt
 
 	SaveLastRunVersion(version)
 
+	emitTrackerEvent(shared.EventStarted, version, port, pid, "")
+
 	metadata, err := shared.WriteRuntimeMetadata(runtimeMetadataPath(), pid, version, port, daemon)
 	if err != nil {
 		log.Printf("Failed to write tracker runtime metadata: %v", err)
@@ -174,6 +204,11 @@ func recordTrackerStart(pid int, version, port string, daemon bool) *shared.Runt
 	return metadata
 }
 
+// emitTrackerEvent reports a lifecycle event of a tracker process.
+func emitTrackerEvent(event, version, port string, pid int, detail string) {
+	shared.EmitModuleEvent(shared.ModuleEvent{Event: event, Module: "tracker", Version: version, Port: port, PID: pid, Detail: detail})
+}
+
 // SaveLastRunVersion persists the version so that --tracker previous can find it.
 func SaveLastRunVersion(version string) {
 	p := filepath.Join(installDir, "last-version.txt")
@@ -193,8 +228,9 @@ func GetLastRunVersion() string {
 }
 
 // LaunchDaemon starts the tracker headlessly (no UI) in daemon mode.
-// It assumes Node.js is already installed locally.
-func LaunchDaemon(version string) error {
+// It assumes Node.js is already installed locally. With autoPort, a busy port
+// is replaced by the next free one.
+func LaunchDaemon(version string, autoPort bool) error {
 	log.Printf("LaunchDaemon: starting tracker %s headlessly", version)
 
 	initConfig()
@@ -202,6 +238,12 @@ func LaunchDaemon(version string) error {
 		return fmt.Errorf("failed to initialize environment: %w", err)
 	}
 
+	if autoPort {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			return err
+		}
+	}
+
 	params, err := prepareTrackerLaunch(version)
 	if err != nil {
 		return err
@@ -211,9 +253,9 @@ func LaunchDaemon(version string) error {
 		return fmt.Errorf("port %s is already in use", params.TargetPort)
 	}
 
-	nodePath, err := shared.FindLocalNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
+	nodePath, err := shared.FindNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
 	if err != nil {
-		return fmt.Errorf("node.js not found locally: %w", err)
+		return fmt.Errorf("node.js not found: %w", err)
 	}
 
 	if shared.GetGoos() != "windows" {
@@ -254,6 +296,8 @@ func LaunchDaemon(version string) error {
 	for time.Now().Before(deadline) {
 		if shared.CheckPort(params.TargetPort) == nil {
 			log.Printf("LaunchDaemon: tracker %s ready on port %s (PID %d)", version, params.TargetPort, pid)
+			shared.MarkRuntimeReady(runtimeMetadataPath())
+			emitTrackerEvent(shared.EventReady, version, params.TargetPort, pid, "")
 			return nil
 		}
 		if !shared.IsProcessRunning(pid) {
@@ -265,7 +309,8 @@ func LaunchDaemon(version string) error {
 }
 
 // LaunchForeground starts Tracker from the command line and blocks until it exits.
-func LaunchForeground(version string) error {
+// With autoPort, a busy port is replaced by the next free one.
+func LaunchForeground(version string, autoPort bool) error {
 	log.Printf("LaunchForeground: starting tracker %s", version)
 
 	initConfig()
@@ -273,6 +318,12 @@ func LaunchForeground(version string) error {
 		return fmt.Errorf("failed to initialize environment: %w", err)
 	}
 
+	if autoPort {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			return err
+		}
+	}
+
 	params, err := prepareTrackerLaunch(version)
 	if err != nil {
 		return err
@@ -287,9 +338,9 @@ func LaunchForeground(version string) error {
 		}
 	}
 
-	nodePath, err := shared.FindLocalNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
+	nodePath, err := shared.FindNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
 	if err != nil {
-		return fmt.Errorf("node.js not found locally: %w", err)
+		return fmt.Errorf("node.js not found: %w", err)
 	}
 	if shared.GetGoos() != "windows" {
 		os.Chmod(nodePath, 0755)
@@ -309,7 +360,9 @@ func LaunchForeground(version string) error {
 
 	pid := cmd.Process.Pid
 	activeRuntime = recordTrackerStart(pid, version, params.TargetPort, false)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 	log.Printf("LaunchForeground: tracker %s (PID %d), waiting for port %s...", version, pid, params.TargetPort)
+	shared.NotifySystemd(fmt.Sprintf("STATUS=Tracker %s starting on port %s", version, params.TargetPort))
 
 	deadline := time.Now().Add(30 * time.Second)
 	ready := false
@@ -323,13 +376,30 @@ func LaunchForeground(version string) error {
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
-	if ready {
+	announceReady := func() {
 		log.Printf("LaunchForeground: tracker %s ready on port %s (PID %d)", version, params.TargetPort, pid)
+		shared.MarkRuntimeReady(runtimeMetadataPath())
+		emitTrackerEvent(shared.EventReady, version, params.TargetPort, pid, "")
+		shared.NotifySystemdReady("Tracker", version, params.TargetPort)
 		fmt.Printf("tracker %s started successfully\n", version)
 	}
+	if ready {
+		announceReady()
+	} else if shared.IsProcessRunning(pid) {
+		// systemd allows more time than this wait; READY=1 is still due
+		// when the port opens.
+		log.Printf("LaunchForeground: tracker %s not answering on port %s yet, still waiting", version, params.TargetPort)
+		go shared.AwaitPortReady(params.TargetPort, pid, announceReady)
+	}
+	stopWatchdog := shared.StartSystemdWatchdog("Tracker", func(timeout time.Duration) error {
+		return shared.ProbeHTTP(params.TargetPort, timeout)
+	})
 
 	waitErr := cmd.Wait()
+	stopWatchdog()
+	shared.NotifySystemd("STOPPING=1")
 	clearRuntimeState()
+	emitTrackerEvent(shared.ExitEvent(waitErr, false), version, params.TargetPort, pid, shared.EventDetail(waitErr))
 	if waitErr == nil {
 		log.Printf("LaunchForeground: tracker %s exited normally", version)
 		return nil
@@ -375,6 +445,16 @@ func reconnectTrackerRuntime() bool {
 	return true
 }
 
+// ReconnectRuntime attaches the tab to a process started outside of it, such
+// as by a launch profile, unless the tab already tracks a running process.
+// Must be called on the UI thread.
+func ReconnectRuntime() {
+	if IsRunning() {
+		return
+	}
+	reconnectTrackerRuntime()
+}
+
 func acquireTrackerLock() (*flock.Flock, error) {
 	pid, source, err := shared.ResolvePIDFromFileOrPort(pidFilePath, GetPort())
 	if err != nil {
@@ -431,6 +511,14 @@ func launchTracker(version string, launchButton, stopBtn *widget.Button) error {
 		return err
 	}
 
+	if shared.IsAutoPortEnabled() {
+		if _, err := selectFreePortForRelease(version); err != nil {
+			goBackToMainScreen()
+			releaseTrackerLock()
+			return err
+		}
+	}
+
 	targetPort := GetPortForRelease(version)
 
 	// Free port if already in use
@@ -499,8 +587,14 @@ func continueTrackerLaunch(version, targetPort string, launchButton, stopBtn *wi
 
 	// Find Node.js locally, download if needed
 	var nodeExe string
-	nodePath, err := shared.FindLocalNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
+	nodePath, err := shared.FindNodeForVersion(params.RequiredNodeVer, shared.GetGoos)
 	if err != nil {
+		if err := shared.CheckRuntimeDownloadAllowed(err); err != nil {
+			launchButton.Show()
+			goBackToMainScreen()
+			dialog.ShowError(err, mainWindow)
+			return
+		}
 		// No suitable Node.js found, download appropriate version
 		var targetVersion string
 		if params.RequiredNodeVer != "" {
@@ -625,6 +719,7 @@ func continueTrackerLaunch(version, targetPort string, launchButton, stopBtn *wi
 	// Store the PID in the PID file and globally (after Start() succeeds)
 	nodePID = cmd.Process.Pid
 	activeRuntime = recordTrackerStart(nodePID, version, targetPort, false)
+	shared.MarkRuntimeSupervised(runtimeMetadataPath())
 
 	log.Printf("Launching owlcms-tracker %s (PID: %d), waiting for port %s...\n", version, nodePID, targetPort)
 	statusLabel.SetText(fmt.Sprintf("Starting owlcms-tracker %s (PID: %d), waiting for port %s.\nFull startup can take up to 15 seconds.", version, nodePID, targetPort))
@@ -656,6 +751,7 @@ func continueTrackerLaunch(version, targetPort string, launchButton, stopBtn *wi
 			log.Printf("owlcms-tracker process %d failed to start properly: %v\n", nodePID, err)
 			currentProcess = nil
 			clearRuntimeState()
+			emitTrackerEvent(shared.ExitEvent(err, killedByUs), version, targetPort, cmd.Process.Pid, shared.EventDetail(err))
 			fyne.Do(func() {
 				statusLabel.SetText(fmt.Sprintf("owlcms-tracker process %d failed to start properly", nodePID))
 				stopBtn.Hide()
@@ -668,6 +764,8 @@ func continueTrackerLaunch(version, targetPort string, launchButton, stopBtn *wi
 		}
 
 		log.Printf("owlcms-tracker process %d is ready (port %s responding)\n", nodePID, targetPort)
+		shared.MarkRuntimeReady(runtimeMetadataPath())
+		emitTrackerEvent(shared.EventReady, version, targetPort, cmd.Process.Pid, "")
 		url := fmt.Sprintf("http://localhost:%s", targetPort)
 		fyne.Do(func() {
 			statusLabel.SetText(fmt.Sprintf("owlcms-tracker running (PID: %d) on port %s", nodePID, targetPort))
@@ -713,6 +811,7 @@ func continueTrackerLaunch(version, targetPort string, launchButton, stopBtn *wi
 			exitMessage = fmt.Sprintf("owlcms-tracker %s (PID: %d) exited normally", version, pid)
 		}
 
+		emitTrackerEvent(shared.ExitEvent(err, killedByUs), version, targetPort, pid, shared.EventDetail(err))
 		currentProcess = nil
 		killedByUs = false // Reset flag
 		clearRuntimeState()
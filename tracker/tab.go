@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -338,7 +339,10 @@ func createMenuBar(w fyne.Window) *fyne.Container {
 	setPortItem := fyne.NewMenuItem("Port Number", func() {
 		showPortNumberDialog(w)
 	})
-	optionsMenuItems := []*fyne.MenuItem{setPortItem}
+	settingsItem := fyne.NewMenuItem("All Settings", func() {
+		showSettingsEditor(w, "")
+	})
+	optionsMenuItems := []*fyne.MenuItem{setPortItem, settingsItem}
 	optionsMenu := shared.CreateMenuButton("Options", optionsMenuItems)
 
 	// Add small vertical padding
@@ -382,6 +386,10 @@ func showPortNumberDialog(w fyne.Window) {
 				return
 			}
 
+			if err := shared.ClaimPort("tracker", "", newPort); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
 			if err := SaveProperty("TRACKER_PORT", newPort); err != nil {
 				dialog.ShowError(fmt.Errorf("failed to save tracker port: %w", err), w)
 				return
@@ -393,6 +401,34 @@ func showPortNumberDialog(w fyne.Window) {
 	)
 }
 
+// showSettingsEditor edits the shared env.properties, or the one of version
+// when it is set.
+func showSettingsEditor(w fyne.Window, version string) {
+	editor := shared.EnvSettingsEditor{
+		Title:         "Tracker Settings",
+		Module:        "tracker",
+		ParentEnvPath: filepath.Join(installDir, "env.properties"),
+		Save:          SaveProperty,
+	}
+	if version != "" {
+		editor.Title = fmt.Sprintf("Tracker %s Settings", version)
+		editor.ReleaseEnvPath = filepath.Join(installDir, version, "env.properties")
+		editor.Save = func(key, value string) error {
+			return SavePropertyForRelease(version, key, value)
+		}
+	}
+	save := editor.Save
+	editor.Save = func(key, value string) error {
+		if key == "TRACKER_PORT" && value != "" {
+			if err := shared.ClaimPort("tracker", version, value); err != nil {
+				return err
+			}
+		}
+		return save(key, value)
+	}
+	shared.ShowEnvSettingsEditor(w, editor)
+}
+
 func refreshAvailableVersions(w fyne.Window) {
 	go func() {
 		releases, err := fetchReleases()
@@ -577,3 +613,14 @@ func resetToExplainMode() {
 	versionContainer.Show()
 	versionContainer.Refresh()
 }
+
+// showEffectiveSettings lists the variables a launch of version passes to
+// Tracker, with where each one comes from.
+func showEffectiveSettings(w fyne.Window, version string) {
+	vars, err := EffectiveLaunchEnvironment(version)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	shared.ShowLaunchEnvironment(w, fmt.Sprintf("Tracker %s Effective Settings", version), vars)
+}
@@ -162,6 +162,7 @@ func createVersionList(w fyne.Window, stopBtn *widget.Button) *widget.List {
 			buttonContainer.RemoveAll()
 
 			createLaunchButton(w, version, stopBtn, buttonContainer)
+			createVersionOptionsButton(w, version, buttonContainer)
 			createFilesButton(version, w, buttonContainer)
 			if len(allReleases) > 0 {
 				createUpdateButton(version, w, buttonContainer)
@@ -203,6 +204,19 @@ func createVersionList(w fyne.Window, stopBtn *widget.Button) *widget.List {
 	return versionList
 }
 
+func createVersionOptionsButton(w fyne.Window, version string, buttonContainer *fyne.Container) {
+	menuItems := []*fyne.MenuItem{
+		fyne.NewMenuItem("All Settings", func() {
+			showSettingsEditor(w, version)
+		}),
+		fyne.NewMenuItem("Effective Settings", func() {
+			showEffectiveSettings(w, version)
+		}),
+	}
+
+	buttonContainer.Add(container.NewPadded(shared.CreateMenuButton("Options", menuItems)))
+}
+
 func createImportButton(versions []string, version string, w fyne.Window, buttonContainer *fyne.Container) {
 	importButton := widget.NewButton("Import", nil)
 	importButton.Show()